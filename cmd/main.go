@@ -8,6 +8,7 @@ import (
 	authmodule "gitlab.com/balconygames/analytics/modules/auth"
 	leaderboardmodule "gitlab.com/balconygames/analytics/modules/leaderboard"
 	primarymodule "gitlab.com/balconygames/analytics/modules/primary"
+	remoteconfigmodule "gitlab.com/balconygames/analytics/modules/remoteconfig"
 	"gitlab.com/balconygames/analytics/pkg/runtime"
 )
 
@@ -32,6 +33,7 @@ func run() error {
 		authmodule.New,
 		primarymodule.New,
 		leaderboardmodule.New,
+		remoteconfigmodule.New,
 	}
 
 	for _, m := range runtimes {
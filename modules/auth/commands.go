@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/modules/auth/internal/service"
+	"gitlab.com/balconygames/analytics/pkg/runtime"
+)
+
+// inviteCommand creates invitation without signed in admin, used to
+// bootstrap the first operator of organisation created in primary
+// module, organisation of platform operators is used by default:
+//
+//	analytics auth.invite -email=admin@example.com -roles=admin -games=*
+func inviteCommand(svc *service.Service, platformID string) runtime.CommandFunc {
+	return func(ctx context.Context, args []string) error {
+		flags := flag.NewFlagSet("auth.invite", flag.ContinueOnError)
+		organisationID := flags.String("organisation", platformID, "organisation id")
+		email := flags.String("email", "", "email of operator")
+		roles := flags.String("roles", "", "comma separated roles")
+		games := flags.String("games", "", "comma separated game ids")
+
+		err := flags.Parse(args)
+		if err != nil {
+			return err
+		}
+
+		if *organisationID == "" || *email == "" || *roles == "" || *games == "" {
+			flags.Usage()
+			return errors.New("organisation, email, roles and games are required")
+		}
+
+		invitation := &models.Invitation{
+			OrganisationID: *organisationID,
+			Email:          *email,
+			Roles:          splitList(*roles),
+			Games:          splitList(*games),
+		}
+
+		err = svc.Invite(ctx, invitation)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("organisation: %s\n", invitation.OrganisationID)
+		fmt.Printf("invitation token: %s\n", invitation.Token)
+		fmt.Printf("expires at: %s\n", invitation.ExpiresAt)
+
+		return nil
+	}
+}
+
+func splitList(s string) []string {
+	out := []string{}
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
+
+// deletionsCommand deletes personal data of users with passed grace
+// period of deletion request:
+//
+//	analytics auth.deletions
+func deletionsCommand(svc *service.Service) runtime.CommandFunc {
+	return func(ctx context.Context, args []string) error {
+		deleted, err := svc.RunDueDeletions(ctx)
+		fmt.Printf("deleted users: %d\n", deleted)
+
+		return err
+	}
+}
+
+// rebuildAliasesCommand sets aliases of merged users in redis by aliases
+// stored in postgres:
+//
+//	analytics auth.aliases.rebuild
+func rebuildAliasesCommand(svc *service.Service) runtime.CommandFunc {
+	return func(ctx context.Context, args []string) error {
+		rebuilt, err := svc.RebuildAliases(ctx)
+		fmt.Printf("rebuilt aliases: %d\n", rebuilt)
+
+		return err
+	}
+}
+
+// rotateTOTPCommand re-encrypts secrets of second factor stored plain
+// or encrypted by previous keys with the current key:
+//
+//	analytics auth.totp.rotate
+func rotateTOTPCommand(svc *service.Service) runtime.CommandFunc {
+	return func(ctx context.Context, args []string) error {
+		rotated, err := svc.RotateTOTPSecrets(ctx)
+		fmt.Printf("rotated secrets: %d\n", rotated)
+
+		return err
+	}
+}
+
+// backfillPropertiesCommand imports properties sections stored only
+// in redis into postgres:
+//
+//	analytics auth.props.backfill
+func backfillPropertiesCommand(svc *service.Service) runtime.CommandFunc {
+	return func(ctx context.Context, args []string) error {
+		result, err := svc.BackfillProperties(ctx)
+		if result != nil {
+			fmt.Printf("imported sections: %d\n", result.Imported)
+			fmt.Printf("skipped sections: %d\n", result.Skipped)
+		}
+
+		return err
+	}
+}
+
+// migratePropertiesCommand moves properties sections stored by legacy
+// keys into keys namespaced by game and app, values of shared sections
+// are moved into shared values:
+//
+//	analytics auth.props.migrate
+func migratePropertiesCommand(svc *service.Service) runtime.CommandFunc {
+	return func(ctx context.Context, args []string) error {
+		result, err := svc.MigratePropertyKeys(ctx)
+		if result != nil {
+			fmt.Printf("migrated sections: %d\n", result.Imported)
+			fmt.Printf("skipped sections: %d\n", result.Skipped)
+			fmt.Printf("shared sections: %d\n", result.Shared)
+		}
+
+		return err
+	}
+}
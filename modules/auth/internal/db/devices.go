@@ -0,0 +1,220 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// upsertDevice records the device of the user, empty attributes
+// keep values of the previous sync. Revoked devices stay revoked and
+// ErrDeviceRevoked is returned for them, only reactivate used after
+// the user is authenticated again makes them active.
+func upsertDevice(ctx context.Context, tx pgx.Tx, device *models.Device, reactivate bool) error {
+	if device.DeviceID == "" {
+		return nil
+	}
+
+	query := `
+		INSERT INTO
+			users_devices (
+				user_id
+				, game_id
+				, app_id
+				, device_id
+				, platform
+				, app_version
+				, os_version
+				, push_token
+			)
+			VALUES (
+				$1
+				, $2
+				, $3
+				, $4
+				, $5
+				, $6
+				, $7
+				, $8
+			)
+		ON CONFLICT (
+			game_id
+			, app_id
+			, user_id
+			, device_id
+		)
+		DO UPDATE SET
+			platform = COALESCE(NULLIF(EXCLUDED.platform, ''), users_devices.platform)
+			, app_version = COALESCE(NULLIF(EXCLUDED.app_version, ''), users_devices.app_version)
+			, os_version = COALESCE(NULLIF(EXCLUDED.os_version, ''), users_devices.os_version)
+			, push_token = COALESCE(NULLIF(EXCLUDED.push_token, ''), users_devices.push_token)
+			, last_seen_at = NOW()
+			, revoked_at = CASE WHEN $9 THEN NULL ELSE users_devices.revoked_at END
+		RETURNING first_seen_at, last_seen_at, revoked_at IS NOT NULL
+	`
+
+	var revoked bool
+	err := tx.QueryRow(ctx, query, device.UserID, device.GameID, device.AppID, device.DeviceID,
+		device.Platform, device.AppVersion, device.OSVersion, device.PushToken, reactivate).
+		Scan(&device.FirstSeenAt, &device.LastSeenAt, &revoked)
+	if err != nil {
+		return err
+	}
+
+	if revoked {
+		return models.ErrDeviceRevoked
+	}
+
+	return nil
+}
+
+// findDeviceGuest sets id and name of the guest who used the device the
+// last time. Guests of other apps of the game are used in case if game
+// has game scoped guests, the guest of the current app is preferred.
+// Revoked devices and guests with linked identities are not recognised,
+// they should sign in again.
+func findDeviceGuest(ctx context.Context, tx pgx.Tx, user *models.User) (bool, error) {
+	query := `
+		WITH device AS (
+			SELECT d.user_id, d.app_id
+			FROM users_devices d
+			WHERE
+				d.game_id=$1
+				AND d.device_id=$3
+				AND (d.app_id=$2 OR (` + guestScopeQuery + `)=$4)
+				AND d.revoked_at IS NULL
+				AND NOT EXISTS (
+					SELECT 1
+					FROM user_identities i
+					WHERE
+						i.game_id=d.game_id
+						AND i.app_id=d.app_id
+						AND i.user_id=d.user_id
+				)
+			ORDER BY d.app_id=$2 DESC, d.last_seen_at DESC
+			LIMIT 1
+		)
+		` + reuseGuestQuery + `
+	`
+
+	err := tx.QueryRow(ctx, query, user.GameID, user.AppID, user.DeviceID, models.GuestScopeGame).
+		Scan(&user.UserID, &user.Name)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// UpdateDevice updates attributes of the device used by the user.
+func (r PostgresRepository) UpdateDevice(ctx context.Context, device *models.Device) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = upsertDevice(ctx, tx, device, false)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ListDevices respond with devices used by the user, the last
+// used device goes first.
+func (r PostgresRepository) ListDevices(ctx context.Context, scope *sharedmodels.Scope) ([]*models.Device, error) {
+	query := `
+		SELECT
+			device_id
+			, platform
+			, app_version
+			, os_version
+			, push_token
+			, first_seen_at
+			, last_seen_at
+			, revoked_at
+		FROM users_devices
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+		ORDER BY last_seen_at DESC
+	`
+
+	rows, err := r.pool.Query(ctx, query, scope.GameID, scope.AppID, scope.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []*models.Device{}
+	for rows.Next() {
+		device := &models.Device{Scope: *scope}
+		err = rows.Scan(&device.DeviceID, &device.Platform, &device.AppVersion, &device.OSVersion,
+			&device.PushToken, &device.FirstSeenAt, &device.LastSeenAt, &device.RevokedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		devices = append(devices, device)
+	}
+
+	return devices, rows.Err()
+}
+
+// RevokeDevice marks the device of the user as revoked, push token
+// is removed to stop notifications.
+func (r PostgresRepository) RevokeDevice(ctx context.Context, scope *sharedmodels.Scope, deviceID string) error {
+	query := `
+		UPDATE users_devices
+		SET
+			revoked_at = COALESCE(revoked_at, NOW())
+			, push_token = ''
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+			AND device_id=$4
+	`
+
+	tag, err := r.pool.Exec(ctx, query, scope.GameID, scope.AppID, scope.UserID, deviceID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return models.ErrDeviceNotFound
+	}
+
+	return nil
+}
+
+// IsDeviceRevoked checks if the device of the user is revoked,
+// unknown devices are not revoked.
+func (r PostgresRepository) IsDeviceRevoked(ctx context.Context, scope *sharedmodels.Scope, deviceID string) (bool, error) {
+	query := `
+		SELECT revoked_at IS NOT NULL
+		FROM users_devices
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+			AND device_id=$4
+	`
+
+	var revoked bool
+	err := r.pool.QueryRow(ctx, query, scope.GameID, scope.AppID, scope.UserID, deviceID).Scan(&revoked)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+
+	return revoked, err
+}
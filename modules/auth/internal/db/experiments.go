@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+)
+
+// ListExperiments respond with experiments of the game.
+func (r PostgresRepository) ListExperiments(ctx context.Context, gameID string) ([]*models.Experiment, error) {
+	query := `
+		SELECT
+			game_id
+			, key
+			, description
+			, status
+			, variants
+			, created_at
+			, updated_at
+		FROM experiments
+		WHERE game_id=$1
+		ORDER BY key
+	`
+
+	rows, err := r.pool.Query(ctx, query, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*models.Experiment{}
+	for rows.Next() {
+		experiment := &models.Experiment{}
+
+		var variants []byte
+		err = rows.Scan(&experiment.GameID, &experiment.Key, &experiment.Description,
+			&experiment.Status, &variants, &experiment.CreatedAt, &experiment.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(variants, &experiment.Variants)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, experiment)
+	}
+
+	return list, rows.Err()
+}
+
+// UpsertExperiment creates or updates experiment of the game.
+func (r PostgresRepository) UpsertExperiment(ctx context.Context, experiment *models.Experiment) error {
+	variants, err := json.Marshal(experiment.Variants)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO
+			experiments (
+				game_id
+				, key
+				, description
+				, status
+				, variants
+			)
+			VALUES (
+				$1
+				, $2
+				, $3
+				, $4
+				, $5
+			)
+		ON CONFLICT (
+			game_id
+			, key
+		)
+		DO UPDATE SET
+			description = EXCLUDED.description
+			, status = EXCLUDED.status
+			, variants = EXCLUDED.variants
+			, updated_at = NOW()
+		RETURNING created_at, updated_at
+	`
+
+	return r.pool.QueryRow(ctx, query, experiment.GameID, experiment.Key,
+		experiment.Description, experiment.Status, variants).
+		Scan(&experiment.CreatedAt, &experiment.UpdatedAt)
+}
+
+// DeleteExperiment removes experiment of the game.
+func (r PostgresRepository) DeleteExperiment(ctx context.Context, gameID, key string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM experiments WHERE game_id=$1 AND key=$2`, gameID, key)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return models.ErrExperimentNotFound
+	}
+
+	return nil
+}
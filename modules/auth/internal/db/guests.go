@@ -0,0 +1,191 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/jackc/pgx/v4"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// guestScopeQuery selects guest scope of the game $1.
+const guestScopeQuery = `
+	SELECT COALESCE((SELECT scope FROM guest_policies WHERE game_id=$1), 'app')
+`
+
+// reuseGuestQuery creates the guest of the app $2 from the guest of
+// the app selected by `device` query with user_id and app_id, the same
+// guest is touched only. Parameters $1 and $3 are game and device.
+const reuseGuestQuery = `
+	INSERT INTO
+		anonymouses (
+			user_id
+			, game_id
+			, app_id
+			, device_id
+			, name
+		)
+	SELECT
+		a.user_id
+		, a.game_id
+		, $2
+		, $3
+		, a.name
+	FROM device
+	JOIN anonymouses a ON
+		a.game_id=$1
+		AND a.app_id=device.app_id
+		AND a.user_id=device.user_id
+	ON CONFLICT (
+		game_id
+		, app_id
+		, user_id
+	)
+	DO UPDATE SET updated_at = NOW()
+	RETURNING user_id, name
+`
+
+// lockDevice serializes syncs of the device of the game
+// until the end of transaction.
+func lockDevice(ctx context.Context, tx pgx.Tx, gameID, deviceID string) error {
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1 || ':' || $2))`, gameID, deviceID)
+	return err
+}
+
+// recoveryCodeHash is using hash of the code to don't store
+// codes as is.
+func recoveryCodeHash(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetGuestPolicy respond with guest policy of the game, games without
+// policy use app scoped guests.
+func (r PostgresRepository) GetGuestPolicy(ctx context.Context, gameID string) (*models.GuestPolicy, error) {
+	policy := &models.GuestPolicy{GameID: gameID}
+
+	err := r.pool.QueryRow(ctx, `SELECT scope, updated_at FROM guest_policies WHERE game_id=$1`, gameID).
+		Scan(&policy.Scope, &policy.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		policy.Scope = models.GuestScopeApp
+		return policy, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// UpsertGuestPolicy creates or updates guest policy of the game.
+func (r PostgresRepository) UpsertGuestPolicy(ctx context.Context, policy *models.GuestPolicy) error {
+	query := `
+		INSERT INTO
+			guest_policies (
+				game_id
+				, scope
+			)
+			VALUES (
+				$1
+				, $2
+			)
+		ON CONFLICT (
+			game_id
+		)
+		DO UPDATE SET
+			scope = EXCLUDED.scope
+			, updated_at = NOW()
+		RETURNING updated_at
+	`
+
+	return r.pool.QueryRow(ctx, query, policy.GameID, policy.Scope).Scan(&policy.UpdatedAt)
+}
+
+// SetRecoveryCode replaces recovery code of the user.
+func (r PostgresRepository) SetRecoveryCode(ctx context.Context, scope *sharedmodels.Scope, code string) error {
+	query := `
+		INSERT INTO
+			guest_recovery_codes (
+				user_id
+				, game_id
+				, app_id
+				, code_hash
+			)
+			VALUES (
+				$1
+				, $2
+				, $3
+				, $4
+			)
+		ON CONFLICT (
+			game_id
+			, app_id
+			, user_id
+		)
+		DO UPDATE SET
+			code_hash = EXCLUDED.code_hash
+			, created_at = NOW()
+			, used_at = NULL
+	`
+
+	_, err := r.pool.Exec(ctx, query, scope.UserID, scope.GameID, scope.AppID, recoveryCodeHash(code))
+	return err
+}
+
+// RecoverGuest sets id and name of the guest by recovery code and
+// records the device of the user for the guest, so the device is
+// recognised by the following syncs. Revoked device is active again
+// because the recovery code authenticates the guest. Codes of other apps of the game
+// are accepted in case if game has game scoped guests.
+func (r PostgresRepository) RecoverGuest(ctx context.Context, user *models.User, code string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = lockDevice(ctx, tx, user.GameID, user.DeviceID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		WITH device AS (
+			UPDATE guest_recovery_codes c
+			SET used_at = NOW()
+			WHERE
+				c.game_id=$1
+				AND c.code_hash=$4
+				AND (c.app_id=$2 OR (` + guestScopeQuery + `)=$5)
+				AND NOT EXISTS (
+					SELECT 1
+					FROM user_identities i
+					WHERE
+						i.game_id=c.game_id
+						AND i.app_id=c.app_id
+						AND i.user_id=c.user_id
+				)
+			RETURNING c.user_id, c.app_id
+		)
+		` + reuseGuestQuery + `
+	`
+
+	err = tx.QueryRow(ctx, query, user.GameID, user.AppID, user.DeviceID,
+		recoveryCodeHash(code), models.GuestScopeGame).Scan(&user.UserID, &user.Name)
+	if err == pgx.ErrNoRows {
+		return models.ErrInvalidRecoveryCode
+	}
+	if err != nil {
+		return err
+	}
+
+	err = upsertDevice(ctx, tx, user.Device(), true)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
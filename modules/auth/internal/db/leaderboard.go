@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// LeaderboardRepository gives access to scores stored by leaderboard
+// module. Keys should be in sync with modules/leaderboard/internal/db/redis.go.
+type LeaderboardRepository struct {
+	conn *redis.Client
+
+	logger *zap.SugaredLogger
+}
+
+func NewLeaderboardRepository(c *redis.Client, l *zap.SugaredLogger) *LeaderboardRepository {
+	return &LeaderboardRepository{c, l.With("scope", "redis.leaderboard")}
+}
+
+func scoreKey(leaderboardID string) string {
+	return fmt.Sprintf("scores:%s", leaderboardID)
+}
+
+func scoreUserKey(leaderboardID, userID string) string {
+	return fmt.Sprintf("users:%s:%s", leaderboardID, userID)
+}
+
+func userLeaderboardsKey(userID string) string {
+	return fmt.Sprintf("user_leaderboards:%s", userID)
+}
+
+// ListLeaderboards respond with leaderboard ids where the user has score.
+func (r *LeaderboardRepository) ListLeaderboards(ctx context.Context, userID string) ([]string, error) {
+	ids, err := r.conn.SMembers(ctx, userLeaderboardsKey(userID)).Result()
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't get leaderboards of the user")
+	}
+
+	return ids, nil
+}
+
+// MoveScores moves scores of one user to another one, the best score
+// is kept in case if both users have score in the same leaderboard.
+func (r *LeaderboardRepository) MoveScores(ctx context.Context, fromUserID, toUserID string) error {
+	ids, err := r.ListLeaderboards(ctx, fromUserID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		err = r.moveScore(ctx, id, fromUserID, toUserID)
+		if err != nil {
+			return errors.WithMessagef(err, "can't move score of leaderboard %s", id)
+		}
+	}
+
+	return nil
+}
+
+func (r *LeaderboardRepository) moveScore(ctx context.Context, leaderboardID, fromUserID, toUserID string) error {
+	log := r.logger.With("leaderboard_id", leaderboardID, "from_user_id", fromUserID, "to_user_id", toUserID)
+
+	fromScore, err := r.conn.ZScore(ctx, scoreKey(leaderboardID), fromUserID).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	fromExists := err == nil
+
+	toScore, err := r.conn.ZScore(ctx, scoreKey(leaderboardID), toUserID).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	toExists := err == nil
+
+	pipe := r.conn.TxPipeline()
+
+	if fromExists && (!toExists || fromScore > toScore) {
+		attrs, err := r.conn.HGetAll(ctx, scoreUserKey(leaderboardID, fromUserID)).Result()
+		if err != nil {
+			return err
+		}
+
+		var values []string
+		for key, value := range attrs {
+			values = append(values, key, value)
+		}
+		values = append(values, "user_id", toUserID)
+
+		log.Debugf("move score %v", values)
+
+		pipe.ZAdd(ctx, scoreKey(leaderboardID), &redis.Z{Score: fromScore, Member: toUserID})
+		pipe.HSet(ctx, scoreUserKey(leaderboardID, toUserID), values)
+		pipe.SAdd(ctx, userLeaderboardsKey(toUserID), leaderboardID)
+	}
+
+	pipe.ZRem(ctx, scoreKey(leaderboardID), fromUserID)
+	pipe.Del(ctx, scoreUserKey(leaderboardID, fromUserID))
+	pipe.SRem(ctx, userLeaderboardsKey(fromUserID), leaderboardID)
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// RenameUser sets name of the user in all leaderboards where
+// the user has score.
+func (r *LeaderboardRepository) RenameUser(ctx context.Context, userID, name string) error {
+	ids, err := r.ListLeaderboards(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	pipe := r.conn.Pipeline()
+	for _, id := range ids {
+		pipe.HSet(ctx, scoreUserKey(id, userID), "name", name)
+	}
+
+	_, err = pipe.Exec(ctx)
+	return errors.WithMessage(err, "can't set name in leaderboards")
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+const magicLinkTokenSize = 32
+
+// magicLinkKey is using hash of the token to don't store
+// tokens as is.
+func magicLinkKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("magic:%s", hex.EncodeToString(sum[:]))
+}
+
+// CreateMagicLink generates one-time token of magic link
+// expiring after ttl.
+func (r *RedisRepository) CreateMagicLink(ctx context.Context, link *models.MagicLink, ttl time.Duration) error {
+	token, err := randomToken(magicLinkTokenSize)
+	if err != nil {
+		return errors.WithMessage(err, "can't generate magic link token")
+	}
+
+	key := magicLinkKey(token)
+
+	pipe := r.conn.TxPipeline()
+	pipe.HSet(ctx, key,
+		"email", link.Email,
+		"gid", link.GameID,
+		"aid", link.AppID,
+		"did", link.DeviceID,
+		"guid", link.GuestID,
+	)
+	pipe.Expire(ctx, key, ttl)
+
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "can't exec pipeline to store magic link")
+	}
+
+	link.Token = token
+
+	return nil
+}
+
+// UseMagicLink respond with magic link and deletes it, only the
+// first use of token succeeds.
+func (r *RedisRepository) UseMagicLink(ctx context.Context, token string) (*models.MagicLink, error) {
+	key := magicLinkKey(token)
+
+	pipe := r.conn.TxPipeline()
+	attrs := pipe.HGetAll(ctx, key)
+	deleted := pipe.Del(ctx, key)
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't exec pipeline to use magic link")
+	}
+
+	if deleted.Val() == 0 {
+		return nil, models.ErrInvalidMagicLink
+	}
+
+	values := attrs.Val()
+
+	return &models.MagicLink{
+		Scope: sharedmodels.Scope{
+			GameID: values["gid"],
+			AppID:  values["aid"],
+		},
+		Email:    values["email"],
+		DeviceID: values["did"],
+		GuestID:  values["guid"],
+	}, nil
+}
+
+func magicLinkRequestsKey(kind, value string) string {
+	return fmt.Sprintf("magic:requests:%s:%s", kind, value)
+}
+
+// AddMagicLinkRequest counts magic link requests of the email and ip
+// address, the window starts on the first request.
+func (r *RedisRepository) AddMagicLinkRequest(ctx context.Context, email, ip string,
+	window time.Duration) (int64, int64, error) {
+	counts := make([]int64, 2)
+
+	for i, key := range []string{magicLinkRequestsKey("email", email), magicLinkRequestsKey("ip", ip)} {
+		if i == 1 && ip == "" {
+			break
+		}
+
+		count, err := r.conn.Incr(ctx, key).Result()
+		if err != nil {
+			return 0, 0, errors.WithMessage(err, "can't count magic link request")
+		}
+
+		if count == 1 {
+			err = r.conn.Expire(ctx, key, window).Err()
+			if err != nil {
+				return 0, 0, errors.WithMessage(err, "can't set window of magic link requests")
+			}
+		}
+
+		counts[i] = count
+	}
+
+	return counts[0], counts[1], nil
+}
@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// ListNameTemplates respond with guest name templates of the game.
+func (r PostgresRepository) ListNameTemplates(ctx context.Context, gameID string) ([]*models.NameTemplate, error) {
+	query := `
+		SELECT
+			game_id
+			, locale
+			, pattern
+			, adjectives
+			, nouns
+			, updated_at
+		FROM name_templates
+		WHERE game_id=$1
+		ORDER BY locale
+	`
+
+	rows, err := r.pool.Query(ctx, query, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []*models.NameTemplate{}
+	for rows.Next() {
+		template := &models.NameTemplate{}
+		err = rows.Scan(&template.GameID, &template.Locale, &template.Pattern,
+			&template.Adjectives, &template.Nouns, &template.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		templates = append(templates, template)
+	}
+
+	return templates, rows.Err()
+}
+
+// UpsertNameTemplate creates or updates guest name template of the game.
+func (r PostgresRepository) UpsertNameTemplate(ctx context.Context, template *models.NameTemplate) error {
+	query := `
+		INSERT INTO
+			name_templates (
+				game_id
+				, locale
+				, pattern
+				, adjectives
+				, nouns
+			)
+			VALUES (
+				$1
+				, $2
+				, $3
+				, $4
+				, $5
+			)
+		ON CONFLICT (
+			game_id
+			, locale
+		)
+		DO UPDATE SET
+			pattern = EXCLUDED.pattern
+			, adjectives = EXCLUDED.adjectives
+			, nouns = EXCLUDED.nouns
+			, updated_at = NOW()
+		RETURNING updated_at
+	`
+
+	return r.pool.QueryRow(ctx, query, template.GameID, template.Locale, template.Pattern,
+		template.Adjectives, template.Nouns).Scan(&template.UpdatedAt)
+}
+
+// DeleteNameTemplate removes guest name template of the game.
+func (r PostgresRepository) DeleteNameTemplate(ctx context.Context, gameID, locale string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM name_templates WHERE game_id=$1 AND locale=$2`, gameID, locale)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return models.ErrNameTemplateNotFound
+	}
+
+	return nil
+}
+
+// RenameUser sets display name of the user in all apps of the game, names
+// are unique per game ignoring case and stored with models.SharedApp.
+// Users who changed the name during cooldown get models.NameCooldownError,
+// the first change is always allowed.
+func (r PostgresRepository) RenameUser(ctx context.Context, scope *sharedmodels.Scope, name string, cooldown time.Duration) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	key := strings.ToLower(name)
+
+	// claims of the same name are serialized until commit
+	_, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1 || ':' || $2))`, scope.GameID, key)
+	if err != nil {
+		return err
+	}
+
+	var renamedAt, now time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT renamed_at, NOW()
+		FROM user_names
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+		FOR UPDATE
+	`, scope.GameID, models.SharedApp, scope.UserID).Scan(&renamedAt, &now)
+	if err != nil && err != pgx.ErrNoRows {
+		return err
+	}
+	if err == nil && now.Sub(renamedAt) < cooldown {
+		return &models.NameCooldownError{RetryAfter: renamedAt.Add(cooldown).Sub(now)}
+	}
+
+	var taken bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM user_names
+			WHERE
+				game_id=$1
+				AND name_key=$2
+				AND user_id<>$3
+		)
+	`, scope.GameID, key, scope.UserID).Scan(&taken)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return models.ErrNameTaken
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO
+			user_names (
+				user_id
+				, game_id
+				, app_id
+				, name
+				, name_key
+			)
+			VALUES (
+				$1
+				, $2
+				, $3
+				, $4
+				, $5
+			)
+		ON CONFLICT (
+			game_id
+			, app_id
+			, user_id
+		)
+		DO UPDATE SET
+			name = EXCLUDED.name
+			, name_key = EXCLUDED.name_key
+			, renamed_at = NOW()
+	`, scope.UserID, scope.GameID, models.SharedApp, name, key)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range []string{"anonymouses", "users"} {
+		_, err = tx.Exec(ctx, `
+			UPDATE `+table+`
+			SET
+				name=$3
+				, updated_at=NOW()
+			WHERE
+				game_id=$1
+				AND user_id=$2
+		`, scope.GameID, scope.UserID, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
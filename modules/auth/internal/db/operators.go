@@ -0,0 +1,346 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+)
+
+const invitationTokenSize = 32
+
+// invitationHash is using hash of the token to don't store
+// tokens as is.
+func invitationHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// CreateInvitation stores the invitation and generates its token.
+func (r PostgresRepository) CreateInvitation(ctx context.Context, invitation *models.Invitation) error {
+	token, err := randomToken(invitationTokenSize)
+	if err != nil {
+		return errors.WithMessage(err, "can't generate invitation token")
+	}
+
+	query := `
+		INSERT INTO
+			operator_invitations (
+				token_hash
+				, organisation_id
+				, email
+				, roles
+				, games
+				, invited_by
+				, expires_at
+			)
+			VALUES (
+				$1
+				, $2
+				, $3
+				, $4
+				, $5
+				, NULLIF($6, '')
+				, $7
+			)
+	`
+
+	invitation.Email = normalizeEmail(invitation.Email)
+
+	_, err = r.pool.Exec(ctx, query, invitationHash(token), invitation.OrganisationID,
+		invitation.Email, invitation.Roles, invitation.Games, invitation.InvitedBy,
+		invitation.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	invitation.Token = token
+
+	return nil
+}
+
+// CreateOperator accepts the invitation and creates operator in organisation
+// of invitation with its email, roles and games.
+func (r PostgresRepository) CreateOperator(ctx context.Context, operator *models.Operator, invitationToken string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE operator_invitations
+		SET accepted_at = NOW()
+		WHERE
+			token_hash=$1
+			AND accepted_at IS NULL
+			AND expires_at > NOW()
+		RETURNING
+			organisation_id
+			, email
+			, roles
+			, games
+	`
+
+	row := tx.QueryRow(ctx, query, invitationHash(invitationToken))
+	err = row.Scan(&operator.OrganisationID, &operator.Email, &operator.Roles, &operator.Games)
+	if err == pgx.ErrNoRows {
+		return models.ErrInvalidInvitation
+	}
+	if err != nil {
+		return err
+	}
+
+	operatorID, err := uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO
+			operators (
+				operator_id
+				, organisation_id
+				, email
+				, name
+				, password_hash
+				, roles
+				, games
+			)
+			VALUES (
+				$1
+				, $2
+				, $3
+				, $4
+				, $5
+				, $6
+				, $7
+			)
+		ON CONFLICT (email) DO NOTHING
+		RETURNING operator_id, created_at
+	`
+
+	row = tx.QueryRow(ctx, query, operatorID, operator.OrganisationID, operator.Email,
+		operator.Name, operator.PasswordHash, operator.Roles, operator.Games)
+	err = row.Scan(&operator.ID, &operator.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return models.ErrOperatorExists
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+const selectOperator = `
+	SELECT
+		operator_id
+		, organisation_id
+		, email
+		, name
+		, password_hash
+		, roles
+		, games
+		, COALESCE(totp_secret, '')
+		, totp_enabled
+		, signed_in_at
+		, created_at
+	FROM operators
+`
+
+func scanOperator(row pgx.Row) (*models.Operator, error) {
+	operator := &models.Operator{}
+
+	err := row.Scan(&operator.ID, &operator.OrganisationID, &operator.Email,
+		&operator.Name, &operator.PasswordHash, &operator.Roles, &operator.Games,
+		&operator.TOTPSecret, &operator.TOTPEnabled, &operator.SignedInAt,
+		&operator.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, models.ErrOperatorNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return operator, nil
+}
+
+// FindOperatorByEmail respond with operator or models.ErrOperatorNotFound.
+func (r PostgresRepository) FindOperatorByEmail(ctx context.Context, email string) (*models.Operator, error) {
+	row := r.pool.QueryRow(ctx, selectOperator+`WHERE email=$1`, normalizeEmail(email))
+	return scanOperator(row)
+}
+
+// FindOperator respond with operator or models.ErrOperatorNotFound.
+func (r PostgresRepository) FindOperator(ctx context.Context, operatorID string) (*models.Operator, error) {
+	row := r.pool.QueryRow(ctx, selectOperator+`WHERE operator_id=$1`, operatorID)
+	return scanOperator(row)
+}
+
+// SetOperatorTOTP stores secret of second factor, empty secret disables it.
+func (r PostgresRepository) SetOperatorTOTP(ctx context.Context, operatorID, secret string, enabled bool) error {
+	query := `
+		UPDATE operators
+		SET
+			totp_secret = NULLIF($2, '')
+			, totp_enabled = $3
+			, updated_at = NOW()
+		WHERE operator_id=$1
+	`
+
+	tag, err := r.pool.Exec(ctx, query, operatorID, secret, enabled)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return models.ErrOperatorNotFound
+	}
+
+	return nil
+}
+
+// UseOperatorTOTPStep records time step of accepted code of second factor,
+// codes of the same and previous steps are rejected to prevent replay.
+// It respond with false once the step is already used.
+func (r PostgresRepository) UseOperatorTOTPStep(ctx context.Context, operatorID string, step int64) (bool, error) {
+	query := `
+		UPDATE operators
+		SET totp_last_step = $2
+		WHERE
+			operator_id=$1
+			AND (totp_last_step IS NULL OR totp_last_step < $2)
+	`
+
+	tag, err := r.pool.Exec(ctx, query, operatorID, step)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// ListOperatorsTOTP respond with operators having secret of second factor.
+func (r PostgresRepository) ListOperatorsTOTP(ctx context.Context) ([]*models.Operator, error) {
+	rows, err := r.pool.Query(ctx, selectOperator+`WHERE totp_secret IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var operators []*models.Operator
+	for rows.Next() {
+		operator, err := scanOperator(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		operators = append(operators, operator)
+	}
+
+	return operators, rows.Err()
+}
+
+// ReplaceOperatorTOTPSecret replaces secret of second factor in case if
+// it's not changed since it was read. It respond with false otherwise.
+func (r PostgresRepository) ReplaceOperatorTOTPSecret(ctx context.Context, operatorID, secret, previous string) (bool, error) {
+	query := `
+		UPDATE operators
+		SET
+			totp_secret = $2
+			, updated_at = NOW()
+		WHERE
+			operator_id=$1
+			AND totp_secret=$3
+	`
+
+	tag, err := r.pool.Exec(ctx, query, operatorID, secret, previous)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// TouchOperatorSignin updates time of the last sign in.
+func (r PostgresRepository) TouchOperatorSignin(ctx context.Context, operatorID string, at time.Time) error {
+	query := `UPDATE operators SET signed_in_at = $2 WHERE operator_id=$1`
+
+	_, err := r.pool.Exec(ctx, query, operatorID, at)
+	return err
+}
+
+// ListOperators respond with operators of the organisation.
+func (r PostgresRepository) ListOperators(ctx context.Context, organisationID string) ([]*models.Operator, error) {
+	rows, err := r.pool.Query(ctx, selectOperator+`WHERE organisation_id=$1 ORDER BY created_at`, organisationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	operators := []*models.Operator{}
+	for rows.Next() {
+		operator, err := scanOperator(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		operators = append(operators, operator)
+	}
+
+	return operators, rows.Err()
+}
+
+// UpdateOperatorMembership replaces roles and games of operator
+// in the organisation.
+func (r PostgresRepository) UpdateOperatorMembership(ctx context.Context, organisationID, operatorID string, roles, games []string) error {
+	query := `
+		UPDATE operators
+		SET
+			roles = $3
+			, games = $4
+			, updated_at = NOW()
+		WHERE
+			organisation_id=$1
+			AND operator_id=$2
+	`
+
+	tag, err := r.pool.Exec(ctx, query, organisationID, operatorID, roles, games)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return models.ErrOperatorNotFound
+	}
+
+	return nil
+}
+
+// DeleteOperator removes operator from the organisation.
+func (r PostgresRepository) DeleteOperator(ctx context.Context, organisationID, operatorID string) error {
+	query := `DELETE FROM operators WHERE organisation_id=$1 AND operator_id=$2`
+
+	tag, err := r.pool.Exec(ctx, query, organisationID, operatorID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return models.ErrOperatorNotFound
+	}
+
+	return nil
+}
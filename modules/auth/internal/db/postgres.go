@@ -2,11 +2,15 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"github.com/hashicorp/go-uuid"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
 
 	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
 )
 
 type PostgresRepository struct {
@@ -17,12 +21,44 @@ func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
 	return &PostgresRepository{pool: pool}
 }
 
-const NamePattern = "Guest-"
-
-// AnomSync would create or return user_id from database
+// AnomSync would return the guest who used the device the last time
+// or create the new one, the device is recorded for the guest. Syncs
+// of the same device are serialized to create the only guest.
 func (r PostgresRepository) AnomSync(ctx context.Context, user *models.User) error {
-	var err error
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// the same device could sync concurrently on the first launch
+	err = lockDevice(ctx, tx, user.GameID, user.DeviceID)
+	if err != nil {
+		return err
+	}
+
+	known, err := findDeviceGuest(ctx, tx, user)
+	if err != nil {
+		return errors.WithMessage(err, "can't find guest of device")
+	}
 
+	if !known {
+		err = insertGuest(ctx, tx, user)
+		if err != nil {
+			return errors.WithMessage(err, "can't create guest")
+		}
+	}
+
+	err = upsertDevice(ctx, tx, user.Device(), false)
+	if err != nil {
+		return errors.WithMessage(err, "can't record device")
+	}
+
+	return tx.Commit(ctx)
+}
+
+// insertGuest creates the new guest with generated user id.
+func insertGuest(ctx context.Context, tx pgx.Tx, user *models.User) error {
 	query := `
 		INSERT INTO
 			anonymouses (
@@ -39,57 +75,38 @@ func (r PostgresRepository) AnomSync(ctx context.Context, user *models.User) err
 				, $4
 				, $5
 			)
-		ON CONFLICT (
-			user_id
-			, game_id
-			, app_id
-		)
-		DO UPDATE SET updated_at = NOW()
 		RETURNING user_id
 	`
 
-	// should set uuid for user_id column
-	// only for new records.
-	uuid, err := uuid.GenerateUUID()
-	if err != nil {
-		return nil
-	}
-
-	// TODO: allow to pass pattern or custom name on guest sign in
-	if user.Name == "" {
-		if len(user.DeviceID) > 5 {
-			user.Name = NamePattern + user.DeviceID[0:5]
-		} else {
-			// in case if we have weird device id, we should
-			// use 5 letters of guid
-			user.Name = NamePattern + uuid[0:5]
-		}
-	}
-
-	row := r.pool.QueryRow(ctx, query, uuid, user.Scope.GameID, user.AppID, user.DeviceID, user.Name)
-	err = row.Scan(&user.Scope.UserID)
+	userID, err := uuid.GenerateUUID()
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return tx.QueryRow(ctx, query, userID, user.Scope.GameID, user.AppID, user.DeviceID, user.Name).
+		Scan(&user.Scope.UserID)
 }
 
-// AnomSync would create or return user_id from database
+// UsersSync would find the user by linked identity or create the user
+// with identity using guest id as user id.
 func (r PostgresRepository) UsersSync(ctx context.Context, user *models.User) error {
 	var err error
 
 	query := `
 		select
-			user_id
-			, name
-			, guest_id
-		from users
+			u.user_id
+			, u.name
+			, u.guest_id
+		from user_identities i
+		join users u on
+			u.game_id = i.game_id
+			and u.app_id = i.app_id
+			and u.user_id = i.user_id
 		where
-			network_name=$1
-			and network_id=$2
-			and game_id=$3
-			and app_id=$4
+			i.network_name=$1
+			and i.network_id=$2
+			and i.game_id=$3
+			and i.app_id=$4
 		limit 1
 	`
 	row := r.pool.QueryRow(ctx, query, user.Network,
@@ -99,10 +116,202 @@ func (r PostgresRepository) UsersSync(ctx context.Context, user *models.User) er
 	if err == nil {
 		// if we found user, we should respond with
 		// settings.
-		return nil
+		return r.UpdateDevice(ctx, user.Device())
+	}
+	if err != pgx.ErrNoRows {
+		return err
+	}
+
+	if user.UserID == "" {
+		// use guest id as primary id because of using
+		// using only once anom sign in to generate
+		// player id.
+		user.UserID = user.GuestID
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = upsertUser(ctx, tx, user)
+	if err != nil {
+		return err
+	}
+
+	err = insertIdentity(ctx, tx, identityOf(user))
+	if err != nil {
+		return err
+	}
+
+	err = upsertDevice(ctx, tx, user.Device(), false)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// LinkIdentity links network identity to the existing user, in case if
+// identity belongs to another user it returns IdentityConflictError.
+func (r PostgresRepository) LinkIdentity(ctx context.Context, user *models.User) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if user.GuestID == "" {
+		user.GuestID = user.UserID
+	}
+
+	err = upsertUser(ctx, tx, user)
+	if err != nil {
+		return err
+	}
+
+	err = insertIdentity(ctx, tx, identityOf(user))
+	if err != nil {
+		return err
+	}
+
+	err = upsertDevice(ctx, tx, user.Device(), false)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UnlinkIdentity removes network identity from the user. The last identity
+// of the user can't be removed because the account would become unreachable.
+func (r PostgresRepository) UnlinkIdentity(ctx context.Context, identity *models.Identity) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// rows of the user are locked so concurrent unlinks can't remove
+	// the remaining identities at the same time
+	query := `
+		SELECT network_name, network_id
+		FROM user_identities
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+		FOR UPDATE
+	`
+
+	rows, err := tx.Query(ctx, query, identity.GameID, identity.AppID, identity.UserID)
+	if err != nil {
+		return err
+	}
+
+	var count int
+	var found bool
+	for rows.Next() {
+		var network, networkID string
+		err = rows.Scan(&network, &networkID)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		count++
+		if network == identity.Network && networkID == identity.NetworkID {
+			found = true
+		}
+	}
+	rows.Close()
+
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return models.ErrIdentityNotFound
+	}
+
+	if count <= 1 {
+		return models.ErrLastIdentity
 	}
 
 	query = `
+		DELETE FROM user_identities
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+			AND network_name=$4
+			AND network_id=$5
+	`
+
+	_, err = tx.Exec(ctx, query, identity.GameID, identity.AppID,
+		identity.UserID, identity.Network, identity.NetworkID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ListIdentities respond with network identities linked to the user.
+func (r PostgresRepository) ListIdentities(ctx context.Context, scope *sharedmodels.Scope) ([]*models.Identity, error) {
+	query := `
+		SELECT
+			network_name
+			, network_id
+			, COALESCE(email, '')
+			, COALESCE(name, '')
+			, created_at
+			, updated_at
+		FROM user_identities
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+		ORDER BY created_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, scope.GameID, scope.AppID, scope.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []*models.Identity{}
+	for rows.Next() {
+		identity := &models.Identity{Scope: *scope}
+		err = rows.Scan(&identity.Network, &identity.NetworkID, &identity.Email,
+			&identity.Name, &identity.CreatedAt, &identity.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		identities = append(identities, identity)
+	}
+
+	return identities, rows.Err()
+}
+
+func identityOf(user *models.User) *models.Identity {
+	return &models.Identity{
+		Scope:     user.Scope,
+		Network:   user.Network,
+		NetworkID: user.NetworkID,
+		Email:     user.Email,
+		Name:      user.Name,
+	}
+}
+
+// upsertUser creates the player row once, the following
+// calls only touch updated_at.
+func upsertUser(ctx context.Context, tx pgx.Tx, user *models.User) error {
+	query := `
 		INSERT INTO
 			users (
 				user_id
@@ -112,9 +321,44 @@ func (r PostgresRepository) UsersSync(ctx context.Context, user *models.User) er
 				, game_id
 				, app_id
 
+				, email
+				, name
+			)
+			VALUES (
+				$1
+				, $2
+				, $3
+				, $4
+				, $5
+				, $6
+				, $7
+			)
+		ON CONFLICT (
+			game_id
+			, app_id
+			, user_id
+		)
+		DO UPDATE SET updated_at = NOW()
+		RETURNING name, guest_id
+	`
+
+	row := tx.QueryRow(ctx, query, user.UserID, user.GuestID,
+		user.DeviceID, user.Scope.GameID, user.AppID,
+		user.Email, user.Name)
+	return row.Scan(&user.Name, &user.GuestID)
+}
+
+// insertIdentity links identity to the user, it does nothing
+// if identity already linked to the same user.
+func insertIdentity(ctx context.Context, tx pgx.Tx, identity *models.Identity) error {
+	query := `
+		INSERT INTO
+			user_identities (
+				user_id
+				, game_id
+				, app_id
 				, network_name
 				, network_id
-
 				, email
 				, name
 			)
@@ -126,44 +370,274 @@ func (r PostgresRepository) UsersSync(ctx context.Context, user *models.User) er
 				, $5
 				, $6
 				, $7
-				, $8
-				, $9
 			)
 		ON CONFLICT (
-			user_id
+			game_id
+			, app_id
 			, network_name
 			, network_id
-			, game_id
-			, app_id
 		)
 		DO UPDATE SET updated_at = NOW()
+		WHERE user_identities.user_id = EXCLUDED.user_id
 		RETURNING user_id
 	`
 
-	if user.UserID == "" {
-		// use guest id as primary id because of using
-		// using only once anom sign in to generate
-		// player id.
-		user.UserID = user.GuestID
+	var userID string
+	row := tx.QueryRow(ctx, query, identity.UserID, identity.GameID, identity.AppID,
+		identity.Network, identity.NetworkID, identity.Email, identity.Name)
+	err := row.Scan(&userID)
+	if err == nil {
+		return nil
+	}
+	if err != pgx.ErrNoRows {
+		return err
+	}
+
+	// conflict row wasn't updated, identity belongs to another user.
+	query = `
+		SELECT user_id
+		FROM user_identities
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND network_name=$3
+			AND network_id=$4
+	`
+	row = tx.QueryRow(ctx, query, identity.GameID, identity.AppID,
+		identity.Network, identity.NetworkID)
+	err = row.Scan(&userID)
+	if err != nil {
+		return err
+	}
+
+	return &models.IdentityConflictError{
+		UserID:    userID,
+		Network:   identity.Network,
+		NetworkID: identity.NetworkID,
+	}
+}
+
+// FindIdentityOwner sets user id of the identity owner.
+func (r PostgresRepository) FindIdentityOwner(ctx context.Context, identity *models.Identity) error {
+	query := `
+		SELECT user_id
+		FROM user_identities
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND network_name=$3
+			AND network_id=$4
+	`
+
+	row := r.pool.QueryRow(ctx, query, identity.GameID, identity.AppID,
+		identity.Network, identity.NetworkID)
+	err := row.Scan(&identity.UserID)
+	if err == pgx.ErrNoRows {
+		return models.ErrIdentityNotFound
+	}
+
+	return err
+}
+
+// LastSeen respond with the last time the user was synced.
+func (r PostgresRepository) LastSeen(ctx context.Context, scope *sharedmodels.Scope) (time.Time, error) {
+	query := `
+		SELECT COALESCE(GREATEST(
+			(SELECT MAX(updated_at) FROM users WHERE game_id=$1 AND app_id=$2 AND user_id=$3)
+			, (SELECT MAX(updated_at) FROM anonymouses WHERE game_id=$1 AND app_id=$2 AND user_id=$3)
+		), to_timestamp(0))
+	`
+
+	var seenAt time.Time
+	row := r.pool.QueryRow(ctx, query, scope.GameID, scope.AppID, scope.UserID)
+	err := row.Scan(&seenAt)
+
+	return seenAt, err
+}
+
+// PlayerExists checks if the guest or the user is registered
+// in any app of the game.
+func (r PostgresRepository) PlayerExists(ctx context.Context, gameID, userID string) (bool, error) {
+	query := `
+		SELECT
+			EXISTS (SELECT 1 FROM anonymouses WHERE game_id=$1 AND user_id=$2)
+			OR EXISTS (SELECT 1 FROM users WHERE game_id=$1 AND user_id=$2)
+	`
+
+	var exists bool
+	err := r.pool.QueryRow(ctx, query, gameID, userID).Scan(&exists)
+
+	return exists, err
+}
+
+// MergeUsers moves identities of the merged user to the surviving user
+// and records alias. Merged sections are written to the surviving user and
+// deleted sections of the merged user are removed in the same transaction,
+// sections are written like by SetProperties. It respond with all user ids
+// which should be resolved to the surviving user including previously
+// merged ones.
+func (r PostgresRepository) MergeUsers(ctx context.Context, merge *models.Merge, collection []*models.Properties,
+	deleted map[sharedmodels.Scope][]string, validate func(before, after *models.Properties) error) ([]string, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = setProperties(ctx, tx, collection, validate, "")
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't set merged properties")
 	}
 
-	if user.Name == "" {
-		if len(user.DeviceID) > 5 {
-			user.Name = NamePattern + user.DeviceID[0:5]
-		} else {
-			// in case if we have weird device id, we should
-			// use 5 letters of guid
-			user.Name = NamePattern + user.UserID[0:5]
+	for storage, sections := range deleted {
+		storage := storage
+
+		err = deleteProperties(ctx, tx, sections, &storage)
+		if err != nil {
+			return nil, errors.WithMessage(err, "can't delete properties of merged user")
 		}
 	}
 
-	row = r.pool.QueryRow(ctx, query, user.UserID, user.GuestID,
-		user.DeviceID, user.Scope.GameID, user.AppID,
-		user.Network, user.NetworkID, user.Email, user.Name)
-	err = row.Scan(&user.Scope.UserID)
+	_, err = tx.Exec(ctx, `
+		UPDATE user_identities
+		SET
+			user_id=$4
+			, updated_at=NOW()
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+	`, merge.GameID, merge.AppID, merge.MergedUserID, merge.UserID)
 	if err != nil {
-		return err
+		return nil, errors.WithMessage(err, "can't move identities")
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM users
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+	`, merge.GameID, merge.AppID, merge.MergedUserID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't delete merged user")
+	}
+
+	// devices of merged user are used by surviving user now
+	_, err = tx.Exec(ctx, `
+		UPDATE users_devices d
+		SET user_id=$4
+		WHERE
+			d.game_id=$1
+			AND d.app_id=$2
+			AND d.user_id=$3
+			AND NOT EXISTS (
+				SELECT 1
+				FROM users_devices s
+				WHERE
+					s.game_id=d.game_id
+					AND s.app_id=d.app_id
+					AND s.user_id=$4
+					AND s.device_id=d.device_id
+			)
+	`, merge.GameID, merge.AppID, merge.MergedUserID, merge.UserID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't move devices")
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM users_devices
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+	`, merge.GameID, merge.AppID, merge.MergedUserID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't delete devices of merged user")
+	}
+
+	// users merged previously into merged user should be
+	// resolved to the surviving user.
+	rows, err := tx.Query(ctx, `
+		UPDATE user_aliases
+		SET user_id=$2
+		WHERE user_id=$1
+		RETURNING alias_user_id
+	`, merge.MergedUserID, merge.UserID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't update aliases")
+	}
+
+	aliases := []string{merge.MergedUserID}
+	for rows.Next() {
+		var alias string
+		err = rows.Scan(&alias)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		aliases = append(aliases, alias)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO
+			user_aliases (
+				alias_user_id
+				, user_id
+				, game_id
+				, app_id
+				, strategy
+			)
+			VALUES (
+				$1
+				, $2
+				, $3
+				, $4
+				, $5
+			)
+		ON CONFLICT (
+			alias_user_id
+		)
+		DO UPDATE SET user_id = $2
+	`, merge.MergedUserID, merge.UserID, merge.GameID, merge.AppID, string(merge.Strategy))
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't insert alias")
+	}
+
+	return aliases, tx.Commit(ctx)
+}
+
+// ListAliases respond with aliases ordered by merged user id and following
+// after the passed one, empty list means all aliases are listed.
+func (r PostgresRepository) ListAliases(ctx context.Context, after string, limit int) ([]*models.Alias, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT
+			alias_user_id
+			, user_id
+		FROM user_aliases
+		WHERE alias_user_id > $1
+		ORDER BY alias_user_id
+		LIMIT $2
+	`, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var aliases []*models.Alias
+	for rows.Next() {
+		alias := &models.Alias{}
+		err = rows.Scan(&alias.AliasUserID, &alias.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		aliases = append(aliases, alias)
 	}
 
-	return nil
+	return aliases, rows.Err()
 }
@@ -2,14 +2,18 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 
 	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/experiments"
 	test_helpers "gitlab.com/balconygames/analytics/pkg/test_helpers"
 	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
 )
@@ -117,3 +121,409 @@ func (s *serviceSuite) TestAnomSyncSameUsers() {
 
 	s.Require().Equal(id1, id2)
 }
+
+func (s *serviceSuite) TestLinkIdentityConflict() {
+	repo := NewPostgresRepository(s.PostgresPool)
+
+	owner := &models.User{
+		Scope: sharedmodels.Scope{
+			AppID:  appID,
+			GameID: gameID,
+		},
+		DeviceID:  deviceID,
+		GuestID:   "guest-1",
+		Network:   "GOOGLE",
+		NetworkID: "google-id",
+	}
+	err := repo.UsersSync(context.Background(), owner)
+	s.Require().NoError(err)
+
+	// linking the same identity twice for the same user is allowed
+	err = repo.LinkIdentity(context.Background(), owner)
+	s.Require().NoError(err)
+
+	another := &models.User{
+		Scope: sharedmodels.Scope{
+			AppID:  appID,
+			GameID: gameID,
+			UserID: "guest-2",
+		},
+		DeviceID:  deviceID,
+		Network:   "GOOGLE",
+		NetworkID: "google-id",
+	}
+	err = repo.LinkIdentity(context.Background(), another)
+	s.Require().Error(err)
+
+	var conflict *models.IdentityConflictError
+	s.Require().True(errors.As(err, &conflict))
+	s.Require().Equal(owner.UserID, conflict.UserID)
+
+	identities, err := repo.ListIdentities(context.Background(), &owner.Scope)
+	s.Require().NoError(err)
+	s.Require().Len(identities, 1)
+
+	// the only identity of the user can't be unlinked
+	err = repo.UnlinkIdentity(context.Background(), identities[0])
+	s.Require().Equal(models.ErrLastIdentity, err)
+
+	owner.Network = "FACEBOOK"
+	owner.NetworkID = "facebook-id"
+	err = repo.LinkIdentity(context.Background(), owner)
+	s.Require().NoError(err)
+
+	err = repo.UnlinkIdentity(context.Background(), identities[0])
+	s.Require().NoError(err)
+
+	err = repo.UnlinkIdentity(context.Background(), identities[0])
+	s.Require().Equal(models.ErrIdentityNotFound, err)
+}
+
+func (s *serviceSuite) TestSetPropertiesMergesSection() {
+	repo := NewPostgresRepository(s.PostgresPool)
+
+	scope := sharedmodels.Scope{GameID: gameID, AppID: appID, UserID: userID}
+
+	err := repo.SetProperties(context.Background(), []*models.Properties{
+		{Scope: scope, Section: "cloud", Data: models.Document{"coins": json.RawMessage(`10`), "level": json.RawMessage(`1`)}},
+	}, nil, "")
+	s.Require().NoError(err)
+
+	collection := []*models.Properties{
+		{Scope: scope, Section: "cloud", Data: models.Document{"coins": json.RawMessage(`20`)}},
+	}
+	err = repo.SetProperties(context.Background(), collection, nil, "")
+	s.Require().NoError(err)
+	s.Require().Equal(models.Document{"coins": json.RawMessage(`20`), "level": json.RawMessage(`1`)}, collection[0].Data)
+
+	// stored values have priority over imported ones
+	err = repo.ImportProperties(context.Background(), []*models.Properties{
+		{Scope: scope, Section: "cloud", Data: models.StringDocument(map[string]string{"coins": "5", "skin": "red"})},
+	})
+	s.Require().NoError(err)
+
+	output, err := repo.GetProperties(context.Background(), []string{"cloud", "game"}, &scope)
+	s.Require().NoError(err)
+	s.Require().Len(output, 1)
+	s.Require().Equal(models.Document{
+		"coins": json.RawMessage(`20`),
+		"level": json.RawMessage(`1`),
+		"skin":  json.RawMessage(`"red"`),
+	}, output[0].Data)
+
+	sections, err := repo.ListSections(context.Background(), &scope)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"cloud"}, sections)
+}
+
+func (s *serviceSuite) TestSetPropertiesVersions() {
+	repo := NewPostgresRepository(s.PostgresPool)
+
+	scope := sharedmodels.Scope{GameID: gameID, AppID: appID, UserID: userID}
+	created := int64(0)
+
+	collection := []*models.Properties{
+		{Scope: scope, Section: "versions", Data: models.Document{"coins": json.RawMessage(`10`), "level": json.RawMessage(`1`)}, ExpectedVersion: &created},
+	}
+	err := repo.SetProperties(context.Background(), collection, nil, "")
+	s.Require().NoError(err)
+	s.Require().Equal(int64(1), collection[0].Version)
+
+	// the second device still expects the section to be new
+	err = repo.SetProperties(context.Background(), []*models.Properties{
+		{Scope: scope, Section: "versions", Data: models.Document{"coins": json.RawMessage(`5`)}, ExpectedVersion: &created},
+	}, nil, "")
+	var conflict *models.PropertiesConflictError
+	s.Require().True(errors.As(err, &conflict))
+	s.Require().Len(conflict.Sections, 1)
+	s.Require().Equal(int64(1), conflict.Sections[0].Version)
+	s.Require().Equal(models.Document{"coins": json.RawMessage(`10`), "level": json.RawMessage(`1`)}, conflict.Sections[0].Data)
+
+	collection = []*models.Properties{
+		{Scope: scope, Section: "versions", Data: models.Document{"coins": json.RawMessage(`15`)}, Delete: []string{"level"}, ExpectedVersion: &collection[0].Version},
+	}
+	err = repo.SetProperties(context.Background(), collection, nil, "")
+	s.Require().NoError(err)
+	s.Require().Equal(int64(2), collection[0].Version)
+	s.Require().Equal(models.Document{"coins": json.RawMessage(`15`)}, collection[0].Data)
+
+	// rejected sections are not written
+	rejected := errors.New("rejected")
+	err = repo.SetProperties(context.Background(), []*models.Properties{
+		{Scope: scope, Section: "versions", Data: models.Document{"coins": json.RawMessage(`{"gold": 1}`)}},
+	}, func(before, after *models.Properties) error { return rejected }, "")
+	s.Require().Equal(rejected, err)
+
+	output, err := repo.GetProperties(context.Background(), []string{"versions"}, &scope)
+	s.Require().NoError(err)
+	s.Require().Equal(int64(2), output[0].Version)
+}
+
+func (s *serviceSuite) TestSetPropertiesAudit() {
+	repo := NewPostgresRepository(s.PostgresPool)
+
+	scope := sharedmodels.Scope{GameID: gameID, AppID: appID, UserID: userID}
+
+	err := repo.SetProperties(context.Background(), []*models.Properties{
+		{Scope: scope, Section: "wallet", Data: models.Document{"gems": json.RawMessage(`10`)}},
+	}, nil, "")
+	s.Require().NoError(err)
+
+	err = repo.SetProperties(context.Background(), []*models.Properties{
+		{Scope: scope, Section: "wallet", Data: models.Document{"gems": json.RawMessage(`50`)}},
+	}, nil, "operator")
+	s.Require().NoError(err)
+
+	changes, err := repo.ListPropertiesChanges(context.Background(), &scope, "wallet", 10)
+	s.Require().NoError(err)
+	s.Require().Len(changes, 1)
+	s.Require().Equal("operator", changes[0].ActorID)
+	s.Require().Equal(models.Document{"gems": json.RawMessage(`10`)}, changes[0].Before)
+	s.Require().Equal(models.Document{"gems": json.RawMessage(`50`)}, changes[0].After)
+	s.Require().Equal(int64(2), changes[0].Version)
+}
+
+func (s *serviceSuite) TestPutSaveConflict() {
+	repo := NewPostgresRepository(s.PostgresPool)
+
+	scope := sharedmodels.Scope{GameID: gameID, AppID: appID, UserID: userID}
+	empty := int64(0)
+
+	save := &models.Save{Scope: scope, Slot: "auto", BlobRef: "1", Device: "phone"}
+	replaced, err := repo.PutSave(context.Background(), save, &empty, 10)
+	s.Require().NoError(err)
+	s.Require().Equal("", replaced)
+	s.Require().Equal(int64(1), save.Version)
+
+	// tablet still expects the empty slot
+	_, err = repo.PutSave(context.Background(), &models.Save{Scope: scope, Slot: "auto", BlobRef: "2", Device: "tablet"}, &empty, 10)
+	var conflict *models.SaveConflictError
+	s.Require().True(errors.As(err, &conflict))
+	s.Require().Equal("phone", conflict.Current.Device)
+
+	// tablet keeps the local save
+	replaced, err = repo.PutSave(context.Background(), &models.Save{Scope: scope, Slot: "auto", BlobRef: "2", Device: "tablet"}, &conflict.Current.Version, 10)
+	s.Require().NoError(err)
+	s.Require().Equal("1", replaced)
+
+	_, err = repo.PutSave(context.Background(), &models.Save{Scope: scope, Slot: "manual", BlobRef: "3"}, nil, 1)
+	s.Require().Equal(models.ErrTooManySaveSlots, err)
+
+	refs, err := repo.UserSaveRefs(context.Background(), &scope)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"2"}, refs)
+}
+
+func (s *serviceSuite) TestRenameUser() {
+	repo := NewPostgresRepository(s.PostgresPool)
+
+	scope := sharedmodels.Scope{GameID: gameID, AppID: appID, UserID: userID}
+	other := sharedmodels.Scope{GameID: gameID, AppID: appID, UserID: "other-user"}
+
+	s.Require().NoError(repo.RenameUser(context.Background(), &scope, "BraveFox", time.Hour))
+
+	// names are unique ignoring case
+	err := repo.RenameUser(context.Background(), &other, "bravefox", time.Hour)
+	s.Require().Equal(models.ErrNameTaken, err)
+
+	var cooldown *models.NameCooldownError
+	err = repo.RenameUser(context.Background(), &scope, "CleverOwl", time.Hour)
+	s.Require().True(errors.As(err, &cooldown))
+	s.Require().True(cooldown.RetryAfter > 0)
+
+	s.Require().NoError(repo.RenameUser(context.Background(), &scope, "CleverOwl", 0))
+	s.Require().NoError(repo.RenameUser(context.Background(), &other, "BraveFox", time.Hour))
+}
+
+func (s *serviceSuite) TestRenameUserInAppsOfGame() {
+	repo := NewPostgresRepository(s.PostgresPool)
+
+	ios := sharedmodels.Scope{GameID: gameID, AppID: "ios", UserID: userID}
+	android := sharedmodels.Scope{GameID: gameID, AppID: "android", UserID: userID}
+
+	s.Require().NoError(repo.RenameUser(context.Background(), &ios, "BraveFox", 0))
+
+	// game scoped guest keeps its name in other app of the game
+	s.Require().NoError(repo.RenameUser(context.Background(), &android, "bravefox", 0))
+
+	// cooldown is shared by apps of the game
+	var cooldown *models.NameCooldownError
+	err := repo.RenameUser(context.Background(), &ios, "QuietBear", time.Hour)
+	s.Require().True(errors.As(err, &cooldown))
+}
+
+func (s *serviceSuite) TestAnomSyncKnownDevice() {
+	repo := NewPostgresRepository(s.PostgresPool)
+	scope := sharedmodels.Scope{GameID: gameID, AppID: appID}
+
+	first := &models.User{Scope: scope, DeviceID: deviceID, Name: "Guest-1", Platform: "ios", PushToken: "token"}
+	s.Require().NoError(repo.AnomSync(context.Background(), first))
+
+	second := &models.User{Scope: scope, DeviceID: deviceID, Name: "Guest-2", AppVersion: "1.2.0"}
+	s.Require().NoError(repo.AnomSync(context.Background(), second))
+	s.Require().Equal(first.UserID, second.UserID)
+	s.Require().Equal("Guest-1", second.Name)
+
+	devices, err := repo.ListDevices(context.Background(), &first.Scope)
+	s.Require().NoError(err)
+	s.Require().Len(devices, 1)
+	s.Require().Equal("ios", devices[0].Platform)
+	s.Require().Equal("1.2.0", devices[0].AppVersion)
+	s.Require().Equal("token", devices[0].PushToken)
+
+	// revoked device signs in as the new guest
+	s.Require().NoError(repo.RevokeDevice(context.Background(), &first.Scope, deviceID))
+	revoked, err := repo.IsDeviceRevoked(context.Background(), &first.Scope, deviceID)
+	s.Require().NoError(err)
+	s.Require().True(revoked)
+
+	third := &models.User{Scope: scope, DeviceID: deviceID, Name: "Guest-3"}
+	s.Require().NoError(repo.AnomSync(context.Background(), third))
+	s.Require().NotEqual(first.UserID, third.UserID)
+
+	// revocation isn't undone by updates of the device
+	err = repo.UpdateDevice(context.Background(), first.Device())
+	s.Require().Equal(models.ErrDeviceRevoked, err)
+	revoked, err = repo.IsDeviceRevoked(context.Background(), &first.Scope, deviceID)
+	s.Require().NoError(err)
+	s.Require().True(revoked)
+
+	s.Require().Equal(models.ErrDeviceNotFound, repo.RevokeDevice(context.Background(), &first.Scope, "unknown"))
+}
+
+func (s *serviceSuite) TestPlayerExists() {
+	repo := NewPostgresRepository(s.PostgresPool)
+
+	guest := &models.User{Scope: sharedmodels.Scope{GameID: gameID, AppID: appID}, DeviceID: deviceID, Name: "Guest-1"}
+	s.Require().NoError(repo.AnomSync(context.Background(), guest))
+
+	exists, err := repo.PlayerExists(context.Background(), gameID, guest.UserID)
+	s.Require().NoError(err)
+	s.Require().True(exists)
+
+	exists, err = repo.PlayerExists(context.Background(), "other-game", guest.UserID)
+	s.Require().NoError(err)
+	s.Require().False(exists)
+}
+
+func (s *serviceSuite) TestMergeUsersProperties() {
+	repo := NewPostgresRepository(s.PostgresPool)
+
+	survivor := sharedmodels.Scope{GameID: gameID, AppID: appID, UserID: "merge-survivor"}
+	merged := sharedmodels.Scope{GameID: gameID, AppID: appID, UserID: "merge-merged"}
+	merge := &models.Merge{Scope: survivor, MergedUserID: merged.UserID, Strategy: models.MergePreferNewest}
+
+	err := repo.SetProperties(context.Background(), []*models.Properties{
+		{Scope: merged, Section: "merge", Data: models.Document{"coins": json.RawMessage(`10`)}},
+	}, nil, "")
+	s.Require().NoError(err)
+
+	collection := func() []*models.Properties {
+		return []*models.Properties{
+			{Scope: survivor, Section: "merge", Data: models.Document{"coins": json.RawMessage(`10`)}},
+		}
+	}
+	deleted := map[sharedmodels.Scope][]string{merged: {"merge"}}
+
+	// nothing is changed once merged sections are rejected
+	rejected := errors.New("rejected")
+	_, err = repo.MergeUsers(context.Background(), merge, collection(), deleted,
+		func(before, after *models.Properties) error { return rejected })
+	s.Require().Equal(rejected, errors.Cause(err))
+
+	sections, err := repo.ListSections(context.Background(), &merged)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"merge"}, sections)
+
+	aliases, err := repo.ListAliases(context.Background(), "", 10)
+	s.Require().NoError(err)
+	s.Require().Empty(aliases)
+
+	ids, err := repo.MergeUsers(context.Background(), merge, collection(), deleted, nil)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{merged.UserID}, ids)
+
+	sections, err = repo.ListSections(context.Background(), &merged)
+	s.Require().NoError(err)
+	s.Require().Empty(sections)
+
+	output, err := repo.GetProperties(context.Background(), []string{"merge"}, &survivor)
+	s.Require().NoError(err)
+	s.Require().Len(output, 1)
+	s.Require().Equal(models.Document{"coins": json.RawMessage(`10`)}, output[0].Data)
+
+	aliases, err = repo.ListAliases(context.Background(), "", 10)
+	s.Require().NoError(err)
+	s.Require().Equal([]*models.Alias{{AliasUserID: merged.UserID, UserID: survivor.UserID}}, aliases)
+}
+
+func (s *serviceSuite) TestAnomSyncGameScopedGuests() {
+	repo := NewPostgresRepository(s.PostgresPool)
+
+	ios := &models.User{Scope: sharedmodels.Scope{GameID: gameID, AppID: "ios"}, DeviceID: deviceID, Name: "Guest-1"}
+	s.Require().NoError(repo.AnomSync(context.Background(), ios))
+
+	// apps have own guests by default
+	tvos := &models.User{Scope: sharedmodels.Scope{GameID: gameID, AppID: "tvos"}, DeviceID: deviceID, Name: "Guest-2"}
+	s.Require().NoError(repo.AnomSync(context.Background(), tvos))
+	s.Require().NotEqual(ios.UserID, tvos.UserID)
+
+	s.Require().NoError(repo.UpsertGuestPolicy(context.Background(), &models.GuestPolicy{GameID: gameID, Scope: models.GuestScopeGame}))
+
+	macos := &models.User{Scope: sharedmodels.Scope{GameID: gameID, AppID: "macos"}, DeviceID: deviceID, Name: "Guest-3"}
+	s.Require().NoError(repo.AnomSync(context.Background(), macos))
+	s.Require().Contains([]string{ios.UserID, tvos.UserID}, macos.UserID)
+
+	// guest of the current app is preferred
+	again := &models.User{Scope: sharedmodels.Scope{GameID: gameID, AppID: "ios"}, DeviceID: deviceID}
+	s.Require().NoError(repo.AnomSync(context.Background(), again))
+	s.Require().Equal(ios.UserID, again.UserID)
+}
+
+func (s *serviceSuite) TestRecoverGuest() {
+	repo := NewPostgresRepository(s.PostgresPool)
+
+	guest := &models.User{Scope: sharedmodels.Scope{GameID: gameID, AppID: appID}, DeviceID: deviceID, Name: "Guest-1"}
+	s.Require().NoError(repo.AnomSync(context.Background(), guest))
+	s.Require().NoError(repo.SetRecoveryCode(context.Background(), &guest.Scope, "ABCDEFGHJKMNPQRS"))
+
+	restored := &models.User{Scope: sharedmodels.Scope{GameID: gameID, AppID: appID}, DeviceID: "device-2"}
+	s.Require().Equal(models.ErrInvalidRecoveryCode, repo.RecoverGuest(context.Background(), restored, "SRQPNMKJHGFEDCBA"))
+
+	s.Require().NoError(repo.RecoverGuest(context.Background(), restored, "ABCDEFGHJKMNPQRS"))
+	s.Require().Equal(guest.UserID, restored.UserID)
+	s.Require().Equal("Guest-1", restored.Name)
+
+	// the new device is recognised
+	synced := &models.User{Scope: sharedmodels.Scope{GameID: gameID, AppID: appID}, DeviceID: "device-2"}
+	s.Require().NoError(repo.AnomSync(context.Background(), synced))
+	s.Require().Equal(guest.UserID, synced.UserID)
+
+	// codes of other apps require game scoped guests
+	other := &models.User{Scope: sharedmodels.Scope{GameID: gameID, AppID: "other"}, DeviceID: "device-3"}
+	s.Require().Equal(models.ErrInvalidRecoveryCode, repo.RecoverGuest(context.Background(), other, "ABCDEFGHJKMNPQRS"))
+}
+
+func (s *serviceSuite) TestUpsertExperiment() {
+	repo := NewPostgresRepository(s.PostgresPool)
+
+	experiment := &models.Experiment{
+		GameID:   gameID,
+		Key:      "difficulty",
+		Status:   models.ExperimentRunning,
+		Variants: []experiments.Variant{{Name: "easy", Weight: 50}, {Name: "hard", Weight: 50}},
+	}
+	s.Require().NoError(repo.UpsertExperiment(context.Background(), experiment))
+
+	experiment.Status = models.ExperimentStopped
+	s.Require().NoError(repo.UpsertExperiment(context.Background(), experiment))
+
+	list, err := repo.ListExperiments(context.Background(), gameID)
+	s.Require().NoError(err)
+	s.Require().Len(list, 1)
+	s.Require().Equal(models.ExperimentStopped, list[0].Status)
+	s.Require().Equal(experiment.Variants, list[0].Variants)
+
+	s.Require().NoError(repo.DeleteExperiment(context.Background(), gameID, "difficulty"))
+	s.Require().Equal(models.ErrExperimentNotFound, repo.DeleteExperiment(context.Background(), gameID, "difficulty"))
+}
@@ -0,0 +1,329 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// personalTables store personal data of users per game and app, tables
+// with user data added later should be listed here to be exported
+// and deleted.
+var personalTables = []string{
+	"users",
+	"anonymouses",
+	"user_identities",
+	"users_devices",
+	"guest_recovery_codes",
+	"user_aliases",
+	"user_properties",
+	"user_properties_changes",
+	"user_saves",
+	"user_names",
+	"user_deletions",
+}
+
+// userIDs respond with user id and ids of users merged into it.
+func userIDs(ctx context.Context, q pgx.Tx, scope *sharedmodels.Scope) ([]string, error) {
+	rows, err := q.Query(ctx, `
+		SELECT alias_user_id
+		FROM user_aliases
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+	`, scope.GameID, scope.AppID, scope.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{scope.UserID}
+	for rows.Next() {
+		var id string
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// ExportUser respond with rows of personal tables of the user and
+// users merged into it, rows are encoded as JSON objects per table.
+func (r PostgresRepository) ExportUser(ctx context.Context, scope *sharedmodels.Scope) (map[string]json.RawMessage, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	ids, err := userIDs(ctx, tx, scope)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't list merged users")
+	}
+
+	out := make(map[string]json.RawMessage, len(personalTables))
+	for _, table := range personalTables {
+		// rows shared by apps of the game belong to the user too
+		query := fmt.Sprintf(`
+			SELECT COALESCE(json_agg(t), '[]'::json)
+			FROM %s t
+			WHERE
+				game_id=$1
+				AND app_id IN ($2, $4)
+				AND user_id=ANY($3)
+		`, table)
+
+		var rows []byte
+		err = tx.QueryRow(ctx, query, scope.GameID, scope.AppID, ids, models.SharedApp).Scan(&rows)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "can't export %s", table)
+		}
+
+		out[table] = rows
+	}
+
+	return out, nil
+}
+
+// DeleteUser deletes rows of personal tables of the user and users merged
+// into it except of the deletion request. It respond with deleted ids.
+func (r PostgresRepository) DeleteUser(ctx context.Context, scope *sharedmodels.Scope) ([]string, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	ids, err := userIDs(ctx, tx, scope)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't list merged users")
+	}
+
+	for _, table := range personalTables {
+		// deletion request is kept to prove the data was deleted,
+		// it doesn't contain personal data except of user id.
+		if table == "user_deletions" {
+			continue
+		}
+
+		query := fmt.Sprintf(`
+			DELETE FROM %s
+			WHERE
+				game_id=$1
+				AND app_id IN ($2, $4)
+				AND user_id=ANY($3)
+		`, table)
+
+		_, err = tx.Exec(ctx, query, scope.GameID, scope.AppID, ids, models.SharedApp)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "can't delete %s", table)
+		}
+	}
+
+	return ids, tx.Commit(ctx)
+}
+
+// ScheduleDeletion creates pending deletion request or respond with
+// the existing pending one.
+func (r PostgresRepository) ScheduleDeletion(ctx context.Context, deletion *models.Deletion) error {
+	query := `
+		INSERT INTO
+			user_deletions (
+				game_id
+				, app_id
+				, user_id
+				, requested_by
+				, status
+				, requested_at
+				, execute_after
+			)
+			VALUES (
+				$1
+				, $2
+				, $3
+				, $4
+				, $5
+				, NOW()
+				, $6
+			)
+		ON CONFLICT (
+			game_id
+			, app_id
+			, user_id
+		)
+		DO UPDATE SET
+			requested_by = EXCLUDED.requested_by
+			, status = EXCLUDED.status
+			, requested_at = EXCLUDED.requested_at
+			, execute_after = EXCLUDED.execute_after
+			, completed_at = NULL
+			, attempts = 0
+			, last_error = NULL
+			, failed_at = NULL
+		WHERE user_deletions.status <> $5
+		RETURNING requested_at
+	`
+
+	deletion.Status = models.DeletionPending
+
+	err := r.pool.QueryRow(ctx, query, deletion.GameID, deletion.AppID, deletion.UserID,
+		deletion.RequestedBy, deletion.Status, deletion.ExecuteAfter).Scan(&deletion.RequestedAt)
+	if err == pgx.ErrNoRows {
+		// already pending
+		found, err := r.FindDeletion(ctx, &deletion.Scope)
+		if err != nil {
+			return err
+		}
+		*deletion = *found
+		return nil
+	}
+
+	return err
+}
+
+// FindDeletion respond with the deletion request of the user.
+func (r PostgresRepository) FindDeletion(ctx context.Context, scope *sharedmodels.Scope) (*models.Deletion, error) {
+	query := `
+		SELECT
+			requested_by
+			, status
+			, requested_at
+			, execute_after
+			, completed_at
+		FROM user_deletions
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+	`
+
+	deletion := &models.Deletion{Scope: *scope}
+	err := r.pool.QueryRow(ctx, query, scope.GameID, scope.AppID, scope.UserID).Scan(
+		&deletion.RequestedBy, &deletion.Status, &deletion.RequestedAt,
+		&deletion.ExecuteAfter, &deletion.CompletedAt)
+	if err == pgx.ErrNoRows {
+		return nil, models.ErrDeletionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return deletion, nil
+}
+
+// CancelDeletion cancels pending deletion request during grace period.
+func (r PostgresRepository) CancelDeletion(ctx context.Context, scope *sharedmodels.Scope) error {
+	query := `
+		UPDATE user_deletions
+		SET status=$4
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+			AND status=$5
+	`
+
+	tag, err := r.pool.Exec(ctx, query, scope.GameID, scope.AppID, scope.UserID,
+		models.DeletionCancelled, models.DeletionPending)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return models.ErrDeletionNotFound
+	}
+
+	return nil
+}
+
+// DueDeletions respond with pending deletions with passed grace period,
+// deletions failed since now are skipped to be retried by the next run.
+func (r PostgresRepository) DueDeletions(ctx context.Context, now time.Time, limit int) ([]*models.Deletion, error) {
+	query := `
+		SELECT
+			game_id
+			, app_id
+			, user_id
+			, requested_by
+			, status
+			, requested_at
+			, execute_after
+		FROM user_deletions
+		WHERE
+			status=$1
+			AND execute_after <= $2
+			AND (failed_at IS NULL OR failed_at < $2)
+		ORDER BY execute_after
+		LIMIT $3
+	`
+
+	rows, err := r.pool.Query(ctx, query, models.DeletionPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deletions := []*models.Deletion{}
+	for rows.Next() {
+		deletion := &models.Deletion{}
+		err = rows.Scan(&deletion.GameID, &deletion.AppID, &deletion.UserID,
+			&deletion.RequestedBy, &deletion.Status, &deletion.RequestedAt,
+			&deletion.ExecuteAfter)
+		if err != nil {
+			return nil, err
+		}
+
+		deletions = append(deletions, deletion)
+	}
+
+	return deletions, rows.Err()
+}
+
+// CompleteDeletion marks deletion request as completed.
+func (r PostgresRepository) CompleteDeletion(ctx context.Context, scope *sharedmodels.Scope) error {
+	query := `
+		UPDATE user_deletions
+		SET
+			status=$4
+			, completed_at=NOW()
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+	`
+
+	_, err := r.pool.Exec(ctx, query, scope.GameID, scope.AppID, scope.UserID,
+		models.DeletionCompleted)
+	return err
+}
+
+// FailDeletion records failed attempt of pending deletion request.
+func (r PostgresRepository) FailDeletion(ctx context.Context, scope *sharedmodels.Scope, at time.Time, reason string) error {
+	query := `
+		UPDATE user_deletions
+		SET
+			attempts=attempts+1
+			, last_error=$4
+			, failed_at=$5
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+			AND status=$6
+	`
+
+	_, err := r.pool.Exec(ctx, query, scope.GameID, scope.AppID, scope.UserID,
+		reason, at, models.DeletionPending)
+	return err
+}
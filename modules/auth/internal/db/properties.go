@@ -0,0 +1,332 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// GetProperties respond with stored sections of the user, missing
+// sections are skipped.
+func (r PostgresRepository) GetProperties(ctx context.Context, sections []string, scope *sharedmodels.Scope) ([]*models.Properties, error) {
+	query := `
+		SELECT
+			section
+			, data
+			, version
+		FROM user_properties
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+			AND section=ANY($4)
+	`
+
+	rows, err := r.pool.Query(ctx, query, scope.GameID, scope.AppID, scope.UserID, sections)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*models.Properties
+	for rows.Next() {
+		properties := &models.Properties{Scope: *scope}
+		err = rows.Scan(&properties.Section, &properties.Data, &properties.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, properties)
+	}
+
+	return result, rows.Err()
+}
+
+// SetProperties merges passed values into stored sections and removes
+// deleted fields, the whole section data and version are set back into
+// the collection. Sections with expected version are written only if
+// the stored version matches, otherwise nothing is written and
+// PropertiesConflictError with current sections is returned. Sections
+// before and after the write are passed to validate before commit,
+// nothing is written if any section is rejected. Changes are audited
+// if actorID is set.
+func (r PostgresRepository) SetProperties(ctx context.Context, collection []*models.Properties, validate func(before, after *models.Properties) error, actorID string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = setProperties(ctx, tx, collection, validate, actorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func setProperties(ctx context.Context, tx pgx.Tx, collection []*models.Properties, validate func(before, after *models.Properties) error, actorID string) error {
+	query := `
+		INSERT INTO
+			user_properties (
+				game_id
+				, app_id
+				, user_id
+				, section
+				, data
+			)
+			VALUES (
+				$1
+				, $2
+				, $3
+				, $4
+				, $5
+			)
+		ON CONFLICT (
+			game_id
+			, app_id
+			, user_id
+			, section
+		)
+		DO UPDATE SET
+			data = (user_properties.data || EXCLUDED.data) - $6::text[]
+			, version = user_properties.version + 1
+			, updated_at = NOW()
+		WHERE
+			$7::bigint IS NULL
+			OR user_properties.version = $7
+		RETURNING data, version
+	`
+
+	var conflicts []*models.Properties
+	for _, properties := range collection {
+		current, err := lockProperties(ctx, tx, properties)
+		if err != nil {
+			return err
+		}
+		if properties.ExpectedVersion != nil && current.Version != *properties.ExpectedVersion {
+			conflicts = append(conflicts, current)
+			continue
+		}
+
+		data := properties.Data
+		if data == nil {
+			data = models.Document{}
+		}
+		deleted := properties.Delete
+		if deleted == nil {
+			deleted = []string{}
+		}
+
+		err = tx.QueryRow(ctx, query, properties.GameID, properties.AppID,
+			properties.UserID, properties.Section, data, deleted,
+			properties.ExpectedVersion).Scan(&properties.Data, &properties.Version)
+		if err == pgx.ErrNoRows {
+			// section is created by concurrent write
+			current, err := lockProperties(ctx, tx, properties)
+			if err != nil {
+				return err
+			}
+			conflicts = append(conflicts, current)
+			continue
+		}
+		if err != nil {
+			return errors.WithMessagef(err, "can't store section %s", properties.Section)
+		}
+
+		if validate != nil {
+			err = validate(current, properties)
+			if err != nil {
+				return err
+			}
+		}
+
+		if actorID != "" {
+			err = insertPropertiesChange(ctx, tx, &models.PropertiesChange{
+				Scope:   properties.Scope,
+				Section: properties.Section,
+				ActorID: actorID,
+				Before:  current.Data,
+				After:   properties.Data,
+				Version: properties.Version,
+			})
+			if err != nil {
+				return errors.WithMessagef(err, "can't audit section %s", properties.Section)
+			}
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return &models.PropertiesConflictError{Sections: conflicts}
+	}
+
+	return nil
+}
+
+// lockProperties respond with current section locked till the end of
+// transaction, missing section is responded with zero version.
+func lockProperties(ctx context.Context, tx pgx.Tx, properties *models.Properties) (*models.Properties, error) {
+	query := `
+		SELECT
+			data
+			, version
+		FROM user_properties
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+			AND section=$4
+		FOR UPDATE
+	`
+
+	current := &models.Properties{
+		Scope:   properties.Scope,
+		Section: properties.Section,
+		Data:    models.Document{},
+	}
+
+	err := tx.QueryRow(ctx, query, properties.GameID, properties.AppID,
+		properties.UserID, properties.Section).Scan(&current.Data, &current.Version)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, errors.WithMessagef(err, "can't lock section %s", properties.Section)
+	}
+
+	return current, nil
+}
+
+// ImportProperties stores sections imported from redis, stored values
+// have priority over imported ones.
+func (r PostgresRepository) ImportProperties(ctx context.Context, collection []*models.Properties) error {
+	query := `
+		INSERT INTO
+			user_properties (
+				game_id
+				, app_id
+				, user_id
+				, section
+				, data
+			)
+			VALUES (
+				$1
+				, $2
+				, $3
+				, $4
+				, $5
+			)
+		ON CONFLICT (
+			game_id
+			, app_id
+			, user_id
+			, section
+		)
+		DO UPDATE SET
+			data = EXCLUDED.data || user_properties.data
+			, version = user_properties.version + 1
+		RETURNING data, version
+	`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, properties := range collection {
+		data := properties.Data
+		if data == nil {
+			data = models.Document{}
+		}
+
+		err = tx.QueryRow(ctx, query, properties.GameID, properties.AppID,
+			properties.UserID, properties.Section, data).Scan(&properties.Data, &properties.Version)
+		if err != nil {
+			return errors.WithMessagef(err, "can't store section %s", properties.Section)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ListSections respond with section names stored for the user.
+func (r PostgresRepository) ListSections(ctx context.Context, scope *sharedmodels.Scope) ([]string, error) {
+	query := `
+		SELECT section
+		FROM user_properties
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+		ORDER BY section
+	`
+
+	rows, err := r.pool.Query(ctx, query, scope.GameID, scope.AppID, scope.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sections []string
+	for rows.Next() {
+		var section string
+		err = rows.Scan(&section)
+		if err != nil {
+			return nil, err
+		}
+
+		sections = append(sections, section)
+	}
+
+	return sections, rows.Err()
+}
+
+// DeleteProperties removes sections stored for the user.
+func (r PostgresRepository) DeleteProperties(ctx context.Context, sections []string, scope *sharedmodels.Scope) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = deleteProperties(ctx, tx, sections, scope)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func deleteProperties(ctx context.Context, tx pgx.Tx, sections []string, scope *sharedmodels.Scope) error {
+	query := `
+		DELETE FROM user_properties
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+			AND section=ANY($4)
+	`
+
+	_, err := tx.Exec(ctx, query, scope.GameID, scope.AppID, scope.UserID, sections)
+	return err
+}
+
+// FindUserScope fills game and app of the user by user id, it's used
+// for data stored without game and app.
+func (r PostgresRepository) FindUserScope(ctx context.Context, scope *sharedmodels.Scope) error {
+	query := `
+		SELECT game_id, app_id FROM anonymouses WHERE user_id=$1
+		UNION ALL
+		SELECT game_id, app_id FROM users WHERE user_id=$1
+		LIMIT 1
+	`
+
+	err := r.pool.QueryRow(ctx, query, scope.UserID).Scan(&scope.GameID, &scope.AppID)
+	if err == pgx.ErrNoRows {
+		return models.ErrUserNotFound
+	}
+
+	return err
+}
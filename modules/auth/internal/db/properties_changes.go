@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+func insertPropertiesChange(ctx context.Context, tx pgx.Tx, change *models.PropertiesChange) error {
+	query := `
+		INSERT INTO
+			user_properties_changes (
+				game_id
+				, app_id
+				, user_id
+				, section
+				, actor_id
+				, before
+				, after
+				, version
+			)
+			VALUES (
+				$1
+				, $2
+				, $3
+				, $4
+				, $5
+				, $6
+				, $7
+				, $8
+			)
+		RETURNING id, created_at
+	`
+
+	return tx.QueryRow(ctx, query, change.GameID, change.AppID, change.UserID,
+		change.Section, change.ActorID, change.Before, change.After, change.Version).
+		Scan(&change.ID, &change.CreatedAt)
+}
+
+// ListPropertiesChanges respond with the latest audited changes of sections
+// of the user and shared sections of the game, newest first. Empty section
+// lists changes of all sections.
+func (r PostgresRepository) ListPropertiesChanges(ctx context.Context, scope *sharedmodels.Scope, section string, limit int) ([]*models.PropertiesChange, error) {
+	query := `
+		SELECT
+			id
+			, game_id
+			, app_id
+			, user_id
+			, section
+			, actor_id
+			, before
+			, after
+			, version
+			, created_at
+		FROM user_properties_changes
+		WHERE
+			game_id=$1
+			AND app_id IN ($2, $3)
+			AND user_id=$4
+			AND ($5='' OR section=$5)
+		ORDER BY id DESC
+		LIMIT $6
+	`
+
+	rows, err := r.pool.Query(ctx, query, scope.GameID, scope.AppID, models.SharedApp,
+		scope.UserID, section, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []*models.PropertiesChange{}
+	for rows.Next() {
+		change := &models.PropertiesChange{}
+		err = rows.Scan(&change.ID, &change.GameID, &change.AppID, &change.UserID,
+			&change.Section, &change.ActorID, &change.Before, &change.After,
+			&change.Version, &change.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
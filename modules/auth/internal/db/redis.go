@@ -2,13 +2,18 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
 	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/auth"
 	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
 )
 
@@ -23,10 +28,30 @@ func NewRedisRepository(c *redis.Client, l *zap.SugaredLogger) *RedisRepository
 	return &RedisRepository{c, l.With("scope", "redis")}
 }
 
+// scanCount is the hint for redis SCAN batch size
+const scanCount = 100
+
+// propsKey is namespaced by game and app, app is models.SharedApp
+// for sections shared by apps of the game.
 func propsKey(section string, scope sharedmodels.Scope) string {
-	return fmt.Sprintf("props:%s:%s", section, scope.UserID)
+	return fmt.Sprintf("props:v2:%s:%s:%s:%s", scope.GameID, scope.AppID, section, scope.UserID)
+}
+
+// legacyPropsKey is key of properties stored before namespacing by game.
+func legacyPropsKey(section, userID string) string {
+	return fmt.Sprintf("props:%s:%s", section, userID)
 }
 
+const propsKeyPrefix = "props:v2:"
+
+// metadata of cached section is stored along with section fields,
+// sections cached before values became JSON have no format field.
+const (
+	propsVersionField = models.ReservedFieldPrefix + "version"
+	propsFormatField  = models.ReservedFieldPrefix + "format"
+	propsFormatJSON   = "json"
+)
+
 // GetProperties should respond with user properties per game, app, user id and section
 // Example: cloud properties, game properties and so on
 func (r *RedisRepository) GetProperties(ctx context.Context, sections []string, scope *sharedmodels.Scope) ([]*models.Properties, error) {
@@ -60,12 +85,25 @@ func (r *RedisRepository) GetProperties(ctx context.Context, sections []string,
 			if err != nil {
 				continue
 			}
-			if len(attrs) == 0 {
+			// sections cached in previous format are read from postgres
+			if attrs[propsFormatField] != propsFormatJSON {
+				continue
+			}
+			version, err := strconv.ParseInt(attrs[propsVersionField], 10, 64)
+			if err != nil {
 				continue
 			}
+			delete(attrs, propsVersionField)
+			delete(attrs, propsFormatField)
+
+			data := make(models.Document, len(attrs))
+			for field, value := range attrs {
+				data[field] = json.RawMessage(value)
+			}
 
 			properties := &models.Properties{}
-			properties.Data = attrs
+			properties.Data = data
+			properties.Version = version
 			properties.Scope = *scope
 			properties.Section = sections[i]
 
@@ -76,27 +114,277 @@ func (r *RedisRepository) GetProperties(ctx context.Context, sections []string,
 	return result, nil
 }
 
-// SetProperties should respond with user properties per game, app, user id and section
-func (r *RedisRepository) SetProperties(ctx context.Context, collection []*models.Properties) error {
+// CacheProperties replaces cached sections by whole section data and
+// version, cached sections are evicted after ttl.
+func (r *RedisRepository) CacheProperties(ctx context.Context, collection []*models.Properties, ttl time.Duration) error {
+	pipe := r.conn.TxPipeline()
+
+	for _, properties := range collection {
+		key := propsKey(properties.Section, properties.Scope)
+
+		pipe.Del(ctx, key)
+
+		values := make([]string, 0, 2*len(properties.Data)+4)
+		for field, value := range properties.Data {
+			values = append(values, field, string(value))
+		}
+		values = append(values,
+			propsVersionField, strconv.FormatInt(properties.Version, 10),
+			propsFormatField, propsFormatJSON)
+		pipe.HSet(ctx, key, values)
+		if ttl > 0 {
+			pipe.Expire(ctx, key, ttl)
+		}
+	}
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "can't exec pipeline to cache properties")
+	}
+
+	return nil
+}
+
+// ScanLegacyProperties respond with batch of sections stored by legacy keys,
+// game and app are not the part of key and should be filled by caller.
+// Iteration is done once the returned cursor is zero.
+func (r *RedisRepository) ScanLegacyProperties(ctx context.Context, cursor uint64) ([]*models.Properties, uint64, error) {
+	keys, next, err := r.conn.Scan(ctx, cursor, "props:*", scanCount).Result()
+	if err != nil {
+		return nil, 0, errors.WithMessage(err, "can't scan properties keys")
+	}
+
+	var collection []*models.Properties
+	for _, key := range keys {
+		if strings.HasPrefix(key, propsKeyPrefix) {
+			continue
+		}
+
+		// props:<section>:<user_id>, section could have colons
+		idx := strings.LastIndex(key, ":")
+		if idx <= len("props:") {
+			continue
+		}
+
+		attrs, err := r.conn.HGetAll(ctx, key).Result()
+		if err != nil {
+			return nil, 0, errors.WithMessagef(err, "can't get properties %s", key)
+		}
+		if len(attrs) == 0 {
+			continue
+		}
+
+		collection = append(collection, &models.Properties{
+			Scope:   sharedmodels.Scope{UserID: key[idx+1:]},
+			Section: key[len("props:"):idx],
+			Data:    models.StringDocument(attrs),
+		})
+	}
+
+	return collection, next, nil
+}
+
+// GetLegacyProperties respond with sections of the user stored by legacy
+// keys, game and app are not the part of key and should be filled by caller.
+func (r *RedisRepository) GetLegacyProperties(ctx context.Context, sections []string, userID string) ([]*models.Properties, error) {
 	pipe := r.conn.Pipeline()
 
+	cmds := make([]*redis.StringStringMapCmd, 0, len(sections))
+	for _, section := range sections {
+		cmds = append(cmds, pipe.HGetAll(ctx, legacyPropsKey(section, userID)))
+	}
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't exec pipeline to get legacy properties")
+	}
+
+	var collection []*models.Properties
+	for i, cmd := range cmds {
+		attrs := cmd.Val()
+		if len(attrs) == 0 {
+			continue
+		}
+
+		collection = append(collection, &models.Properties{
+			Scope:   sharedmodels.Scope{UserID: userID},
+			Section: sections[i],
+			Data:    models.StringDocument(attrs),
+		})
+	}
+
+	return collection, nil
+}
+
+// DeleteLegacyProperties removes sections stored by legacy keys.
+func (r *RedisRepository) DeleteLegacyProperties(ctx context.Context, collection []*models.Properties) error {
+	if len(collection) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(collection))
 	for _, properties := range collection {
-		var values []string
-		for key, value := range properties.Data {
-			values = append(values, key, value)
+		keys = append(keys, legacyPropsKey(properties.Section, properties.UserID))
+	}
+
+	err := r.conn.Del(ctx, keys...).Err()
+	if err != nil {
+		return errors.WithMessage(err, "can't delete legacy properties")
+	}
+
+	return nil
+}
+
+// ListSections respond with section names stored for the user.
+func (r *RedisRepository) ListSections(ctx context.Context, scope *sharedmodels.Scope) ([]string, error) {
+	prefix := fmt.Sprintf("%s%s:%s:", propsKeyPrefix, scope.GameID, scope.AppID)
+	suffix := ":" + scope.UserID
+
+	var sections []string
+	var cursor uint64
+
+	for {
+		keys, next, err := r.conn.Scan(ctx, cursor, propsKey("*", *scope), scanCount).Result()
+		if err != nil {
+			return nil, errors.WithMessage(err, "can't scan properties keys")
 		}
 
-		key := propsKey(properties.Section, properties.Scope)
-		r.logger.
-			With("key", key).
-			With("section", properties.Section).
-			Debugf("set values: %v", values)
-		pipe.HSet(ctx, key, values)
+		for _, key := range keys {
+			section := strings.TrimSuffix(strings.TrimPrefix(key, prefix), suffix)
+			sections = append(sections, section)
+		}
+
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+
+	return sections, nil
+}
+
+// DeleteProperties removes sections stored for the user.
+func (r *RedisRepository) DeleteProperties(ctx context.Context, sections []string, scope *sharedmodels.Scope) error {
+	if len(sections) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(sections))
+	for _, section := range sections {
+		keys = append(keys, propsKey(section, *scope))
+	}
+
+	err := r.conn.Del(ctx, keys...).Err()
+	if err != nil {
+		return errors.WithMessage(err, "can't delete properties from redis")
+	}
+
+	return nil
+}
+
+// SetAliases points merged user ids to the surviving user id, aliases
+// are read by auth.RedisAliasResolver.
+func (r *RedisRepository) SetAliases(ctx context.Context, aliases []string, userID string) error {
+	pipe := r.conn.Pipeline()
+
+	for _, alias := range aliases {
+		pipe.Set(ctx, auth.AliasKey(alias), userID, 0)
 	}
 
 	_, err := pipe.Exec(ctx)
 	if err != nil {
-		return errors.WithMessage(err, "can't exec pipeline to set properties from redis")
+		return errors.WithMessage(err, "can't exec pipeline to set aliases")
+	}
+
+	return nil
+}
+
+// failedSigninsKey counts attempts per email and ip, so failed attempts
+// from other addresses don't lock out the operator.
+func failedSigninsKey(email, ip string) string {
+	return fmt.Sprintf("signin:failures:%s:%s", normalizeEmail(email), ip)
+}
+
+func failedTOTPsKey(operatorID string) string {
+	return fmt.Sprintf("totp:failures:%s", operatorID)
+}
+
+// FailedSignins respond with number of failed sign in attempts
+// for the email from the ip during the current window.
+func (r *RedisRepository) FailedSignins(ctx context.Context, email, ip string) (int64, error) {
+	count, err := r.failures(ctx, failedSigninsKey(email, ip))
+	return count, errors.WithMessage(err, "can't get failed sign ins")
+}
+
+// AddFailedSignin counts failed sign in attempt, the window starts
+// on the first failed attempt.
+func (r *RedisRepository) AddFailedSignin(ctx context.Context, email, ip string, window time.Duration) error {
+	err := r.addFailure(ctx, failedSigninsKey(email, ip), window)
+	return errors.WithMessage(err, "can't count failed sign in")
+}
+
+// ResetFailedSignins clears failed attempts on successful sign in.
+func (r *RedisRepository) ResetFailedSignins(ctx context.Context, email, ip string) error {
+	err := r.conn.Del(ctx, failedSigninsKey(email, ip)).Err()
+	return errors.WithMessage(err, "can't reset failed sign ins")
+}
+
+// FailedTOTPs respond with number of invalid codes of second factor
+// of the operator during the current window.
+func (r *RedisRepository) FailedTOTPs(ctx context.Context, operatorID string) (int64, error) {
+	count, err := r.failures(ctx, failedTOTPsKey(operatorID))
+	return count, errors.WithMessage(err, "can't get failed totp codes")
+}
+
+// AddFailedTOTP counts invalid code of second factor, the window starts
+// on the first invalid code.
+func (r *RedisRepository) AddFailedTOTP(ctx context.Context, operatorID string, window time.Duration) error {
+	err := r.addFailure(ctx, failedTOTPsKey(operatorID), window)
+	return errors.WithMessage(err, "can't count failed totp code")
+}
+
+// ResetFailedTOTPs clears invalid codes once valid code is accepted.
+func (r *RedisRepository) ResetFailedTOTPs(ctx context.Context, operatorID string) error {
+	err := r.conn.Del(ctx, failedTOTPsKey(operatorID)).Err()
+	return errors.WithMessage(err, "can't reset failed totp codes")
+}
+
+func (r *RedisRepository) failures(ctx context.Context, key string) (int64, error) {
+	count, err := r.conn.Get(ctx, key).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+
+	return count, err
+}
+
+func (r *RedisRepository) addFailure(ctx context.Context, key string, window time.Duration) error {
+	count, err := r.conn.Incr(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+
+	if count == 1 {
+		return r.conn.Expire(ctx, key, window).Err()
+	}
+
+	return nil
+}
+
+// DeleteAliases removes aliases of merged users.
+func (r *RedisRepository) DeleteAliases(ctx context.Context, aliases []string) error {
+	if len(aliases) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(aliases))
+	for _, alias := range aliases {
+		keys = append(keys, auth.AliasKey(alias))
+	}
+
+	err := r.conn.Del(ctx, keys...).Err()
+	if err != nil {
+		return errors.WithMessage(err, "can't delete aliases")
 	}
 
 	return nil
@@ -2,13 +2,17 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
+	"time"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 
 	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/auth"
 	"gitlab.com/balconygames/analytics/pkg/logging"
 	test_helpers "gitlab.com/balconygames/analytics/pkg/test_helpers"
 	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
@@ -50,23 +54,128 @@ func (s serviceRedisSuite) TestGetProperties() {
 	collection := []*models.Properties{
 		{
 			Section: "users",
-			Data:    map[string]string{"users1": "users-value1", "users2": "users-value2"},
+			Data:    models.StringDocument(map[string]string{"users1": "users-value1", "users2": "users-value2"}),
+			Version: 3,
 			Scope:   scope,
 		},
 		{
 			Section: "game",
-			Data:    map[string]string{"game1": "game-value1", "game2": "game-value2"},
+			Data:    models.Document{"game1": json.RawMessage(`{"level":1}`), "game2": json.RawMessage(`[1,2]`)},
+			Version: 1,
 			Scope:   scope,
 		},
 	}
-	err = repo.SetProperties(context.Background(), collection)
+	err = repo.CacheProperties(context.Background(), collection, time.Minute)
 	s.Require().Nil(err)
 
 	output, err := repo.GetProperties(context.Background(), []string{"users", "cloud", "game"}, &scope)
 	s.Require().Nil(err)
 	s.Require().Len(output, 2)
-	s.Require().Equal("users-value1", output[0].Data["users1"])
-	s.Require().Equal("users-value2", output[0].Data["users2"])
-	s.Require().Equal("game-value1", output[1].Data["game1"])
-	s.Require().Equal("game-value2", output[1].Data["game2"])
+	s.Require().Equal(`"users-value1"`, string(output[0].Data["users1"]))
+	s.Require().Equal(`"users-value2"`, string(output[0].Data["users2"]))
+	s.Require().Equal(`{"level":1}`, string(output[1].Data["game1"]))
+	s.Require().Equal(`[1,2]`, string(output[1].Data["game2"]))
+	s.Require().Equal(int64(3), output[0].Version)
+	s.Require().Len(output[0].Data, 2)
+}
+
+func (s serviceRedisSuite) TestGetLegacyProperties() {
+	repo := NewRedisRepository(s.Conn, s.logger)
+
+	err := s.Conn.HSet(context.Background(), legacyPropsKey("progress", userID), "level", "3").Err()
+	s.Require().Nil(err)
+
+	output, err := repo.GetLegacyProperties(context.Background(), []string{"settings", "progress"}, userID)
+	s.Require().Nil(err)
+	s.Require().Len(output, 1)
+	s.Require().Equal("progress", output[0].Section)
+	s.Require().Equal(userID, output[0].UserID)
+	s.Require().Equal(`"3"`, string(output[0].Data["level"]))
+}
+
+func (s serviceRedisSuite) TestRefreshTokenRotation() {
+	repo := NewRedisRepository(s.Conn, s.logger)
+
+	session := &models.Session{
+		UserInfo: auth.UserInfo{
+			GameID:    gameID,
+			AppID:     appID,
+			UserID:    userID,
+			SessionID: "session-1",
+			Type:      auth.GuestType,
+		},
+	}
+	err := repo.CreateRefreshToken(context.Background(), session, time.Minute)
+	s.Require().NoError(err)
+	s.Require().NotEmpty(session.RefreshToken)
+
+	used, err := repo.UseRefreshToken(context.Background(), session.RefreshToken)
+	s.Require().NoError(err)
+	s.Require().Equal(session.UserInfo, used.UserInfo)
+
+	_, err = repo.UseRefreshToken(context.Background(), session.RefreshToken)
+	s.Require().Equal(models.ErrRefreshTokenReused, err)
+
+	_, err = repo.UseRefreshToken(context.Background(), "unknown")
+	s.Require().Equal(models.ErrInvalidRefreshToken, err)
+
+	err = repo.CreateRefreshToken(context.Background(), session, time.Minute)
+	s.Require().NoError(err)
+	err = repo.RevokeSession(context.Background(), session.SessionID, time.Minute)
+	s.Require().NoError(err)
+
+	_, err = repo.UseRefreshToken(context.Background(), session.RefreshToken)
+	s.Require().Equal(models.ErrInvalidRefreshToken, err)
+}
+
+func (s serviceRedisSuite) TestMagicLinkOneTimeUse() {
+	repo := NewRedisRepository(s.Conn, s.logger)
+
+	link := &models.MagicLink{
+		Scope: sharedmodels.Scope{
+			GameID: gameID,
+			AppID:  appID,
+		},
+		Email:    "player@example.com",
+		DeviceID: "device-1",
+		GuestID:  userID,
+	}
+	err := repo.CreateMagicLink(context.Background(), link, time.Minute)
+	s.Require().NoError(err)
+	s.Require().NotEmpty(link.Token)
+
+	used, err := repo.UseMagicLink(context.Background(), link.Token)
+	s.Require().NoError(err)
+	s.Require().Equal(link.Email, used.Email)
+	s.Require().Equal(link.Scope, used.Scope)
+	s.Require().Equal(link.GuestID, used.GuestID)
+
+	_, err = repo.UseMagicLink(context.Background(), link.Token)
+	s.Require().Equal(models.ErrInvalidMagicLink, err)
+}
+
+func (s serviceRedisSuite) TestMoveScores() {
+	repo := NewLeaderboardRepository(s.Conn, s.logger)
+	ctx := context.Background()
+
+	// scores are set by leaderboard module
+	s.Require().Nil(s.Conn.ZAdd(ctx, scoreKey("board"), &redis.Z{Score: 10, Member: "merged"}).Err())
+	s.Require().Nil(s.Conn.HSet(ctx, scoreUserKey("board", "merged"), "value", "10").Err())
+	s.Require().Nil(s.Conn.SAdd(ctx, userLeaderboardsKey("merged"), "board").Err())
+
+	s.Require().Nil(repo.MoveScores(ctx, "merged", "survivor"))
+	// moved scores are skipped on retry
+	s.Require().Nil(repo.MoveScores(ctx, "merged", "survivor"))
+
+	ids, err := repo.ListLeaderboards(ctx, "survivor")
+	s.Require().Nil(err)
+	s.Require().Equal([]string{"board"}, ids)
+
+	ids, err = repo.ListLeaderboards(ctx, "merged")
+	s.Require().Nil(err)
+	s.Require().Empty(ids)
+
+	score, err := s.Conn.ZScore(ctx, scoreKey("board"), "survivor").Result()
+	s.Require().Nil(err)
+	s.Require().Equal(float64(10), score)
 }
@@ -0,0 +1,270 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+const selectSave = `
+	SELECT
+		game_id
+		, app_id
+		, user_id
+		, slot
+		, version
+		, blob_ref
+		, size
+		, compressed_size
+		, checksum
+		, device
+		, play_time
+		, progress
+		, created_at
+		, updated_at
+	FROM user_saves
+`
+
+func scanSave(row pgx.Row) (*models.Save, error) {
+	save := &models.Save{}
+	err := row.Scan(&save.GameID, &save.AppID, &save.UserID, &save.Slot,
+		&save.Version, &save.BlobRef, &save.Size, &save.CompressedSize,
+		&save.Checksum, &save.Device, &save.PlayTime, &save.Progress,
+		&save.CreatedAt, &save.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, models.ErrSaveNotFound
+	}
+
+	return save, err
+}
+
+// ListSaves respond with saves of the user ordered by slot.
+func (r PostgresRepository) ListSaves(ctx context.Context, scope *sharedmodels.Scope) ([]*models.Save, error) {
+	rows, err := r.pool.Query(ctx, selectSave+`
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+		ORDER BY slot
+	`, scope.GameID, scope.AppID, scope.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	saves := []*models.Save{}
+	for rows.Next() {
+		save, err := scanSave(rows)
+		if err != nil {
+			return nil, err
+		}
+		saves = append(saves, save)
+	}
+
+	return saves, rows.Err()
+}
+
+// FindSave respond with save of the slot.
+func (r PostgresRepository) FindSave(ctx context.Context, scope *sharedmodels.Scope, slot string) (*models.Save, error) {
+	return scanSave(r.pool.QueryRow(ctx, selectSave+`
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+			AND slot=$4
+	`, scope.GameID, scope.AppID, scope.UserID, slot))
+}
+
+// PutSave stores save into the slot and respond with blob reference of
+// the replaced save. Save with expected version is stored only if the
+// slot has the version, zero version expects the empty slot, otherwise
+// SaveConflictError with the current save is returned. New slots are
+// rejected by ErrTooManySaveSlots once the user has maxSlots saves.
+func (r PostgresRepository) PutSave(ctx context.Context, save *models.Save, expectedVersion *int64, maxSlots int) (string, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	current, err := scanSave(tx.QueryRow(ctx, selectSave+`
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+			AND slot=$4
+		FOR UPDATE
+	`, save.GameID, save.AppID, save.UserID, save.Slot))
+	if err != nil && err != models.ErrSaveNotFound {
+		return "", errors.WithMessage(err, "can't lock save")
+	}
+
+	var version int64
+	if current != nil {
+		version = current.Version
+	}
+	if expectedVersion != nil && *expectedVersion != version {
+		if current == nil {
+			current = &models.Save{Scope: save.Scope, Slot: save.Slot}
+		}
+		return "", &models.SaveConflictError{Current: current}
+	}
+
+	if current == nil && maxSlots > 0 {
+		var slots int
+		err = tx.QueryRow(ctx, `
+			SELECT COUNT(*)
+			FROM user_saves
+			WHERE
+				game_id=$1
+				AND app_id=$2
+				AND user_id=$3
+		`, save.GameID, save.AppID, save.UserID).Scan(&slots)
+		if err != nil {
+			return "", err
+		}
+		if slots >= maxSlots {
+			return "", models.ErrTooManySaveSlots
+		}
+	}
+
+	query := `
+		INSERT INTO
+			user_saves (
+				game_id
+				, app_id
+				, user_id
+				, slot
+				, blob_ref
+				, size
+				, compressed_size
+				, checksum
+				, device
+				, play_time
+				, progress
+			)
+			VALUES (
+				$1
+				, $2
+				, $3
+				, $4
+				, $5
+				, $6
+				, $7
+				, $8
+				, $9
+				, $10
+				, $11
+			)
+		ON CONFLICT (
+			game_id
+			, app_id
+			, user_id
+			, slot
+		)
+		DO UPDATE SET
+			version = user_saves.version + 1
+			, blob_ref = EXCLUDED.blob_ref
+			, size = EXCLUDED.size
+			, compressed_size = EXCLUDED.compressed_size
+			, checksum = EXCLUDED.checksum
+			, device = EXCLUDED.device
+			, play_time = EXCLUDED.play_time
+			, progress = EXCLUDED.progress
+			, updated_at = NOW()
+		WHERE user_saves.version = $12
+		RETURNING version, created_at, updated_at
+	`
+
+	err = tx.QueryRow(ctx, query, save.GameID, save.AppID, save.UserID, save.Slot,
+		save.BlobRef, save.Size, save.CompressedSize, save.Checksum, save.Device,
+		save.PlayTime, save.Progress, version).
+		Scan(&save.Version, &save.CreatedAt, &save.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		// slot is created by concurrent save
+		current, err = scanSave(tx.QueryRow(ctx, selectSave+`
+			WHERE
+				game_id=$1
+				AND app_id=$2
+				AND user_id=$3
+				AND slot=$4
+		`, save.GameID, save.AppID, save.UserID, save.Slot))
+		if err != nil {
+			return "", err
+		}
+		return "", &models.SaveConflictError{Current: current}
+	}
+	if err != nil {
+		return "", errors.WithMessage(err, "can't store save")
+	}
+
+	var replaced string
+	if current != nil {
+		replaced = current.BlobRef
+	}
+
+	return replaced, tx.Commit(ctx)
+}
+
+// DeleteSave removes save of the slot and respond with its blob reference.
+func (r PostgresRepository) DeleteSave(ctx context.Context, scope *sharedmodels.Scope, slot string) (string, error) {
+	var ref string
+	err := r.pool.QueryRow(ctx, `
+		DELETE FROM user_saves
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=$3
+			AND slot=$4
+		RETURNING blob_ref
+	`, scope.GameID, scope.AppID, scope.UserID, slot).Scan(&ref)
+	if err == pgx.ErrNoRows {
+		return "", models.ErrSaveNotFound
+	}
+
+	return ref, err
+}
+
+// UserSaveRefs respond with blob references of saves of the user and
+// users merged into it, blobs should be deleted along with the user.
+func (r PostgresRepository) UserSaveRefs(ctx context.Context, scope *sharedmodels.Scope) ([]string, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	ids, err := userIDs(ctx, tx, scope)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't list merged users")
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT blob_ref
+		FROM user_saves
+		WHERE
+			game_id=$1
+			AND app_id=$2
+			AND user_id=ANY($3)
+	`, scope.GameID, scope.AppID, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []string
+	for rows.Next() {
+		var ref string
+		err = rows.Scan(&ref)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+
+	return refs, rows.Err()
+}
@@ -0,0 +1,213 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+)
+
+const selectSection = `
+	SELECT
+		game_id
+		, section
+		, shared
+		, schema
+		, max_size
+		, mode
+		, updated_at
+	FROM property_sections
+`
+
+func scanSections(rows pgx.Rows) ([]*models.Section, error) {
+	defer rows.Close()
+
+	sections := []*models.Section{}
+	for rows.Next() {
+		var schema []byte
+
+		section := &models.Section{}
+		err := rows.Scan(&section.GameID, &section.Name, &section.Shared,
+			&schema, &section.MaxSize, &section.Mode, &section.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		section.Schema = schema
+
+		sections = append(sections, section)
+	}
+
+	return sections, rows.Err()
+}
+
+// ListSectionSettings respond with settings of sections of the game.
+func (r PostgresRepository) ListSectionSettings(ctx context.Context, gameID string) ([]*models.Section, error) {
+	rows, err := r.pool.Query(ctx, selectSection+`WHERE game_id=$1 ORDER BY section`, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanSections(rows)
+}
+
+// ListSharedSections respond with shared sections of all games.
+func (r PostgresRepository) ListSharedSections(ctx context.Context) ([]*models.Section, error) {
+	rows, err := r.pool.Query(ctx, selectSection+`WHERE shared ORDER BY game_id, section`)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanSections(rows)
+}
+
+// UpsertSectionSettings creates or updates settings of section.
+func (r PostgresRepository) UpsertSectionSettings(ctx context.Context, section *models.Section) error {
+	query := `
+		INSERT INTO
+			property_sections (
+				game_id
+				, section
+				, shared
+				, schema
+				, max_size
+				, mode
+			)
+			VALUES (
+				$1
+				, $2
+				, $3
+				, $4
+				, $5
+				, $6
+			)
+		ON CONFLICT (
+			game_id
+			, section
+		)
+		DO UPDATE SET
+			shared = EXCLUDED.shared
+			, schema = EXCLUDED.schema
+			, max_size = EXCLUDED.max_size
+			, mode = EXCLUDED.mode
+			, updated_at = NOW()
+		RETURNING updated_at
+	`
+
+	// nil schema is stored as NULL instead of JSON null
+	return r.pool.QueryRow(ctx, query, section.GameID, section.Name, section.Shared,
+		[]byte(section.Schema), section.MaxSize, section.Mode).Scan(&section.UpdatedAt)
+}
+
+// HasSharedProperties checks if any user has values of shared section.
+func (r PostgresRepository) HasSharedProperties(ctx context.Context, gameID, section string) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1
+			FROM user_properties
+			WHERE
+				game_id=$1
+				AND app_id=$2
+				AND section=$3
+		)
+	`
+
+	var exists bool
+	err := r.pool.QueryRow(ctx, query, gameID, models.SharedApp, section).Scan(&exists)
+
+	return exists, err
+}
+
+// ShareSection moves values of section stored per app into shared values,
+// values of the latest updated app win. It respond with number of moved rows.
+func (r PostgresRepository) ShareSection(ctx context.Context, gameID, section string) (int, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT
+			user_id
+			, data
+		FROM user_properties
+		WHERE
+			game_id=$1
+			AND app_id<>$2
+			AND section=$3
+		ORDER BY updated_at
+		FOR UPDATE
+	`, gameID, models.SharedApp, section)
+	if err != nil {
+		return 0, err
+	}
+
+	type movedRow struct {
+		userID string
+		data   models.Document
+	}
+
+	var moved []movedRow
+	for rows.Next() {
+		var row movedRow
+		err = rows.Scan(&row.userID, &row.data)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		moved = append(moved, row)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// rows are ordered by update time, values of the latest
+	// updated app override previous ones.
+	for _, row := range moved {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO
+				user_properties (
+					game_id
+					, app_id
+					, user_id
+					, section
+					, data
+				)
+				VALUES (
+					$1
+					, $2
+					, $3
+					, $4
+					, $5
+				)
+			ON CONFLICT (
+				game_id
+				, app_id
+				, user_id
+				, section
+			)
+			DO UPDATE SET
+				data = user_properties.data || EXCLUDED.data
+				, version = user_properties.version + 1
+				, updated_at = NOW()
+		`, gameID, models.SharedApp, row.userID, section, row.data)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM user_properties
+		WHERE
+			game_id=$1
+			AND app_id<>$2
+			AND section=$3
+	`, gameID, models.SharedApp, section)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(moved), tx.Commit(ctx)
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+)
+
+const refreshTokenSize = 32
+
+// refreshKey is using hash of the token to don't store
+// tokens as is.
+func refreshKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("refresh:%s", hex.EncodeToString(sum[:]))
+}
+
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CreateRefreshToken generates the new refresh token for the session.
+func (r *RedisRepository) CreateRefreshToken(ctx context.Context, session *models.Session, ttl time.Duration) error {
+	token, err := randomToken(refreshTokenSize)
+	if err != nil {
+		return errors.WithMessage(err, "can't generate refresh token")
+	}
+
+	key := refreshKey(token)
+
+	pipe := r.conn.TxPipeline()
+	pipe.HSet(ctx, key,
+		"sid", session.SessionID,
+		"uid", session.UserID,
+		"did", session.DeviceID,
+		"gid", session.GameID,
+		"aid", session.AppID,
+		"utp", session.Type,
+		"iat_ns", strconv.FormatInt(time.Now().UTC().UnixNano(), 10),
+		"used", "0",
+	)
+	pipe.Expire(ctx, key, ttl)
+
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "can't exec pipeline to store refresh token")
+	}
+
+	session.RefreshToken = token
+
+	return nil
+}
+
+// UseRefreshToken marks refresh token as used and respond with its session,
+// models.ErrRefreshTokenReused returned on the second use of the token.
+func (r *RedisRepository) UseRefreshToken(ctx context.Context, token string) (*models.Session, error) {
+	key := refreshKey(token)
+
+	attrs, err := r.conn.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't get refresh token")
+	}
+	if attrs["sid"] == "" {
+		return nil, models.ErrInvalidRefreshToken
+	}
+
+	session := &models.Session{
+		UserInfo: auth.UserInfo{
+			SessionID: attrs["sid"],
+			UserID:    attrs["uid"],
+			DeviceID:  attrs["did"],
+			GameID:    attrs["gid"],
+			AppID:     attrs["aid"],
+			Type:      attrs["utp"],
+		},
+	}
+
+	// counter is atomic, only the first use of the token
+	// receives 1.
+	used, err := r.conn.HIncrBy(ctx, key, "used", 1).Result()
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't mark refresh token as used")
+	}
+	if used > 1 {
+		return session, models.ErrRefreshTokenReused
+	}
+
+	// refresh tokens created before iat_ns have iat in seconds
+	issuedAt, _ := strconv.ParseInt(attrs["iat"], 10, 64)
+	issuedAtNano, _ := strconv.ParseInt(attrs["iat_ns"], 10, 64)
+	claims := &auth.Claims{
+		UserInfo:       session.UserInfo,
+		StandardClaims: jwt.StandardClaims{IssuedAt: issuedAt},
+		IssuedAtNano:   issuedAtNano,
+	}
+
+	revoked, err := r.IsRevoked(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, models.ErrInvalidRefreshToken
+	}
+
+	return session, nil
+}
+
+// IsRevoked checks if session or all sessions of the user of claims were revoked.
+func (r *RedisRepository) IsRevoked(ctx context.Context, claims *auth.Claims) (bool, error) {
+	return auth.NewRedisRevocationChecker(r.conn).IsRevoked(ctx, claims)
+}
+
+// RevokeSession revokes access and refresh tokens of the session.
+func (r *RedisRepository) RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error {
+	err := r.conn.Set(ctx, auth.RevokedSessionKey(sessionID), "1", ttl).Err()
+	if err != nil {
+		return errors.WithMessage(err, "can't revoke session")
+	}
+
+	return nil
+}
+
+// RevokeUser revokes all tokens of the user issued till now.
+func (r *RedisRepository) RevokeUser(ctx context.Context, userID string, ttl time.Duration) error {
+	err := r.conn.Set(ctx, auth.RevokedUserKey(userID), auth.RevokedAt(time.Now()), ttl).Err()
+	if err != nil {
+		return errors.WithMessage(err, "can't revoke user sessions")
+	}
+
+	return nil
+}
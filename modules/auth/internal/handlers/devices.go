@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+)
+
+// deviceRequest contains attributes of the device sent on sync
+// and device update.
+type deviceRequest struct {
+	// Platform is ios, android, web
+	Platform   string `json:"platform"`
+	AppVersion string `json:"app_version"`
+	OSVersion  string `json:"os_version"`
+	PushToken  string `json:"push_token"`
+}
+
+// ListDevicesHandler respond with devices used by the user by JWT token.
+func (h *Handler) ListDevicesHandler(w http.ResponseWriter, r *http.Request) {
+	devices, err := h.service.ListDevices(r.Context(), auth.GetScope(r), auth.GetUser(r).DeviceID)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't list devices"))
+		return
+	}
+
+	httpreq.JSON(w, map[string]interface{}{"devices": devices})
+}
+
+// UpdateDeviceHandler updates attributes of the device of JWT token,
+// e.g. push token refreshed by the platform.
+func (h *Handler) UpdateDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	data := deviceRequest{}
+	err := httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read device body"))
+		return
+	}
+
+	device := &models.Device{
+		Scope:      *auth.GetScope(r),
+		DeviceID:   auth.GetUser(r).DeviceID,
+		Platform:   data.Platform,
+		AppVersion: data.AppVersion,
+		OSVersion:  data.OSVersion,
+		PushToken:  data.PushToken,
+	}
+
+	err = h.service.UpdateDevice(r.Context(), device)
+	if errors.Is(err, models.ErrDeviceRevoked) {
+		httpreq.Forbidden(w, models.ErrDeviceRevoked)
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't update device"))
+		return
+	}
+
+	httpreq.OK(w)
+}
+
+// RevokeDeviceHandler revokes the device of the user by JWT token,
+// e.g. lost phone. Sessions of the device can't be refreshed after that.
+func (h *Handler) RevokeDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	scope := auth.GetScope(r)
+	deviceID := chi.URLParam(r, "device_id")
+
+	err := h.service.RevokeDevice(r.Context(), scope, deviceID)
+	if errors.Is(err, models.ErrDeviceNotFound) {
+		httpreq.JSONWithStatus(w, http.StatusNotFound, map[string]string{"error": models.ErrDeviceNotFound.Error()})
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't revoke device"))
+		return
+	}
+
+	h.logger.With(scope.Fields()...).With("device_id", deviceID).Info("revoked device")
+
+	httpreq.OK(w)
+}
+
+// ServerListDevices respond with devices used by the player.
+func (h *Handler) ServerListDevices(w http.ResponseWriter, r *http.Request) {
+	devices, err := h.service.ListDevices(r.Context(), playerScope(r), "")
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't list devices"))
+		return
+	}
+
+	httpreq.JSON(w, map[string]interface{}{"devices": devices})
+}
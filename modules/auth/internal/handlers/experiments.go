@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/experiments"
+	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+type experimentRequest struct {
+	Description string                `json:"description"`
+	Status      string                `json:"status"`
+	Variants    []experiments.Variant `json:"variants"`
+}
+
+// assignExperiments respond with variants of the user, sync doesn't
+// fail once experiments can't be loaded.
+func (h *Handler) assignExperiments(r *http.Request, log *zap.SugaredLogger, scope *sharedmodels.Scope) map[string]string {
+	assignments, err := h.service.Assign(r.Context(), scope)
+	if err != nil {
+		log.Errorf("can't assign experiments: %s", err)
+		return map[string]string{}
+	}
+
+	return assignments
+}
+
+// ListExperimentsHandler respond with experiments of the game.
+func (h *Handler) ListExperimentsHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := h.service.ListExperiments(r.Context(), chi.URLParam(r, "game_id"))
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't list experiments"))
+		return
+	}
+
+	httpreq.JSON(w, map[string]interface{}{"experiments": list})
+}
+
+// UpdateExperimentHandler creates or updates experiment of the game,
+// changes of weights reassign part of users to other variants.
+func (h *Handler) UpdateExperimentHandler(w http.ResponseWriter, r *http.Request) {
+	data := experimentRequest{}
+	err := httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read experiment body"))
+		return
+	}
+
+	experiment := &models.Experiment{
+		GameID:      chi.URLParam(r, "game_id"),
+		Key:         chi.URLParam(r, "key"),
+		Description: data.Description,
+		Status:      data.Status,
+		Variants:    data.Variants,
+	}
+
+	err = h.service.UpdateExperiment(r.Context(), experiment)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't update experiment"))
+		return
+	}
+
+	h.logger.
+		With("game_id", experiment.GameID, "key", experiment.Key).
+		Infof("updated experiment %s with variants %v", experiment.Status, experiment.Variants)
+
+	httpreq.JSON(w, experiment)
+}
+
+// DeleteExperimentHandler removes experiment of the game.
+func (h *Handler) DeleteExperimentHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "game_id")
+	key := chi.URLParam(r, "key")
+
+	err := h.service.DeleteExperiment(r.Context(), gameID, key)
+	if errors.Is(err, models.ErrExperimentNotFound) {
+		httpreq.JSONWithStatus(w, http.StatusNotFound, map[string]string{"error": models.ErrExperimentNotFound.Error()})
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't delete experiment"))
+		return
+	}
+
+	h.logger.With("game_id", gameID, "key", key).Info("deleted experiment")
+
+	httpreq.OK(w)
+}
+
+// ServerGetPlayerExperiments respond with variants assigned to the player.
+func (h *Handler) ServerGetPlayerExperiments(w http.ResponseWriter, r *http.Request) {
+	assignments, err := h.service.Assign(r.Context(), playerScope(r))
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't assign experiments"))
+		return
+	}
+
+	httpreq.JSON(w, map[string]interface{}{"experiments": assignments})
+}
@@ -3,18 +3,44 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/go-chi/chi"
+
 	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+	"gitlab.com/balconygames/analytics/pkg/secrets"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
 )
 
-// FacebookMiddleware should have callback implementation to support web sign in
-func (h Handler) FacebookMiddleware(next http.Handler) http.Handler {
+// credentialsMiddleware looks up credentials of the game in the network,
+// responds with 404 once they are not configured.
+func (h Handler) credentialsMiddleware(network string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// todo: search for creds per app_id
-		ctx := r.Context()
+		gameID := chi.URLParam(r, "game_id")
+
+		var c *sharedmodels.NetworkCredentials
+		err := secrets.ErrCredentialsNotFound
+		if h.service.Credentials != nil {
+			c, err = h.service.Credentials.LookupCredentials(r.Context(), gameID, network, "")
+		}
+		if err == secrets.ErrCredentialsNotFound {
+			httpreq.JSONWithStatus(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			h.logger.With("game_id", gameID, "network", network).Errorf("can't lookup credentials: %v", err)
+			httpreq.Error(w, err)
+			return
+		}
+
+		ctx := secrets.WithCredentials(r.Context(), c)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// FacebookMiddleware stores Facebook credentials of the game in the context
+func (h Handler) FacebookMiddleware(next http.Handler) http.Handler {
+	return h.credentialsMiddleware(sharedmodels.FacebookNetwork, next)
+}
+
 // FacebookLogin should have callback implementation to support web sign in
 func (h Handler) FacebookLogin(w http.ResponseWriter, r *http.Request) {
 	httpreq.NotImplemented(w)
@@ -25,13 +51,9 @@ func (h Handler) FacebookCallback(w http.ResponseWriter, r *http.Request) {
 	httpreq.NotImplemented(w)
 }
 
-// GoogleMiddleware should have callback implementation to support web sign in
+// GoogleMiddleware stores Google credentials of the game in the context
 func (h Handler) GoogleMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// todo: search for creds per app_id
-		ctx := r.Context()
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+	return h.credentialsMiddleware(sharedmodels.GoogleNetwork, next)
 }
 
 // GoogleLogin should have callback implementation to support web sign in
@@ -44,13 +66,9 @@ func (h Handler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 	httpreq.NotImplemented(w)
 }
 
-// TwitterMiddleware should have callback implementation to support web sign in
+// TwitterMiddleware stores Twitter credentials of the game in the context
 func (h Handler) TwitterMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// todo: search for creds per app_id
-		ctx := r.Context()
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+	return h.credentialsMiddleware(sharedmodels.TwitterNetwork, next)
 }
 
 // TwitterLogin should have callback implementation to support web sign in
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+type recoverGuestRequest struct {
+	// required to be here
+	DeviceID     string `json:"device_id"`
+	RecoveryCode string `json:"recovery_code"`
+
+	deviceRequest
+
+	PropertiesSections []string `json:"props_sections"`
+}
+
+type recoveryCodeResponse struct {
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type guestPolicyRequest struct {
+	Scope string `json:"scope"`
+}
+
+// CreateRecoveryCodeHandler generates the new recovery code of the
+// guest by JWT token, the previous code can't be used anymore.
+func (h *Handler) CreateRecoveryCodeHandler(w http.ResponseWriter, r *http.Request) {
+	scope := auth.GetScope(r)
+
+	code, err := h.service.CreateRecoveryCode(r.Context(), scope)
+	if err == models.ErrRecoveryNotAvailable {
+		httpreq.JSONWithStatus(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't create recovery code"))
+		return
+	}
+
+	h.logger.With(scope.Fields()...).Info("created recovery code")
+
+	httpreq.JSON(w, recoveryCodeResponse{RecoveryCode: code})
+}
+
+// RecoverGuestHandler restores the guest by recovery code on the new
+// device and respond like anonymous sync, the device is recognised
+// as device of the guest by the following syncs.
+func (h *Handler) RecoverGuestHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "game_id")
+	appID := chi.URLParam(r, "app_id")
+
+	log := h.logger.With("game_id", gameID, "app_id", appID)
+
+	data := recoverGuestRequest{}
+	err := httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read recover body"))
+		return
+	}
+
+	user := models.User{
+		DeviceID: data.DeviceID,
+
+		Platform:   data.Platform,
+		AppVersion: data.AppVersion,
+		OSVersion:  data.OSVersion,
+		PushToken:  data.PushToken,
+
+		Scope: sharedmodels.Scope{
+			GameID: gameID,
+			AppID:  appID,
+		},
+	}
+
+	log = log.With("device_id", data.DeviceID)
+
+	properties, err := h.service.RecoverGuest(r.Context(), data.PropertiesSections, &user, data.RecoveryCode)
+	if errors.Is(err, models.ErrInvalidRecoveryCode) {
+		log.Info("invalid recovery code")
+		httpreq.Unauthorized(w, models.ErrInvalidRecoveryCode)
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't recover guest"))
+		return
+	}
+
+	log = log.With("user_id", user.UserID)
+	log.Info("recovered guest")
+
+	h.respondGuestSync(w, r, log, &user, properties)
+}
+
+// GetGuestPolicyHandler respond with guest policy of the game.
+func (h *Handler) GetGuestPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	policy, err := h.service.GetGuestPolicy(r.Context(), chi.URLParam(r, "game_id"))
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't get guest policy"))
+		return
+	}
+
+	httpreq.JSON(w, policy)
+}
+
+// UpdateGuestPolicyHandler sets guest policy of the game, e.g. to use
+// the same guest by all apps of the game on the device.
+func (h *Handler) UpdateGuestPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	data := guestPolicyRequest{}
+	err := httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read guest policy body"))
+		return
+	}
+
+	policy := &models.GuestPolicy{
+		GameID: chi.URLParam(r, "game_id"),
+		Scope:  data.Scope,
+	}
+
+	h.logger.With("game_id", policy.GameID).Infof("update guest policy %s", policy.Scope)
+
+	err = h.service.UpdateGuestPolicy(r.Context(), policy)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't update guest policy"))
+		return
+	}
+
+	httpreq.JSON(w, policy)
+}
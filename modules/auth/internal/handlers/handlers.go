@@ -33,6 +33,10 @@ func New(s *service.Service, l *zap.SugaredLogger) *Handler {
 type syncAnomRequest struct {
 	// required to be here
 	DeviceID string `json:"device_id"`
+	// Locale is used to generate guest name, e.g. en or pt-BR
+	Locale string `json:"locale"`
+
+	deviceRequest
 
 	// PropertiesSections should be used to get on auth request settings in response
 	// for further initialize in the client.
@@ -44,7 +48,15 @@ type syncAnomResponse struct {
 	UserName string `json:"user_name"`
 	JWT      string `json:"jwt"`
 
+	// RefreshToken used to receive the new jwt token
+	// once it's expired in ExpiresIn seconds.
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+
 	PropertiesSections []*models.Properties `json:"props_sections"`
+	// Experiments are variants of running experiments assigned
+	// to the user by experiment key.
+	Experiments map[string]string `json:"experiments"`
 
 	// Timestamp could be used to sync the server time
 	// and player time in case of using for daily bonuses
@@ -56,6 +68,9 @@ type syncRegRequest struct {
 	// required to be here
 	DeviceID string `json:"device_id"`
 	GuestID  string `json:"guest_id"`
+	Locale   string `json:"locale"`
+
+	deviceRequest
 
 	Name  string `json:"name"`
 	Email string `json:"email"`
@@ -76,9 +91,18 @@ type syncRegResponse struct {
 	NetworkID string `json:"network_id"`
 	Network   string `json:"network"`
 
+	// Networks linked to the user
+	Networks []models.Network `json:"networks"`
+
 	JWT string `json:"jwt"`
 
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+
 	PropertiesSections []*models.Properties `json:"props_sections"`
+	// Experiments are variants of running experiments assigned
+	// to the user by experiment key.
+	Experiments map[string]string `json:"experiments"`
 
 	// Timestamp could be used to sync the server time
 	// and player time in case of using for daily bonuses
@@ -117,6 +141,13 @@ func (h *Handler) SyncAnomHandler(w http.ResponseWriter, r *http.Request) {
 	// to models user and synced up
 	user := models.User{
 		DeviceID: data.DeviceID,
+		Locale:   data.Locale,
+
+		Platform:   data.Platform,
+		AppVersion: data.AppVersion,
+		OSVersion:  data.OSVersion,
+		PushToken:  data.PushToken,
+
 		Scope: sharedmodels.Scope{
 			GameID: gameID,
 			AppID:  appID,
@@ -127,21 +158,28 @@ func (h *Handler) SyncAnomHandler(w http.ResponseWriter, r *http.Request) {
 	log.Debug("begin auth sync request")
 
 	properties, err := h.service.Sync(r.Context(), data.PropertiesSections, &user)
+	if errors.Is(err, models.ErrDeviceRevoked) {
+		httpreq.Forbidden(w, models.ErrDeviceRevoked)
+		return
+	}
 	if err != nil {
 		httpreq.Error(w, errors.Wrap(err, "can't sync the user"))
 		return
 	}
 
-	signer := auth.GetSigner(r)
+	h.respondGuestSync(w, r, log, &user, properties)
+}
 
-	jwt, err := signer.Encode(auth.Claims{
-		UserInfo: auth.UserInfo{
-			AppID:    user.Scope.AppID,
-			GameID:   user.Scope.GameID,
-			UserID:   user.Scope.UserID,
-			DeviceID: user.DeviceID,
-			Type:     auth.GuestType,
-		},
+// respondGuestSync issues tokens of the guest and responds
+// with properties of the guest.
+func (h *Handler) respondGuestSync(w http.ResponseWriter, r *http.Request, log *zap.SugaredLogger,
+	user *models.User, properties []*models.Properties) {
+	tokens, err := h.issueTokens(r, auth.UserInfo{
+		AppID:    user.Scope.AppID,
+		GameID:   user.Scope.GameID,
+		UserID:   user.Scope.UserID,
+		DeviceID: user.DeviceID,
+		Type:     auth.GuestType,
 	})
 	if err != nil {
 		httpreq.Error(w, errors.Wrap(err, "can't generate jwt token properly"))
@@ -152,9 +190,12 @@ func (h *Handler) SyncAnomHandler(w http.ResponseWriter, r *http.Request) {
 
 	out := &syncAnomResponse{
 		PropertiesSections: properties,
+		Experiments:        h.assignExperiments(r, log, &user.Scope),
 		UserID:             user.Scope.UserID,
 		UserName:           user.Name,
-		JWT:                jwt,
+		JWT:                tokens.JWT,
+		RefreshToken:       tokens.RefreshToken,
+		ExpiresIn:          tokens.ExpiresIn,
 		Timestamp:          timestamp(),
 	}
 
@@ -193,6 +234,13 @@ func (h *Handler) SyncRegHandler(w http.ResponseWriter, r *http.Request) {
 		Network:   data.Network,
 		NetworkID: data.NetworkID,
 		GuestID:   data.GuestID,
+		Locale:    data.Locale,
+
+		Platform:   data.Platform,
+		AppVersion: data.AppVersion,
+		OSVersion:  data.OSVersion,
+		PushToken:  data.PushToken,
+
 		Scope: sharedmodels.Scope{
 			GameID: gameID,
 			AppID:  appID,
@@ -203,21 +251,28 @@ func (h *Handler) SyncRegHandler(w http.ResponseWriter, r *http.Request) {
 	log.Debug("begin auth sync request")
 
 	properties, err := h.service.UsersSync(r.Context(), data.PropertiesSections, &user)
+	if errors.Is(err, models.ErrDeviceRevoked) {
+		httpreq.Forbidden(w, models.ErrDeviceRevoked)
+		return
+	}
 	if err != nil {
 		httpreq.Error(w, errors.Wrap(err, "can't sync the user"))
 		return
 	}
 
-	signer := auth.GetSigner(r)
+	h.respondUserSync(w, r, log, &user, properties)
+}
 
-	jwt, err := signer.Encode(auth.Claims{
-		UserInfo: auth.UserInfo{
-			AppID:    user.Scope.AppID,
-			GameID:   user.Scope.GameID,
-			UserID:   user.Scope.UserID,
-			DeviceID: user.DeviceID,
-			Type:     auth.RealType,
-		},
+// respondUserSync issues tokens of signed in network user and
+// respond with its properties.
+func (h *Handler) respondUserSync(w http.ResponseWriter, r *http.Request, log *zap.SugaredLogger,
+	user *models.User, properties []*models.Properties) {
+	tokens, err := h.issueTokens(r, auth.UserInfo{
+		AppID:    user.Scope.AppID,
+		GameID:   user.Scope.GameID,
+		UserID:   user.Scope.UserID,
+		DeviceID: user.DeviceID,
+		Type:     auth.RealType,
 	})
 	if err != nil {
 		httpreq.Error(w, errors.Wrap(err, "can't generate jwt token properly"))
@@ -228,15 +283,19 @@ func (h *Handler) SyncRegHandler(w http.ResponseWriter, r *http.Request) {
 
 	out := &syncRegResponse{
 		PropertiesSections: properties,
+		Experiments:        h.assignExperiments(r, log, &user.Scope),
 
 		UserID:   user.Scope.UserID,
 		UserName: user.Name,
 		GuestID:  user.GuestID,
 
-		JWT: jwt,
+		JWT:          tokens.JWT,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
 
 		Network:   user.Network,
 		NetworkID: user.NetworkID,
+		Networks:  user.Networks,
 
 		Timestamp: timestamp(),
 	}
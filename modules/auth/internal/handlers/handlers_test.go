@@ -73,7 +73,9 @@ func (s *serviceSuite) TestRequestHandlerToVerifyToken() {
 	redisRepo := db.NewRedisRepository(s.redisSuite.Conn, s.logger)
 	repo := db.NewPostgresRepository(s.PostgresPool)
 
-	svc := service.NewService(repo, redisRepo, s.logger)
+	lbRepo := db.NewLeaderboardRepository(s.redisSuite.Conn, s.logger)
+
+	svc := service.NewService(repo, redisRepo, lbRepo, s.logger)
 
 	h := New(svc, s.logger)
 
@@ -117,7 +119,9 @@ func (s *serviceSuite) TestUserWorkflow() {
 	redisRepo := db.NewRedisRepository(s.redisSuite.Conn, s.logger)
 	repo := db.NewPostgresRepository(s.PostgresPool)
 
-	svc := service.NewService(repo, redisRepo, s.logger)
+	lbRepo := db.NewLeaderboardRepository(s.redisSuite.Conn, s.logger)
+
+	svc := service.NewService(repo, redisRepo, lbRepo, s.logger)
 
 	h := New(svc, s.logger)
 
@@ -221,5 +225,11 @@ func (s *serviceSuite) TestUserWorkflow() {
 	row = s.PostgresPool.QueryRow(context.Background(),
 		"SELECT COUNT(*) FROM users")
 	row.Scan(&usersCount)
-	s.Require().Equal(usersCount, 2)
+	s.Require().Equal(usersCount, 1)
+
+	var identitiesCount int
+	row = s.PostgresPool.QueryRow(context.Background(),
+		"SELECT COUNT(*) FROM user_identities")
+	row.Scan(&identitiesCount)
+	s.Require().Equal(identitiesCount, 2)
 }
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+)
+
+type linkIdentityRequest struct {
+	Network   string `json:"network"`
+	NetworkID string `json:"network_id"`
+
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type identitiesResponse struct {
+	Identities []*models.Identity `json:"identities"`
+}
+
+// identityConflictResponse used to let the client know the identity
+// is linked to another user, the client could offer to switch
+// the account by using users sync request or merge the accounts.
+type identityConflictResponse struct {
+	Error string `json:"error"`
+
+	UserID    string `json:"user_id"`
+	Network   string `json:"network"`
+	NetworkID string `json:"network_id"`
+
+	Actions []string `json:"actions"`
+}
+
+const identityConflictCode = "identity_conflict"
+
+// ListIdentitiesHandler respond with network identities linked
+// to the user by JWT token.
+func (h *Handler) ListIdentitiesHandler(w http.ResponseWriter, r *http.Request) {
+	scope := auth.GetScope(r)
+
+	identities, err := h.service.ListIdentities(r.Context(), scope)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't list identities"))
+		return
+	}
+
+	httpreq.JSON(w, identitiesResponse{identities})
+}
+
+// LinkIdentityHandler links network identity to the user by JWT token.
+func (h *Handler) LinkIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	info := auth.GetUser(r)
+	scope := auth.GetScope(r)
+
+	log := h.logger.With(scope.Fields()...)
+
+	data := linkIdentityRequest{}
+	err = httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read link identity body"))
+		return
+	}
+
+	if data.Network == "" || data.NetworkID == "" {
+		httpreq.Error(w, errors.New("network and network_id are required"))
+		return
+	}
+
+	user := models.User{
+		Scope:     *scope,
+		DeviceID:  info.DeviceID,
+		Name:      data.Name,
+		Email:     data.Email,
+		Network:   data.Network,
+		NetworkID: data.NetworkID,
+	}
+
+	log = log.With("network", data.Network, "network_id", data.NetworkID)
+	log.Debug("begin link identity request")
+
+	err = h.service.LinkIdentity(r.Context(), &user)
+
+	var conflict *models.IdentityConflictError
+	if errors.As(err, &conflict) {
+		log.With("owner_id", conflict.UserID).Info("identity linked to another user")
+
+		httpreq.JSONWithStatus(w, http.StatusConflict, identityConflictResponse{
+			Error:     identityConflictCode,
+			UserID:    conflict.UserID,
+			Network:   conflict.Network,
+			NetworkID: conflict.NetworkID,
+			Actions:   []string{"switch", "merge"},
+		})
+		return
+	}
+	if errors.Is(err, models.ErrDeviceRevoked) {
+		httpreq.Forbidden(w, models.ErrDeviceRevoked)
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't link identity"))
+		return
+	}
+
+	identities, err := h.service.ListIdentities(r.Context(), scope)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't list identities"))
+		return
+	}
+
+	httpreq.JSON(w, identitiesResponse{identities})
+}
+
+// UnlinkIdentityHandler removes network identity from the user by JWT token.
+func (h *Handler) UnlinkIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	scope := auth.GetScope(r)
+
+	identity := &models.Identity{
+		Scope:     *scope,
+		Network:   chi.URLParam(r, "network"),
+		NetworkID: chi.URLParam(r, "network_id"),
+	}
+
+	err := h.service.UnlinkIdentity(r.Context(), identity)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't unlink identity"))
+		return
+	}
+
+	httpreq.OK(w)
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/modules/auth/internal/service"
+	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+type emailLoginRequest struct {
+	Email string `json:"email"`
+
+	DeviceID string `json:"device_id"`
+	GuestID  string `json:"guest_id"`
+}
+
+type emailVerifyRequest struct {
+	Token    string `json:"token"`
+	DeviceID string `json:"device_id"`
+
+	// PropertiesSections should be used to get on auth request settings in response
+	// for further initialize in the client.
+	PropertiesSections []string `json:"props_sections"`
+}
+
+// EmailLoginHandler sends magic link to the email, the response doesn't
+// disclose if the email is linked to any user.
+func (h *Handler) EmailLoginHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	gameID := chi.URLParam(r, "game_id")
+	appID := chi.URLParam(r, "app_id")
+
+	log := h.logger.With("game_id", gameID, "app_id", appID)
+
+	data := emailLoginRequest{}
+	err = httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read email login body"))
+		return
+	}
+
+	link := &models.MagicLink{
+		Scope: sharedmodels.Scope{
+			GameID: gameID,
+			AppID:  appID,
+		},
+		Email:    data.Email,
+		DeviceID: data.DeviceID,
+		GuestID:  data.GuestID,
+		IP:       clientIP(r),
+	}
+
+	log = log.With("device_id", data.DeviceID)
+	log.Debug("begin email login request")
+
+	err = h.service.RequestMagicLink(r.Context(), link)
+	if err == service.ErrInvalidEmail {
+		httpreq.Error(w, err)
+		return
+	}
+	if err == service.ErrMagicLinksDisabled {
+		httpreq.JSONWithStatus(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err == service.ErrTooManyMagicLinks {
+		httpreq.JSONWithStatus(w, http.StatusTooManyRequests, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Errorf("can't send magic link: %s", err)
+		httpreq.Error(w, errors.Wrap(err, "can't send magic link"))
+		return
+	}
+
+	httpreq.OK(w)
+}
+
+// clientIP respond with ip address of the request without port,
+// address is set by real ip middleware behind proxies.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// EmailVerifyHandler signs in the user by magic link token, the user
+// is synced with EMAIL network identity.
+func (h *Handler) EmailVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	gameID := chi.URLParam(r, "game_id")
+	appID := chi.URLParam(r, "app_id")
+
+	log := h.logger.With("game_id", gameID, "app_id", appID)
+
+	data := emailVerifyRequest{}
+	err = httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read email verify body"))
+		return
+	}
+
+	user, properties, err := h.service.VerifyMagicLink(r.Context(), data.Token,
+		gameID, appID, data.DeviceID, data.PropertiesSections)
+	if err == models.ErrInvalidMagicLink {
+		httpreq.Unauthorized(w, err)
+		return
+	}
+	if errors.Is(err, models.ErrDeviceRevoked) {
+		httpreq.Forbidden(w, models.ErrDeviceRevoked)
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't verify magic link"))
+		return
+	}
+
+	log = log.With("device_id", user.DeviceID, "user_id", user.UserID)
+	log.Debug("verified magic link")
+
+	h.respondUserSync(w, r, log, user, properties)
+}
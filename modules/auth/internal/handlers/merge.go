@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/modules/auth/internal/service"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+)
+
+type mergeRequest struct {
+	// Network, NetworkID of identity owned by another user,
+	// usually received in identity conflict response.
+	Network   string `json:"network"`
+	NetworkID string `json:"network_id"`
+
+	// SurvivorToken is JWT token of the identity owner, received on
+	// sign in with the identity, it proves access to the owner account.
+	SurvivorToken string `json:"survivor_token"`
+
+	// Strategy is optional, default strategy is used by server
+	// in case if it's empty.
+	Strategy models.MergeStrategy `json:"strategy"`
+}
+
+type mergeResponse struct {
+	UserID       string               `json:"user_id"`
+	MergedUserID string               `json:"merged_user_id"`
+	Strategy     models.MergeStrategy `json:"strategy"`
+}
+
+// MergeHandler merges the user by JWT token into the owner of identity
+// proved by survivor token, JWT token of the user keeps working and
+// resolved to the owner.
+func (h *Handler) MergeHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	scope := auth.GetScope(r)
+
+	log := h.logger.With(scope.Fields()...)
+
+	data := mergeRequest{}
+	err = httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read merge body"))
+		return
+	}
+
+	if data.Network == "" || data.NetworkID == "" {
+		httpreq.Error(w, errors.New("network and network_id are required"))
+		return
+	}
+
+	if data.SurvivorToken == "" {
+		httpreq.Forbidden(w, service.ErrMergeProof)
+		return
+	}
+
+	survivor, err := auth.GetSigner(r).Decode(data.SurvivorToken)
+	if err != nil {
+		httpreq.Forbidden(w, errors.Wrap(service.ErrMergeProof, err.Error()))
+		return
+	}
+
+	log = log.With("network", data.Network, "network_id", data.NetworkID)
+	log.Debug("begin merge request")
+
+	identity := &models.Identity{
+		Network:   data.Network,
+		NetworkID: data.NetworkID,
+	}
+
+	merge, err := h.service.Merge(r.Context(), scope, survivor, identity, data.Strategy)
+	if err == service.ErrMergeProof {
+		httpreq.Forbidden(w, err)
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't merge users"))
+		return
+	}
+
+	httpreq.JSON(w, mergeResponse{
+		UserID:       merge.UserID,
+		MergedUserID: merge.MergedUserID,
+		Strategy:     merge.Strategy,
+	})
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+)
+
+type renameRequest struct {
+	Name string `json:"name"`
+}
+
+type renameResponse struct {
+	UserName string `json:"user_name"`
+}
+
+type nameTakenResponse struct {
+	Error string `json:"error"`
+}
+
+const nameTakenCode = "name_taken"
+
+type nameCooldownResponse struct {
+	Error string `json:"error"`
+
+	// RetryAfter is seconds to wait before the next change
+	RetryAfter int64 `json:"retry_after"`
+}
+
+const nameCooldownCode = "name_cooldown"
+
+type invalidNameResponse struct {
+	Error string `json:"error"`
+
+	Reason string `json:"reason"`
+}
+
+const invalidNameCode = "invalid_name"
+
+type nameTemplateRequest struct {
+	Pattern    string   `json:"pattern"`
+	Adjectives []string `json:"adjectives"`
+	Nouns      []string `json:"nouns"`
+}
+
+// RenameHandler changes display name of the user by JWT token. Names
+// taken by another player of the game are rejected with 409, names
+// changed during cooldown with 429, invalid or offensive names with 422.
+func (h *Handler) RenameHandler(w http.ResponseWriter, r *http.Request) {
+	scope := auth.GetScope(r)
+	log := h.logger.With(scope.Fields()...)
+
+	data := renameRequest{}
+	err := httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read rename body"))
+		return
+	}
+
+	name, err := h.service.RenameUser(r.Context(), scope, data.Name)
+	if errors.Is(err, models.ErrNameTaken) {
+		httpreq.JSONWithStatus(w, http.StatusConflict, nameTakenResponse{Error: nameTakenCode})
+		return
+	}
+
+	var cooldown *models.NameCooldownError
+	if errors.As(err, &cooldown) {
+		seconds := int64(math.Ceil(cooldown.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+		httpreq.JSONWithStatus(w, http.StatusTooManyRequests, nameCooldownResponse{
+			Error:      nameCooldownCode,
+			RetryAfter: seconds,
+		})
+		return
+	}
+
+	var invalid *models.InvalidNameError
+	if errors.As(err, &invalid) {
+		httpreq.JSONWithStatus(w, http.StatusUnprocessableEntity, invalidNameResponse{
+			Error:  invalidNameCode,
+			Reason: invalid.Reason,
+		})
+		return
+	}
+
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't rename user"))
+		return
+	}
+
+	log.Infof("renamed user to %s", name)
+
+	httpreq.JSON(w, renameResponse{UserName: name})
+}
+
+// ListNameTemplatesHandler respond with guest name templates of the game.
+func (h *Handler) ListNameTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.service.ListNameTemplates(r.Context(), chi.URLParam(r, "game_id"))
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't list name templates"))
+		return
+	}
+
+	httpreq.JSON(w, map[string]interface{}{"templates": templates})
+}
+
+// UpdateNameTemplateHandler creates or updates guest name template of
+// the game for the locale, default locale is used for other locales.
+func (h *Handler) UpdateNameTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	data := nameTemplateRequest{}
+	err := httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read name template body"))
+		return
+	}
+
+	template := &models.NameTemplate{
+		GameID:     chi.URLParam(r, "game_id"),
+		Locale:     chi.URLParam(r, "locale"),
+		Pattern:    data.Pattern,
+		Adjectives: data.Adjectives,
+		Nouns:      data.Nouns,
+	}
+
+	h.logger.
+		With("game_id", template.GameID, "locale", template.Locale).
+		Infof("update name template %s", template.Pattern)
+
+	err = h.service.UpdateNameTemplate(r.Context(), template)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't update name template"))
+		return
+	}
+
+	httpreq.JSON(w, template)
+}
+
+// DeleteNameTemplateHandler removes guest name template of the game.
+func (h *Handler) DeleteNameTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "game_id")
+	locale := chi.URLParam(r, "locale")
+
+	err := h.service.DeleteNameTemplate(r.Context(), gameID, locale)
+	if errors.Is(err, models.ErrNameTemplateNotFound) {
+		httpreq.JSONWithStatus(w, http.StatusNotFound, map[string]string{"error": models.ErrNameTemplateNotFound.Error()})
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't delete name template"))
+		return
+	}
+
+	h.logger.With("game_id", gameID, "locale", locale).Info("deleted name template")
+
+	httpreq.OK(w)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+)
+
+type propertiesChangesResponse struct {
+	Changes []*models.PropertiesChange `json:"changes"`
+}
+
+// ServerGetPlayerProperties respond with all properties sections of the
+// player including sections hidden from clients.
+func (h *Handler) ServerGetPlayerProperties(w http.ResponseWriter, r *http.Request) {
+	scope := playerScope(r)
+
+	properties, err := h.service.GetPlayerProperties(r.Context(), scope)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't get player properties"))
+		return
+	}
+
+	httpreq.JSON(w, getPropertiesResponse{properties})
+}
+
+// ServerSetPlayerProperties changes properties sections of the player by
+// operator, modes of sections are not applied and changes are audited.
+func (h *Handler) ServerSetPlayerProperties(w http.ResponseWriter, r *http.Request) {
+	scope := playerScope(r)
+	actorID := auth.GetUser(r).UserID
+
+	data := setPropertiesRequest{}
+	err := httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read properties body"))
+		return
+	}
+
+	for _, properties := range data.PropertiesSections {
+		properties.Scope = *scope
+	}
+
+	log := h.logger.With(scope.Fields()...).With("actor_id", actorID)
+	log.Infof("change %d player properties sections", len(data.PropertiesSections))
+
+	err = h.service.SetPlayerProperties(r.Context(), data.PropertiesSections, actorID)
+	if respondPropertiesError(w, log, err) {
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't set player properties"))
+		return
+	}
+
+	respondStoredProperties(w, data.PropertiesSections)
+}
+
+// ServerListPropertiesChanges respond with audited changes of properties
+// of the player, filtered by section query param.
+func (h *Handler) ServerListPropertiesChanges(w http.ResponseWriter, r *http.Request) {
+	scope := playerScope(r)
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	changes, err := h.service.ListPropertiesChanges(r.Context(), scope, query.Get("section"), limit)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't list properties changes"))
+		return
+	}
+
+	httpreq.JSON(w, propertiesChangesResponse{changes})
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// ExportPersonalDataHandler respond with archive of personal data
+// of the user by JWT token.
+func (h *Handler) ExportPersonalDataHandler(w http.ResponseWriter, r *http.Request) {
+	h.exportPersonalData(w, r, auth.GetScope(r))
+}
+
+// RequestDeletionHandler schedules deletion of the user by JWT token.
+func (h *Handler) RequestDeletionHandler(w http.ResponseWriter, r *http.Request) {
+	scope := auth.GetScope(r)
+	h.requestDeletion(w, r, scope, scope.UserID)
+}
+
+// CancelDeletionHandler cancels deletion of the user by JWT token.
+func (h *Handler) CancelDeletionHandler(w http.ResponseWriter, r *http.Request) {
+	h.cancelDeletion(w, r, auth.GetScope(r))
+}
+
+// ServerExportPersonalData respond with archive of personal data of
+// the player requested via support.
+func (h *Handler) ServerExportPersonalData(w http.ResponseWriter, r *http.Request) {
+	h.exportPersonalData(w, r, playerScope(r))
+}
+
+// ServerRequestDeletion schedules deletion of the player requested via support.
+func (h *Handler) ServerRequestDeletion(w http.ResponseWriter, r *http.Request) {
+	h.requestDeletion(w, r, playerScope(r), auth.GetUser(r).UserID)
+}
+
+// ServerCancelDeletion cancels deletion of the player.
+func (h *Handler) ServerCancelDeletion(w http.ResponseWriter, r *http.Request) {
+	h.cancelDeletion(w, r, playerScope(r))
+}
+
+// playerScope is scope of player by url params of server routes.
+func playerScope(r *http.Request) *sharedmodels.Scope {
+	return &sharedmodels.Scope{
+		GameID: chi.URLParam(r, "game_id"),
+		AppID:  chi.URLParam(r, "app_id"),
+		UserID: chi.URLParam(r, "user_id"),
+	}
+}
+
+func (h *Handler) exportPersonalData(w http.ResponseWriter, r *http.Request, scope *sharedmodels.Scope) {
+	h.logger.With(scope.Fields()...).Info("export personal data")
+
+	archive, err := h.service.ExportPersonalData(r.Context(), scope)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't export personal data"))
+		return
+	}
+
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="personal-data-%s.json"`, scope.UserID))
+	httpreq.JSON(w, archive)
+}
+
+func (h *Handler) requestDeletion(w http.ResponseWriter, r *http.Request, scope *sharedmodels.Scope, requestedBy string) {
+	h.logger.With(scope.Fields()...).With("requested_by", requestedBy).Info("request deletion")
+
+	deletion, err := h.service.RequestDeletion(r.Context(), scope, requestedBy)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't request deletion"))
+		return
+	}
+
+	httpreq.JSONWithStatus(w, http.StatusAccepted, deletion)
+}
+
+func (h *Handler) cancelDeletion(w http.ResponseWriter, r *http.Request, scope *sharedmodels.Scope) {
+	err := h.service.CancelDeletion(r.Context(), scope)
+	if err == models.ErrDeletionNotFound {
+		httpreq.JSONWithStatus(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't cancel deletion"))
+		return
+	}
+
+	httpreq.OK(w)
+}
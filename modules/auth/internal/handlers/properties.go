@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
 	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
 	"gitlab.com/balconygames/analytics/pkg/auth"
 	httpreq "gitlab.com/balconygames/analytics/pkg/http"
@@ -13,8 +15,46 @@ type setPropertiesRequest struct {
 	PropertiesSections []*models.Properties `json:"props_sections"`
 }
 
+type setPropertiesResponse struct {
+	Response string `json:"response"`
+
+	PropertiesSections []*models.Properties `json:"props_sections"`
+}
+
+type propertiesConflictResponse struct {
+	Error string `json:"error"`
+
+	// PropertiesSections contains current sections to merge and retry
+	PropertiesSections []*models.Properties `json:"props_sections"`
+}
+
+const propertiesConflictCode = "version_conflict"
+
+type propertiesValidationResponse struct {
+	Error string `json:"error"`
+
+	Section string `json:"section"`
+	Reason  string `json:"reason"`
+}
+
+const propertiesValidationCode = "validation_failed"
+
+type propertiesAccessResponse struct {
+	Error string `json:"error"`
+
+	Section string `json:"section"`
+	Mode    string `json:"mode"`
+}
+
+const propertiesAccessCode = "section_forbidden"
+
 // SetPropertiesHandler set properties list with their section names for
-// user by JWT token.
+// user by JWT token. Fields are merged into stored sections, fields listed
+// in delete are removed. Sections with expected_version are written only
+// if they are not changed by another device, otherwise 409 is responded
+// with current sections. Sections exceeding size limit or not matching
+// the schema of section are rejected with 422, sections not writable
+// by clients are rejected with 403.
 func (h *Handler) SetPropertiesHandler(w http.ResponseWriter, r *http.Request) {
 	var err error
 
@@ -37,6 +77,9 @@ func (h *Handler) SetPropertiesHandler(w http.ResponseWriter, r *http.Request) {
 	log.Debugf("begin set properties %v", data.PropertiesSections)
 
 	err = h.service.SetProperties(r.Context(), data.PropertiesSections)
+	if respondPropertiesError(w, log, err) {
+		return
+	}
 	if err != nil {
 		httpreq.Error(w, errors.Wrap(err, "can't set properties for the user"))
 		return
@@ -44,7 +87,62 @@ func (h *Handler) SetPropertiesHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Debugf("done set properties %v", data.PropertiesSections)
 
-	httpreq.OK(w)
+	respondStoredProperties(w, data.PropertiesSections)
+}
+
+// respondPropertiesError responds with structured errors of properties
+// write, it reports false for other errors.
+func respondPropertiesError(w http.ResponseWriter, log *zap.SugaredLogger, err error) bool {
+	var conflict *models.PropertiesConflictError
+	if errors.As(err, &conflict) {
+		log.Infof("conflict of %d properties sections", len(conflict.Sections))
+
+		httpreq.JSONWithStatus(w, http.StatusConflict, propertiesConflictResponse{
+			Error:              propertiesConflictCode,
+			PropertiesSections: conflict.Sections,
+		})
+		return true
+	}
+
+	var invalid *models.PropertiesValidationError
+	if errors.As(err, &invalid) {
+		log.With("section", invalid.Section).Infof("invalid properties section: %s", invalid.Reason)
+
+		httpreq.JSONWithStatus(w, http.StatusUnprocessableEntity, propertiesValidationResponse{
+			Error:   propertiesValidationCode,
+			Section: invalid.Section,
+			Reason:  invalid.Reason,
+		})
+		return true
+	}
+
+	var access *models.SectionAccessError
+	if errors.As(err, &access) {
+		log.With("section", access.Section).Infof("rejected write of %s section", access.Mode)
+
+		httpreq.JSONWithStatus(w, http.StatusForbidden, propertiesAccessResponse{
+			Error:   propertiesAccessCode,
+			Section: access.Section,
+			Mode:    access.Mode,
+		})
+		return true
+	}
+
+	return false
+}
+
+// respondStoredProperties responds with stored sections and their
+// new versions.
+func respondStoredProperties(w http.ResponseWriter, collection []*models.Properties) {
+	for _, properties := range collection {
+		properties.ExpectedVersion = nil
+		properties.Delete = nil
+	}
+
+	httpreq.JSON(w, setPropertiesResponse{
+		Response:           "OK",
+		PropertiesSections: collection,
+	})
 }
 
 type getPropertiesRequest struct {
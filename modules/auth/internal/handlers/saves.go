@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/modules/auth/internal/service"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// Headers of save metadata, save data is passed as request
+// and response body.
+const (
+	// saveVersionHeader is expected version on upload, 0 expects the
+	// empty slot, and the current version on download.
+	saveVersionHeader  = "X-Save-Version"
+	saveDeviceHeader   = "X-Save-Device"
+	savePlayTimeHeader = "X-Save-Play-Time"
+	saveProgressHeader = "X-Save-Progress"
+	saveChecksumHeader = "X-Save-Checksum"
+)
+
+type saveConflictResponse struct {
+	Error string `json:"error"`
+
+	// Save is the current save of the slot
+	Save *models.Save `json:"save"`
+
+	Actions []string `json:"actions"`
+}
+
+const saveConflictCode = "save_conflict"
+
+type listSavesResponse struct {
+	Saves []*models.Save `json:"saves"`
+}
+
+// ListSavesHandler respond with saves metadata of the user by JWT token.
+func (h *Handler) ListSavesHandler(w http.ResponseWriter, r *http.Request) {
+	h.listSaves(w, r, auth.GetScope(r))
+}
+
+// ServerListSaves respond with saves metadata of the player.
+func (h *Handler) ServerListSaves(w http.ResponseWriter, r *http.Request) {
+	h.listSaves(w, r, playerScope(r))
+}
+
+func (h *Handler) listSaves(w http.ResponseWriter, r *http.Request, scope *sharedmodels.Scope) {
+	saves, err := h.service.ListSaves(r.Context(), scope)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't list saves"))
+		return
+	}
+
+	httpreq.JSON(w, listSavesResponse{saves})
+}
+
+// GetSaveHandler respond with data of save in the slot, metadata is
+// passed by headers. Data is responded gzip compressed if client
+// accepts gzip encoding.
+func (h *Handler) GetSaveHandler(w http.ResponseWriter, r *http.Request) {
+	scope := auth.GetScope(r)
+	slot := chi.URLParam(r, "slot")
+
+	save, compressed, err := h.service.GetSave(r.Context(), scope, slot)
+	if err == models.ErrSaveNotFound {
+		httpreq.JSONWithStatus(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't get save"))
+		return
+	}
+
+	data := compressed
+	if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		w.Header().Set("Content-Encoding", "gzip")
+	} else {
+		data, err = service.Decompress(compressed)
+		if err != nil {
+			httpreq.Error(w, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set(saveVersionHeader, strconv.FormatInt(save.Version, 10))
+	w.Header().Set(saveDeviceHeader, save.Device)
+	w.Header().Set(savePlayTimeHeader, strconv.FormatInt(save.PlayTime, 10))
+	w.Header().Set(saveProgressHeader, strconv.FormatFloat(save.Progress, 'f', -1, 64))
+	w.Header().Set(saveChecksumHeader, save.Checksum)
+	_, _ = w.Write(data)
+}
+
+// PutSaveHandler stores data of request body into the slot, metadata is
+// passed by headers. Body could be gzip compressed. Once the slot is
+// changed since the version passed by X-Save-Version 409 is responded
+// with the current save, client keeps it by downloading or overrides it
+// by uploading again with the current version.
+func (h *Handler) PutSaveHandler(w http.ResponseWriter, r *http.Request) {
+	scope := auth.GetScope(r)
+
+	save := &models.Save{
+		Scope:  *scope,
+		Slot:   chi.URLParam(r, "slot"),
+		Device: r.Header.Get(saveDeviceHeader),
+	}
+
+	var err error
+	var expectedVersion *int64
+
+	if value := r.Header.Get(saveVersionHeader); value != "" {
+		version, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			httpreq.Error(w, errors.Wrapf(err, "invalid %s", saveVersionHeader))
+			return
+		}
+		expectedVersion = &version
+	}
+	if value := r.Header.Get(savePlayTimeHeader); value != "" {
+		save.PlayTime, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			httpreq.Error(w, errors.Wrapf(err, "invalid %s", savePlayTimeHeader))
+			return
+		}
+	}
+	if value := r.Header.Get(saveProgressHeader); value != "" {
+		save.Progress, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			httpreq.Error(w, errors.Wrapf(err, "invalid %s", saveProgressHeader))
+			return
+		}
+	}
+
+	data, err := h.readSave(r)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read save body"))
+		return
+	}
+
+	log := h.logger.With(scope.Fields()...).With("slot", save.Slot, "size", len(data))
+	log.Debug("put save")
+
+	err = h.service.PutSave(r.Context(), save, data, expectedVersion)
+
+	var conflict *models.SaveConflictError
+	if errors.As(err, &conflict) {
+		log.With("version", conflict.Current.Version).Info("save conflict")
+
+		httpreq.JSONWithStatus(w, http.StatusConflict, saveConflictResponse{
+			Error:   saveConflictCode,
+			Save:    conflict.Current,
+			Actions: []string{"keep_server", "keep_local"},
+		})
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't put save"))
+		return
+	}
+
+	httpreq.JSON(w, save)
+}
+
+// readSave reads save data limited by max size of saves.
+func (h *Handler) readSave(r *http.Request) ([]byte, error) {
+	defer r.Body.Close()
+
+	limit := int64(h.service.Saves.MaxSize) + 1
+
+	var body io.Reader = io.LimitReader(r.Body, limit)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+
+		body = io.LimitReader(gz, limit)
+	}
+
+	return ioutil.ReadAll(body)
+}
+
+// DeleteSaveHandler removes save of the slot.
+func (h *Handler) DeleteSaveHandler(w http.ResponseWriter, r *http.Request) {
+	err := h.service.DeleteSave(r.Context(), auth.GetScope(r), chi.URLParam(r, "slot"))
+	if err == models.ErrSaveNotFound {
+		httpreq.JSONWithStatus(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't delete save"))
+		return
+	}
+
+	httpreq.OK(w)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+)
+
+type sectionSettingsRequest struct {
+	Shared  bool            `json:"shared"`
+	Schema  json.RawMessage `json:"schema"`
+	MaxSize int             `json:"max_size"`
+	Mode    string          `json:"mode"`
+}
+
+// ListSectionsHandler respond with settings of properties sections of the game.
+func (h *Handler) ListSectionsHandler(w http.ResponseWriter, r *http.Request) {
+	sections, err := h.service.ListSectionSettings(r.Context(), chi.URLParam(r, "game_id"))
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't list sections"))
+		return
+	}
+
+	httpreq.JSON(w, map[string]interface{}{"sections": sections})
+}
+
+// UpdateSectionHandler updates settings of properties section of the game,
+// e.g. to share the progress between iOS and Android apps, to validate
+// section data by JSON schema or to protect section from client writes.
+func (h *Handler) UpdateSectionHandler(w http.ResponseWriter, r *http.Request) {
+	data := sectionSettingsRequest{}
+	err := httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read section body"))
+		return
+	}
+
+	section := &models.Section{
+		GameID: chi.URLParam(r, "game_id"),
+		Name:   chi.URLParam(r, "section"),
+		Shared: data.Shared,
+		Schema: data.Schema,
+
+		MaxSize: data.MaxSize,
+		Mode:    data.Mode,
+	}
+
+	// null schema removes the schema
+	if string(section.Schema) == "null" {
+		section.Schema = nil
+	}
+
+	h.logger.
+		With("game_id", section.GameID, "section", section.Name).
+		With("shared", section.Shared, "mode", section.Mode).
+		Info("update section settings")
+
+	err = h.service.UpdateSectionSettings(r.Context(), section)
+	if err == models.ErrSectionHasSharedData {
+		httpreq.JSONWithStatus(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+	if respondPropertiesError(w, h.logger, err) {
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't update section"))
+		return
+	}
+
+	httpreq.JSON(w, section)
+}
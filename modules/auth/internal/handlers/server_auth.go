@@ -1,22 +1,310 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/modules/auth/internal/service"
+	"gitlab.com/balconygames/analytics/pkg/audit"
+	"gitlab.com/balconygames/analytics/pkg/auth"
 	httpreq "gitlab.com/balconygames/analytics/pkg/http"
 )
 
+type serverSigninRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+
+	// TOTPCode is required in case if operator enabled
+	// second factor.
+	TOTPCode string `json:"totp_code"`
+}
+
+type serverSignupRequest struct {
+	InvitationToken string `json:"invitation_token"`
+
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+type serverSigninResponse struct {
+	JWT       string `json:"jwt"`
+	ExpiresIn int64  `json:"expires_in"`
+
+	Operator *models.Operator `json:"operator"`
+}
+
+type invitationRequest struct {
+	// OrganisationID is used only by platform operators,
+	// others invite into own organisation.
+	OrganisationID string `json:"organisation_id"`
+
+	Email string   `json:"email"`
+	Roles []string `json:"roles"`
+	Games []string `json:"games"`
+}
+
+type membershipRequest struct {
+	Roles []string `json:"roles"`
+	Games []string `json:"games"`
+}
+
+type totpRequest struct {
+	Code string `json:"code"`
+}
+
+// signOperatorToken signs server JWT for the new operator session,
+// operators don't receive refresh tokens and sign in again.
+func signOperatorToken(r *http.Request, operator *models.Operator) (*serverSigninResponse, error) {
+	info, err := service.OperatorUserInfo(operator)
+	if err != nil {
+		return nil, err
+	}
+
+	signer := auth.GetSigner(r)
+
+	jwt, err := signer.Encode(auth.Claims{UserInfo: info})
+	if err != nil {
+		return nil, err
+	}
+
+	return &serverSigninResponse{
+		JWT:       jwt,
+		ExpiresIn: int64(signer.TTL().Seconds()),
+		Operator:  operator,
+	}, nil
+}
+
 // ServerSignin used to use to make the dashboard application
 func (h *Handler) ServerSignin(w http.ResponseWriter, r *http.Request) {
-	httpreq.NotImplemented(w)
+	var err error
+
+	data := serverSigninRequest{}
+	err = httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read signin body"))
+		return
+	}
+
+	log := h.logger.With("email", data.Email)
+	log.Debug("begin server signin request")
+
+	operator, err := h.service.Signin(r.Context(), data.Email, data.Password, data.TOTPCode, clientIP(r))
+	if err == models.ErrTooManySignins || err == models.ErrTooManyTOTPs {
+		log.Warn("too many failed signin attempts")
+		httpreq.TooManyRequests(w, err)
+		return
+	}
+	if err == models.ErrInvalidCredentials || err == models.ErrTOTPRequired || err == models.ErrInvalidTOTP {
+		httpreq.Unauthorized(w, err)
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't signin"))
+		return
+	}
+
+	out, err := signOperatorToken(r, operator)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't generate jwt token properly"))
+		return
+	}
+
+	httpreq.JSON(w, out)
 }
 
-// ServerSignout log out the user
+// ServerSignout log out the user, revokes the session of jwt token
+// or all sessions on passing all=true query param.
 func (h *Handler) ServerSignout(w http.ResponseWriter, r *http.Request) {
-	httpreq.NotImplemented(w)
+	h.SignoutHandler(w, r)
 }
 
-// ServerSignup creates the new user
+// ServerSignup creates the new operator by invitation
 func (h *Handler) ServerSignup(w http.ResponseWriter, r *http.Request) {
-	httpreq.NotImplemented(w)
+	var err error
+
+	data := serverSignupRequest{}
+	err = httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read signup body"))
+		return
+	}
+
+	if data.InvitationToken == "" || data.Name == "" {
+		httpreq.Error(w, errors.New("invitation_token and name are required"))
+		return
+	}
+
+	operator, err := h.service.Signup(r.Context(), data.InvitationToken, data.Name, data.Password)
+	if errors.Cause(err) == models.ErrInvalidInvitation {
+		httpreq.Forbidden(w, models.ErrInvalidInvitation)
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't signup"))
+		return
+	}
+
+	h.logger.
+		With("operator_id", operator.ID, "organisation_id", operator.OrganisationID).
+		Info("operator signed up")
+
+	out, err := signOperatorToken(r, operator)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't generate jwt token properly"))
+		return
+	}
+
+	httpreq.JSON(w, out)
+}
+
+// InviteOperatorHandler creates invitation to organisation of
+// the admin, the token should be passed to invited operator.
+func (h *Handler) InviteOperatorHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	user := auth.GetUser(r)
+
+	data := invitationRequest{}
+	err = httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read invitation body"))
+		return
+	}
+
+	organisationID := user.OrganisationID
+	if data.OrganisationID != "" && auth.IsPlatformOperator(r) {
+		organisationID = data.OrganisationID
+	}
+	if organisationID == "" {
+		httpreq.Forbidden(w, errors.New("operator doesn't belong to organisation"))
+		return
+	}
+
+	invitation := &models.Invitation{
+		OrganisationID: organisationID,
+		Email:          data.Email,
+		Roles:          data.Roles,
+		Games:          data.Games,
+		InvitedBy:      user.UserID,
+	}
+
+	err = h.service.Invite(r.Context(), invitation)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't invite operator"))
+		return
+	}
+
+	httpreq.JSON(w, invitation)
+}
+
+// ListMembersHandler respond with operators of the organisation.
+func (h *Handler) ListMembersHandler(w http.ResponseWriter, r *http.Request) {
+	operators, err := h.service.ListMembers(r.Context(), chi.URLParam(r, "organisation_id"))
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't list members"))
+		return
+	}
+
+	httpreq.JSON(w, operators)
+}
+
+// UpdateMemberHandler replaces roles and games of the operator
+// in organisation.
+func (h *Handler) UpdateMemberHandler(w http.ResponseWriter, r *http.Request) {
+	data := membershipRequest{}
+	err := httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read membership body"))
+		return
+	}
+
+	operatorID := chi.URLParam(r, "operator_id")
+	audit.SetTarget(r, "operator", operatorID)
+
+	err = h.service.UpdateMember(r.Context(), chi.URLParam(r, "organisation_id"), operatorID, data.Roles, data.Games)
+	if errors.Is(err, models.ErrOperatorNotFound) {
+		httpreq.JSONWithStatus(w, http.StatusNotFound, map[string]string{"error": models.ErrOperatorNotFound.Error()})
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't update member"))
+		return
+	}
+
+	audit.SetChanges(r, nil, data)
+
+	httpreq.OK(w)
+}
+
+// RemoveMemberHandler deletes operator from the organisation.
+func (h *Handler) RemoveMemberHandler(w http.ResponseWriter, r *http.Request) {
+	operatorID := chi.URLParam(r, "operator_id")
+	audit.SetTarget(r, "operator", operatorID)
+
+	err := h.service.RemoveMember(r.Context(), chi.URLParam(r, "organisation_id"), operatorID)
+	if errors.Is(err, models.ErrOperatorNotFound) {
+		httpreq.JSONWithStatus(w, http.StatusNotFound, map[string]string{"error": models.ErrOperatorNotFound.Error()})
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't remove member"))
+		return
+	}
+
+	httpreq.OK(w)
+}
+
+// SetupTOTPHandler generates secret of second factor for
+// the signed in operator.
+func (h *Handler) SetupTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUser(r)
+
+	setup, err := h.service.SetupTOTP(r.Context(), user.UserID)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't setup totp"))
+		return
+	}
+
+	httpreq.JSON(w, setup)
+}
+
+// ConfirmTOTPHandler enables second factor by the first code.
+func (h *Handler) ConfirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	h.updateTOTP(w, r, h.service.ConfirmTOTP)
+}
+
+// DisableTOTPHandler disables second factor by the current code.
+func (h *Handler) DisableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	h.updateTOTP(w, r, h.service.DisableTOTP)
+}
+
+func (h *Handler) updateTOTP(w http.ResponseWriter, r *http.Request, fn func(ctx context.Context, operatorID, code string) error) {
+	user := auth.GetUser(r)
+
+	data := totpRequest{}
+	err := httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read totp body"))
+		return
+	}
+
+	err = fn(r.Context(), user.UserID, data.Code)
+	if err == models.ErrTooManyTOTPs {
+		httpreq.TooManyRequests(w, err)
+		return
+	}
+	if err == models.ErrInvalidTOTP {
+		httpreq.Forbidden(w, err)
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't update totp"))
+		return
+	}
+
+	httpreq.OK(w)
 }
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+)
+
+type tokens struct {
+	JWT          string `json:"jwt"`
+	RefreshToken string `json:"refresh_token"`
+
+	// ExpiresIn is lifetime of jwt token in seconds,
+	// zero for tokens which never expire.
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+// issueTokens starts the new session and signs jwt token for it.
+func (h *Handler) issueTokens(r *http.Request, info auth.UserInfo) (*tokens, error) {
+	session, err := h.service.StartSession(r.Context(), info)
+	if err != nil {
+		return nil, err
+	}
+
+	return signTokens(r, session)
+}
+
+func signTokens(r *http.Request, session *models.Session) (*tokens, error) {
+	signer := auth.GetSigner(r)
+
+	jwt, err := signer.Encode(auth.Claims{UserInfo: session.UserInfo})
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokens{
+		JWT:          jwt,
+		RefreshToken: session.RefreshToken,
+		ExpiresIn:    int64(signer.TTL().Seconds()),
+	}, nil
+}
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshTokenHandler rotates refresh token and respond with
+// the new jwt token for the same session.
+func (h *Handler) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	data := refreshTokenRequest{}
+	err = httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read refresh token body"))
+		return
+	}
+
+	session, err := h.service.RefreshSession(r.Context(), data.RefreshToken)
+	if err == models.ErrInvalidRefreshToken || err == models.ErrRefreshTokenReused || err == models.ErrDeviceRevoked {
+		httpreq.Unauthorized(w, err)
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't refresh session"))
+		return
+	}
+
+	out, err := signTokens(r, session)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't generate jwt token properly"))
+		return
+	}
+
+	httpreq.JSON(w, out)
+}
+
+// SignoutHandler revokes the session of jwt token, all sessions
+// of the user are revoked on passing all=true query param.
+func (h *Handler) SignoutHandler(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUser(r)
+	all := r.URL.Query().Get("all") == "true"
+
+	h.logger.
+		With("user_id", user.UserID, "session_id", user.SessionID, "all", all).
+		Debug("begin signout request")
+
+	err := h.service.Signout(r.Context(), user, all)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't signout"))
+		return
+	}
+
+	httpreq.OK(w)
+}
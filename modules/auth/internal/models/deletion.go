@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// Statuses of deletion requests.
+const (
+	DeletionPending   = "pending"
+	DeletionCancelled = "cancelled"
+	DeletionCompleted = "completed"
+)
+
+// ErrDeletionNotFound returned on cancel of deletion which
+// is not pending.
+var ErrDeletionNotFound = errors.New("deletion not found")
+
+// Deletion is request to delete personal data of the user,
+// the data is deleted by all modules after grace period.
+type Deletion struct {
+	sharedmodels.Scope
+
+	RequestedBy string `json:"requested_by"`
+	Status      string `json:"status"`
+
+	RequestedAt  time.Time  `json:"requested_at"`
+	ExecuteAfter time.Time  `json:"execute_after"`
+	CompletedAt  *time.Time `json:"completed_at"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// Device is device used by the player, the same device could
+// be used by multiple players of the game.
+type Device struct {
+	sharedmodels.Scope
+
+	DeviceID   string `json:"device_id"`
+	Platform   string `json:"platform"`
+	AppVersion string `json:"app_version"`
+	OSVersion  string `json:"os_version"`
+	PushToken  string `json:"push_token,omitempty"`
+
+	// Current is set for the device of the request.
+	Current bool `json:"current"`
+
+	FirstSeenAt time.Time  `json:"first_seen_at"`
+	LastSeenAt  time.Time  `json:"last_seen_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Device respond with the device of sync request of the user.
+func (u *User) Device() *Device {
+	return &Device{
+		Scope:      u.Scope,
+		DeviceID:   u.DeviceID,
+		Platform:   u.Platform,
+		AppVersion: u.AppVersion,
+		OSVersion:  u.OSVersion,
+		PushToken:  u.PushToken,
+	}
+}
+
+// ErrDeviceNotFound returned if device is not used by the user.
+var ErrDeviceNotFound = errors.New("device not found")
+
+// ErrDeviceRevoked returned on refresh of session of revoked device.
+var ErrDeviceRevoked = errors.New("device revoked")
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Document is data of properties section, values are arbitrary
+// JSON values kept as is.
+type Document map[string]json.RawMessage
+
+// StringDocument converts flat string values into document,
+// e.g. data stored before values were JSON.
+func StringDocument(values map[string]string) Document {
+	document := make(Document, len(values))
+	for field, value := range values {
+		document[field], _ = json.Marshal(value)
+	}
+
+	return document
+}
+
+// Size respond with size of document encoded as JSON.
+func (d Document) Size() int {
+	size := 2
+	for field, value := range d {
+		// "field":value,
+		size += len(field) + len(value) + 4
+	}
+
+	return size
+}
+
+// Validate checks values are valid JSON and fields are not reserved.
+func (d Document) Validate() error {
+	for field, value := range d {
+		if strings.HasPrefix(field, ReservedFieldPrefix) {
+			return errors.Errorf("field %s is reserved", field)
+		}
+		if !json.Valid(value) {
+			return errors.Errorf("field %s is not valid JSON", field)
+		}
+	}
+
+	return nil
+}
+
+// Decode respond with document decoded into generic JSON values.
+func (d Document) Decode() (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(d))
+	for field, value := range d {
+		var decoded interface{}
+		err := json.Unmarshal(value, &decoded)
+		if err != nil {
+			return nil, errors.Wrapf(err, "field %s is not valid JSON", field)
+		}
+		result[field] = decoded
+	}
+
+	return result, nil
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/pkg/experiments"
+)
+
+// Statuses of experiments, only running experiments assign variants.
+const (
+	ExperimentRunning = "running"
+	ExperimentStopped = "stopped"
+)
+
+// MaxExperimentVariants limits variants per experiment.
+const MaxExperimentVariants = 10
+
+// ErrExperimentNotFound returned for unknown experiment of the game.
+var ErrExperimentNotFound = errors.New("experiment not found")
+
+var experimentKeyRe = regexp.MustCompile(`^[a-z0-9_\-]{1,64}$`)
+
+// Experiment of the game, users are assigned to variants by hash
+// of user id, changes of weights reassign part of users.
+type Experiment struct {
+	GameID      string                `json:"game_id"`
+	Key         string                `json:"key"`
+	Description string                `json:"description"`
+	Status      string                `json:"status"`
+	Variants    []experiments.Variant `json:"variants"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks key, status and variants of the experiment.
+func (e *Experiment) Validate() error {
+	if !experimentKeyRe.MatchString(e.Key) {
+		return errors.Errorf("experiment key %q should contain up to 64 lower case letters, digits, - or _", e.Key)
+	}
+
+	if e.Status != ExperimentRunning && e.Status != ExperimentStopped {
+		return errors.Errorf("unknown experiment status %q", e.Status)
+	}
+
+	if len(e.Variants) < 2 || len(e.Variants) > MaxExperimentVariants {
+		return errors.Errorf("experiment should have from 2 to %d variants", MaxExperimentVariants)
+	}
+
+	names := make(map[string]bool, len(e.Variants))
+	total := 0
+	for _, v := range e.Variants {
+		if v.Name == "" || len(v.Name) > 64 {
+			return errors.New("variant name should have from 1 to 64 characters")
+		}
+		if names[v.Name] {
+			return errors.Errorf("variant %s is duplicated", v.Name)
+		}
+		names[v.Name] = true
+
+		if v.Weight < 0 {
+			return errors.Errorf("variant %s should have positive weight", v.Name)
+		}
+		total += v.Weight
+	}
+
+	if total == 0 {
+		return errors.New("experiment should have variants with weight")
+	}
+
+	return nil
+}
+
+// Assign respond with variant of the user.
+func (e *Experiment) Assign(userID string) string {
+	return experiments.Assign(e.GameID+":"+e.Key, userID, e.Variants)
+}
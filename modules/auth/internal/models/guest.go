@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Scopes of guests resolved by device.
+const (
+	// GuestScopeApp guests are resolved by device per app,
+	// each app of the game has own guest on the device.
+	GuestScopeApp = "app"
+	// GuestScopeGame guests are resolved by device per game,
+	// apps of the game on the device use the same guest.
+	GuestScopeGame = "game"
+)
+
+// ValidGuestScope checks if scope is known.
+func ValidGuestScope(scope string) bool {
+	return scope == GuestScopeApp || scope == GuestScopeGame
+}
+
+// GuestPolicy defines how guests of the game are resolved by device.
+type GuestPolicy struct {
+	GameID string `json:"game_id"`
+	Scope  string `json:"scope"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ErrInvalidRecoveryCode returned on restoring guest by unknown code.
+var ErrInvalidRecoveryCode = errors.New("invalid recovery code")
+
+// ErrRecoveryNotAvailable returned on generating recovery code for
+// users with linked identities, they should sign in via network.
+var ErrRecoveryNotAvailable = errors.New("recovery code is available only for guests")
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// NetworkEmail is network of identities verified by magic link,
+// email is used as network id.
+const NetworkEmail = "EMAIL"
+
+// ErrInvalidMagicLink returned for unknown, expired or used magic links.
+var ErrInvalidMagicLink = errors.New("invalid magic link")
+
+// ErrReservedNetwork returned once client passes network which
+// identities are created by server only, e.g. EMAIL by magic link.
+var ErrReservedNetwork = errors.New("network is reserved")
+
+// ReservedNetwork checks if identities of the network can't be
+// passed by clients in sync, link and merge requests.
+func ReservedNetwork(network string) bool {
+	return strings.EqualFold(strings.TrimSpace(network), NetworkEmail)
+}
+
+// MagicLink is one-time token sent by email to sign in without
+// password, it keeps device and guest of the request to link
+// the progress of the guest.
+type MagicLink struct {
+	sharedmodels.Scope
+
+	Token string `json:"-"`
+	Email string `json:"email"`
+
+	DeviceID string `json:"device_id"`
+	GuestID  string `json:"guest_id"`
+
+	// IP is address of the request, used to limit requests.
+	IP string `json:"-"`
+}
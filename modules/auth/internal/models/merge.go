@@ -0,0 +1,38 @@
+package models
+
+import (
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// MergeStrategy defines how to merge property sections
+// of two users when they are merged into one.
+type MergeStrategy string
+
+const (
+	// MergePreferNewest keeps values of the user who was
+	// seen the last time.
+	MergePreferNewest MergeStrategy = "newest"
+	// MergePreferMax keeps max numeric values, other values
+	// are taken from the newest user.
+	MergePreferMax MergeStrategy = "max"
+)
+
+// Valid checks if strategy is supported.
+func (s MergeStrategy) Valid() bool {
+	return s == MergePreferNewest || s == MergePreferMax
+}
+
+// Merge describes merge of two users, scope is pointing
+// to the surviving user.
+type Merge struct {
+	sharedmodels.Scope
+
+	MergedUserID string        `json:"merged_user_id"`
+	Strategy     MergeStrategy `json:"strategy"`
+}
+
+// Alias points the merged user to the surviving user.
+type Alias struct {
+	AliasUserID string
+	UserID      string
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultLocale is locale of the name template used if the game
+// has no template for the locale of the player.
+const DefaultLocale = "default"
+
+// NameTemplate generates names of guests of the game, pattern could
+// contain placeholders {adjective}, {noun} - random word of the lists,
+// {number} - random 4 digits number, {device} - first 5 characters of
+// device id, e.g. "{adjective}{noun}{number}" generates "BraveFox4821".
+type NameTemplate struct {
+	GameID string `json:"game_id"`
+	Locale string `json:"locale"`
+
+	Pattern    string   `json:"pattern"`
+	Adjectives []string `json:"adjectives"`
+	Nouns      []string `json:"nouns"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ErrNameTemplateNotFound returned on deleting missing template.
+var ErrNameTemplateNotFound = errors.New("name template not found")
+
+// ErrNameTaken returned if another player of the game
+// has the same name ignoring case.
+var ErrNameTaken = errors.New("name is taken")
+
+// InvalidNameError returned if name doesn't follow the rules
+// or contains offensive words.
+type InvalidNameError struct {
+	Reason string
+}
+
+func (e *InvalidNameError) Error() string {
+	return fmt.Sprintf("invalid name: %s", e.Reason)
+}
+
+// NameCooldownError returned if player changed the name recently.
+type NameCooldownError struct {
+	RetryAfter time.Duration
+}
+
+func (e *NameCooldownError) Error() string {
+	return fmt.Sprintf("name can be changed in %s", e.RetryAfter)
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrOperatorNotFound returned in case if operator with email
+	// or id doesn't exist.
+	ErrOperatorNotFound = errors.New("operator not found")
+	// ErrOperatorExists returned on sign up with email of existing operator.
+	ErrOperatorExists = errors.New("operator already exists")
+	// ErrInvalidCredentials returned on sign in with unknown email
+	// or wrong password, the reason is not disclosed.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrTOTPRequired returned on sign in without code of enabled second factor.
+	ErrTOTPRequired = errors.New("totp_required")
+	// ErrInvalidTOTP returned on wrong code of second factor.
+	ErrInvalidTOTP = errors.New("invalid totp code")
+	// ErrTooManySignins returned once failed sign in attempts reached the limit.
+	ErrTooManySignins = errors.New("too many failed sign in attempts")
+	// ErrTooManyTOTPs returned once invalid codes of second factor reached the limit.
+	ErrTooManyTOTPs = errors.New("too many invalid totp codes")
+	// ErrInvalidInvitation returned for unknown, expired or accepted invitations.
+	ErrInvalidInvitation = errors.New("invalid invitation")
+)
+
+// Operator is dashboard user signed in by password, it receives
+// server JWT with roles and games of organisation.
+type Operator struct {
+	ID             string `json:"operator_id"`
+	OrganisationID string `json:"organisation_id"`
+
+	Email string `json:"email"`
+	Name  string `json:"name"`
+
+	PasswordHash string `json:"-"`
+
+	Roles []string `json:"roles"`
+	Games []string `json:"games"`
+
+	TOTPSecret  string `json:"-"`
+	TOTPEnabled bool   `json:"totp_enabled"`
+
+	SignedInAt *time.Time `json:"signed_in_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Invitation allows to sign up operator with email in organisation,
+// Token is returned only once on create.
+type Invitation struct {
+	Token          string `json:"token"`
+	OrganisationID string `json:"organisation_id"`
+
+	Email string   `json:"email"`
+	Roles []string `json:"roles"`
+	Games []string `json:"games"`
+
+	InvitedBy string    `json:"invited_by"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// PropertiesChange is audit record of section changed by operator.
+type PropertiesChange struct {
+	ID int64 `json:"id"`
+
+	sharedmodels.Scope
+
+	Section string `json:"section"`
+
+	// ActorID is user id of operator changed the section.
+	ActorID string `json:"actor_id"`
+
+	Before Document `json:"before"`
+	After  Document `json:"after"`
+
+	// Version is the section version after the change.
+	Version int64 `json:"version"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+var (
+	// ErrSaveNotFound returned if slot has no save.
+	ErrSaveNotFound = errors.New("save not found")
+	// ErrTooManySaveSlots returned on save into new slot
+	// once the user has all slots used.
+	ErrTooManySaveSlots = errors.New("too many save slots")
+)
+
+// Save is metadata of cloud save of game state stored in named slot,
+// data of save is stored compressed in blob storage.
+type Save struct {
+	sharedmodels.Scope
+
+	Slot string `json:"slot"`
+
+	// Version is incremented on every save into the slot.
+	Version int64 `json:"version"`
+
+	// Size is size of save data, CompressedSize is size of stored blob.
+	Size           int `json:"size"`
+	CompressedSize int `json:"compressed_size"`
+	// Checksum is hex encoded sha256 of save data.
+	Checksum string `json:"checksum"`
+
+	// Device is id of device made the save.
+	Device string `json:"device"`
+	// PlayTime is total play time in seconds.
+	PlayTime int64 `json:"play_time"`
+	// Progress is game progress reported by client, e.g. percent
+	// of completed levels, it helps players to choose the save.
+	Progress float64 `json:"progress"`
+
+	BlobRef string `json:"-"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SaveConflictError returned on conditional save into slot changed
+// by another device, client could keep the current save or override
+// it by local save passing the current version.
+type SaveConflictError struct {
+	Current *Save
+}
+
+func (e *SaveConflictError) Error() string {
+	return fmt.Sprintf("save %s is changed, current version is %d", e.Current.Slot, e.Current.Version)
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SharedApp is used as app id of sections shared by all
+// apps of the game.
+const SharedApp = "shared"
+
+// Modes of section define access of clients, servers have
+// full access to all sections.
+const (
+	// SectionReadWrite sections are read and written by clients.
+	SectionReadWrite = "read-write"
+	// SectionReadOnly sections are read by clients, e.g. currency balances.
+	SectionReadOnly = "read-only"
+	// SectionAppendOnly sections are read by clients and clients can
+	// add new fields but can't change or delete existing ones.
+	SectionAppendOnly = "append-only"
+	// SectionServerOnly sections are hidden from clients.
+	SectionServerOnly = "server-only"
+)
+
+// ValidSectionMode checks if mode is known.
+func ValidSectionMode(mode string) bool {
+	switch mode {
+	case SectionReadWrite, SectionReadOnly, SectionAppendOnly, SectionServerOnly:
+		return true
+	}
+
+	return false
+}
+
+// ErrSectionHasSharedData returned on disabling sharing of section
+// which already has shared values.
+var ErrSectionHasSharedData = errors.New("section has shared data")
+
+// Section contains settings of properties section per game,
+// sections without settings are stored per app.
+type Section struct {
+	GameID string `json:"game_id"`
+	Name   string `json:"section"`
+
+	// Shared sections are stored once for all apps of the game,
+	// e.g. iOS and Android apps share the progress.
+	Shared bool `json:"shared"`
+
+	// Schema is optional JSON schema of section data, data is
+	// validated after merge of written fields.
+	Schema json.RawMessage `json:"schema,omitempty"`
+
+	// MaxSize is limit of section data size encoded as JSON in bytes,
+	// zero means the default limit is used.
+	MaxSize int `json:"max_size,omitempty"`
+
+	// Mode is access of clients to the section, SectionReadWrite
+	// is used by default.
+	Mode string `json:"mode"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PropertiesValidationError returned on write of section data which
+// doesn't match the schema or exceeds the size limit.
+type PropertiesValidationError struct {
+	Section string
+	Reason  string
+}
+
+func (e *PropertiesValidationError) Error() string {
+	return "section " + e.Section + " is invalid: " + e.Reason
+}
+
+// SectionAccessError returned on client write of section
+// which is not allowed by mode of section.
+type SectionAccessError struct {
+	Section string
+	Mode    string
+}
+
+func (e *SectionAccessError) Error() string {
+	return "section " + e.Section + " is " + e.Mode + " for clients"
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/pkg/auth"
+)
+
+// ErrInvalidRefreshToken returned for unknown, expired or revoked
+// refresh tokens.
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// ErrRefreshTokenReused returned on the second use of the same refresh
+// token, the session is revoked because the token could be stolen.
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+// Session is chain of access tokens issued by rotated
+// refresh tokens.
+type Session struct {
+	auth.UserInfo
+
+	// RefreshToken is opaque token, only hash of token
+	// is stored.
+	RefreshToken string `json:"refresh_token"`
+}
@@ -1,6 +1,11 @@
 package models
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
 	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
 )
 
@@ -24,13 +29,64 @@ type User struct {
 
 	// required to be here
 	DeviceID string `json:"device_id"`
+	// Locale of the device, used to generate name of the new user.
+	Locale string `json:"locale"`
+
+	// Platform, AppVersion, OSVersion, PushToken describe the device
+	// of sync request, stored in `users_devices` table.
+	Platform   string `json:"platform"`
+	AppVersion string `json:"app_version"`
+	OSVersion  string `json:"os_version"`
+	PushToken  string `json:"push_token"`
+
+	// Networks are linked network profiles per user, stored
+	// in `user_identities` table.
+	Networks []Network `json:"networks"`
+
+	// Network, NetworkID used by sign in request to find
+	// or link the identity.
+	Network   string `json:"network"`
+	NetworkID string `json:"network_id"`
+}
 
-	// TODO: Networks should be associated with user, could have multiple
-	// network profiles per user.
-	// Networks []Network
+// Identity is network profile linked to the user,
+// the same identity can't be linked to multiple users
+// per game and app.
+type Identity struct {
+	sharedmodels.Scope
 
 	Network   string `json:"network"`
 	NetworkID string `json:"network_id"`
+
+	Email string `json:"email"`
+	Name  string `json:"name"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ErrUserNotFound returned in case if user doesn't exist.
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrIdentityNotFound returned on unlink of identity which
+// is not linked to the user.
+var ErrIdentityNotFound = errors.New("identity not found")
+
+// ErrLastIdentity returned on unlink of the only identity of the user.
+var ErrLastIdentity = errors.New("last identity can't be unlinked")
+
+// IdentityConflictError returned on linking identity which
+// is already owned by another user, client could offer to switch
+// the account or merge them.
+type IdentityConflictError struct {
+	UserID string
+
+	Network   string
+	NetworkID string
+}
+
+func (e *IdentityConflictError) Error() string {
+	return fmt.Sprintf("identity %s:%s belongs to another user", e.Network, e.NetworkID)
 }
 
 // Properties should store settings per game and app
@@ -39,5 +95,32 @@ type Properties struct {
 
 	Section string `json:"section"`
 
-	Data map[string]string `json:"data"`
+	Data Document `json:"data"`
+
+	// Version is incremented on every write of the section,
+	// zero version means the section is not stored yet.
+	Version int64 `json:"version"`
+
+	// ExpectedVersion makes the write conditional, the write is
+	// rejected by PropertiesConflictError if the stored version
+	// is different.
+	ExpectedVersion *int64 `json:"expected_version,omitempty"`
+
+	// Delete contains fields removed from the section on write.
+	Delete []string `json:"delete,omitempty"`
+}
+
+// ReservedFieldPrefix is prefix of fields which can't be used
+// in sections, the fields are used to cache metadata of section.
+const ReservedFieldPrefix = "@"
+
+// PropertiesConflictError returned on conditional write of sections
+// which are changed by another device, it contains current sections
+// to let client resolve the conflict and retry.
+type PropertiesConflictError struct {
+	Sections []*Properties
+}
+
+func (e *PropertiesConflictError) Error() string {
+	return fmt.Sprintf("version conflict of %d properties sections", len(e.Sections))
 }
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// Limits of device attributes, should be in sync with
+// columns of users_devices table.
+const (
+	maxDeviceIDLength   = 256
+	maxPlatformLength   = 32
+	maxAppVersionLength = 32
+	maxOSVersionLength  = 64
+	maxPushTokenLength  = 512
+)
+
+func validateDevice(device *models.Device) error {
+	for _, attr := range []struct {
+		name  string
+		value string
+		max   int
+	}{
+		{"device_id", device.DeviceID, maxDeviceIDLength},
+		{"platform", device.Platform, maxPlatformLength},
+		{"app_version", device.AppVersion, maxAppVersionLength},
+		{"os_version", device.OSVersion, maxOSVersionLength},
+		{"push_token", device.PushToken, maxPushTokenLength},
+	} {
+		if len(attr.value) > attr.max {
+			return errors.Errorf("%s should have up to %d characters", attr.name, attr.max)
+		}
+	}
+
+	return nil
+}
+
+// ListDevices respond with devices used by the user, the device
+// of the request is marked as current.
+func (s *Service) ListDevices(ctx context.Context, scope *sharedmodels.Scope, currentDeviceID string) ([]*models.Device, error) {
+	devices, err := s.repoPG.ListDevices(ctx, scope)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't list devices")
+	}
+
+	for _, device := range devices {
+		device.Current = currentDeviceID != "" && device.DeviceID == currentDeviceID
+	}
+
+	return devices, nil
+}
+
+// UpdateDevice updates attributes of the device of the user,
+// e.g. push token or app version.
+func (s *Service) UpdateDevice(ctx context.Context, device *models.Device) error {
+	if device.DeviceID == "" {
+		return errors.New("device id is required")
+	}
+
+	err := validateDevice(device)
+	if err != nil {
+		return err
+	}
+
+	err = s.repoPG.UpdateDevice(ctx, device)
+	if err != nil {
+		return errors.WithMessage(err, "can't update device")
+	}
+
+	return nil
+}
+
+// RevokeDevice revokes the device of the user, the device is not
+// recognised on guest sign in and sessions of the device can't be
+// refreshed anymore.
+func (s *Service) RevokeDevice(ctx context.Context, scope *sharedmodels.Scope, deviceID string) error {
+	err := s.repoPG.RevokeDevice(ctx, scope, deviceID)
+	if err != nil {
+		return errors.WithMessage(err, "can't revoke device")
+	}
+
+	return nil
+}
@@ -0,0 +1,18 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+)
+
+func TestValidateDevice(t *testing.T) {
+	assert.Nil(t, validateDevice(&models.Device{DeviceID: "device", Platform: "ios", AppVersion: "1.0.0"}))
+	assert.EqualError(t, validateDevice(&models.Device{DeviceID: "device", Platform: strings.Repeat("a", 33)}),
+		"platform should have up to 32 characters")
+	assert.EqualError(t, validateDevice(&models.Device{DeviceID: "device", PushToken: strings.Repeat("a", 513)}),
+		"push_token should have up to 512 characters")
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// experimentsCacheTTL is time to reload experiments, variants
+// are assigned on every sync and pixel request.
+const experimentsCacheTTL = 30 * time.Second
+
+// gameExperiments contains running experiments of the game.
+type gameExperiments struct {
+	running []*models.Experiment
+}
+
+// runningExperiments respond with running experiments of the game.
+func (s *Service) runningExperiments(ctx context.Context, gameID string) ([]*models.Experiment, error) {
+	if cached, ok := s.experiments.Get(gameID); ok {
+		return cached.(*gameExperiments).running, nil
+	}
+
+	list, err := s.repoPG.ListExperiments(ctx, gameID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't list experiments")
+	}
+
+	experiments := &gameExperiments{}
+	for _, experiment := range list {
+		if experiment.Status == models.ExperimentRunning {
+			experiments.running = append(experiments.running, experiment)
+		}
+	}
+
+	s.experiments.Set(gameID, experiments)
+
+	return experiments.running, nil
+}
+
+// Assign respond with variants of running experiments of the game
+// assigned to the user by experiment key.
+func (s *Service) Assign(ctx context.Context, scope *sharedmodels.Scope) (map[string]string, error) {
+	running, err := s.runningExperiments(ctx, scope.GameID)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments := make(map[string]string, len(running))
+	for _, experiment := range running {
+		if variant := experiment.Assign(scope.UserID); variant != "" {
+			assignments[experiment.Key] = variant
+		}
+	}
+
+	return assignments, nil
+}
+
+// ListExperiments respond with experiments of the game.
+func (s *Service) ListExperiments(ctx context.Context, gameID string) ([]*models.Experiment, error) {
+	list, err := s.repoPG.ListExperiments(ctx, gameID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't list experiments")
+	}
+
+	return list, nil
+}
+
+// UpdateExperiment creates or updates experiment of the game.
+func (s *Service) UpdateExperiment(ctx context.Context, experiment *models.Experiment) error {
+	if experiment.Status == "" {
+		experiment.Status = models.ExperimentRunning
+	}
+
+	err := experiment.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = s.repoPG.UpsertExperiment(ctx, experiment)
+	if err != nil {
+		return errors.WithMessage(err, "can't update experiment")
+	}
+
+	s.resetExperiments(experiment.GameID)
+
+	return nil
+}
+
+// DeleteExperiment removes experiment of the game.
+func (s *Service) DeleteExperiment(ctx context.Context, gameID, key string) error {
+	err := s.repoPG.DeleteExperiment(ctx, gameID, key)
+	if err != nil {
+		return errors.WithMessage(err, "can't delete experiment")
+	}
+
+	s.resetExperiments(gameID)
+
+	return nil
+}
+
+func (s *Service) resetExperiments(gameID string) {
+	s.experiments.Delete(gameID)
+}
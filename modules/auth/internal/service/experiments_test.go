@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/experiments"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+func TestAssignExperiments(t *testing.T) {
+	svc := NewService(nil, nil, nil, zaptest.NewLogger(t).Sugar())
+
+	difficulty := &models.Experiment{
+		GameID:   "game-1",
+		Key:      "difficulty",
+		Status:   models.ExperimentRunning,
+		Variants: []experiments.Variant{{Name: "easy", Weight: 1}, {Name: "hard", Weight: 1}},
+	}
+	svc.experiments.Set("game-1", &gameExperiments{
+		running: []*models.Experiment{difficulty},
+	})
+
+	scope := &sharedmodels.Scope{GameID: "game-1", AppID: "app-1", UserID: "user-1"}
+
+	assignments, err := svc.Assign(context.Background(), scope)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"difficulty": difficulty.Assign("user-1")}, assignments)
+
+	// apps of the game share variants of the user
+	scope.AppID = "app-2"
+	other, err := svc.Assign(context.Background(), scope)
+	assert.Nil(t, err)
+	assert.Equal(t, assignments, other)
+}
+
+func TestUpdateExperimentValidation(t *testing.T) {
+	svc := NewService(nil, nil, nil, zaptest.NewLogger(t).Sugar())
+
+	for _, experiment := range []*models.Experiment{
+		{Key: "Invalid Key", Variants: []experiments.Variant{{Name: "a", Weight: 1}, {Name: "b", Weight: 1}}},
+		{Key: "prices", Variants: []experiments.Variant{{Name: "a", Weight: 1}}},
+		{Key: "prices", Variants: []experiments.Variant{{Name: "a", Weight: 1}, {Name: "a", Weight: 1}}},
+		{Key: "prices", Variants: []experiments.Variant{{Name: "a"}, {Name: "b"}}},
+		{Key: "prices", Status: "paused", Variants: []experiments.Variant{{Name: "a", Weight: 1}, {Name: "b", Weight: 1}}},
+	} {
+		experiment.GameID = "game-1"
+		assert.NotNil(t, svc.UpdateExperiment(context.Background(), experiment), experiment.Key)
+	}
+}
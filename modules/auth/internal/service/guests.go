@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// recoveryAlphabet skips similar characters, e.g. 0 and O,
+// to write down the code without mistakes.
+const recoveryAlphabet = "ABCDEFGHJKMNPQRSTVWXYZ23456789"
+
+// Recovery codes are groups of characters separated by dashes,
+// e.g. ABCD-EFGH-JKMN-PQRS.
+const (
+	recoveryCodeGroups    = 4
+	recoveryCodeGroupSize = 4
+)
+
+func generateRecoveryCode() (string, error) {
+	max := big.NewInt(int64(len(recoveryAlphabet)))
+
+	groups := make([]string, recoveryCodeGroups)
+	for i := range groups {
+		group := make([]byte, recoveryCodeGroupSize)
+		for j := range group {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", err
+			}
+			group[j] = recoveryAlphabet[n.Int64()]
+		}
+		groups[i] = string(group)
+	}
+
+	return strings.Join(groups, "-"), nil
+}
+
+// normalizeRecoveryCode removes separators and makes the code
+// upper case, codes are typed by players.
+func normalizeRecoveryCode(code string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(code) {
+		if strings.ContainsRune(recoveryAlphabet, r) {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// GetGuestPolicy respond with guest policy of the game.
+func (s *Service) GetGuestPolicy(ctx context.Context, gameID string) (*models.GuestPolicy, error) {
+	policy, err := s.repoPG.GetGuestPolicy(ctx, gameID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't get guest policy")
+	}
+
+	return policy, nil
+}
+
+// UpdateGuestPolicy sets guest policy of the game, guests created
+// before are kept.
+func (s *Service) UpdateGuestPolicy(ctx context.Context, policy *models.GuestPolicy) error {
+	if !models.ValidGuestScope(policy.Scope) {
+		return errors.Errorf("unknown guest scope %s", policy.Scope)
+	}
+
+	err := s.repoPG.UpsertGuestPolicy(ctx, policy)
+	if err != nil {
+		return errors.WithMessage(err, "can't update guest policy")
+	}
+
+	return nil
+}
+
+// CreateRecoveryCode generates the new recovery code of the guest,
+// the previous code can't be used anymore. Code is responded only
+// once, the player should write it down.
+func (s *Service) CreateRecoveryCode(ctx context.Context, scope *sharedmodels.Scope) (string, error) {
+	identities, err := s.ListIdentities(ctx, scope)
+	if err != nil {
+		return "", err
+	}
+	if len(identities) > 0 {
+		return "", models.ErrRecoveryNotAvailable
+	}
+
+	code, err := generateRecoveryCode()
+	if err != nil {
+		return "", errors.WithMessage(err, "can't generate recovery code")
+	}
+
+	err = s.repoPG.SetRecoveryCode(ctx, scope, normalizeRecoveryCode(code))
+	if err != nil {
+		return "", errors.WithMessage(err, "can't set recovery code")
+	}
+
+	return code, nil
+}
+
+// RecoverGuest restores the guest by recovery code on the device of the
+// user and respond with properties like sync.
+func (s *Service) RecoverGuest(ctx context.Context, propertiesSections []string, user *models.User, code string) ([]*models.Properties, error) {
+	err := validateDevice(user.Device())
+	if err != nil {
+		return nil, err
+	}
+
+	code = normalizeRecoveryCode(code)
+	if len(code) != recoveryCodeGroups*recoveryCodeGroupSize {
+		return nil, models.ErrInvalidRecoveryCode
+	}
+
+	err = s.repoPG.RecoverGuest(ctx, user, code)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't recover guest")
+	}
+
+	properties, err := s.getClientProperties(ctx, propertiesSections, &user.Scope)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't get properties list")
+	}
+
+	return properties, nil
+}
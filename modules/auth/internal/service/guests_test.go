@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+func TestGenerateRecoveryCode(t *testing.T) {
+	code, err := generateRecoveryCode()
+	assert.Nil(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^[A-Z2-9]{4}-[A-Z2-9]{4}-[A-Z2-9]{4}-[A-Z2-9]{4}$`), code)
+
+	other, err := generateRecoveryCode()
+	assert.Nil(t, err)
+	assert.NotEqual(t, code, other)
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	assert.Equal(t, "ABCDEFGHJKMNPQRS", normalizeRecoveryCode("abcd-efgh jkmn-pqrs"))
+	assert.Equal(t, "ABCDEFGHJKMNPQRS", normalizeRecoveryCode("ABCDEFGHJKMNPQRS"))
+	assert.Equal(t, "", normalizeRecoveryCode("--  --"))
+}
+
+// fakeGuestsPG resolves guests by device like users_devices does, the
+// guest of the same app is preferred for game scoped guests.
+type fakeGuestsPG struct {
+	*fakePropertiesPG
+
+	guestScopes map[string]string
+	devices     []*models.User
+	codes       map[string]*models.User
+	recovered   []string
+}
+
+func newFakeGuestsPG() *fakeGuestsPG {
+	return &fakeGuestsPG{
+		fakePropertiesPG: &fakePropertiesPG{stored: map[string]*models.Properties{}},
+		guestScopes:      map[string]string{},
+		codes:            map[string]*models.User{},
+	}
+}
+
+func (f *fakeGuestsPG) ListSectionSettings(_ context.Context, gameID string) ([]*models.Section, error) {
+	return []*models.Section{{GameID: gameID, Name: "progress", Shared: true}}, nil
+}
+
+func (f *fakeGuestsPG) AnomSync(_ context.Context, user *models.User) error {
+	var found *models.User
+	for _, guest := range f.devices {
+		if guest.GameID != user.GameID || guest.DeviceID != user.DeviceID {
+			continue
+		}
+		if guest.AppID == user.AppID {
+			found = guest
+			break
+		}
+		if f.guestScopes[user.GameID] == models.GuestScopeGame && found == nil {
+			found = guest
+		}
+	}
+
+	if found != nil {
+		user.UserID, user.Name = found.UserID, found.Name
+	} else {
+		user.UserID = fmt.Sprintf("user-%d", len(f.devices)+1)
+	}
+
+	f.devices = append(f.devices, &models.User{
+		Scope:    user.Scope,
+		DeviceID: user.DeviceID,
+		Name:     user.Name,
+	})
+	return nil
+}
+
+func (f *fakeGuestsPG) RecoverGuest(_ context.Context, user *models.User, code string) error {
+	f.recovered = append(f.recovered, code)
+
+	guest, ok := f.codes[code]
+	if !ok || guest.GameID != user.GameID {
+		return models.ErrInvalidRecoveryCode
+	}
+
+	user.UserID, user.Name = guest.UserID, guest.Name
+	return nil
+}
+
+func (f *fakeGuestsPG) setProgress(scope sharedmodels.Scope, level string) {
+	scope.AppID = models.SharedApp
+	f.stored[propertiesKey(scope, "progress")] = &models.Properties{
+		Scope:   scope,
+		Section: "progress",
+		Data:    models.Document{"level": json.RawMessage(level)},
+	}
+}
+
+func newGuest(appID string) *models.User {
+	return &models.User{
+		Scope:    sharedmodels.Scope{GameID: "game", AppID: appID},
+		DeviceID: "device",
+		Name:     "Guest",
+	}
+}
+
+func TestSyncReusesGuestByDevice(t *testing.T) {
+	for _, tc := range []struct {
+		scope string
+		same  bool
+	}{
+		{models.GuestScopeApp, false},
+		{models.GuestScopeGame, true},
+	} {
+		t.Run(tc.scope, func(t *testing.T) {
+			repoPG := newFakeGuestsPG()
+			repoPG.guestScopes["game"] = tc.scope
+			svc := NewService(repoPG, &fakePropertiesRedis{}, nil, zap.NewNop().Sugar())
+
+			ios := newGuest("ios")
+			_, err := svc.Sync(context.Background(), []string{"progress"}, ios)
+			require.Nil(t, err)
+			repoPG.setProgress(ios.Scope, `5`)
+
+			android := newGuest("android")
+			collection, err := svc.Sync(context.Background(), []string{"progress"}, android)
+			require.Nil(t, err)
+
+			if !tc.same {
+				assert.NotEqual(t, ios.UserID, android.UserID)
+				assert.Empty(t, collection)
+				return
+			}
+
+			assert.Equal(t, ios.UserID, android.UserID)
+			require.Len(t, collection, 1)
+			assert.Equal(t, json.RawMessage(`5`), collection[0].Data["level"])
+
+			// the guest of the same app is still used by the device
+			again := newGuest("ios")
+			_, err = svc.Sync(context.Background(), nil, again)
+			require.Nil(t, err)
+			assert.Equal(t, ios.UserID, again.UserID)
+		})
+	}
+}
+
+func TestRecoverGuestByCode(t *testing.T) {
+	repoPG := newFakeGuestsPG()
+	svc := NewService(repoPG, &fakePropertiesRedis{}, nil, zap.NewNop().Sugar())
+
+	guest := newGuest("ios")
+	_, err := svc.Sync(context.Background(), nil, guest)
+	require.Nil(t, err)
+	repoPG.setProgress(guest.Scope, `7`)
+	repoPG.codes["ABCDEFGH23456789"] = guest
+
+	// short codes are rejected before the database
+	_, err = svc.RecoverGuest(context.Background(), nil, newGuest("ios"), "ABCD-EFGH")
+	assert.Equal(t, models.ErrInvalidRecoveryCode, err)
+	assert.Empty(t, repoPG.recovered)
+
+	_, err = svc.RecoverGuest(context.Background(), nil, newGuest("ios"), "ZZZZ-ZZZZ-ZZZZ-ZZZZ")
+	assert.Equal(t, models.ErrInvalidRecoveryCode, errors.Cause(err))
+
+	user := newGuest("android")
+	user.DeviceID = "other"
+	collection, err := svc.RecoverGuest(context.Background(), []string{"progress"}, user, "abcd-efgh 2345-6789")
+	require.Nil(t, err)
+	assert.Equal(t, guest.UserID, user.UserID)
+	assert.Equal(t, "ABCDEFGH23456789", repoPG.recovered[len(repoPG.recovered)-1])
+	require.Len(t, collection, 1)
+	assert.Equal(t, json.RawMessage(`7`), collection[0].Data["level"])
+}
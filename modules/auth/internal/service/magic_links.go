@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/mailer"
+)
+
+// MagicLinksConfig contains settings of email sign in.
+type MagicLinksConfig struct {
+	// URL is page of the game opening magic link, token
+	// is passed as query param.
+	URL string
+	// TTL is lifetime of magic link
+	TTL time.Duration
+	// Subject of email
+	Subject string
+
+	// MaxPerEmail, MaxPerIP are numbers of magic links allowed
+	// to request during RequestsWindow.
+	MaxPerEmail    int64
+	MaxPerIP       int64
+	RequestsWindow time.Duration
+}
+
+// DefaultMagicLinksConfig is used in case if settings are not configured.
+var DefaultMagicLinksConfig = MagicLinksConfig{
+	TTL:            15 * time.Minute,
+	Subject:        "Sign in to continue your game",
+	MaxPerEmail:    5,
+	MaxPerIP:       20,
+	RequestsWindow: 15 * time.Minute,
+}
+
+// Validate checks url of magic links, the url should be absolute
+// to be opened from email.
+func (c MagicLinksConfig) Validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return errors.Wrap(err, "invalid magic link url")
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return errors.Errorf("magic link url %q should be absolute", c.URL)
+	}
+
+	return nil
+}
+
+var (
+	// ErrInvalidEmail returned for malformed email on magic link request.
+	ErrInvalidEmail = errors.New("invalid email")
+	// ErrMagicLinksDisabled returned once mailer is not configured.
+	ErrMagicLinksDisabled = errors.New("email sign in is disabled")
+	// ErrTooManyMagicLinks returned once magic links of the email or
+	// ip address reached the limit.
+	ErrTooManyMagicLinks = errors.New("too many magic link requests")
+)
+
+// RequestMagicLink sends magic link to the email, the guest of the request
+// is linked to the email on verification.
+func (s *Service) RequestMagicLink(ctx context.Context, link *models.MagicLink) error {
+	if s.Mailer == nil {
+		return ErrMagicLinksDisabled
+	}
+
+	address, err := mail.ParseAddress(link.Email)
+	if err != nil {
+		return ErrInvalidEmail
+	}
+	link.Email = strings.ToLower(address.Address)
+
+	byEmail, byIP, err := s.repoRedis.AddMagicLinkRequest(ctx, link.Email, link.IP, s.MagicLinks.RequestsWindow)
+	if err != nil {
+		return errors.WithMessage(err, "can't count magic link requests")
+	}
+	if byEmail > s.MagicLinks.MaxPerEmail || (link.IP != "" && byIP > s.MagicLinks.MaxPerIP) {
+		return ErrTooManyMagicLinks
+	}
+
+	err = s.repoRedis.CreateMagicLink(ctx, link, s.MagicLinks.TTL)
+	if err != nil {
+		return errors.WithMessage(err, "can't create magic link")
+	}
+
+	u, err := s.magicLinkURL(link)
+	if err != nil {
+		return err
+	}
+
+	err = s.Mailer.Send(ctx, mailer.Message{
+		To:      link.Email,
+		Subject: s.MagicLinks.Subject,
+		Text: fmt.Sprintf("Open the link to sign in, it expires in %d minutes:\n\n%s\n\n"+
+			"If you didn't request it, just ignore this email.\n",
+			int(s.MagicLinks.TTL.Minutes()), u),
+	})
+	if err != nil {
+		return errors.WithMessage(err, "can't send magic link")
+	}
+
+	return nil
+}
+
+func (s *Service) magicLinkURL(link *models.MagicLink) (string, error) {
+	u, err := url.Parse(s.MagicLinks.URL)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid magic link url")
+	}
+
+	q := u.Query()
+	q.Set("token", link.Token)
+	q.Set("game_id", link.GameID)
+	q.Set("app_id", link.AppID)
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// VerifyMagicLink uses the token and syncs the user with EMAIL network
+// identity, the link could be opened only by the same game and app.
+func (s *Service) VerifyMagicLink(ctx context.Context, token, gameID, appID, deviceID string,
+	propertiesSections []string) (*models.User, []*models.Properties, error) {
+	link, err := s.repoRedis.UseMagicLink(ctx, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if link.GameID != gameID || link.AppID != appID {
+		return nil, nil, models.ErrInvalidMagicLink
+	}
+
+	// the link could be opened on another device
+	if deviceID == "" {
+		deviceID = link.DeviceID
+	}
+
+	guestID := link.GuestID
+	if guestID == "" {
+		guestID, err = uuid.GenerateUUID()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	user := &models.User{
+		Scope:     link.Scope,
+		DeviceID:  deviceID,
+		GuestID:   guestID,
+		Email:     link.Email,
+		Name:      strings.SplitN(link.Email, "@", 2)[0],
+		Network:   models.NetworkEmail,
+		NetworkID: link.Email,
+	}
+
+	properties, err := s.syncNetworkUser(ctx, propertiesSections, user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, properties, nil
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+func TestReservedEmailNetwork(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(nil, nil, nil, zap.NewNop().Sugar())
+	scope := sharedmodels.Scope{GameID: "game", AppID: "app", UserID: "attacker"}
+
+	for _, network := range []string{models.NetworkEmail, "email", " Email "} {
+		_, err := svc.UsersSync(ctx, nil, &models.User{
+			Scope:     scope,
+			DeviceID:  "device",
+			Network:   network,
+			NetworkID: "victim@example.com",
+		})
+		require.Equal(t, models.ErrReservedNetwork, err, network)
+
+		err = svc.LinkIdentity(ctx, &models.User{
+			Scope:     scope,
+			DeviceID:  "device",
+			Network:   network,
+			NetworkID: "victim@example.com",
+		})
+		require.Equal(t, models.ErrReservedNetwork, err, network)
+
+		_, err = svc.Merge(ctx, &scope, nil, &models.Identity{
+			Network:   network,
+			NetworkID: "victim@example.com",
+		}, "")
+		require.Equal(t, models.ErrReservedNetwork, err, network)
+	}
+}
+
+type fakeMagicLinksRedis struct {
+	RedisRepository
+	requests map[string]int64
+}
+
+func (f *fakeMagicLinksRedis) AddMagicLinkRequest(_ context.Context, email, ip string, _ time.Duration) (int64, int64, error) {
+	f.requests["email:"+email]++
+	f.requests["ip:"+ip]++
+	return f.requests["email:"+email], f.requests["ip:"+ip], nil
+}
+
+func (f *fakeMagicLinksRedis) CreateMagicLink(_ context.Context, link *models.MagicLink, _ time.Duration) error {
+	link.Token = "token"
+	return nil
+}
+
+func TestRequestMagicLinkLimits(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(nil, &fakeMagicLinksRedis{requests: map[string]int64{}}, nil, zap.NewNop().Sugar())
+	svc.MagicLinks.URL = "https://game.example.com/signin"
+	svc.MagicLinks.MaxPerEmail = 2
+	svc.MagicLinks.MaxPerIP = 3
+
+	request := func(email, ip string) error {
+		return svc.RequestMagicLink(ctx, &models.MagicLink{Email: email, IP: ip})
+	}
+
+	require.NoError(t, request("a@example.com", "10.0.0.1"))
+	require.NoError(t, request("A@example.com", "10.0.0.2"))
+	require.Equal(t, ErrTooManyMagicLinks, request("a@example.com", "10.0.0.3"))
+
+	require.NoError(t, request("b@example.com", "10.0.0.4"))
+	require.NoError(t, request("c@example.com", "10.0.0.4"))
+	require.NoError(t, request("d@example.com", "10.0.0.4"))
+	require.Equal(t, ErrTooManyMagicLinks, request("e@example.com", "10.0.0.4"))
+
+	svc.Mailer = nil
+	require.Equal(t, ErrMagicLinksDisabled, request("f@example.com", "10.0.0.5"))
+}
+
+func TestMagicLinksConfigValidate(t *testing.T) {
+	for _, u := range []string{"https://game.example.com/signin", "mygame://signin"} {
+		require.NoError(t, MagicLinksConfig{URL: u}.Validate(), u)
+	}
+
+	for _, u := range []string{"", "/signin", "game.example.com/signin", "://"} {
+		require.Error(t, MagicLinksConfig{URL: u}.Validate(), u)
+	}
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// ErrMergeSameUser returned on attempt to merge the user into itself.
+var ErrMergeSameUser = errors.New("can't merge the user into itself")
+
+// ErrMergeStrategy returned on unknown merge strategy.
+var ErrMergeStrategy = errors.New("unknown merge strategy")
+
+// ErrMergeProof returned once token of the surviving user is missing,
+// revoked or belongs to another user than the identity owner.
+var ErrMergeProof = errors.New("merge requires token of the identity owner")
+
+// Merge merges the user by scope into the owner of the identity. The owner
+// survives because of having linked network profile, the user by scope
+// becomes alias of the owner, so old JWT tokens are resolved to the owner.
+// Survivor is claims of the owner token proving access to the owner account.
+// Postgres is changed in one transaction, aliases, scores and cached
+// sections are written to redis after commit and are idempotent, so
+// failed merge is completed once it's retried.
+func (s *Service) Merge(ctx context.Context, scope *sharedmodels.Scope, survivor *auth.Claims,
+	identity *models.Identity, strategy models.MergeStrategy) (*models.Merge, error) {
+	if strategy == "" {
+		strategy = s.MergeStrategy
+	}
+	if !strategy.Valid() {
+		return nil, ErrMergeStrategy
+	}
+	if models.ReservedNetwork(identity.Network) {
+		return nil, models.ErrReservedNetwork
+	}
+
+	if survivor == nil || survivor.Type == auth.ServerType ||
+		survivor.GameID != scope.GameID || survivor.AppID != scope.AppID {
+		return nil, ErrMergeProof
+	}
+
+	revoked, err := s.repoRedis.IsRevoked(ctx, survivor)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't check survivor token revocation")
+	}
+	if revoked {
+		return nil, ErrMergeProof
+	}
+
+	identity.GameID = scope.GameID
+	identity.AppID = scope.AppID
+	identity.UserID = ""
+
+	err = s.repoPG.FindIdentityOwner(ctx, identity)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't find identity owner")
+	}
+
+	if identity.UserID == scope.UserID {
+		return nil, ErrMergeSameUser
+	}
+	if identity.UserID != survivor.UserID {
+		return nil, ErrMergeProof
+	}
+
+	merge := &models.Merge{
+		Scope: sharedmodels.Scope{
+			GameID: scope.GameID,
+			AppID:  scope.AppID,
+			UserID: identity.UserID,
+		},
+		MergedUserID: scope.UserID,
+		Strategy:     strategy,
+	}
+
+	log := s.logger.With(merge.Scope.Fields()...).With("merged_user_id", merge.MergedUserID)
+	log.Infof("begin merge users with strategy %s", strategy)
+
+	collection, deleted, err := s.mergeProperties(ctx, merge)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't merge properties")
+	}
+
+	stored, validate, err := s.storedProperties(ctx, collection, propertiesWriter{})
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't merge properties")
+	}
+
+	aliases, err := s.repoPG.MergeUsers(ctx, merge, stored, deleted, validate)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't merge users")
+	}
+
+	err = s.repoRedis.SetAliases(ctx, aliases, merge.UserID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't set aliases")
+	}
+
+	if s.repoLeaderboard != nil {
+		err = s.repoLeaderboard.MoveScores(ctx, merge.MergedUserID, merge.UserID)
+		if err != nil {
+			return nil, errors.WithMessage(err, "can't move leaderboard scores")
+		}
+	}
+
+	err = s.repoRedis.CacheProperties(ctx, stored, s.PropertiesCacheTTL)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't cache merged properties")
+	}
+
+	for storage, sections := range deleted {
+		storage := storage
+
+		err = s.repoRedis.DeleteProperties(ctx, sections, &storage)
+		if err != nil {
+			return nil, errors.WithMessage(err, "can't delete cached properties of merged user")
+		}
+	}
+
+	log.Info("done merge users")
+
+	return merge, nil
+}
+
+// aliasesBatch is number of aliases rebuilt at once.
+const aliasesBatch = 1000
+
+// RebuildAliases sets aliases of all merged users in redis by aliases
+// stored in postgres, it's used once aliases are lost or merge isn't
+// retried after failed redis write.
+func (s *Service) RebuildAliases(ctx context.Context) (int, error) {
+	var rebuilt int
+	var after string
+
+	for {
+		aliases, err := s.repoPG.ListAliases(ctx, after, aliasesBatch)
+		if err != nil {
+			return rebuilt, errors.WithMessage(err, "can't list aliases")
+		}
+		if len(aliases) == 0 {
+			return rebuilt, nil
+		}
+
+		for _, alias := range aliases {
+			err = s.repoRedis.SetAliases(ctx, []string{alias.AliasUserID}, alias.UserID)
+			if err != nil {
+				return rebuilt, errors.WithMessage(err, "can't set aliases")
+			}
+			rebuilt++
+		}
+
+		after = aliases[len(aliases)-1].AliasUserID
+	}
+}
+
+// mergeProperties respond with sections of the surviving user merged with
+// sections of the merged user and storage scopes of merged user sections
+// to delete.
+func (s *Service) mergeProperties(ctx context.Context, merge *models.Merge) ([]*models.Properties, map[sharedmodels.Scope][]string, error) {
+	survivor := merge.Scope
+	merged := merge.Scope
+	merged.UserID = merge.MergedUserID
+
+	survivorSections, err := s.listSections(ctx, &survivor)
+	if err != nil {
+		return nil, nil, err
+	}
+	mergedSections, err := s.listSections(ctx, &merged)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(mergedSections) == 0 {
+		return nil, nil, nil
+	}
+
+	survivorSeen, err := s.repoPG.LastSeen(ctx, &survivor)
+	if err != nil {
+		return nil, nil, err
+	}
+	mergedSeen, err := s.repoPG.LastSeen(ctx, &merged)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sections := unique(append(survivorSections, mergedSections...))
+
+	survivorProps, err := s.getProperties(ctx, sections, &survivor)
+	if err != nil {
+		return nil, nil, err
+	}
+	mergedProps, err := s.getProperties(ctx, sections, &merged)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	survivorData := dataBySection(survivorProps)
+	mergedData := dataBySection(mergedProps)
+
+	var collection []*models.Properties
+	for _, section := range sections {
+		older, newer := survivorData[section], mergedData[section]
+		if survivorSeen.After(mergedSeen) {
+			older, newer = newer, older
+		}
+
+		collection = append(collection, &models.Properties{
+			Scope:   survivor,
+			Section: section,
+			Data:    mergeSection(merge.Strategy, older, newer),
+		})
+	}
+
+	deleted, err := s.storageGroups(ctx, mergedSections, &merged)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return collection, deleted, nil
+}
+
+// mergeSection merges section values of two users, values of newer user
+// are preferred unless strategy asks to keep max numeric values.
+func mergeSection(strategy models.MergeStrategy, older, newer models.Document) models.Document {
+	result := make(models.Document, len(older)+len(newer))
+
+	for key, value := range older {
+		result[key] = value
+	}
+
+	for key, value := range newer {
+		current, ok := result[key]
+		if ok && strategy == models.MergePreferMax {
+			currentNum, okCurrent := numericValue(current)
+			valueNum, okValue := numericValue(value)
+			if okCurrent && okValue {
+				if currentNum > valueNum {
+					continue
+				}
+			}
+		}
+
+		result[key] = value
+	}
+
+	return result
+}
+
+// numericValue parses JSON number or string with number,
+// numbers were stored as strings before values became JSON.
+func numericValue(value json.RawMessage) (float64, bool) {
+	var number float64
+	if err := json.Unmarshal(value, &number); err == nil {
+		return number, true
+	}
+
+	var str string
+	if err := json.Unmarshal(value, &str); err != nil {
+		return 0, false
+	}
+
+	number, err := strconv.ParseFloat(str, 64)
+	return number, err == nil
+}
+
+func dataBySection(collection []*models.Properties) map[string]models.Document {
+	result := make(map[string]models.Document, len(collection))
+	for _, properties := range collection {
+		result[properties.Section] = properties.Data
+	}
+	return result
+}
+
+func unique(values []string) []string {
+	seen := make(map[string]bool, len(values))
+
+	var result []string
+	for _, value := range values {
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+
+	return result
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+type fakeMergePG struct {
+	PostgresRepository
+	owner string
+}
+
+func (f *fakeMergePG) FindIdentityOwner(_ context.Context, identity *models.Identity) error {
+	identity.UserID = f.owner
+	return nil
+}
+
+type fakeMergeRedis struct {
+	RedisRepository
+	revoked bool
+}
+
+func (f *fakeMergeRedis) IsRevoked(context.Context, *auth.Claims) (bool, error) {
+	return f.revoked, nil
+}
+
+func TestMergeRequiresSurvivorToken(t *testing.T) {
+	ctx := context.Background()
+	redis := &fakeMergeRedis{}
+	svc := NewService(&fakeMergePG{owner: "victim"}, redis, nil, zap.NewNop().Sugar())
+
+	scope := &sharedmodels.Scope{GameID: "game", AppID: "app", UserID: "attacker"}
+	identity := func() *models.Identity {
+		return &models.Identity{Network: "FACEBOOK", NetworkID: "victim-profile"}
+	}
+	token := func(userID, gameID string) *auth.Claims {
+		return &auth.Claims{UserInfo: auth.UserInfo{UserID: userID, GameID: gameID, AppID: "app", Type: auth.RealType}}
+	}
+
+	// no proof of the owner account
+	_, err := svc.Merge(ctx, scope, nil, identity(), "")
+	require.Equal(t, ErrMergeProof, err)
+
+	// token of another user than identity owner
+	_, err = svc.Merge(ctx, scope, token("attacker", "game"), identity(), "")
+	require.Equal(t, ErrMergeProof, err)
+
+	// token of the owner in another game
+	_, err = svc.Merge(ctx, scope, token("victim", "other-game"), identity(), "")
+	require.Equal(t, ErrMergeProof, err)
+
+	// revoked token of the owner
+	redis.revoked = true
+	_, err = svc.Merge(ctx, scope, token("victim", "game"), identity(), "")
+	require.Equal(t, ErrMergeProof, err)
+}
+
+// fakeMergeTxPG merges users like one postgres transaction, nothing is
+// changed once it fails.
+type fakeMergeTxPG struct {
+	fakePropertiesPG
+	owner string
+	fail  bool
+}
+
+func (f *fakeMergeTxPG) FindIdentityOwner(_ context.Context, identity *models.Identity) error {
+	identity.UserID = f.owner
+	return nil
+}
+
+func (f *fakeMergeTxPG) ListSections(_ context.Context, scope *sharedmodels.Scope) ([]string, error) {
+	var sections []string
+	for _, properties := range f.stored {
+		if properties.Scope == *scope {
+			sections = append(sections, properties.Section)
+		}
+	}
+	return sections, nil
+}
+
+func (f *fakeMergeTxPG) LastSeen(context.Context, *sharedmodels.Scope) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+func (f *fakeMergeTxPG) MergeUsers(_ context.Context, merge *models.Merge, collection []*models.Properties,
+	deleted map[sharedmodels.Scope][]string, _ func(before, after *models.Properties) error) ([]string, error) {
+	if f.fail {
+		return nil, errors.New("connection is lost")
+	}
+
+	for _, properties := range collection {
+		f.merge(properties, false)
+	}
+	for storage, sections := range deleted {
+		for _, section := range sections {
+			delete(f.stored, propertiesKey(storage, section))
+		}
+	}
+
+	return []string{merge.MergedUserID}, nil
+}
+
+// fakeMergeTxRedis fails to set aliases given number of times.
+type fakeMergeTxRedis struct {
+	fakePropertiesRedis
+	failAliases int
+	aliases     map[string]string
+}
+
+func (f *fakeMergeTxRedis) IsRevoked(context.Context, *auth.Claims) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeMergeTxRedis) SetAliases(_ context.Context, aliases []string, userID string) error {
+	if f.failAliases > 0 {
+		f.failAliases--
+		return errors.New("connection is lost")
+	}
+	for _, alias := range aliases {
+		f.aliases[alias] = userID
+	}
+	return nil
+}
+
+func (f *fakeMergeTxRedis) ListSections(context.Context, *sharedmodels.Scope) ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeMergeTxRedis) DeleteProperties(context.Context, []string, *sharedmodels.Scope) error {
+	return nil
+}
+
+func TestMergeIsCompletedByRetry(t *testing.T) {
+	ctx := context.Background()
+	scope := &sharedmodels.Scope{GameID: "game", AppID: "app", UserID: "merged"}
+	survivor := &auth.Claims{UserInfo: auth.UserInfo{UserID: "survivor", GameID: "game", AppID: "app", Type: auth.RealType}}
+	identity := func() *models.Identity {
+		return &models.Identity{Network: "FACEBOOK", NetworkID: "profile"}
+	}
+
+	repoPG := &fakeMergeTxPG{fakePropertiesPG: fakePropertiesPG{stored: map[string]*models.Properties{}}, owner: "survivor", fail: true}
+	repoPG.merge(&models.Properties{Scope: *scope, Section: "progress", Data: models.StringDocument(map[string]string{"level": "3"})}, false)
+	repoRedis := &fakeMergeTxRedis{aliases: map[string]string{}, failAliases: 1}
+	svc := NewService(repoPG, repoRedis, nil, zap.NewNop().Sugar())
+
+	// redis isn't changed once postgres transaction fails
+	_, err := svc.Merge(ctx, scope, survivor, identity(), "")
+	require.NotNil(t, err)
+	require.Empty(t, repoRedis.aliases)
+	require.Len(t, repoPG.stored, 1)
+
+	// aliases aren't set after commit
+	repoPG.fail = false
+	_, err = svc.Merge(ctx, scope, survivor, identity(), "")
+	require.NotNil(t, err)
+	require.Empty(t, repoRedis.aliases)
+
+	_, err = svc.Merge(ctx, scope, survivor, identity(), "")
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{"merged": "survivor"}, repoRedis.aliases)
+
+	merged := *scope
+	merged.UserID = "survivor"
+	stored := repoPG.stored[propertiesKey(merged, "progress")]
+	require.NotNil(t, stored)
+	require.Equal(t, json.RawMessage(`"3"`), stored.Data["level"])
+	require.Len(t, repoPG.stored, 1)
+}
+
+func TestMergeSectionPreferNewest(t *testing.T) {
+	older := models.StringDocument(map[string]string{"coins": "100", "level": "5", "skin": "red"})
+	newer := models.StringDocument(map[string]string{"coins": "20", "level": "2"})
+
+	result := mergeSection(models.MergePreferNewest, older, newer)
+	require.Equal(t, models.StringDocument(map[string]string{"coins": "20", "level": "2", "skin": "red"}), result)
+}
+
+func TestMergeSectionPreferMax(t *testing.T) {
+	older := models.StringDocument(map[string]string{"coins": "100", "level": "5", "skin": "red"})
+	newer := models.StringDocument(map[string]string{"coins": "20", "level": "7", "skin": "blue"})
+
+	result := mergeSection(models.MergePreferMax, older, newer)
+	require.Equal(t, models.StringDocument(map[string]string{"coins": "100", "level": "7", "skin": "blue"}), result)
+}
+
+func TestMergeSectionPreferMaxJSON(t *testing.T) {
+	older := models.Document{"coins": json.RawMessage(`100`), "level": json.RawMessage(`"5"`), "items": json.RawMessage(`[1]`)}
+	newer := models.Document{"coins": json.RawMessage(`20.5`), "level": json.RawMessage(`7`), "items": json.RawMessage(`[2]`)}
+
+	result := mergeSection(models.MergePreferMax, older, newer)
+	require.Equal(t, models.Document{
+		"coins": json.RawMessage(`100`),
+		"level": json.RawMessage(`7`),
+		"items": json.RawMessage(`[2]`),
+	}, result)
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/profanity"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// NamesConfig contains rules of display names chosen by players.
+type NamesConfig struct {
+	MinLength int
+	MaxLength int
+	// Cooldown is time between name changes of the player.
+	Cooldown time.Duration
+	// Profanity rejects names with offensive words.
+	Profanity *profanity.Filter
+}
+
+// DefaultNamesConfig is used in case if names are not configured.
+var DefaultNamesConfig = NamesConfig{
+	MinLength: 3,
+	MaxLength: 24,
+	Cooldown:  7 * 24 * time.Hour,
+	Profanity: profanity.Default(),
+}
+
+// DefaultNamePattern generates guest names of games without templates.
+const DefaultNamePattern = "Guest-{device}"
+
+// Limits of name templates, generated names should fit into
+// name columns.
+const (
+	maxNamePatternLength = 32
+	maxNameWordLength    = 16
+	maxNameWords         = 1000
+)
+
+var (
+	// letters, digits, spaces, underscores and dashes
+	validName   = regexp.MustCompile(`^[\p{L}\p{N}_\- ]+$`)
+	validLocale = regexp.MustCompile(`^[a-z]{2,3}(-[a-zA-Z0-9]{2,8})?$`)
+)
+
+// nameTemplatesCacheTTL is time to reload name templates,
+// templates are read on every guest sync.
+const nameTemplatesCacheTTL = 30 * time.Second
+
+// gameNameTemplates contains name templates of the game by locale.
+type gameNameTemplates struct {
+	byLocale map[string]*models.NameTemplate
+}
+
+// nameTemplates respond with name templates of the game.
+func (s *Service) nameTemplates(ctx context.Context, gameID string) (*gameNameTemplates, error) {
+	if cached, ok := s.templates.Get(gameID); ok {
+		return cached.(*gameNameTemplates), nil
+	}
+
+	list, err := s.repoPG.ListNameTemplates(ctx, gameID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't list name templates")
+	}
+
+	templates := &gameNameTemplates{
+		byLocale: make(map[string]*models.NameTemplate, len(list)),
+	}
+	for _, template := range list {
+		templates.byLocale[strings.ToLower(template.Locale)] = template
+	}
+
+	s.templates.Set(gameID, templates)
+
+	return templates, nil
+}
+
+// find respond with template of the locale, template of the language
+// or default one, e.g. pt-BR, pt, default.
+func (g *gameNameTemplates) find(locale string) *models.NameTemplate {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+
+	candidates := []string{locale}
+	if i := strings.Index(locale, "-"); i > 0 {
+		candidates = append(candidates, locale[:i])
+	}
+	candidates = append(candidates, models.DefaultLocale)
+
+	for _, candidate := range candidates {
+		if template, ok := g.byLocale[candidate]; ok {
+			return template
+		}
+	}
+
+	return nil
+}
+
+// guestName generates name of the new user by template of the game,
+// names are generated by DefaultNamePattern if game has no templates.
+func (s *Service) guestName(ctx context.Context, user *models.User) string {
+	template := &models.NameTemplate{Pattern: DefaultNamePattern}
+
+	templates, err := s.nameTemplates(ctx, user.GameID)
+	if err != nil {
+		// sync should work even if templates are not available
+		s.logger.With("game_id", user.GameID).Errorf("can't load name templates: %s", err)
+	} else if found := templates.find(user.Locale); found != nil {
+		template = found
+	}
+
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return generateName(template, user.DeviceID, rnd)
+}
+
+// generateName replaces placeholders of the template pattern.
+func generateName(template *models.NameTemplate, deviceID string, rnd *rand.Rand) string {
+	device := deviceID
+	if len(device) > 5 {
+		device = device[0:5]
+	} else {
+		// in case if we have weird device id, we should
+		// use random digits
+		device = fmt.Sprintf("%05d", rnd.Intn(100000))
+	}
+
+	pick := func(words []string) string {
+		if len(words) == 0 {
+			return ""
+		}
+		return words[rnd.Intn(len(words))]
+	}
+
+	return strings.NewReplacer(
+		"{adjective}", pick(template.Adjectives),
+		"{noun}", pick(template.Nouns),
+		"{number}", fmt.Sprintf("%04d", rnd.Intn(10000)),
+		"{device}", device,
+	).Replace(template.Pattern)
+}
+
+// ListNameTemplates respond with guest name templates of the game.
+func (s *Service) ListNameTemplates(ctx context.Context, gameID string) ([]*models.NameTemplate, error) {
+	templates, err := s.repoPG.ListNameTemplates(ctx, gameID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't list name templates")
+	}
+
+	return templates, nil
+}
+
+// UpdateNameTemplate creates or updates guest name template of the game.
+func (s *Service) UpdateNameTemplate(ctx context.Context, template *models.NameTemplate) error {
+	err := validateNameTemplate(template)
+	if err != nil {
+		return err
+	}
+
+	err = s.repoPG.UpsertNameTemplate(ctx, template)
+	if err != nil {
+		return errors.WithMessage(err, "can't update name template")
+	}
+
+	s.resetNameTemplates(template.GameID)
+
+	return nil
+}
+
+// DeleteNameTemplate removes guest name template of the game.
+func (s *Service) DeleteNameTemplate(ctx context.Context, gameID, locale string) error {
+	err := s.repoPG.DeleteNameTemplate(ctx, gameID, locale)
+	if err != nil {
+		return errors.WithMessage(err, "can't delete name template")
+	}
+
+	s.resetNameTemplates(gameID)
+
+	return nil
+}
+
+func (s *Service) resetNameTemplates(gameID string) {
+	s.templates.Delete(gameID)
+}
+
+func validateNameTemplate(template *models.NameTemplate) error {
+	if template.Locale != models.DefaultLocale && !validLocale.MatchString(template.Locale) {
+		return errors.Errorf("invalid locale %s, should be language, language with region or %s",
+			template.Locale, models.DefaultLocale)
+	}
+
+	if template.Pattern == "" || len(template.Pattern) > maxNamePatternLength {
+		return errors.Errorf("pattern should have 1 to %d characters", maxNamePatternLength)
+	}
+
+	for placeholder, words := range map[string][]string{
+		"{adjective}": template.Adjectives,
+		"{noun}":      template.Nouns,
+	} {
+		if strings.Contains(template.Pattern, placeholder) && len(words) == 0 {
+			return errors.Errorf("pattern uses %s without words", placeholder)
+		}
+
+		if len(words) > maxNameWords {
+			return errors.Errorf("template should have up to %d words of %s", maxNameWords, placeholder)
+		}
+
+		for _, word := range words {
+			if word == "" || utf8.RuneCountInString(word) > maxNameWordLength {
+				return errors.Errorf("words should have 1 to %d characters", maxNameWordLength)
+			}
+		}
+	}
+
+	if template.Adjectives == nil {
+		template.Adjectives = []string{}
+	}
+	if template.Nouns == nil {
+		template.Nouns = []string{}
+	}
+
+	return nil
+}
+
+// RenameUser changes display name of the player, names are unique per
+// game ignoring case and could be changed once per cooldown. The name is
+// updated in leaderboards of the player too.
+func (s *Service) RenameUser(ctx context.Context, scope *sharedmodels.Scope, name string) (string, error) {
+	name, err := s.checkName(name)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.repoPG.RenameUser(ctx, scope, name, s.Names.Cooldown)
+	if err != nil {
+		return "", errors.WithMessage(err, "can't rename user")
+	}
+
+	if s.repoLeaderboard != nil {
+		// the name is already changed, leaderboards get the name
+		// with the next score otherwise
+		err = s.repoLeaderboard.RenameUser(ctx, scope.UserID, name)
+		if err != nil {
+			s.logger.With(scope.Fields()...).Errorf("can't rename user in leaderboards: %s", err)
+		}
+	}
+
+	return name, nil
+}
+
+// checkName respond with trimmed name or models.InvalidNameError.
+func (s *Service) checkName(name string) (string, error) {
+	// repeated spaces are collapsed
+	name = strings.Join(strings.Fields(name), " ")
+
+	length := utf8.RuneCountInString(name)
+	if length < s.Names.MinLength || length > s.Names.MaxLength {
+		return "", &models.InvalidNameError{
+			Reason: fmt.Sprintf("name should have %d to %d characters", s.Names.MinLength, s.Names.MaxLength),
+		}
+	}
+
+	if !validName.MatchString(name) {
+		return "", &models.InvalidNameError{
+			Reason: "name should contain only letters, digits, spaces, underscores and dashes",
+		}
+	}
+
+	if s.Names.Profanity != nil && s.Names.Profanity.Contains(name) {
+		return "", &models.InvalidNameError{Reason: "name contains offensive words"}
+	}
+
+	return name, nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"math/rand"
+	"regexp"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+)
+
+func TestGenerateName(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	template := &models.NameTemplate{
+		Pattern:    "{adjective}{noun}{number}",
+		Adjectives: []string{"Brave", "Clever"},
+		Nouns:      []string{"Fox", "Owl"},
+	}
+	assert.Regexp(t, regexp.MustCompile(`^(Brave|Clever)(Fox|Owl)\d{4}$`), generateName(template, "device", rnd))
+
+	guest := &models.NameTemplate{Pattern: DefaultNamePattern}
+	assert.Equal(t, "Guest-e4f51", generateName(guest, "e4f5142c7553", rnd))
+	assert.Regexp(t, regexp.MustCompile(`^Guest-\d{5}$`), generateName(guest, "abc", rnd))
+}
+
+func TestFindNameTemplate(t *testing.T) {
+	templates := &gameNameTemplates{byLocale: map[string]*models.NameTemplate{
+		"pt-br":              {Locale: "pt-BR"},
+		"de":                 {Locale: "de"},
+		models.DefaultLocale: {Locale: models.DefaultLocale},
+	}}
+
+	assert.Equal(t, "pt-BR", templates.find("pt_BR").Locale)
+	assert.Equal(t, "de", templates.find("de-AT").Locale)
+	assert.Equal(t, models.DefaultLocale, templates.find("fr").Locale)
+	assert.Equal(t, models.DefaultLocale, templates.find("").Locale)
+
+	empty := &gameNameTemplates{byLocale: map[string]*models.NameTemplate{}}
+	assert.Nil(t, empty.find("en"))
+}
+
+func TestValidateNameTemplate(t *testing.T) {
+	assert.Nil(t, validateNameTemplate(&models.NameTemplate{
+		Locale: "pt-BR", Pattern: "{adjective}{noun}", Adjectives: []string{"Bravo"}, Nouns: []string{"Lobo"},
+	}))
+	assert.Nil(t, validateNameTemplate(&models.NameTemplate{Locale: models.DefaultLocale, Pattern: "Hero-{number}"}))
+
+	assert.NotNil(t, validateNameTemplate(&models.NameTemplate{Locale: "english", Pattern: "Hero"}))
+	assert.NotNil(t, validateNameTemplate(&models.NameTemplate{Locale: "en", Pattern: ""}))
+	assert.NotNil(t, validateNameTemplate(&models.NameTemplate{Locale: "en", Pattern: "{noun}"}))
+	assert.NotNil(t, validateNameTemplate(&models.NameTemplate{Locale: "en", Pattern: "{noun}", Nouns: []string{""}}))
+}
+
+func TestCheckName(t *testing.T) {
+	s := &Service{Names: DefaultNamesConfig}
+
+	name, err := s.checkName("  Brave   Fox_1 ")
+	assert.Nil(t, err)
+	assert.Equal(t, "Brave Fox_1", name)
+
+	name, err = s.checkName("Лиса")
+	assert.Nil(t, err)
+	assert.Equal(t, "Лиса", name)
+
+	for _, name := range []string{"ab", "a very long name of the player", "<script>", "sh1t happens"} {
+		_, err = s.checkName(name)
+
+		var invalid *models.InvalidNameError
+		assert.True(t, errors.As(err, &invalid), name)
+	}
+}
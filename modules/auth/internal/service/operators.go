@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+)
+
+// OperatorsConfig contains settings of operators sign in.
+type OperatorsConfig struct {
+	// PasswordHash is algorithm of new password hashes: bcrypt, argon2id
+	PasswordHash string
+	// InvitationTTL is lifetime of invitation
+	InvitationTTL time.Duration
+
+	// MaxFailedSignins is number of failed attempts per email and ip
+	// allowed during FailedSigninsWindow, the same number of invalid
+	// codes of second factor is allowed per operator.
+	MaxFailedSignins    int64
+	FailedSigninsWindow time.Duration
+
+	// TOTPIssuer is shown by authenticator apps
+	TOTPIssuer string
+}
+
+// DefaultOperatorsConfig is used in case if settings are not configured.
+var DefaultOperatorsConfig = OperatorsConfig{
+	PasswordHash:        PasswordBcrypt,
+	InvitationTTL:       7 * 24 * time.Hour,
+	MaxFailedSignins:    5,
+	FailedSigninsWindow: 15 * time.Minute,
+	TOTPIssuer:          "Balcony Games",
+}
+
+// ErrUnknownRole returned on invitation with unknown role.
+var ErrUnknownRole = errors.New("unknown role")
+
+// ErrUnknownOrganisation returned on invitation into organisation
+// which isn't created in primary module.
+var ErrUnknownOrganisation = errors.New("unknown organisation")
+
+// Invite creates invitation to organisation, the token is
+// returned only once.
+func (s *Service) Invite(ctx context.Context, invitation *models.Invitation) error {
+	if invitation.OrganisationID == "" || invitation.Email == "" {
+		return errors.New("organisation and email are required")
+	}
+
+	for _, role := range invitation.Roles {
+		if !auth.ValidRole(role) {
+			return errors.Wrap(ErrUnknownRole, role)
+		}
+	}
+	if invitation.Roles == nil {
+		invitation.Roles = []string{}
+	}
+	if invitation.Games == nil {
+		invitation.Games = []string{}
+	}
+
+	if s.Organisations != nil {
+		exists, err := s.Organisations.OrganisationExists(ctx, invitation.OrganisationID)
+		if err != nil {
+			return errors.WithMessage(err, "can't check organisation")
+		}
+		if !exists {
+			return errors.Wrap(ErrUnknownOrganisation, invitation.OrganisationID)
+		}
+	}
+
+	invitation.ExpiresAt = time.Now().UTC().Add(s.Operators.InvitationTTL)
+
+	err := s.repoPG.CreateInvitation(ctx, invitation)
+	if err != nil {
+		return errors.WithMessage(err, "can't create invitation")
+	}
+
+	return nil
+}
+
+// ListMembers respond with operators of the organisation.
+func (s *Service) ListMembers(ctx context.Context, organisationID string) ([]*models.Operator, error) {
+	operators, err := s.repoPG.ListOperators(ctx, organisationID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't list operators")
+	}
+
+	return operators, nil
+}
+
+// UpdateMember replaces roles and games of operator, sessions of
+// operator are revoked to issue tokens with the new claims.
+func (s *Service) UpdateMember(ctx context.Context, organisationID, operatorID string, roles, games []string) error {
+	for _, role := range roles {
+		if !auth.ValidRole(role) {
+			return errors.Wrap(ErrUnknownRole, role)
+		}
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+	if games == nil {
+		games = []string{}
+	}
+
+	err := s.repoPG.UpdateOperatorMembership(ctx, organisationID, operatorID, roles, games)
+	if err != nil {
+		return errors.WithMessage(err, "can't update operator")
+	}
+
+	return s.revokeOperator(ctx, operatorID)
+}
+
+// RemoveMember deletes operator from organisation and
+// revokes all of its sessions.
+func (s *Service) RemoveMember(ctx context.Context, organisationID, operatorID string) error {
+	err := s.repoPG.DeleteOperator(ctx, organisationID, operatorID)
+	if err != nil {
+		return errors.WithMessage(err, "can't delete operator")
+	}
+
+	return s.revokeOperator(ctx, operatorID)
+}
+
+func (s *Service) revokeOperator(ctx context.Context, operatorID string) error {
+	err := s.repoRedis.RevokeUser(ctx, operatorID, s.RefreshTTL)
+	if err != nil {
+		return errors.WithMessage(err, "can't revoke operator sessions")
+	}
+
+	return nil
+}
+
+// Signup creates operator by invitation token, organisation,
+// email and roles are taken from the invitation.
+func (s *Service) Signup(ctx context.Context, invitationToken, name, password string) (*models.Operator, error) {
+	hash, err := hashPassword(s.Operators.PasswordHash, password)
+	if err != nil {
+		return nil, err
+	}
+
+	operator := &models.Operator{Name: name, PasswordHash: hash}
+
+	err = s.repoPG.CreateOperator(ctx, operator, invitationToken)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't create operator")
+	}
+
+	return operator, nil
+}
+
+// Signin checks password and code of second factor in case if it's enabled,
+// failed attempts are throttled per email and ip.
+func (s *Service) Signin(ctx context.Context, email, password, code, ip string) (*models.Operator, error) {
+	failed, err := s.repoRedis.FailedSignins(ctx, email, ip)
+	if err != nil {
+		return nil, err
+	}
+	if failed >= s.Operators.MaxFailedSignins {
+		return nil, models.ErrTooManySignins
+	}
+
+	operator, err := s.checkCredentials(ctx, email, password, code)
+	if err == models.ErrInvalidCredentials || err == models.ErrInvalidTOTP {
+		errFailed := s.repoRedis.AddFailedSignin(ctx, email, ip, s.Operators.FailedSigninsWindow)
+		if errFailed != nil {
+			return nil, errFailed
+		}
+
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repoRedis.ResetFailedSignins(ctx, email, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repoPG.TouchOperatorSignin(ctx, operator.ID, time.Now().UTC())
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't update operator sign in time")
+	}
+
+	return operator, nil
+}
+
+func (s *Service) checkCredentials(ctx context.Context, email, password, code string) (*models.Operator, error) {
+	operator, err := s.repoPG.FindOperatorByEmail(ctx, email)
+	if err == models.ErrOperatorNotFound {
+		return nil, models.ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't find operator")
+	}
+
+	ok, err := checkPassword(operator.PasswordHash, password)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't check password")
+	}
+	if !ok {
+		return nil, models.ErrInvalidCredentials
+	}
+
+	if !operator.TOTPEnabled {
+		return operator, nil
+	}
+
+	// password is correct, the client should ask for the code
+	if code == "" {
+		return nil, models.ErrTOTPRequired
+	}
+
+	err = s.checkTOTP(ctx, operator, code)
+	if err != nil {
+		return nil, err
+	}
+
+	return operator, nil
+}
+
+// OperatorUserInfo builds claims of server JWT for the new
+// session of operator.
+func OperatorUserInfo(operator *models.Operator) (auth.UserInfo, error) {
+	sessionID, err := uuid.GenerateUUID()
+	if err != nil {
+		return auth.UserInfo{}, errors.WithMessage(err, "can't generate session id")
+	}
+
+	return auth.UserInfo{
+		UserID:         operator.ID,
+		Type:           auth.ServerType,
+		SessionID:      sessionID,
+		Roles:          operator.Roles,
+		Games:          operator.Games,
+		OrganisationID: operator.OrganisationID,
+	}, nil
+}
+
+// TOTPSetup contains secret of second factor to add
+// into authenticator app.
+type TOTPSetup struct {
+	Secret string `json:"secret"`
+	URL    string `json:"url"`
+}
+
+// SetupTOTP generates the new secret, second factor is enabled
+// once the code is confirmed by ConfirmTOTP.
+func (s *Service) SetupTOTP(ctx context.Context, operatorID string) (*TOTPSetup, error) {
+	operator, err := s.repoPG.FindOperator(ctx, operatorID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't find operator")
+	}
+	if operator.TOTPEnabled {
+		return nil, errors.New("totp is already enabled")
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't generate totp secret")
+	}
+
+	encrypted, err := s.encryptTOTPSecret(operatorID, secret)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't encrypt totp secret")
+	}
+
+	err = s.repoPG.SetOperatorTOTP(ctx, operatorID, encrypted, false)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't store totp secret")
+	}
+
+	return &TOTPSetup{
+		Secret: secret,
+		URL:    totpURL(s.Operators.TOTPIssuer, operator.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables second factor in case if the code is valid.
+func (s *Service) ConfirmTOTP(ctx context.Context, operatorID, code string) error {
+	operator, err := s.repoPG.FindOperator(ctx, operatorID)
+	if err != nil {
+		return errors.WithMessage(err, "can't find operator")
+	}
+	if operator.TOTPSecret == "" {
+		return errors.New("totp is not set up")
+	}
+
+	err = s.checkTOTP(ctx, operator, code)
+	if err != nil {
+		return err
+	}
+
+	return s.repoPG.SetOperatorTOTP(ctx, operatorID, operator.TOTPSecret, true)
+}
+
+// DisableTOTP disables second factor, the current code is required.
+func (s *Service) DisableTOTP(ctx context.Context, operatorID, code string) error {
+	operator, err := s.repoPG.FindOperator(ctx, operatorID)
+	if err != nil {
+		return errors.WithMessage(err, "can't find operator")
+	}
+	if !operator.TOTPEnabled {
+		return nil
+	}
+
+	err = s.checkTOTP(ctx, operator, code)
+	if err != nil {
+		return err
+	}
+
+	return s.repoPG.SetOperatorTOTP(ctx, operatorID, "", false)
+}
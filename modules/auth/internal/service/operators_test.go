@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	"gitlab.com/balconygames/analytics/pkg/secrets"
+)
+
+func TestPasswordHashes(t *testing.T) {
+	for _, algorithm := range []string{PasswordBcrypt, PasswordArgon2id} {
+		hash, err := hashPassword(algorithm, "correct horse battery")
+		require.Nil(t, err, algorithm)
+
+		ok, err := checkPassword(hash, "correct horse battery")
+		require.Nil(t, err, algorithm)
+		require.True(t, ok, algorithm)
+
+		ok, err = checkPassword(hash, "wrong horse battery")
+		require.Nil(t, err, algorithm)
+		require.False(t, ok, algorithm)
+	}
+
+	_, err := hashPassword(PasswordBcrypt, "short")
+	require.Equal(t, ErrWeakPassword, err)
+}
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 test vector of SHA1 with secret "12345678901234567890"
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	code, err := totpCode(secret, uint64(59/30), 8)
+	require.Nil(t, err)
+	require.Equal(t, "94287082", code)
+
+	code, err = totpCode(secret, uint64(1111111109/30), 8)
+	require.Nil(t, err)
+	require.Equal(t, "07081804", code)
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	require.Nil(t, err)
+
+	now := time.Now()
+	code, err := totpCode(secret, uint64(now.Unix()/30), totpDigits)
+	require.Nil(t, err)
+
+	step, ok := validateTOTP(secret, code, now)
+	require.True(t, ok)
+	require.Equal(t, now.Unix()/30, step)
+
+	step, ok = validateTOTP(secret, code, now.Add(totpPeriod))
+	require.True(t, ok)
+	require.Equal(t, now.Unix()/30, step)
+
+	_, ok = validateTOTP(secret, code, now.Add(3*totpPeriod))
+	require.False(t, ok)
+	_, ok = validateTOTP(secret, "", now)
+	require.False(t, ok)
+}
+
+// fakeTOTPPG keeps the operator and the last used step in memory.
+type fakeTOTPPG struct {
+	PostgresRepository
+
+	operator *models.Operator
+	lastStep int64
+}
+
+func (f *fakeTOTPPG) FindOperator(context.Context, string) (*models.Operator, error) {
+	operator := *f.operator
+	return &operator, nil
+}
+
+func (f *fakeTOTPPG) SetOperatorTOTP(_ context.Context, _ string, secret string, enabled bool) error {
+	f.operator.TOTPSecret = secret
+	f.operator.TOTPEnabled = enabled
+	return nil
+}
+
+func (f *fakeTOTPPG) ReplaceOperatorTOTPSecret(_ context.Context, _ string, secret, previous string) (bool, error) {
+	if f.operator.TOTPSecret != previous {
+		return false, nil
+	}
+	f.operator.TOTPSecret = secret
+	return true, nil
+}
+
+func (f *fakeTOTPPG) UseOperatorTOTPStep(_ context.Context, _ string, step int64) (bool, error) {
+	if step <= f.lastStep {
+		return false, nil
+	}
+	f.lastStep = step
+	return true, nil
+}
+
+// fakeTOTPRedis counts invalid codes without window.
+type fakeTOTPRedis struct {
+	RedisRepository
+
+	failed int64
+}
+
+func (f *fakeTOTPRedis) FailedTOTPs(context.Context, string) (int64, error) {
+	return f.failed, nil
+}
+
+func (f *fakeTOTPRedis) AddFailedTOTP(context.Context, string, time.Duration) error {
+	f.failed++
+	return nil
+}
+
+func (f *fakeTOTPRedis) ResetFailedTOTPs(context.Context, string) error {
+	f.failed = 0
+	return nil
+}
+
+func newTOTPService(t *testing.T, operator *models.Operator) (*Service, *fakeTOTPPG, *fakeTOTPRedis) {
+	repoPG := &fakeTOTPPG{operator: operator}
+	repoRedis := &fakeTOTPRedis{}
+
+	svc := NewService(repoPG, repoRedis, nil, zap.NewNop().Sugar())
+	keys, err := secrets.NewKeys("0123456789abcdef0123456789abcdef")
+	require.Nil(t, err)
+	svc.Keys = keys
+
+	return svc, repoPG, repoRedis
+}
+
+func currentTOTPCode(t *testing.T, secret string) string {
+	code, err := totpCode(secret, uint64(time.Now().Unix()/30), totpDigits)
+	require.Nil(t, err)
+	return code
+}
+
+func TestTOTPSecretIsEncrypted(t *testing.T) {
+	ctx := context.Background()
+	svc, repoPG, _ := newTOTPService(t, &models.Operator{ID: "operator", Email: "admin@example.com"})
+
+	setup, err := svc.SetupTOTP(ctx, "operator")
+	require.Nil(t, err)
+	require.NotEqual(t, setup.Secret, repoPG.operator.TOTPSecret)
+	require.True(t, secrets.Encrypted(repoPG.operator.TOTPSecret))
+
+	err = svc.ConfirmTOTP(ctx, "operator", currentTOTPCode(t, setup.Secret))
+	require.Nil(t, err)
+	require.True(t, repoPG.operator.TOTPEnabled)
+}
+
+func TestTOTPCodeIsNotReplayed(t *testing.T) {
+	ctx := context.Background()
+	secret, err := generateTOTPSecret()
+	require.Nil(t, err)
+
+	// secrets stored before encryption are plain
+	svc, repoPG, _ := newTOTPService(t, &models.Operator{ID: "operator", TOTPSecret: secret, TOTPEnabled: true})
+
+	code := currentTOTPCode(t, secret)
+	require.Nil(t, svc.checkTOTP(ctx, repoPG.operator, code))
+	require.True(t, secrets.Encrypted(repoPG.operator.TOTPSecret))
+
+	require.Equal(t, models.ErrInvalidTOTP, svc.checkTOTP(ctx, repoPG.operator, code))
+}
+
+func TestTOTPCodesAreThrottled(t *testing.T) {
+	ctx := context.Background()
+	secret, err := generateTOTPSecret()
+	require.Nil(t, err)
+
+	svc, _, repoRedis := newTOTPService(t, &models.Operator{ID: "operator", TOTPSecret: secret, TOTPEnabled: true})
+
+	for i := int64(0); i < svc.Operators.MaxFailedSignins; i++ {
+		require.Equal(t, models.ErrInvalidTOTP, svc.DisableTOTP(ctx, "operator", "000000"))
+	}
+	require.Equal(t, svc.Operators.MaxFailedSignins, repoRedis.failed)
+
+	// valid code isn't checked till the window ends
+	require.Equal(t, models.ErrTooManyTOTPs, svc.DisableTOTP(ctx, "operator", currentTOTPCode(t, secret)))
+}
+
+type fakeOrganisations struct {
+	auth.OrganisationResolver
+	ids map[string]bool
+}
+
+func (f fakeOrganisations) OrganisationExists(_ context.Context, organisationID string) (bool, error) {
+	return f.ids[organisationID], nil
+}
+
+func TestInviteUnknownOrganisation(t *testing.T) {
+	svc := NewService(nil, nil, nil, zap.NewNop().Sugar())
+	svc.Organisations = fakeOrganisations{ids: map[string]bool{"studio": true}}
+
+	err := svc.Invite(context.Background(), &models.Invitation{
+		OrganisationID: "random",
+		Email:          "admin@example.com",
+		Roles:          []string{auth.RoleAdmin},
+	})
+	require.True(t, errors.Is(err, ErrUnknownOrganisation))
+}
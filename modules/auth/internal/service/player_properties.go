@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// DefaultPropertiesChangesLimit is number of audited changes
+// responded if limit is not passed.
+const DefaultPropertiesChangesLimit = 50
+
+// GetPlayerProperties respond with all sections of the player
+// including sections hidden from clients.
+func (s *Service) GetPlayerProperties(ctx context.Context, scope *sharedmodels.Scope) ([]*models.Properties, error) {
+	sections, err := s.listSections(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	properties, err := s.getProperties(ctx, sections, scope)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't get properties list")
+	}
+	if properties == nil {
+		return []*models.Properties{}, nil
+	}
+
+	return properties, nil
+}
+
+// SetPlayerProperties writes sections of the player by operator, modes
+// of sections are not applied and every change is audited.
+func (s *Service) SetPlayerProperties(ctx context.Context, collection []*models.Properties, actorID string) error {
+	if actorID == "" {
+		return errors.New("actor is required to change properties")
+	}
+
+	err := validateProperties(collection)
+	if err != nil {
+		return err
+	}
+
+	err = s.writeProperties(ctx, collection, propertiesWriter{actorID: actorID})
+	if err != nil {
+		return errors.WithMessage(err, "can't set player properties")
+	}
+
+	return nil
+}
+
+// ListPropertiesChanges respond with the latest changes of sections
+// of the player made by operators.
+func (s *Service) ListPropertiesChanges(ctx context.Context, scope *sharedmodels.Scope, section string, limit int) ([]*models.PropertiesChange, error) {
+	if limit <= 0 || limit > DefaultPropertiesChangesLimit*10 {
+		limit = DefaultPropertiesChangesLimit
+	}
+
+	return s.repoPG.ListPropertiesChanges(ctx, scope, section, limit)
+}
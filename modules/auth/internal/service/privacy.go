@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/privacy"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// DefaultDeletionGracePeriod is used in case if grace period is not configured.
+const DefaultDeletionGracePeriod = 30 * 24 * time.Hour
+
+// deletionsBatch is number of deletions processed per query.
+const deletionsBatch = 100
+
+func subjectOf(scope *sharedmodels.Scope) privacy.Subject {
+	return privacy.Subject{
+		GameID: scope.GameID,
+		AppID:  scope.AppID,
+		UserID: scope.UserID,
+	}
+}
+
+// ExportPersonalData gathers personal data of the user from all modules.
+func (s *Service) ExportPersonalData(ctx context.Context, scope *sharedmodels.Scope) (*privacy.Archive, error) {
+	archive, err := s.PersonalData.Export(ctx, subjectOf(scope))
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't export personal data")
+	}
+
+	return archive, nil
+}
+
+// RequestDeletion schedules deletion of personal data after grace period,
+// the user could cancel it during this time.
+func (s *Service) RequestDeletion(ctx context.Context, scope *sharedmodels.Scope, requestedBy string) (*models.Deletion, error) {
+	deletion := &models.Deletion{
+		Scope:        *scope,
+		RequestedBy:  requestedBy,
+		ExecuteAfter: time.Now().UTC().Add(s.DeletionGracePeriod),
+	}
+
+	err := s.repoPG.ScheduleDeletion(ctx, deletion)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't schedule deletion")
+	}
+
+	return deletion, nil
+}
+
+// CancelDeletion cancels pending deletion request.
+func (s *Service) CancelDeletion(ctx context.Context, scope *sharedmodels.Scope) error {
+	return s.repoPG.CancelDeletion(ctx, scope)
+}
+
+// RunDueDeletions deletes personal data of users with passed grace period
+// in all modules, it respond with number of deleted users. Failed deletions
+// stay pending to be retried by the next run and don't stop deletions
+// of other users.
+func (s *Service) RunDueDeletions(ctx context.Context) (int, error) {
+	deleted := 0
+	startedAt := time.Now().UTC()
+
+	// failed users are skipped by query, they are also tracked here
+	// in case if failure can't be recorded.
+	failed := map[string]bool{}
+
+	for {
+		deletions, err := s.repoPG.DueDeletions(ctx, startedAt, deletionsBatch)
+		if err != nil {
+			return deleted, errors.WithMessage(err, "can't list due deletions")
+		}
+
+		processed := 0
+		for _, deletion := range deletions {
+			key := deletion.GameID + ":" + deletion.AppID + ":" + deletion.UserID
+			if failed[key] {
+				continue
+			}
+			processed++
+
+			err = s.runDeletion(ctx, deletion)
+			if err != nil {
+				failed[key] = true
+				continue
+			}
+
+			deleted++
+		}
+
+		if processed == 0 {
+			break
+		}
+	}
+
+	if len(failed) > 0 {
+		return deleted, errors.Errorf("can't delete personal data of %d users", len(failed))
+	}
+
+	return deleted, nil
+}
+
+// runDeletion deletes personal data of the user, failure is recorded
+// on the deletion request.
+func (s *Service) runDeletion(ctx context.Context, deletion *models.Deletion) error {
+	log := s.logger.With(deletion.Scope.Fields()...)
+
+	err := s.PersonalData.Delete(ctx, subjectOf(&deletion.Scope))
+	if err != nil {
+		s.failDeletion(ctx, deletion, errors.WithMessage(err, "can't delete personal data"))
+		return err
+	}
+
+	err = s.repoPG.CompleteDeletion(ctx, &deletion.Scope)
+	if err != nil {
+		s.failDeletion(ctx, deletion, errors.WithMessage(err, "can't complete deletion"))
+		return err
+	}
+
+	log.Info("deleted personal data")
+	return nil
+}
+
+func (s *Service) failDeletion(ctx context.Context, deletion *models.Deletion, err error) {
+	log := s.logger.With(deletion.Scope.Fields()...)
+	log.Errorf("deletion failed: %s", err)
+
+	err = s.repoPG.FailDeletion(ctx, &deletion.Scope, time.Now().UTC(), err.Error())
+	if err != nil {
+		log.Errorf("can't record failed deletion: %s", err)
+	}
+}
+
+// PersonalDataProvider exports and deletes data stored by auth module.
+func (s *Service) PersonalDataProvider() privacy.Provider {
+	return authPersonalData{s}
+}
+
+type authPersonalData struct {
+	s *Service
+}
+
+// authArchive contains rows of tables including properties sections.
+type authArchive struct {
+	Tables map[string]json.RawMessage `json:"tables"`
+}
+
+func (p authPersonalData) Export(ctx context.Context, subject privacy.Subject) (interface{}, error) {
+	scope := &sharedmodels.Scope{GameID: subject.GameID, AppID: subject.AppID, UserID: subject.UserID}
+
+	tables, err := p.s.repoPG.ExportUser(ctx, scope)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't export user")
+	}
+
+	return authArchive{Tables: tables}, nil
+}
+
+func (p authPersonalData) Delete(ctx context.Context, subject privacy.Subject) error {
+	scope := &sharedmodels.Scope{GameID: subject.GameID, AppID: subject.AppID, UserID: subject.UserID}
+
+	sections, err := p.s.listSections(ctx, scope)
+	if err != nil {
+		return errors.WithMessage(err, "can't list properties sections")
+	}
+
+	err = p.s.deleteProperties(ctx, sections, scope)
+	if err != nil {
+		return errors.WithMessage(err, "can't delete properties")
+	}
+
+	refs, err := p.s.repoPG.UserSaveRefs(ctx, scope)
+	if err != nil {
+		return errors.WithMessage(err, "can't list saves")
+	}
+
+	ids, err := p.s.repoPG.DeleteUser(ctx, scope)
+	if err != nil {
+		return errors.WithMessage(err, "can't delete user")
+	}
+
+	// saves are deleted from database, blobs are not referenced anymore
+	for _, ref := range refs {
+		p.s.deleteBlob(ctx, ref)
+	}
+
+	// merged users are resolved to the deleted user
+	err = p.s.repoRedis.DeleteAliases(ctx, ids[1:])
+	if err != nil {
+		return err
+	}
+
+	return p.s.repoRedis.RevokeUser(ctx, scope.UserID, p.s.RefreshTTL)
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/privacy"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// fakeDeletionsPG keeps deletion requests in memory, failed deletions
+// aren't due until the next run like in postgres.
+type fakeDeletionsPG struct {
+	PostgresRepository
+
+	pending   []*models.Deletion
+	completed []string
+	failed    map[string]time.Time
+}
+
+func (f *fakeDeletionsPG) DueDeletions(_ context.Context, now time.Time, limit int) ([]*models.Deletion, error) {
+	due := []*models.Deletion{}
+	for _, deletion := range f.pending {
+		if failedAt, ok := f.failed[deletion.UserID]; ok && !failedAt.Before(now) {
+			continue
+		}
+		due = append(due, deletion)
+	}
+	return due, nil
+}
+
+func (f *fakeDeletionsPG) CompleteDeletion(_ context.Context, scope *sharedmodels.Scope) error {
+	for i, deletion := range f.pending {
+		if deletion.UserID == scope.UserID {
+			f.pending = append(f.pending[:i], f.pending[i+1:]...)
+			break
+		}
+	}
+	f.completed = append(f.completed, scope.UserID)
+	return nil
+}
+
+func (f *fakeDeletionsPG) FailDeletion(_ context.Context, scope *sharedmodels.Scope, at time.Time, reason string) error {
+	f.failed[scope.UserID] = at
+	return nil
+}
+
+// fakeDataProvider fails to delete data of the broken user.
+type fakeDataProvider struct {
+	broken string
+}
+
+func (p fakeDataProvider) Export(context.Context, privacy.Subject) (interface{}, error) {
+	return nil, nil
+}
+
+func (p fakeDataProvider) Delete(_ context.Context, subject privacy.Subject) error {
+	if subject.UserID == p.broken {
+		return errors.New("storage is unavailable")
+	}
+	return nil
+}
+
+func TestRunDueDeletionsContinuesAfterFailure(t *testing.T) {
+	repo := &fakeDeletionsPG{failed: map[string]time.Time{}}
+	for _, userID := range []string{"user-1", "user-2", "user-3"} {
+		repo.pending = append(repo.pending, &models.Deletion{
+			Scope: sharedmodels.Scope{GameID: "game", AppID: "app", UserID: userID},
+		})
+	}
+
+	svc := NewService(repo, nil, nil, zap.NewNop().Sugar())
+	svc.PersonalData.Register("broken", fakeDataProvider{broken: "user-1"})
+
+	deleted, err := svc.RunDueDeletions(context.Background())
+	require.NotNil(t, err)
+	require.Equal(t, 2, deleted)
+	require.Equal(t, []string{"user-2", "user-3"}, repo.completed)
+
+	// failed deletion stays pending to be retried by the next run
+	require.Len(t, repo.pending, 1)
+	require.Contains(t, repo.failed, "user-1")
+}
@@ -0,0 +1,493 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// DefaultPropertiesCacheTTL is used in case if cache ttl is not configured.
+const DefaultPropertiesCacheTTL = 24 * time.Hour
+
+// DefaultPropertiesMaxSize is limit of section data size in bytes used
+// for sections without own limit.
+const DefaultPropertiesMaxSize = 64 * 1024
+
+// storageGroups groups sections by scope used to store them.
+func (s *Service) storageGroups(ctx context.Context, sections []string, scope *sharedmodels.Scope) (map[sharedmodels.Scope][]string, error) {
+	settings, err := s.sectionSettings(ctx, scope.GameID)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[sharedmodels.Scope][]string)
+	for _, section := range sections {
+		storage := settings.storageScope(section, *scope)
+		groups[storage] = append(groups[storage], section)
+	}
+
+	return groups, nil
+}
+
+// getProperties reads sections from redis cache, missed sections are
+// read from postgres and cached.
+func (s *Service) getProperties(ctx context.Context, sections []string, scope *sharedmodels.Scope) ([]*models.Properties, error) {
+	if len(sections) == 0 {
+		return nil, nil
+	}
+
+	groups, err := s.storageGroups(ctx, sections, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	bySection := make(map[string]*models.Properties, len(sections))
+	for storage, group := range groups {
+		storage := storage
+
+		collection, err := s.getStoredProperties(ctx, group, &storage)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, properties := range collection {
+			bySection[properties.Section] = properties
+		}
+	}
+
+	var result []*models.Properties
+	for _, section := range sections {
+		if properties, ok := bySection[section]; ok {
+			// shared sections are responded in scope of the app
+			properties.Scope = *scope
+			result = append(result, properties)
+		}
+	}
+
+	return result, nil
+}
+
+// getClientProperties respond with sections readable by clients.
+func (s *Service) getClientProperties(ctx context.Context, sections []string, scope *sharedmodels.Scope) ([]*models.Properties, error) {
+	if len(sections) == 0 {
+		return nil, nil
+	}
+
+	settings, err := s.sectionSettings(ctx, scope.GameID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getProperties(ctx, settings.clientSections(sections), scope)
+}
+
+func (s *Service) getStoredProperties(ctx context.Context, sections []string, scope *sharedmodels.Scope) ([]*models.Properties, error) {
+	cached, err := s.repoRedis.GetProperties(ctx, sections, scope)
+	if err != nil {
+		// cache is not the source of truth
+		s.logger.With(scope.Fields()...).Warnf("can't get cached properties: %s", err)
+		cached = nil
+	}
+
+	found := make(map[string]bool, len(cached))
+	for _, properties := range cached {
+		found[properties.Section] = true
+	}
+
+	var missed []string
+	for _, section := range sections {
+		if !found[section] {
+			missed = append(missed, section)
+		}
+	}
+
+	if len(missed) == 0 {
+		return cached, nil
+	}
+
+	stored, err := s.repoPG.GetProperties(ctx, missed, scope)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't get stored properties")
+	}
+
+	imported, err := s.importMissedLegacy(ctx, missed, stored, scope)
+	if err != nil {
+		return nil, err
+	}
+	stored = append(stored, imported...)
+
+	if len(stored) > 0 {
+		err = s.repoRedis.CacheProperties(ctx, stored, s.PropertiesCacheTTL)
+		if err != nil {
+			s.logger.With(scope.Fields()...).Warnf("can't cache properties: %s", err)
+		}
+	}
+
+	return append(cached, stored...), nil
+}
+
+// importMissedLegacy imports sections missed in postgres from legacy
+// redis keys, so players keep progress until legacy keys are imported
+// by auth.props.backfill. Legacy keys are kept to be removed by
+// auth.props.migrate.
+func (s *Service) importMissedLegacy(ctx context.Context, sections []string, stored []*models.Properties, scope *sharedmodels.Scope) ([]*models.Properties, error) {
+	found := make(map[string]bool, len(stored))
+	for _, properties := range stored {
+		found[properties.Section] = true
+	}
+
+	var missed []string
+	for _, section := range sections {
+		if !found[section] {
+			missed = append(missed, section)
+		}
+	}
+
+	if len(missed) == 0 {
+		return nil, nil
+	}
+
+	legacy, err := s.repoRedis.GetLegacyProperties(ctx, missed, scope.UserID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't get legacy properties")
+	}
+
+	if len(legacy) == 0 {
+		return nil, nil
+	}
+
+	for _, properties := range legacy {
+		properties.Scope = *scope
+	}
+
+	// sections stored meanwhile keep their values
+	err = s.repoPG.ImportProperties(ctx, legacy)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't import legacy properties")
+	}
+
+	return legacy, nil
+}
+
+// propertiesWriter defines who writes sections, clients are limited
+// by modes of sections and changes of operators are audited.
+type propertiesWriter struct {
+	client  bool
+	actorID string
+}
+
+// writeProperties stores sections in postgres and refreshes the cache
+// by the whole stored sections. Conditional writes of changed sections
+// are rejected by models.PropertiesConflictError, sections exceeding
+// size limit or not matching schema are rejected by
+// models.PropertiesValidationError.
+func (s *Service) writeProperties(ctx context.Context, collection []*models.Properties, writer propertiesWriter) error {
+	if len(collection) == 0 {
+		return nil
+	}
+
+	stored, validate, err := s.storedProperties(ctx, collection, writer)
+	if err != nil {
+		return err
+	}
+
+	err = s.repoPG.SetProperties(ctx, stored, validate, writer.actorID)
+
+	var conflict *models.PropertiesConflictError
+	if errors.As(err, &conflict) {
+		// cached sections could be stale
+		err = s.repoRedis.CacheProperties(ctx, conflict.Sections, s.PropertiesCacheTTL)
+		if err != nil {
+			s.logger.Warnf("can't cache conflicted properties: %s", err)
+		}
+
+		// shared sections are responded in scope of the app
+		for _, current := range conflict.Sections {
+			for _, properties := range collection {
+				if properties.Section == current.Section {
+					current.Scope = properties.Scope
+				}
+			}
+		}
+
+		return conflict
+	}
+	if err != nil {
+		return errors.WithMessage(err, "can't store properties")
+	}
+
+	err = s.repoRedis.CacheProperties(ctx, stored, s.PropertiesCacheTTL)
+	if err != nil {
+		return errors.WithMessage(err, "can't cache properties")
+	}
+
+	for i, properties := range collection {
+		properties.Data = stored[i].Data
+		properties.Version = stored[i].Version
+	}
+
+	return nil
+}
+
+// storedProperties respond with sections in scopes used to store them and
+// validation of written sections, legacy sections are imported before
+// written fields are merged.
+func (s *Service) storedProperties(ctx context.Context, collection []*models.Properties,
+	writer propertiesWriter) ([]*models.Properties, func(before, after *models.Properties) error, error) {
+	settingsByGame := make(map[string]*gameSections)
+
+	stored := make([]*models.Properties, 0, len(collection))
+	for _, properties := range collection {
+		settings, err := s.sectionSettings(ctx, properties.GameID)
+		if err != nil {
+			return nil, nil, err
+		}
+		settingsByGame[properties.GameID] = settings
+
+		if writer.client {
+			err = settings.checkClientWrite(properties)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		// written fields alone could exceed the limit
+		limit := settings.maxSize(properties.Section, s.PropertiesMaxSize)
+		if limit > 0 && properties.Data.Size() > limit {
+			return nil, nil, &models.PropertiesValidationError{
+				Section: properties.Section,
+				Reason:  fmt.Sprintf("data exceeds %d bytes", limit),
+			}
+		}
+
+		storage := *properties
+		storage.Scope = settings.storageScope(properties.Section, properties.Scope)
+		stored = append(stored, &storage)
+	}
+
+	// merged sections are validated before commit
+	validate := func(before, after *models.Properties) error {
+		settings := settingsByGame[after.GameID]
+		if writer.client && settings.mode(after.Section) == models.SectionAppendOnly {
+			err := checkAppendOnly(before, after)
+			if err != nil {
+				return err
+			}
+		}
+
+		return settings.validate(after, s.PropertiesMaxSize)
+	}
+
+	// legacy sections are imported before written fields are merged
+	groups := make(map[sharedmodels.Scope][]string)
+	for _, properties := range stored {
+		groups[properties.Scope] = append(groups[properties.Scope], properties.Section)
+	}
+	for storage, group := range groups {
+		storage := storage
+
+		_, err := s.getStoredProperties(ctx, group, &storage)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return stored, validate, nil
+}
+
+// checkAppendOnly rejects changes and deletes of stored fields,
+// values are compared as normalized by postgres.
+func checkAppendOnly(before, after *models.Properties) error {
+	for field, value := range before.Data {
+		if !bytes.Equal(value, after.Data[field]) {
+			return &models.SectionAccessError{Section: after.Section, Mode: models.SectionAppendOnly}
+		}
+	}
+
+	return nil
+}
+
+// validateProperties rejects sections with reserved fields
+// or values which are not JSON.
+func validateProperties(collection []*models.Properties) error {
+	for _, properties := range collection {
+		if properties.Section == "" {
+			return errors.New("section name is required")
+		}
+
+		err := properties.Data.Validate()
+		if err != nil {
+			return &models.PropertiesValidationError{Section: properties.Section, Reason: err.Error()}
+		}
+	}
+
+	return nil
+}
+
+// listSections respond with sections of the app and shared sections
+// of the game stored for the user.
+func (s *Service) listSections(ctx context.Context, scope *sharedmodels.Scope) ([]string, error) {
+	shared := *scope
+	shared.AppID = models.SharedApp
+
+	var sections []string
+	for _, storage := range []*sharedmodels.Scope{scope, &shared} {
+		stored, err := s.repoPG.ListSections(ctx, storage)
+		if err != nil {
+			return nil, errors.WithMessage(err, "can't list stored sections")
+		}
+
+		cached, err := s.repoRedis.ListSections(ctx, storage)
+		if err != nil {
+			return nil, errors.WithMessage(err, "can't list cached sections")
+		}
+
+		sections = append(sections, stored...)
+		sections = append(sections, cached...)
+	}
+
+	return unique(sections), nil
+}
+
+// deleteProperties removes sections from postgres and cache.
+func (s *Service) deleteProperties(ctx context.Context, sections []string, scope *sharedmodels.Scope) error {
+	if len(sections) == 0 {
+		return nil
+	}
+
+	groups, err := s.storageGroups(ctx, sections, scope)
+	if err != nil {
+		return err
+	}
+
+	for storage, group := range groups {
+		storage := storage
+
+		err = s.repoPG.DeleteProperties(ctx, group, &storage)
+		if err != nil {
+			return errors.WithMessage(err, "can't delete stored properties")
+		}
+
+		err = s.repoRedis.DeleteProperties(ctx, group, &storage)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BackfillResult contains counters of properties backfill.
+type BackfillResult struct {
+	Imported int
+	Skipped  int
+	Shared   int
+}
+
+// BackfillProperties imports sections stored by legacy redis keys into
+// postgres, sections without known user are skipped. Imported sections
+// are cached by namespaced keys.
+func (s *Service) BackfillProperties(ctx context.Context) (*BackfillResult, error) {
+	return s.importLegacyProperties(ctx, false)
+}
+
+// MigratePropertyKeys moves sections stored by legacy redis keys into keys
+// namespaced by game and app, values of shared sections stored per app
+// are moved into shared values.
+func (s *Service) MigratePropertyKeys(ctx context.Context) (*BackfillResult, error) {
+	result, err := s.importLegacyProperties(ctx, true)
+	if err != nil {
+		return result, err
+	}
+
+	sections, err := s.repoPG.ListSharedSections(ctx)
+	if err != nil {
+		return result, errors.WithMessage(err, "can't list shared sections")
+	}
+
+	for _, section := range sections {
+		moved, err := s.repoPG.ShareSection(ctx, section.GameID, section.Name)
+		if err != nil {
+			return result, errors.WithMessagef(err, "can't share section %s", section.Name)
+		}
+		result.Shared += moved
+	}
+
+	return result, nil
+}
+
+func (s *Service) importLegacyProperties(ctx context.Context, deleteLegacy bool) (*BackfillResult, error) {
+	result := &BackfillResult{}
+	scopes := make(map[string]*sharedmodels.Scope)
+
+	var cursor uint64
+	for {
+		collection, next, err := s.repoRedis.ScanLegacyProperties(ctx, cursor)
+		if err != nil {
+			return result, err
+		}
+
+		var batch []*models.Properties
+		for _, properties := range collection {
+			scope, ok := scopes[properties.UserID]
+			if !ok {
+				scope = &sharedmodels.Scope{UserID: properties.UserID}
+				err = s.repoPG.FindUserScope(ctx, scope)
+				if err == models.ErrUserNotFound {
+					scope = nil
+				} else if err != nil {
+					return result, errors.WithMessage(err, "can't find user scope")
+				}
+				scopes[properties.UserID] = scope
+			}
+
+			if scope == nil {
+				s.logger.
+					With("user_id", properties.UserID, "section", properties.Section).
+					Warn("skip properties of unknown user")
+				result.Skipped++
+				continue
+			}
+
+			settings, err := s.sectionSettings(ctx, scope.GameID)
+			if err != nil {
+				return result, err
+			}
+
+			properties.Scope = settings.storageScope(properties.Section, *scope)
+			batch = append(batch, properties)
+		}
+
+		if len(batch) > 0 {
+			err = s.repoPG.ImportProperties(ctx, batch)
+			if err != nil {
+				return result, errors.WithMessage(err, "can't import properties")
+			}
+
+			err = s.repoRedis.CacheProperties(ctx, batch, s.PropertiesCacheTTL)
+			if err != nil {
+				return result, errors.WithMessage(err, "can't cache imported properties")
+			}
+
+			if deleteLegacy {
+				err = s.repoRedis.DeleteLegacyProperties(ctx, batch)
+				if err != nil {
+					return result, err
+				}
+			}
+
+			result.Imported += len(batch)
+		}
+
+		if next == 0 {
+			return result, nil
+		}
+		cursor = next
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// fakePropertiesPG keeps sections in memory by storage scope and
+// section name.
+type fakePropertiesPG struct {
+	PostgresRepository
+
+	stored   map[string]*models.Properties
+	imported int
+}
+
+func propertiesKey(scope sharedmodels.Scope, section string) string {
+	return scope.GameID + ":" + scope.AppID + ":" + scope.UserID + ":" + section
+}
+
+func (f *fakePropertiesPG) ListSectionSettings(context.Context, string) ([]*models.Section, error) {
+	return nil, nil
+}
+
+func (f *fakePropertiesPG) GetProperties(_ context.Context, sections []string, scope *sharedmodels.Scope) ([]*models.Properties, error) {
+	var collection []*models.Properties
+	for _, section := range sections {
+		if properties, ok := f.stored[propertiesKey(*scope, section)]; ok {
+			current := *properties
+			collection = append(collection, &current)
+		}
+	}
+	return collection, nil
+}
+
+func (f *fakePropertiesPG) merge(properties *models.Properties, stored bool) *models.Properties {
+	key := propertiesKey(properties.Scope, properties.Section)
+
+	current, ok := f.stored[key]
+	if !ok {
+		current = &models.Properties{Scope: properties.Scope, Section: properties.Section, Data: models.Document{}}
+		f.stored[key] = current
+	}
+
+	for field, value := range properties.Data {
+		if _, exists := current.Data[field]; exists && stored {
+			continue
+		}
+		current.Data[field] = value
+	}
+	current.Version++
+
+	return current
+}
+
+func (f *fakePropertiesPG) ImportProperties(_ context.Context, collection []*models.Properties) error {
+	for _, properties := range collection {
+		current := f.merge(properties, true)
+		properties.Data = current.Data
+		properties.Version = current.Version
+		f.imported++
+	}
+	return nil
+}
+
+func (f *fakePropertiesPG) SetProperties(_ context.Context, collection []*models.Properties, _ func(before, after *models.Properties) error, _ string) error {
+	for _, properties := range collection {
+		f.merge(properties, false)
+	}
+	return nil
+}
+
+// fakePropertiesRedis has empty cache and keeps legacy sections by
+// section name.
+type fakePropertiesRedis struct {
+	RedisRepository
+
+	legacy map[string]map[string]string
+}
+
+func (f *fakePropertiesRedis) GetProperties(context.Context, []string, *sharedmodels.Scope) ([]*models.Properties, error) {
+	return nil, nil
+}
+
+func (f *fakePropertiesRedis) CacheProperties(context.Context, []*models.Properties, time.Duration) error {
+	return nil
+}
+
+func (f *fakePropertiesRedis) GetLegacyProperties(_ context.Context, sections []string, userID string) ([]*models.Properties, error) {
+	var collection []*models.Properties
+	for _, section := range sections {
+		if attrs, ok := f.legacy[section]; ok {
+			collection = append(collection, &models.Properties{
+				Scope:   sharedmodels.Scope{UserID: userID},
+				Section: section,
+				Data:    models.StringDocument(attrs),
+			})
+		}
+	}
+	return collection, nil
+}
+
+func TestGetPropertiesImportsLegacy(t *testing.T) {
+	repoPG := &fakePropertiesPG{stored: map[string]*models.Properties{}}
+	repoRedis := &fakePropertiesRedis{legacy: map[string]map[string]string{
+		"progress": {"level": "3"},
+	}}
+	svc := NewService(repoPG, repoRedis, nil, zap.NewNop().Sugar())
+	scope := &sharedmodels.Scope{GameID: "game", AppID: "app", UserID: "user"}
+
+	collection, err := svc.GetProperties(context.Background(), []string{"progress", "settings"}, scope)
+	require.Nil(t, err)
+	require.Len(t, collection, 1)
+	assert.Equal(t, "progress", collection[0].Section)
+	assert.Equal(t, json.RawMessage(`"3"`), collection[0].Data["level"])
+	assert.Equal(t, int64(1), collection[0].Version)
+
+	// imported sections are read from postgres afterwards
+	_, err = svc.GetProperties(context.Background(), []string{"progress"}, scope)
+	require.Nil(t, err)
+	assert.Equal(t, 1, repoPG.imported)
+}
+
+func TestSetPropertiesImportsLegacyBeforeWrite(t *testing.T) {
+	repoPG := &fakePropertiesPG{stored: map[string]*models.Properties{}}
+	repoRedis := &fakePropertiesRedis{legacy: map[string]map[string]string{
+		"progress": {"level": "3", "coins": "10"},
+	}}
+	svc := NewService(repoPG, repoRedis, nil, zap.NewNop().Sugar())
+	scope := sharedmodels.Scope{GameID: "game", AppID: "app", UserID: "user"}
+
+	err := svc.SetProperties(context.Background(), []*models.Properties{{
+		Scope:   scope,
+		Section: "progress",
+		Data:    models.Document{"level": json.RawMessage(`4`)},
+	}})
+	require.Nil(t, err)
+
+	stored := repoPG.stored[propertiesKey(scope, "progress")]
+	require.NotNil(t, stored)
+	assert.Equal(t, json.RawMessage(`4`), stored.Data["level"])
+	assert.Equal(t, json.RawMessage(`"10"`), stored.Data["coins"])
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"regexp"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// SavesConfig contains limits of cloud saves.
+type SavesConfig struct {
+	// MaxSize is limit of save data size in bytes before compression.
+	MaxSize int
+	// MaxSlots is limit of slots per user.
+	MaxSlots int
+}
+
+// DefaultSavesConfig is used in case if limits are not configured.
+var DefaultSavesConfig = SavesConfig{
+	MaxSize:  1 << 20,
+	MaxSlots: 10,
+}
+
+var saveSlotPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// ErrInvalidSaveSlot returned on slot name with unsupported chars.
+var ErrInvalidSaveSlot = errors.New("slot should contain 1-64 latin letters, digits, _ or -")
+
+// ListSaves respond with saves of the user.
+func (s *Service) ListSaves(ctx context.Context, scope *sharedmodels.Scope) ([]*models.Save, error) {
+	return s.repoPG.ListSaves(ctx, scope)
+}
+
+// GetSave respond with save of the slot and its gzip compressed data.
+func (s *Service) GetSave(ctx context.Context, scope *sharedmodels.Scope, slot string) (*models.Save, []byte, error) {
+	save, err := s.repoPG.FindSave(ctx, scope, slot)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	compressed, err := s.Blobs.Get(ctx, save.BlobRef)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "can't get blob of save %s", slot)
+	}
+
+	return save, compressed, nil
+}
+
+// PutSave compresses data and stores it into the slot. Save with
+// expected version is stored only if the slot is not changed since the
+// version, otherwise models.SaveConflictError is returned and the client
+// chooses to keep the current save or to override it passing its version.
+func (s *Service) PutSave(ctx context.Context, save *models.Save, data []byte, expectedVersion *int64) error {
+	if !saveSlotPattern.MatchString(save.Slot) {
+		return ErrInvalidSaveSlot
+	}
+	if len(data) > s.Saves.MaxSize {
+		return errors.Errorf("save exceeds %d bytes", s.Saves.MaxSize)
+	}
+
+	compressed, err := compress(data)
+	if err != nil {
+		return err
+	}
+
+	checksum := sha256.Sum256(data)
+	save.Size = len(data)
+	save.CompressedSize = len(compressed)
+	save.Checksum = hex.EncodeToString(checksum[:])
+
+	save.BlobRef, err = s.Blobs.Put(ctx, compressed)
+	if err != nil {
+		return errors.WithMessage(err, "can't store save blob")
+	}
+
+	replaced, err := s.repoPG.PutSave(ctx, save, expectedVersion, s.Saves.MaxSlots)
+	if err != nil {
+		s.deleteBlob(ctx, save.BlobRef)
+		return err
+	}
+
+	if replaced != "" {
+		s.deleteBlob(ctx, replaced)
+	}
+
+	return nil
+}
+
+// DeleteSave removes save of the slot.
+func (s *Service) DeleteSave(ctx context.Context, scope *sharedmodels.Scope, slot string) error {
+	ref, err := s.repoPG.DeleteSave(ctx, scope, slot)
+	if err != nil {
+		return err
+	}
+
+	s.deleteBlob(ctx, ref)
+	return nil
+}
+
+// deleteBlob removes blob which is not referenced anymore,
+// failures leave orphan blobs and are only logged.
+func (s *Service) deleteBlob(ctx context.Context, ref string) {
+	err := s.Blobs.Delete(ctx, ref)
+	if err != nil {
+		s.logger.With("blob_ref", ref).Errorf("can't delete blob: %s", err)
+	}
+}
+
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't compress save")
+	}
+
+	err = w.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "can't compress save")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decompress respond with data of gzip compressed save.
+func Decompress(compressed []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, errors.Wrap(err, "can't decompress save")
+	}
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't decompress save")
+	}
+
+	return data, nil
+}
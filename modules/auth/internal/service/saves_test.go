@@ -0,0 +1,125 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/blobs"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+func TestCompressSave(t *testing.T) {
+	data := bytes.Repeat([]byte("level:1;coins:100;"), 1000)
+
+	compressed, err := compress(data)
+	require.Nil(t, err)
+	require.True(t, len(compressed) < len(data))
+
+	decompressed, err := Decompress(compressed)
+	require.Nil(t, err)
+	require.Equal(t, data, decompressed)
+
+	_, err = Decompress(data)
+	require.NotNil(t, err)
+}
+
+func TestSaveSlotPattern(t *testing.T) {
+	for _, slot := range []string{"auto", "slot-1", "Manual_2"} {
+		require.True(t, saveSlotPattern.MatchString(slot), slot)
+	}
+
+	for _, slot := range []string{"", "../slot", "slot 1", string(bytes.Repeat([]byte("a"), 65))} {
+		require.False(t, saveSlotPattern.MatchString(slot), slot)
+	}
+}
+
+// fakeSavesPG keeps saves by slot and checks versions like postgres.
+type fakeSavesPG struct {
+	PostgresRepository
+
+	saves map[string]*models.Save
+}
+
+func (f *fakeSavesPG) FindSave(_ context.Context, _ *sharedmodels.Scope, slot string) (*models.Save, error) {
+	save, ok := f.saves[slot]
+	if !ok {
+		return nil, models.ErrSaveNotFound
+	}
+	current := *save
+	return &current, nil
+}
+
+func (f *fakeSavesPG) PutSave(_ context.Context, save *models.Save, expectedVersion *int64, _ int) (string, error) {
+	current, ok := f.saves[save.Slot]
+
+	var version int64
+	if ok {
+		version = current.Version
+	}
+	if expectedVersion != nil && *expectedVersion != version {
+		stored := *current
+		return "", &models.SaveConflictError{Current: &stored}
+	}
+
+	var replaced string
+	if ok {
+		replaced = current.BlobRef
+	}
+
+	save.Version = version + 1
+	stored := *save
+	f.saves[save.Slot] = &stored
+
+	return replaced, nil
+}
+
+func TestPutSaveConflictResolvedByClient(t *testing.T) {
+	ctx := context.Background()
+	store := blobs.NewMemoryStore()
+	svc := NewService(&fakeSavesPG{saves: map[string]*models.Save{}}, nil, nil, zap.NewNop().Sugar())
+	svc.Blobs = store
+
+	scope := sharedmodels.Scope{GameID: "game", AppID: "app", UserID: "user"}
+	created := int64(0)
+
+	phone := &models.Save{Scope: scope, Slot: "auto", Device: "phone"}
+	require.Nil(t, svc.PutSave(ctx, phone, []byte("level:5"), &created))
+	require.Equal(t, int64(1), phone.Version)
+
+	// tablet still expects the empty slot
+	tablet := &models.Save{Scope: scope, Slot: "auto", Device: "tablet"}
+	err := svc.PutSave(ctx, tablet, []byte("level:3"), &created)
+
+	var conflict *models.SaveConflictError
+	require.True(t, errors.As(err, &conflict))
+	require.Equal(t, "phone", conflict.Current.Device)
+	require.Equal(t, int64(1), conflict.Current.Version)
+	// blob of rejected save isn't kept
+	require.Equal(t, 1, store.Len())
+
+	// the player keeps the save of the tablet overriding the current version
+	tablet = &models.Save{Scope: scope, Slot: "auto", Device: "tablet"}
+	require.Nil(t, svc.PutSave(ctx, tablet, []byte("level:3"), &conflict.Current.Version))
+	require.Equal(t, int64(2), tablet.Version)
+	// blob of replaced save is deleted
+	require.Equal(t, 1, store.Len())
+
+	save, compressed, err := svc.GetSave(ctx, &scope, "auto")
+	require.Nil(t, err)
+	require.Equal(t, "tablet", save.Device)
+
+	data, err := Decompress(compressed)
+	require.Nil(t, err)
+	require.Equal(t, []byte("level:3"), data)
+
+	// the phone is behind and gets conflict on the next save
+	err = svc.PutSave(ctx, &models.Save{Scope: scope, Slot: "auto", Device: "phone"}, []byte("level:6"), &phone.Version)
+	require.True(t, errors.As(err, &conflict))
+	require.Equal(t, "tablet", conflict.Current.Device)
+}
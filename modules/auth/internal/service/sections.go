@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/jsonschema"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// sectionsCacheTTL is time to reload settings of sections,
+// settings are read on every properties request.
+const sectionsCacheTTL = 30 * time.Second
+
+// gameSections contains settings of sections of the game by name,
+// schemas are compiled once settings are loaded.
+type gameSections struct {
+	sections map[string]*models.Section
+	schemas  map[string]*jsonschema.Schema
+}
+
+// sectionSettings respond with settings of sections of the game.
+func (s *Service) sectionSettings(ctx context.Context, gameID string) (*gameSections, error) {
+	if cached, ok := s.sections.Get(gameID); ok {
+		return cached.(*gameSections), nil
+	}
+
+	list, err := s.repoPG.ListSectionSettings(ctx, gameID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't list sections settings")
+	}
+
+	settings := &gameSections{
+		sections: make(map[string]*models.Section, len(list)),
+		schemas:  make(map[string]*jsonschema.Schema),
+	}
+	for _, section := range list {
+		settings.sections[section.Name] = section
+
+		if len(section.Schema) == 0 {
+			continue
+		}
+
+		// schemas are validated on update
+		schema, err := jsonschema.Compile(section.Schema)
+		if err != nil {
+			s.logger.
+				With("game_id", gameID, "section", section.Name).
+				Errorf("can't compile section schema: %s", err)
+			continue
+		}
+		settings.schemas[section.Name] = schema
+	}
+
+	s.sections.Set(gameID, settings)
+
+	return settings, nil
+}
+
+func (s *Service) resetSectionSettings(gameID string) {
+	s.sections.Delete(gameID)
+}
+
+// storageScope respond with scope used to store the section, shared
+// sections are stored with models.SharedApp instead of app id.
+func (g *gameSections) storageScope(section string, scope sharedmodels.Scope) sharedmodels.Scope {
+	if g.sections[section] != nil && g.sections[section].Shared {
+		scope.AppID = models.SharedApp
+	}
+
+	return scope
+}
+
+// mode respond with access of clients to the section.
+func (g *gameSections) mode(section string) string {
+	if g.sections[section] != nil && g.sections[section].Mode != "" {
+		return g.sections[section].Mode
+	}
+
+	return models.SectionReadWrite
+}
+
+// clientSections filters out sections hidden from clients.
+func (g *gameSections) clientSections(sections []string) []string {
+	var result []string
+	for _, section := range sections {
+		if g.mode(section) != models.SectionServerOnly {
+			result = append(result, section)
+		}
+	}
+
+	return result
+}
+
+// checkClientWrite rejects client writes of sections not writable by
+// clients, changes of append-only sections are checked on write.
+func (g *gameSections) checkClientWrite(properties *models.Properties) error {
+	mode := g.mode(properties.Section)
+	switch {
+	case mode == models.SectionReadOnly, mode == models.SectionServerOnly:
+		return &models.SectionAccessError{Section: properties.Section, Mode: mode}
+	case mode == models.SectionAppendOnly && len(properties.Delete) > 0:
+		return &models.SectionAccessError{Section: properties.Section, Mode: mode}
+	}
+
+	return nil
+}
+
+// maxSize respond with size limit of section data.
+func (g *gameSections) maxSize(section string, defaultSize int) int {
+	if g.sections[section] != nil && g.sections[section].MaxSize > 0 {
+		return g.sections[section].MaxSize
+	}
+
+	return defaultSize
+}
+
+// validate checks the whole section data by size limit and schema.
+func (g *gameSections) validate(properties *models.Properties, defaultSize int) error {
+	invalid := func(reason string) error {
+		return &models.PropertiesValidationError{Section: properties.Section, Reason: reason}
+	}
+
+	limit := g.maxSize(properties.Section, defaultSize)
+	if limit > 0 && properties.Data.Size() > limit {
+		return invalid(fmt.Sprintf("data exceeds %d bytes", limit))
+	}
+
+	schema := g.schemas[properties.Section]
+	if schema == nil {
+		return nil
+	}
+
+	data, err := properties.Data.Decode()
+	if err != nil {
+		return invalid(err.Error())
+	}
+
+	err = schema.ValidateValue(data)
+	if err != nil {
+		return invalid(err.Error())
+	}
+
+	return nil
+}
+
+// ListSectionSettings respond with settings of sections of the game.
+func (s *Service) ListSectionSettings(ctx context.Context, gameID string) ([]*models.Section, error) {
+	return s.repoPG.ListSectionSettings(ctx, gameID)
+}
+
+// UpdateSectionSettings updates settings of section, stored values are
+// moved into shared values on enabling sharing. Schema is applied to
+// the next writes, stored values are not validated. Sharing can't be disabled
+// once the section has shared values.
+func (s *Service) UpdateSectionSettings(ctx context.Context, section *models.Section) error {
+	if len(section.Schema) > 0 {
+		_, err := jsonschema.Compile(section.Schema)
+		if err != nil {
+			return &models.PropertiesValidationError{Section: section.Name, Reason: err.Error()}
+		}
+	}
+	if section.MaxSize < 0 {
+		return &models.PropertiesValidationError{Section: section.Name, Reason: "max size can't be negative"}
+	}
+	if section.Mode == "" {
+		section.Mode = models.SectionReadWrite
+	}
+	if !models.ValidSectionMode(section.Mode) {
+		return &models.PropertiesValidationError{Section: section.Name, Reason: "unknown mode " + section.Mode}
+	}
+
+	defer s.resetSectionSettings(section.GameID)
+
+	if !section.Shared {
+		exists, err := s.repoPG.HasSharedProperties(ctx, section.GameID, section.Name)
+		if err != nil {
+			return errors.WithMessage(err, "can't check shared properties")
+		}
+		if exists {
+			return models.ErrSectionHasSharedData
+		}
+	}
+
+	err := s.repoPG.UpsertSectionSettings(ctx, section)
+	if err != nil {
+		return errors.WithMessage(err, "can't update section settings")
+	}
+
+	if section.Shared {
+		moved, err := s.repoPG.ShareSection(ctx, section.GameID, section.Name)
+		if err != nil {
+			return errors.WithMessage(err, "can't share section values")
+		}
+
+		s.logger.
+			With("game_id", section.GameID, "section", section.Name, "moved", moved).
+			Info("shared section values")
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/jsonschema"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+func TestStorageScope(t *testing.T) {
+	settings := &gameSections{sections: map[string]*models.Section{
+		"progress": {GameID: "game", Name: "progress", Shared: true},
+		"settings": {GameID: "game", Name: "settings"},
+	}}
+	scope := sharedmodels.Scope{GameID: "game", AppID: "ios", UserID: "user"}
+
+	assert.Equal(t, models.SharedApp, settings.storageScope("progress", scope).AppID)
+	assert.Equal(t, "ios", settings.storageScope("settings", scope).AppID)
+	assert.Equal(t, "ios", settings.storageScope("unknown", scope).AppID)
+	assert.Equal(t, "user", settings.storageScope("progress", scope).UserID)
+}
+
+func TestValidateSection(t *testing.T) {
+	schema, err := jsonschema.Compile([]byte(`{
+		"type": "object",
+		"properties": {"level": {"type": "integer", "minimum": 1}}
+	}`))
+	assert.Nil(t, err)
+
+	settings := &gameSections{
+		sections: map[string]*models.Section{
+			"progress": {GameID: "game", Name: "progress", MaxSize: 32},
+		},
+		schemas: map[string]*jsonschema.Schema{"progress": schema},
+	}
+
+	valid := &models.Properties{Section: "progress", Data: models.Document{"level": json.RawMessage(`2`)}}
+	assert.Nil(t, settings.validate(valid, 1024))
+
+	invalid := &models.Properties{Section: "progress", Data: models.Document{"level": json.RawMessage(`0`)}}
+	assert.EqualError(t, settings.validate(invalid, 1024), "section progress is invalid: /level: number is less than 1")
+
+	large := &models.Properties{Section: "progress", Data: models.Document{"name": json.RawMessage(`"` + strings.Repeat("a", 32) + `"`)}}
+	assert.EqualError(t, settings.validate(large, 1024), "section progress is invalid: data exceeds 32 bytes")
+
+	// sections without own limit use the default one
+	other := &models.Properties{Section: "other", Data: models.Document{"name": json.RawMessage(`"` + strings.Repeat("a", 32) + `"`)}}
+	assert.NotNil(t, settings.validate(other, 16))
+	assert.Nil(t, settings.validate(other, 0))
+}
+
+func TestSectionModes(t *testing.T) {
+	settings := &gameSections{sections: map[string]*models.Section{
+		"wallet":       {Name: "wallet", Mode: models.SectionReadOnly},
+		"achievements": {Name: "achievements", Mode: models.SectionAppendOnly},
+		"fraud":        {Name: "fraud", Mode: models.SectionServerOnly},
+	}}
+
+	assert.Equal(t, []string{"settings", "wallet", "achievements"},
+		settings.clientSections([]string{"settings", "wallet", "achievements", "fraud"}))
+
+	assert.Nil(t, settings.checkClientWrite(&models.Properties{Section: "settings"}))
+	assert.Nil(t, settings.checkClientWrite(&models.Properties{Section: "achievements"}))
+	assert.NotNil(t, settings.checkClientWrite(&models.Properties{Section: "wallet"}))
+	assert.NotNil(t, settings.checkClientWrite(&models.Properties{Section: "fraud"}))
+	assert.NotNil(t, settings.checkClientWrite(&models.Properties{Section: "achievements", Delete: []string{"first_win"}}))
+
+	before := &models.Properties{Section: "achievements", Data: models.Document{"first_win": json.RawMessage(`true`)}}
+	appended := &models.Properties{Section: "achievements", Data: models.Document{
+		"first_win": json.RawMessage(`true`),
+		"level_10":  json.RawMessage(`true`),
+	}}
+	changed := &models.Properties{Section: "achievements", Data: models.Document{"first_win": json.RawMessage(`false`)}}
+
+	assert.Nil(t, checkAppendOnly(before, appended))
+	assert.EqualError(t, checkAppendOnly(before, changed), "section achievements is append-only for clients")
+}
@@ -2,11 +2,19 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
 	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	"gitlab.com/balconygames/analytics/pkg/blobs"
+	"gitlab.com/balconygames/analytics/pkg/cache"
+	"gitlab.com/balconygames/analytics/pkg/mailer"
+	"gitlab.com/balconygames/analytics/pkg/privacy"
+	"gitlab.com/balconygames/analytics/pkg/secrets"
 	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
 )
 
@@ -15,45 +23,224 @@ import (
 type PostgresRepository interface {
 	AnomSync(context.Context, *models.User) error
 	UsersSync(context.Context, *models.User) error
+
+	LinkIdentity(context.Context, *models.User) error
+	UnlinkIdentity(context.Context, *models.Identity) error
+	ListIdentities(context.Context, *sharedmodels.Scope) ([]*models.Identity, error)
+
+	ListDevices(context.Context, *sharedmodels.Scope) ([]*models.Device, error)
+	UpdateDevice(context.Context, *models.Device) error
+	RevokeDevice(ctx context.Context, scope *sharedmodels.Scope, deviceID string) error
+	IsDeviceRevoked(ctx context.Context, scope *sharedmodels.Scope, deviceID string) (bool, error)
+
+	GetGuestPolicy(ctx context.Context, gameID string) (*models.GuestPolicy, error)
+	UpsertGuestPolicy(context.Context, *models.GuestPolicy) error
+	SetRecoveryCode(ctx context.Context, scope *sharedmodels.Scope, code string) error
+	RecoverGuest(ctx context.Context, user *models.User, code string) error
+
+	FindIdentityOwner(context.Context, *models.Identity) error
+	LastSeen(context.Context, *sharedmodels.Scope) (time.Time, error)
+	PlayerExists(ctx context.Context, gameID, userID string) (bool, error)
+	MergeUsers(ctx context.Context, merge *models.Merge, collection []*models.Properties,
+		deleted map[sharedmodels.Scope][]string, validate func(before, after *models.Properties) error) ([]string, error)
+	ListAliases(ctx context.Context, after string, limit int) ([]*models.Alias, error)
+
+	GetProperties(ctx context.Context, sections []string, scope *sharedmodels.Scope) ([]*models.Properties, error)
+	SetProperties(ctx context.Context, collection []*models.Properties, validate func(before, after *models.Properties) error, actorID string) error
+	ListPropertiesChanges(ctx context.Context, scope *sharedmodels.Scope, section string, limit int) ([]*models.PropertiesChange, error)
+
+	ListSaves(ctx context.Context, scope *sharedmodels.Scope) ([]*models.Save, error)
+	FindSave(ctx context.Context, scope *sharedmodels.Scope, slot string) (*models.Save, error)
+	PutSave(ctx context.Context, save *models.Save, expectedVersion *int64, maxSlots int) (string, error)
+	DeleteSave(ctx context.Context, scope *sharedmodels.Scope, slot string) (string, error)
+	UserSaveRefs(ctx context.Context, scope *sharedmodels.Scope) ([]string, error)
+
+	ListNameTemplates(ctx context.Context, gameID string) ([]*models.NameTemplate, error)
+	UpsertNameTemplate(ctx context.Context, template *models.NameTemplate) error
+	DeleteNameTemplate(ctx context.Context, gameID, locale string) error
+	RenameUser(ctx context.Context, scope *sharedmodels.Scope, name string, cooldown time.Duration) error
+
+	ListExperiments(ctx context.Context, gameID string) ([]*models.Experiment, error)
+	UpsertExperiment(ctx context.Context, experiment *models.Experiment) error
+	DeleteExperiment(ctx context.Context, gameID, key string) error
+
+	ImportProperties(ctx context.Context, collection []*models.Properties) error
+	ListSections(ctx context.Context, scope *sharedmodels.Scope) ([]string, error)
+	DeleteProperties(ctx context.Context, sections []string, scope *sharedmodels.Scope) error
+	FindUserScope(ctx context.Context, scope *sharedmodels.Scope) error
+
+	ListSectionSettings(ctx context.Context, gameID string) ([]*models.Section, error)
+	ListSharedSections(ctx context.Context) ([]*models.Section, error)
+	UpsertSectionSettings(ctx context.Context, section *models.Section) error
+	HasSharedProperties(ctx context.Context, gameID, section string) (bool, error)
+	ShareSection(ctx context.Context, gameID, section string) (int, error)
+
+	CreateInvitation(context.Context, *models.Invitation) error
+	CreateOperator(ctx context.Context, operator *models.Operator, invitationToken string) error
+	FindOperatorByEmail(ctx context.Context, email string) (*models.Operator, error)
+	FindOperator(ctx context.Context, operatorID string) (*models.Operator, error)
+	SetOperatorTOTP(ctx context.Context, operatorID, secret string, enabled bool) error
+	UseOperatorTOTPStep(ctx context.Context, operatorID string, step int64) (bool, error)
+	ListOperatorsTOTP(ctx context.Context) ([]*models.Operator, error)
+	ReplaceOperatorTOTPSecret(ctx context.Context, operatorID, secret, previous string) (bool, error)
+	TouchOperatorSignin(ctx context.Context, operatorID string, at time.Time) error
+	ListOperators(ctx context.Context, organisationID string) ([]*models.Operator, error)
+	UpdateOperatorMembership(ctx context.Context, organisationID, operatorID string, roles, games []string) error
+	DeleteOperator(ctx context.Context, organisationID, operatorID string) error
+
+	ExportUser(context.Context, *sharedmodels.Scope) (map[string]json.RawMessage, error)
+	DeleteUser(context.Context, *sharedmodels.Scope) ([]string, error)
+	ScheduleDeletion(context.Context, *models.Deletion) error
+	FindDeletion(context.Context, *sharedmodels.Scope) (*models.Deletion, error)
+	CancelDeletion(context.Context, *sharedmodels.Scope) error
+	DueDeletions(ctx context.Context, now time.Time, limit int) ([]*models.Deletion, error)
+	CompleteDeletion(context.Context, *sharedmodels.Scope) error
+	FailDeletion(ctx context.Context, scope *sharedmodels.Scope, at time.Time, reason string) error
 }
 
-// RedisRepository should provide access to user properties
+// RedisRepository should provide access to cached user properties
 type RedisRepository interface {
 	GetProperties(ctx context.Context, sections []string, scope *sharedmodels.Scope) ([]*models.Properties, error)
-	SetProperties(ctx context.Context, collection []*models.Properties) error
+	CacheProperties(ctx context.Context, collection []*models.Properties, ttl time.Duration) error
+	ScanLegacyProperties(ctx context.Context, cursor uint64) ([]*models.Properties, uint64, error)
+	GetLegacyProperties(ctx context.Context, sections []string, userID string) ([]*models.Properties, error)
+	DeleteLegacyProperties(ctx context.Context, collection []*models.Properties) error
+
+	ListSections(ctx context.Context, scope *sharedmodels.Scope) ([]string, error)
+	DeleteProperties(ctx context.Context, sections []string, scope *sharedmodels.Scope) error
+	SetAliases(ctx context.Context, aliases []string, userID string) error
+	DeleteAliases(ctx context.Context, aliases []string) error
+
+	CreateRefreshToken(ctx context.Context, session *models.Session, ttl time.Duration) error
+	UseRefreshToken(ctx context.Context, token string) (*models.Session, error)
+	RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error
+	RevokeUser(ctx context.Context, userID string, ttl time.Duration) error
+	IsRevoked(ctx context.Context, claims *auth.Claims) (bool, error)
+
+	FailedSignins(ctx context.Context, email, ip string) (int64, error)
+	AddFailedSignin(ctx context.Context, email, ip string, window time.Duration) error
+	ResetFailedSignins(ctx context.Context, email, ip string) error
+	FailedTOTPs(ctx context.Context, operatorID string) (int64, error)
+	AddFailedTOTP(ctx context.Context, operatorID string, window time.Duration) error
+	ResetFailedTOTPs(ctx context.Context, operatorID string) error
+
+	CreateMagicLink(ctx context.Context, link *models.MagicLink, ttl time.Duration) error
+	UseMagicLink(ctx context.Context, token string) (*models.MagicLink, error)
+	AddMagicLinkRequest(ctx context.Context, email, ip string, window time.Duration) (int64, int64, error)
 }
 
+// LeaderboardRepository should provide access to scores stored by
+// leaderboard module.
+type LeaderboardRepository interface {
+	MoveScores(ctx context.Context, fromUserID, toUserID string) error
+	RenameUser(ctx context.Context, userID, name string) error
+}
+
+// DefaultRefreshTTL is used in case if refresh ttl is not configured.
+const DefaultRefreshTTL = 30 * 24 * time.Hour
+
 // Service contains all dependencies to perform common service tasks.
 type Service struct {
-	repoPG    PostgresRepository
-	repoRedis RedisRepository
+	repoPG          PostgresRepository
+	repoRedis       RedisRepository
+	repoLeaderboard LeaderboardRepository
+
+	// MergeStrategy used by default to merge properties
+	// of merged users.
+	MergeStrategy models.MergeStrategy
+	// RefreshTTL is lifetime of refresh token, session
+	// is expired without refresh during this time.
+	RefreshTTL time.Duration
+
+	// PropertiesCacheTTL is lifetime of properties cached in redis.
+	PropertiesCacheTTL time.Duration
+	// PropertiesMaxSize is default limit of section data size in bytes.
+	PropertiesMaxSize int
+	// sections caches settings of sections per game.
+	sections *cache.TTL
+
+	// Names contains rules of display names chosen by players.
+	Names NamesConfig
+	// templates caches guest name templates per game.
+	templates *cache.TTL
+	// experiments caches experiments per game.
+	experiments *cache.TTL
+
+	// Saves contains limits of cloud saves.
+	Saves SavesConfig
+	// Blobs stores compressed data of cloud saves.
+	Blobs blobs.Store
+
+	// Operators contains settings of dashboard operators.
+	Operators OperatorsConfig
+	// Keys encrypt secrets of second factor of operators.
+	Keys *secrets.Keys
+	// MagicLinks contains settings of email sign in.
+	MagicLinks MagicLinksConfig
+	// Mailer sends magic links.
+	Mailer mailer.Mailer
+
+	// PersonalData contains providers of all modules to export
+	// and delete personal data.
+	PersonalData *privacy.Registry
+	// DeletionGracePeriod is time to cancel deletion request.
+	DeletionGracePeriod time.Duration
+
+	// Credentials respond with network credentials of the game
+	// configured in primary module.
+	Credentials secrets.CredentialsProvider
+	// Organisations checks organisations of invitations
+	// stored in primary module.
+	Organisations auth.OrganisationResolver
 
 	logger *zap.SugaredLogger
 }
 
 // NewService should build the service to having the layer between handlers
 // and repositories.
-func NewService(r PostgresRepository, rp RedisRepository, l *zap.SugaredLogger) *Service {
+func NewService(r PostgresRepository, rp RedisRepository, lb LeaderboardRepository, l *zap.SugaredLogger) *Service {
 	return &Service{
-		repoPG:    r,
-		repoRedis: rp,
-		logger:    l,
+		repoPG:          r,
+		repoRedis:       rp,
+		repoLeaderboard: lb,
+		MergeStrategy:   models.MergePreferNewest,
+		RefreshTTL:      DefaultRefreshTTL,
+		Operators:       DefaultOperatorsConfig,
+		Names:           DefaultNamesConfig,
+		Saves:           DefaultSavesConfig,
+		Blobs:           blobs.NewMemoryStore(),
+		MagicLinks:      DefaultMagicLinksConfig,
+		Mailer:          mailer.NewMemoryMailer(),
+		PersonalData:    privacy.NewRegistry(),
+		logger:          l,
+
+		DeletionGracePeriod: DefaultDeletionGracePeriod,
+		PropertiesCacheTTL:  DefaultPropertiesCacheTTL,
+		PropertiesMaxSize:   DefaultPropertiesMaxSize,
+		sections:            cache.NewTTL(sectionsCacheTTL),
+		templates:           cache.NewTTL(nameTemplatesCacheTTL),
+		experiments:         cache.NewTTL(experimentsCacheTTL),
 	}
 }
 
 // Sync the user with the database, in case if we have the record
 // we should respond with properties for the user to boot in the app.
 func (s *Service) Sync(ctx context.Context, propertiesSections []string, user *models.User) ([]*models.Properties, error) {
-	err := s.repoPG.AnomSync(ctx, user)
+	err := validateDevice(user.Device())
 	if err != nil {
-		return nil, errors.WithMessage(err, "can't sync anonymous user with device id")
+		return nil, err
+	}
+
+	if user.Name == "" {
+		user.Name = s.guestName(ctx, user)
 	}
 
-	if len(propertiesSections) == 0 {
-		return nil, nil
+	err = s.repoPG.AnomSync(ctx, user)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't sync anonymous user with device id")
 	}
 
-	properties, err := s.repoRedis.GetProperties(ctx, propertiesSections, &user.Scope)
+	properties, err := s.getClientProperties(ctx, propertiesSections, &user.Scope)
 	if err != nil {
 		return nil, errors.WithMessage(err, "can't get properties list")
 	}
@@ -64,16 +251,35 @@ func (s *Service) Sync(ctx context.Context, propertiesSections []string, user *m
 // UsersSync should create or update the record in database for users signed in
 // via network FACEBOOK, GOOGLE, APPLE.
 func (s *Service) UsersSync(ctx context.Context, propertiesSections []string, user *models.User) ([]*models.Properties, error) {
-	err := s.repoPG.UsersSync(ctx, user)
+	if models.ReservedNetwork(user.Network) {
+		return nil, models.ErrReservedNetwork
+	}
+
+	return s.syncNetworkUser(ctx, propertiesSections, user)
+}
+
+// syncNetworkUser syncs user of the verified network identity.
+func (s *Service) syncNetworkUser(ctx context.Context, propertiesSections []string, user *models.User) ([]*models.Properties, error) {
+	err := validateDevice(user.Device())
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Name == "" {
+		user.Name = s.guestName(ctx, user)
+	}
+
+	err = s.repoPG.UsersSync(ctx, user)
 	if err != nil {
 		return nil, errors.WithMessage(err, "can't sync network user with device id, network and network id")
 	}
 
-	if len(propertiesSections) == 0 {
-		return nil, nil
+	err = s.fillNetworks(ctx, user)
+	if err != nil {
+		return nil, err
 	}
 
-	properties, err := s.repoRedis.GetProperties(ctx, propertiesSections, &user.Scope)
+	properties, err := s.getClientProperties(ctx, propertiesSections, &user.Scope)
 	if err != nil {
 		return nil, errors.WithMessage(err, "can't get properties list")
 	}
@@ -81,8 +287,77 @@ func (s *Service) UsersSync(ctx context.Context, propertiesSections []string, us
 	return properties, nil
 }
 
+// LinkIdentity links network identity to the signed in user, in case
+// if identity is owned by another user models.IdentityConflictError is returned.
+func (s *Service) LinkIdentity(ctx context.Context, user *models.User) error {
+	if models.ReservedNetwork(user.Network) {
+		return models.ErrReservedNetwork
+	}
+
+	if user.Name == "" {
+		user.Name = s.guestName(ctx, user)
+	}
+
+	err := s.repoPG.LinkIdentity(ctx, user)
+	if err != nil {
+		return errors.WithMessage(err, "can't link identity")
+	}
+
+	return s.fillNetworks(ctx, user)
+}
+
+// UnlinkIdentity removes network identity from the user.
+func (s *Service) UnlinkIdentity(ctx context.Context, identity *models.Identity) error {
+	err := s.repoPG.UnlinkIdentity(ctx, identity)
+	if err != nil {
+		return errors.WithMessage(err, "can't unlink identity")
+	}
+
+	return nil
+}
+
+// ListIdentities respond with network identities linked to the user.
+func (s *Service) ListIdentities(ctx context.Context, scope *sharedmodels.Scope) ([]*models.Identity, error) {
+	identities, err := s.repoPG.ListIdentities(ctx, scope)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't list identities")
+	}
+
+	return identities, nil
+}
+
+// PlayerExists checks if the player is registered in the game,
+// it's registered in runtime for server routes of other modules.
+func (s *Service) PlayerExists(ctx context.Context, gameID, userID string) (bool, error) {
+	exists, err := s.repoPG.PlayerExists(ctx, gameID, userID)
+	if err != nil {
+		return false, errors.WithMessage(err, "can't check player")
+	}
+
+	return exists, nil
+}
+
+func (s *Service) fillNetworks(ctx context.Context, user *models.User) error {
+	identities, err := s.ListIdentities(ctx, &user.Scope)
+	if err != nil {
+		return err
+	}
+
+	user.Networks = make([]models.Network, 0, len(identities))
+	for _, identity := range identities {
+		user.Networks = append(user.Networks, models.Network{
+			Name: identity.Network,
+			ID:   identity.NetworkID,
+		})
+	}
+
+	return nil
+}
+
+// GetProperties respond with sections of the user requested by client,
+// server-only sections are skipped.
 func (s *Service) GetProperties(ctx context.Context, propertiesSections []string, scope *sharedmodels.Scope) ([]*models.Properties, error) {
-	properties, err := s.repoRedis.GetProperties(ctx, propertiesSections, scope)
+	properties, err := s.getClientProperties(ctx, propertiesSections, scope)
 	if err != nil {
 		return nil, errors.WithMessage(err, "can't get properties list")
 	}
@@ -93,8 +368,17 @@ func (s *Service) GetProperties(ctx context.Context, propertiesSections []string
 	return properties, nil
 }
 
+// SetProperties merges passed fields into sections and removes deleted
+// fields, sections with expected version are written only if they are
+// not changed since the version. Sections are written by client and
+// limited by modes of sections.
 func (s *Service) SetProperties(ctx context.Context, collection []*models.Properties) error {
-	err := s.repoRedis.SetProperties(ctx, collection)
+	err := validateProperties(collection)
+	if err != nil {
+		return err
+	}
+
+	err = s.writeProperties(ctx, collection, propertiesWriter{client: true})
 	if err != nil {
 		return errors.WithMessage(err, "can't set properties list")
 	}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// StartSession starts the new session for the user and respond
+// with the first refresh token.
+func (s *Service) StartSession(ctx context.Context, info auth.UserInfo) (*models.Session, error) {
+	sessionID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't generate session id")
+	}
+
+	session := &models.Session{UserInfo: info}
+	session.SessionID = sessionID
+
+	err = s.repoRedis.CreateRefreshToken(ctx, session, s.RefreshTTL)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't create refresh token")
+	}
+
+	return session, nil
+}
+
+// RefreshSession rotates refresh token, the used token can't be used again.
+// In case if token is reused the whole session is revoked.
+func (s *Service) RefreshSession(ctx context.Context, token string) (*models.Session, error) {
+	session, err := s.repoRedis.UseRefreshToken(ctx, token)
+	if err == models.ErrRefreshTokenReused {
+		s.logger.
+			With("session_id", session.SessionID, "user_id", session.UserID).
+			Warn("refresh token reused, revoke the session")
+
+		errRevoke := s.repoRedis.RevokeSession(ctx, session.SessionID, s.RefreshTTL)
+		if errRevoke != nil {
+			return nil, errors.WithMessage(errRevoke, "can't revoke session")
+		}
+
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkSessionDevice(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repoRedis.CreateRefreshToken(ctx, session, s.RefreshTTL)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't create refresh token")
+	}
+
+	return session, nil
+}
+
+// checkSessionDevice revokes the session of revoked device.
+func (s *Service) checkSessionDevice(ctx context.Context, session *models.Session) error {
+	// server users and tokens without device are not bound to devices
+	if session.DeviceID == "" || session.Type == auth.ServerType {
+		return nil
+	}
+
+	scope := &sharedmodels.Scope{GameID: session.GameID, AppID: session.AppID, UserID: session.UserID}
+	revoked, err := s.repoPG.IsDeviceRevoked(ctx, scope, session.DeviceID)
+	if err != nil {
+		return errors.WithMessage(err, "can't check device")
+	}
+	if !revoked {
+		return nil
+	}
+
+	err = s.repoRedis.RevokeSession(ctx, session.SessionID, s.RefreshTTL)
+	if err != nil {
+		return errors.WithMessage(err, "can't revoke session")
+	}
+
+	return models.ErrDeviceRevoked
+}
+
+// Signout revokes the session of the token or all sessions of the user.
+func (s *Service) Signout(ctx context.Context, info *auth.UserInfo, all bool) error {
+	// tokens issued before sessions support don't have
+	// session id, the only way is to revoke all of them.
+	if all || info.SessionID == "" {
+		err := s.repoRedis.RevokeUser(ctx, info.UserID, s.RefreshTTL)
+		if err != nil {
+			return errors.WithMessage(err, "can't revoke user sessions")
+		}
+
+		return nil
+	}
+
+	err := s.repoRedis.RevokeSession(ctx, info.SessionID, s.RefreshTTL)
+	if err != nil {
+		return errors.WithMessage(err, "can't revoke session")
+	}
+
+	return nil
+}
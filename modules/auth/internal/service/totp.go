@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/secrets"
+)
+
+// ErrKeysNotConfigured returned once encryption key is not set.
+var ErrKeysNotConfigured = errors.New("encryption key is not configured")
+
+// TOTP parameters compatible with authenticator apps (RFC 6238).
+const (
+	totpPeriod    = 30 * time.Second
+	totpDigits    = 6
+	totpSkew      = 1
+	totpSecretLen = 20
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func generateTOTPSecret() (string, error) {
+	b := make([]byte, totpSecretLen)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// totpURL is provisioning uri rendered as QR code by dashboard.
+func totpURL(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("digits", fmt.Sprint(totpDigits))
+	v.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+
+	return fmt.Sprintf("otpauth://totp/%s:%s?%s",
+		url.PathEscape(issuer), url.PathEscape(account), v.Encode())
+}
+
+// totpCode is HOTP (RFC 4226) code of the time counter.
+func totpCode(secret string, counter uint64, digits int) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, value%mod), nil
+}
+
+// validateTOTP checks the code of current period, codes of adjacent
+// periods are accepted because of clock drift. It respond with time
+// step of the code to reject its replay.
+func validateTOTP(secret, code string, now time.Time) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	counter := now.Unix() / int64(totpPeriod.Seconds())
+	for i := -totpSkew; i <= totpSkew; i++ {
+		step := counter + int64(i)
+
+		expected, err := totpCode(secret, uint64(step), totpDigits)
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// totpOwner is associated data of encrypted secret, secrets can't be
+// copied between operators.
+func totpOwner(operatorID string) []byte {
+	return []byte("totp:" + operatorID)
+}
+
+func (s *Service) encryptTOTPSecret(operatorID, secret string) (string, error) {
+	if s.Keys == nil {
+		return "", ErrKeysNotConfigured
+	}
+
+	return s.Keys.Encrypt([]byte(secret), totpOwner(operatorID))
+}
+
+// decryptTOTPSecret respond with plain secret, secrets stored before
+// encryption are plain and responded as is.
+func (s *Service) decryptTOTPSecret(operator *models.Operator) (string, error) {
+	if !secrets.Encrypted(operator.TOTPSecret) {
+		return operator.TOTPSecret, nil
+	}
+	if s.Keys == nil {
+		return "", ErrKeysNotConfigured
+	}
+
+	plaintext, err := s.Keys.Decrypt(operator.TOTPSecret, totpOwner(operator.ID))
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// checkTOTP accepts the code of second factor once, invalid codes are
+// throttled per operator. Plain secrets are encrypted once the code
+// is accepted.
+func (s *Service) checkTOTP(ctx context.Context, operator *models.Operator, code string) error {
+	failed, err := s.repoRedis.FailedTOTPs(ctx, operator.ID)
+	if err != nil {
+		return err
+	}
+	if failed >= s.Operators.MaxFailedSignins {
+		return models.ErrTooManyTOTPs
+	}
+
+	secret, err := s.decryptTOTPSecret(operator)
+	if err != nil {
+		return errors.WithMessage(err, "can't decrypt totp secret")
+	}
+
+	step, ok := validateTOTP(secret, code, time.Now())
+	if ok {
+		// the code is accepted only once
+		ok, err = s.repoPG.UseOperatorTOTPStep(ctx, operator.ID, step)
+		if err != nil {
+			return errors.WithMessage(err, "can't use totp step")
+		}
+	}
+	if !ok {
+		err = s.repoRedis.AddFailedTOTP(ctx, operator.ID, s.Operators.FailedSigninsWindow)
+		if err != nil {
+			return err
+		}
+
+		return models.ErrInvalidTOTP
+	}
+
+	err = s.repoRedis.ResetFailedTOTPs(ctx, operator.ID)
+	if err != nil {
+		return err
+	}
+
+	if secret == operator.TOTPSecret {
+		s.encryptPlainTOTP(ctx, operator, secret)
+	}
+
+	return nil
+}
+
+func (s *Service) encryptPlainTOTP(ctx context.Context, operator *models.Operator, secret string) {
+	log := s.logger.With("operator_id", operator.ID)
+
+	encrypted, err := s.encryptTOTPSecret(operator.ID, secret)
+	if err != nil {
+		log.Warnf("can't encrypt totp secret: %s", err)
+		return
+	}
+
+	_, err = s.repoPG.ReplaceOperatorTOTPSecret(ctx, operator.ID, encrypted, operator.TOTPSecret)
+	if err != nil {
+		log.Warnf("can't store encrypted totp secret: %s", err)
+		return
+	}
+
+	operator.TOTPSecret = encrypted
+}
+
+// RotateTOTPSecrets re-encrypts secrets of second factor stored plain
+// or encrypted by previous keys with the current key, previous keys
+// could be removed after it.
+func (s *Service) RotateTOTPSecrets(ctx context.Context) (int, error) {
+	if s.Keys == nil {
+		return 0, ErrKeysNotConfigured
+	}
+
+	operators, err := s.repoPG.ListOperatorsTOTP(ctx)
+	if err != nil {
+		return 0, errors.WithMessage(err, "can't list operators")
+	}
+
+	rotated := 0
+	for _, operator := range operators {
+		if secrets.Encrypted(operator.TOTPSecret) && !s.Keys.NeedsRotation(operator.TOTPSecret) {
+			continue
+		}
+
+		secret, err := s.decryptTOTPSecret(operator)
+		if err != nil {
+			return rotated, errors.WithMessagef(err, "can't decrypt totp secret of operator %s", operator.ID)
+		}
+
+		encrypted, err := s.encryptTOTPSecret(operator.ID, secret)
+		if err != nil {
+			return rotated, err
+		}
+
+		// secrets changed during rotation are already encrypted by the current key
+		ok, err := s.repoPG.ReplaceOperatorTOTPSecret(ctx, operator.ID, encrypted, operator.TOTPSecret)
+		if err != nil {
+			return rotated, errors.WithMessage(err, "can't replace totp secret")
+		}
+		if ok {
+			rotated++
+		}
+	}
+
+	return rotated, nil
+}
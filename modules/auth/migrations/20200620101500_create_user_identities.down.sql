@@ -0,0 +1,44 @@
+ALTER TABLE users DROP CONSTRAINT users_pkey;
+ALTER TABLE users ADD COLUMN network_name varchar(128) not null default '';
+ALTER TABLE users ADD COLUMN network_id varchar(128) not null default '';
+
+-- restore row per network for players with identities.
+INSERT INTO users (
+    user_id
+    , guest_id
+    , device_id
+    , game_id
+    , app_id
+    , email
+    , name
+    , network_name
+    , network_id
+    , created_at
+    , updated_at
+)
+SELECT
+    u.user_id
+    , u.guest_id
+    , u.device_id
+    , u.game_id
+    , u.app_id
+    , i.email
+    , u.name
+    , i.network_name
+    , i.network_id
+    , i.created_at
+    , i.updated_at
+FROM user_identities i
+JOIN users u ON
+    u.game_id = i.game_id
+    AND u.app_id = i.app_id
+    AND u.user_id = i.user_id;
+
+DELETE FROM users WHERE network_name = '' AND network_id = '';
+
+ALTER TABLE users ALTER COLUMN network_name DROP DEFAULT;
+ALTER TABLE users ALTER COLUMN network_id DROP DEFAULT;
+ALTER TABLE users ADD PRIMARY KEY(game_id, app_id, user_id, network_id, network_name);
+CREATE INDEX idx_users_game_id_app_id_user_id ON users(game_id, app_id, user_id);
+
+DROP TABLE user_identities;
@@ -0,0 +1,66 @@
+CREATE TABLE user_identities (
+    -- GUID, player id from users table
+    user_id varchar(36) not null,
+
+    game_id varchar(36) not null,
+    app_id varchar(36) not null,
+
+    -- FACEBOOK, GOOGLE, APPLE
+    network_name varchar(128) not null,
+    network_id varchar(128) not null,
+
+    -- profile columns received from network
+    email varchar(256),
+    name varchar(256),
+
+    created_at timestamp not null default now(),
+    updated_at timestamp not null default now(),
+
+    -- identity could belong only to the single player
+    -- per game, app.
+    PRIMARY KEY(game_id, app_id, network_name, network_id)
+);
+COMMENT ON TABLE user_identities IS 'Network identities linked to users per game, app';
+
+CREATE INDEX idx_user_identities_game_id_app_id_user_id ON user_identities(game_id, app_id, user_id);
+
+-- move existing network rows to identities, the first
+-- linked player owns the identity.
+INSERT INTO user_identities (
+    user_id
+    , game_id
+    , app_id
+    , network_name
+    , network_id
+    , email
+    , name
+    , created_at
+    , updated_at
+)
+SELECT DISTINCT ON (game_id, app_id, network_name, network_id)
+    user_id
+    , game_id
+    , app_id
+    , network_name
+    , network_id
+    , email
+    , name
+    , created_at
+    , updated_at
+FROM users
+ORDER BY game_id, app_id, network_name, network_id, created_at;
+
+-- keep the single row per player, the oldest one.
+DELETE FROM users a
+USING users b
+WHERE
+    a.game_id = b.game_id
+    AND a.app_id = b.app_id
+    AND a.user_id = b.user_id
+    AND (a.created_at, a.network_name, a.network_id) > (b.created_at, b.network_name, b.network_id);
+
+DROP INDEX idx_users_game_id_app_id_user_id;
+ALTER TABLE users DROP CONSTRAINT users_pkey;
+ALTER TABLE users DROP COLUMN network_name;
+ALTER TABLE users DROP COLUMN network_id;
+ALTER TABLE users ADD PRIMARY KEY(game_id, app_id, user_id);
@@ -0,0 +1,19 @@
+CREATE TABLE user_aliases (
+    -- GUID, merged user id
+    alias_user_id varchar(36) not null,
+    -- GUID, surviving user id
+    user_id varchar(36) not null,
+
+    game_id varchar(36) not null,
+    app_id varchar(36) not null,
+
+    -- newest, max
+    strategy varchar(64) not null,
+
+    created_at timestamp not null default now(),
+
+    PRIMARY KEY(alias_user_id)
+);
+COMMENT ON TABLE user_aliases IS 'Merged users resolved to surviving users';
+
+CREATE INDEX idx_user_aliases_user_id ON user_aliases(user_id);
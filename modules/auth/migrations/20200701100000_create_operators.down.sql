@@ -0,0 +1,2 @@
+DROP TABLE operator_invitations;
+DROP TABLE operators;
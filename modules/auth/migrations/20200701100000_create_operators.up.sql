@@ -0,0 +1,57 @@
+CREATE TABLE operators (
+    -- GUID
+    operator_id varchar(36) not null,
+    -- GUID
+    organisation_id varchar(36) not null,
+
+    email varchar(256) not null,
+    name varchar(256) not null,
+
+    -- bcrypt or argon2id encoded hash
+    password_hash varchar(256) not null,
+
+    -- admin, live-ops, read-only
+    roles text[] not null default '{}',
+    -- granted game ids, * for all games
+    games text[] not null default '{}',
+
+    -- base32 secret of TOTP second factor, enabled
+    -- once the first code is confirmed.
+    totp_secret varchar(64),
+    totp_enabled boolean not null default false,
+
+    signed_in_at timestamp,
+    created_at timestamp not null default now(),
+    updated_at timestamp not null default now(),
+
+    PRIMARY KEY(operator_id)
+);
+COMMENT ON TABLE operators IS 'Dashboard operators signed in by password';
+
+CREATE UNIQUE INDEX idx_operators_email ON operators(email);
+CREATE INDEX idx_operators_organisation_id ON operators(organisation_id);
+
+
+CREATE TABLE operator_invitations (
+    -- sha256 of invitation token
+    token_hash varchar(64) not null,
+    -- GUID
+    organisation_id varchar(36) not null,
+
+    email varchar(256) not null,
+    roles text[] not null default '{}',
+    games text[] not null default '{}',
+
+    -- operator created invitation, empty for
+    -- invitations created by command.
+    invited_by varchar(36),
+
+    expires_at timestamp not null,
+    accepted_at timestamp,
+    created_at timestamp not null default now(),
+
+    PRIMARY KEY(token_hash)
+);
+COMMENT ON TABLE operator_invitations IS 'Invitations to sign up operators in organisation';
+
+CREATE INDEX idx_operator_invitations_organisation_id ON operator_invitations(organisation_id);
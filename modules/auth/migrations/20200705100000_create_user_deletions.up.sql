@@ -0,0 +1,22 @@
+CREATE TABLE user_deletions (
+    -- GUID
+    user_id varchar(36) not null,
+    game_id varchar(36) not null,
+    app_id varchar(36) not null,
+
+    -- user or operator id requested deletion
+    requested_by varchar(36) not null,
+
+    -- pending, cancelled, completed
+    status varchar(16) not null default 'pending',
+
+    requested_at timestamp not null default now(),
+    -- personal data is deleted after grace period
+    execute_after timestamp not null,
+    completed_at timestamp,
+
+    PRIMARY KEY(game_id, app_id, user_id)
+);
+COMMENT ON TABLE user_deletions IS 'Requests to delete personal data of users';
+
+CREATE INDEX idx_user_deletions_status_execute_after ON user_deletions(status, execute_after);
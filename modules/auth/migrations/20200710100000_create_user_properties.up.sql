@@ -0,0 +1,15 @@
+CREATE TABLE user_properties (
+    -- GUID
+    user_id varchar(36) not null,
+    game_id varchar(36) not null,
+    app_id varchar(36) not null,
+
+    section varchar(128) not null,
+    data jsonb not null default '{}',
+
+    created_at timestamp not null default now(),
+    updated_at timestamp not null default now(),
+
+    PRIMARY KEY(game_id, app_id, user_id, section)
+);
+COMMENT ON TABLE user_properties IS 'Properties sections of users, cached in redis';
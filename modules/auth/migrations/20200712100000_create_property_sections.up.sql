@@ -0,0 +1,15 @@
+CREATE TABLE property_sections (
+    -- GUID
+    game_id varchar(36) not null,
+    section varchar(128) not null,
+
+    -- shared sections are stored once per game and user,
+    -- apps of the game share the same values.
+    shared boolean not null default false,
+
+    created_at timestamp not null default now(),
+    updated_at timestamp not null default now(),
+
+    PRIMARY KEY(game_id, section)
+);
+COMMENT ON TABLE property_sections IS 'Settings of properties sections per game';
@@ -0,0 +1 @@
+ALTER TABLE user_properties DROP COLUMN version;
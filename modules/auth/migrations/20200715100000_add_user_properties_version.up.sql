@@ -0,0 +1,3 @@
+-- version is incremented on every write of section,
+-- clients pass expected version to avoid lost updates.
+ALTER TABLE user_properties ADD COLUMN version bigint not null default 1;
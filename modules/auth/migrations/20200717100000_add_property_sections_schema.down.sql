@@ -0,0 +1,4 @@
+COMMENT ON COLUMN user_properties.data IS NULL;
+
+ALTER TABLE property_sections DROP COLUMN max_size;
+ALTER TABLE property_sections DROP COLUMN schema;
@@ -0,0 +1,8 @@
+-- optional JSON schema of section data and size limit in bytes,
+-- zero size uses the default limit of the module.
+ALTER TABLE property_sections ADD COLUMN schema jsonb;
+ALTER TABLE property_sections ADD COLUMN max_size integer not null default 0;
+
+-- values of sections are JSON values instead of strings,
+-- stored values are kept as JSON strings.
+COMMENT ON COLUMN user_properties.data IS 'Section data, values are arbitrary JSON values';
@@ -0,0 +1,3 @@
+DROP TABLE user_properties_changes;
+
+ALTER TABLE property_sections DROP COLUMN mode;
@@ -0,0 +1,25 @@
+-- access of clients to section: read-write, read-only,
+-- append-only, server-only
+ALTER TABLE property_sections ADD COLUMN mode varchar(32) not null default 'read-write';
+
+CREATE TABLE user_properties_changes (
+    id bigserial PRIMARY KEY,
+
+    -- GUID
+    user_id varchar(36) not null,
+    game_id varchar(36) not null,
+    app_id varchar(36) not null,
+
+    section varchar(128) not null,
+
+    -- user id of operator
+    actor_id varchar(36) not null,
+
+    before jsonb not null default '{}',
+    after jsonb not null default '{}',
+    version bigint not null,
+
+    created_at timestamp not null default now()
+);
+CREATE INDEX user_properties_changes_user_idx ON user_properties_changes(game_id, app_id, user_id, created_at);
+COMMENT ON TABLE user_properties_changes IS 'Audit of properties sections changed by operators';
@@ -0,0 +1,25 @@
+CREATE TABLE user_saves (
+    -- GUID
+    user_id varchar(36) not null,
+    game_id varchar(36) not null,
+    app_id varchar(36) not null,
+
+    slot varchar(64) not null,
+    version bigint not null default 1,
+
+    -- reference to compressed data in blob storage
+    blob_ref varchar(64) not null,
+    size integer not null,
+    compressed_size integer not null,
+    checksum varchar(64) not null,
+
+    device varchar(128) not null default '',
+    play_time bigint not null default 0,
+    progress double precision not null default 0,
+
+    created_at timestamp not null default now(),
+    updated_at timestamp not null default now(),
+
+    PRIMARY KEY(game_id, app_id, user_id, slot)
+);
+COMMENT ON TABLE user_saves IS 'Metadata of cloud saves, data is stored in blob storage';
@@ -0,0 +1,2 @@
+DROP TABLE user_names;
+DROP TABLE name_templates;
@@ -0,0 +1,33 @@
+CREATE TABLE name_templates (
+    game_id varchar(36) not null,
+    -- language or language with region, e.g. en, pt-BR or default
+    locale varchar(16) not null,
+
+    pattern varchar(64) not null,
+    adjectives text[] not null default '{}',
+    nouns text[] not null default '{}',
+
+    created_at timestamp not null default now(),
+    updated_at timestamp not null default now(),
+
+    PRIMARY KEY(game_id, locale)
+);
+COMMENT ON TABLE name_templates IS 'Patterns of generated guest names per game and locale';
+
+CREATE TABLE user_names (
+    -- GUID
+    user_id varchar(36) not null,
+    game_id varchar(36) not null,
+    app_id varchar(36) not null,
+
+    name varchar(64) not null,
+    -- lower case name to keep names unique ignoring case
+    name_key varchar(64) not null,
+
+    created_at timestamp not null default now(),
+    renamed_at timestamp not null default now(),
+
+    PRIMARY KEY(game_id, app_id, user_id),
+    UNIQUE(game_id, name_key)
+);
+COMMENT ON TABLE user_names IS 'Display names chosen by players, unique per game';
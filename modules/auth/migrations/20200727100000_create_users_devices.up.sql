@@ -0,0 +1,34 @@
+CREATE TABLE users_devices (
+    -- GUID
+    user_id varchar(36) not null,
+    game_id varchar(36) not null,
+    app_id varchar(36) not null,
+    device_id varchar(256) not null,
+
+    -- ios, android, web
+    platform varchar(32) not null default '',
+    app_version varchar(32) not null default '',
+    os_version varchar(64) not null default '',
+    push_token varchar(512) not null default '',
+
+    first_seen_at timestamp not null default now(),
+    last_seen_at timestamp not null default now(),
+    -- revoked devices are not recognised on guest sign in
+    revoked_at timestamp,
+
+    PRIMARY KEY(game_id, app_id, user_id, device_id)
+);
+COMMENT ON TABLE users_devices IS 'Devices used by players per game, app';
+
+CREATE INDEX idx_users_devices_device_id ON users_devices(game_id, app_id, device_id, last_seen_at);
+
+-- devices known before the registry
+INSERT INTO users_devices (user_id, game_id, app_id, device_id, first_seen_at, last_seen_at)
+SELECT user_id, game_id, app_id, device_id, MIN(created_at), MAX(updated_at)
+FROM (
+    SELECT user_id, game_id, app_id, device_id, created_at, updated_at FROM anonymouses
+    UNION ALL
+    SELECT user_id, game_id, app_id, device_id, created_at, updated_at FROM users
+) devices
+WHERE device_id <> ''
+GROUP BY user_id, game_id, app_id, device_id;
@@ -0,0 +1,3 @@
+DROP TABLE guest_recovery_codes;
+DROP INDEX idx_users_devices_game_device_id;
+DROP TABLE guest_policies;
@@ -0,0 +1,32 @@
+CREATE TABLE guest_policies (
+    game_id varchar(36) not null,
+
+    -- app: guests are resolved by device per app
+    -- game: the same guest is used by all apps of the game on the device
+    scope varchar(16) not null default 'app',
+
+    created_at timestamp not null default now(),
+    updated_at timestamp not null default now(),
+
+    PRIMARY KEY(game_id)
+);
+COMMENT ON TABLE guest_policies IS 'Resolution of guests by device per game';
+
+CREATE INDEX idx_users_devices_game_device_id ON users_devices(game_id, device_id, last_seen_at);
+
+CREATE TABLE guest_recovery_codes (
+    -- GUID
+    user_id varchar(36) not null,
+    game_id varchar(36) not null,
+    app_id varchar(36) not null,
+
+    -- sha256 of normalized code, codes are not stored as is
+    code_hash varchar(64) not null,
+
+    created_at timestamp not null default now(),
+    used_at timestamp,
+
+    PRIMARY KEY(game_id, app_id, user_id),
+    UNIQUE(game_id, code_hash)
+);
+COMMENT ON TABLE guest_recovery_codes IS 'Codes to restore guests on new devices';
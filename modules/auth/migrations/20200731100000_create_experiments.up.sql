@@ -0,0 +1,19 @@
+CREATE TABLE experiments (
+    -- GUID
+    game_id varchar(36) not null,
+    -- key is stamped on events with variant of the user
+    key varchar(64) not null,
+
+    description text not null default '',
+    -- running: users get variants
+    -- stopped: users don't get variants anymore
+    status varchar(16) not null default 'running',
+    -- list of variants with names and weights
+    variants jsonb not null default '[]',
+
+    created_at timestamp not null default now(),
+    updated_at timestamp not null default now(),
+
+    PRIMARY KEY(game_id, key)
+);
+COMMENT ON TABLE experiments IS 'Experiments per game, users are assigned to variants by hash of user id';
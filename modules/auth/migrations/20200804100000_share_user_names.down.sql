@@ -0,0 +1,2 @@
+-- apps of names are not restored, names stay shared by apps of the game
+SELECT 1;
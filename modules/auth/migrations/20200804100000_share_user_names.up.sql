@@ -0,0 +1,10 @@
+-- names and rename cooldown are per game, the latest name of the user
+-- in apps of the game is kept and stored with shared app id
+DELETE FROM user_names n
+USING user_names o
+WHERE
+    n.game_id = o.game_id
+    AND n.user_id = o.user_id
+    AND (n.renamed_at, n.app_id) < (o.renamed_at, o.app_id);
+
+UPDATE user_names SET app_id = 'shared';
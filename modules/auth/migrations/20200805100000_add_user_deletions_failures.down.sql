@@ -0,0 +1,3 @@
+ALTER TABLE user_deletions DROP COLUMN failed_at;
+ALTER TABLE user_deletions DROP COLUMN last_error;
+ALTER TABLE user_deletions DROP COLUMN attempts;
@@ -0,0 +1,5 @@
+-- failed deletions are retried by the next run, other due deletions
+-- are processed in the meantime
+ALTER TABLE user_deletions ADD COLUMN attempts integer not null default 0;
+ALTER TABLE user_deletions ADD COLUMN last_error text;
+ALTER TABLE user_deletions ADD COLUMN failed_at timestamp;
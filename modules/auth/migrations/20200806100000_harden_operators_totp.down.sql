@@ -0,0 +1,2 @@
+-- encrypted secrets don't fit into varchar(64), the column is kept as text
+ALTER TABLE operators DROP COLUMN totp_last_step;
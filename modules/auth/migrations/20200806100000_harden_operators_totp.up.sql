@@ -0,0 +1,4 @@
+-- time step of the last accepted code, codes can't be replayed
+ALTER TABLE operators ADD COLUMN totp_last_step bigint;
+-- secrets are encrypted by AES-256-GCM and don't fit into varchar(64)
+ALTER TABLE operators ALTER COLUMN totp_secret TYPE text;
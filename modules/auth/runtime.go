@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/go-redis/redis/v8"
@@ -12,11 +13,17 @@ import (
 
 	"gitlab.com/balconygames/analytics/modules/auth/internal/db"
 	"gitlab.com/balconygames/analytics/modules/auth/internal/handlers"
+	"gitlab.com/balconygames/analytics/modules/auth/internal/models"
 	"gitlab.com/balconygames/analytics/modules/auth/internal/service"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	"gitlab.com/balconygames/analytics/pkg/blobs"
 	"gitlab.com/balconygames/analytics/pkg/logging"
+	"gitlab.com/balconygames/analytics/pkg/mailer"
 	"gitlab.com/balconygames/analytics/pkg/postgres"
+	"gitlab.com/balconygames/analytics/pkg/profanity"
 	redisconf "gitlab.com/balconygames/analytics/pkg/redis"
 	"gitlab.com/balconygames/analytics/pkg/runtime"
+	"gitlab.com/balconygames/analytics/pkg/secrets"
 )
 
 type spec struct {
@@ -24,6 +31,54 @@ type spec struct {
 	Postgres  postgres.Config  `envconfig:"POSTGRES" required:"True"`
 	Redis     redisconf.Config `envconfig:"REDIS" required:"True"`
 	AES256Key string           `envconfig:"AES256_KEY" required:"True"`
+	// AES256PreviousKeys decrypt secrets until they are rotated
+	AES256PreviousKeys []string `envconfig:"AES256_PREVIOUS_KEYS"`
+
+	// LeaderboardRedis is used to move scores on users merge,
+	// auth redis is used if it's not set.
+	LeaderboardRedis redisconf.Config `envconfig:"LEADERBOARD_REDIS"`
+	// MergeStrategy is default strategy to merge properties: newest, max
+	MergeStrategy string `envconfig:"MERGE_STRATEGY" default:"newest"`
+	// RefreshTTL is lifetime of refresh tokens
+	RefreshTTL time.Duration `envconfig:"REFRESH_TTL" default:"720h"`
+
+	// PasswordHash is algorithm of operators passwords: bcrypt, argon2id
+	PasswordHash string `envconfig:"PASSWORD_HASH" default:"bcrypt"`
+	// MaxFailedSignins per email during FailedSigninsWindow
+	MaxFailedSignins    int64         `envconfig:"MAX_FAILED_SIGNINS" default:"5"`
+	FailedSigninsWindow time.Duration `envconfig:"FAILED_SIGNINS_WINDOW" default:"15m"`
+
+	// Mailer sends magic links of email sign in
+	Mailer mailer.Config `envconfig:"MAILER"`
+	// MagicLinkURL is page of the game opening magic links
+	MagicLinkURL string        `envconfig:"MAGIC_LINK_URL"`
+	MagicLinkTTL time.Duration `envconfig:"MAGIC_LINK_TTL" default:"15m"`
+	// MagicLinkMaxPerEmail, MagicLinkMaxPerIP limit magic link
+	// requests during MagicLinkRequestsWindow
+	MagicLinkMaxPerEmail    int64         `envconfig:"MAGIC_LINK_MAX_PER_EMAIL" default:"5"`
+	MagicLinkMaxPerIP       int64         `envconfig:"MAGIC_LINK_MAX_PER_IP" default:"20"`
+	MagicLinkRequestsWindow time.Duration `envconfig:"MAGIC_LINK_REQUESTS_WINDOW" default:"15m"`
+
+	// PropsCacheTTL is lifetime of properties cached in redis
+	PropsCacheTTL time.Duration `envconfig:"PROPS_CACHE_TTL" default:"24h"`
+	// PropsMaxSize is default limit of section data size in bytes
+	PropsMaxSize int `envconfig:"PROPS_MAX_SIZE" default:"65536"`
+
+	// NameChangeCooldown is time between display name changes of the player
+	NameChangeCooldown time.Duration `envconfig:"NAME_CHANGE_COOLDOWN" default:"168h"`
+	// ProfanityWords is file with offensive words rejected in names
+	// in addition to built-in ones, a word per line
+	ProfanityWords string `envconfig:"PROFANITY_WORDS"`
+
+	// SavesBlobs stores data of cloud saves: postgres, file
+	SavesBlobs blobs.Config `envconfig:"SAVES_BLOBS"`
+	// SavesMaxSize is limit of cloud save size in bytes before compression
+	SavesMaxSize int `envconfig:"SAVES_MAX_SIZE" default:"1048576"`
+	// SavesMaxSlots is limit of cloud save slots per user
+	SavesMaxSlots int `envconfig:"SAVES_MAX_SLOTS" default:"10"`
+
+	// DeletionGracePeriod is time to cancel deletion of personal data
+	DeletionGracePeriod time.Duration `envconfig:"DELETION_GRACE_PERIOD" default:"720h"`
 }
 
 func New(r *runtime.Runtime) error {
@@ -59,17 +114,144 @@ func withSpec(r *runtime.Runtime, s spec) error {
 
 	redisConn := redis.NewClient(s.Redis.Options())
 	redisRepo := db.NewRedisRepository(redisConn, logger)
+
+	leaderboardConn := redisConn
+	if s.LeaderboardRedis.Host != "" {
+		leaderboardConn = redis.NewClient(s.LeaderboardRedis.Options())
+	}
+	leaderboardRepo := db.NewLeaderboardRepository(leaderboardConn, logger)
+
 	repo := db.NewPostgresRepository(pool)
-	svc := service.NewService(repo, redisRepo, logger)
+	svc := service.NewService(repo, redisRepo, leaderboardRepo, logger)
+	svc.MergeStrategy = models.MergeStrategy(s.MergeStrategy)
+	if !svc.MergeStrategy.Valid() {
+		return errors.Errorf("unknown merge strategy %s", s.MergeStrategy)
+	}
+	if s.RefreshTTL > 0 {
+		svc.RefreshTTL = s.RefreshTTL
+	}
+	if s.PasswordHash != "" {
+		if s.PasswordHash != service.PasswordBcrypt && s.PasswordHash != service.PasswordArgon2id {
+			return errors.Errorf("unknown password hash %s", s.PasswordHash)
+		}
+		svc.Operators.PasswordHash = s.PasswordHash
+	}
+	// secrets of second factor of operators are encrypted
+	svc.Keys, err = secrets.NewKeys(s.AES256Key, s.AES256PreviousKeys...)
+	if err != nil {
+		return errors.Wrap(err, "invalid aes256 key")
+	}
+	if s.MaxFailedSignins > 0 {
+		svc.Operators.MaxFailedSignins = s.MaxFailedSignins
+	}
+	if s.FailedSigninsWindow > 0 {
+		svc.Operators.FailedSigninsWindow = s.FailedSigninsWindow
+	}
+
+	// email sign in is disabled until mailer is configured
+	svc.Mailer = nil
+	if s.Mailer.Driver != "" {
+		if s.Mailer.Driver == mailer.DriverMemory && !r.Dev() {
+			return errors.Errorf("%s mailer drops emails, it's allowed only in dev environments", mailer.DriverMemory)
+		}
+
+		svc.Mailer, err = mailer.New(s.Mailer)
+		if err != nil {
+			return err
+		}
+
+		svc.MagicLinks.URL = s.MagicLinkURL
+		err = svc.MagicLinks.Validate()
+		if err != nil {
+			return err
+		}
+	}
+	if s.MagicLinkTTL > 0 {
+		svc.MagicLinks.TTL = s.MagicLinkTTL
+	}
+	if s.MagicLinkMaxPerEmail > 0 {
+		svc.MagicLinks.MaxPerEmail = s.MagicLinkMaxPerEmail
+	}
+	if s.MagicLinkMaxPerIP > 0 {
+		svc.MagicLinks.MaxPerIP = s.MagicLinkMaxPerIP
+	}
+	if s.MagicLinkRequestsWindow > 0 {
+		svc.MagicLinks.RequestsWindow = s.MagicLinkRequestsWindow
+	}
+	if s.PropsCacheTTL > 0 {
+		svc.PropertiesCacheTTL = s.PropsCacheTTL
+	}
+	if s.PropsMaxSize > 0 {
+		svc.PropertiesMaxSize = s.PropsMaxSize
+	}
+	if s.NameChangeCooldown > 0 {
+		svc.Names.Cooldown = s.NameChangeCooldown
+	}
+	if s.ProfanityWords != "" {
+		svc.Names.Profanity, err = profanity.Load(s.ProfanityWords)
+		if err != nil {
+			return err
+		}
+	}
+	svc.Blobs, err = blobs.New(s.SavesBlobs, pool)
+	if err != nil {
+		return err
+	}
+	if s.SavesMaxSize > 0 {
+		svc.Saves.MaxSize = s.SavesMaxSize
+	}
+	if s.SavesMaxSlots > 0 {
+		svc.Saves.MaxSlots = s.SavesMaxSlots
+	}
+	if s.DeletionGracePeriod > 0 {
+		svc.DeletionGracePeriod = s.DeletionGracePeriod
+	}
+	// personal data is exported and deleted by all modules
+	svc.PersonalData = r.PersonalData()
+	r.WithPersonalData("auth", svc.PersonalDataProvider())
+	// social sign in uses network credentials stored by primary module
+	svc.Credentials = r.Credentials()
+	svc.Organisations = r.Organisations()
+
 	h := handlers.New(svc, logger)
 
+	// JWT tokens of merged users should be served for surviving users
+	r.WithAliasResolver(auth.NewRedisAliasResolver(redisConn))
+	// JWT tokens of revoked sessions should be rejected by all modules
+	r.WithRevocationChecker(auth.NewRedisRevocationChecker(redisConn))
+	// variants of experiments are stamped on events by pixel module
+	r.WithExperiments(svc)
+	// server scores of leaderboards are accepted only for players of the game
+	r.WithPlayers(svc)
+
+	// creates the first invitation of organisation
+	r.WithCommand("auth.invite", inviteCommand(svc, r.PlatformOrganisationID()))
+	// deletes personal data after grace period, should be scheduled
+	r.WithCommand("auth.deletions", deletionsCommand(svc))
+	// sets aliases of merged users lost in redis
+	r.WithCommand("auth.aliases.rebuild", rebuildAliasesCommand(svc))
+	// re-encrypts secrets of second factor by the current key
+	r.WithCommand("auth.totp.rotate", rotateTOTPCommand(svc))
+	// imports properties stored only in redis into postgres
+	r.WithCommand("auth.props.backfill", backfillPropertiesCommand(svc))
+	// moves properties into keys namespaced by game and app
+	r.WithCommand("auth.props.migrate", migratePropertiesCommand(svc))
+
 	r.WithClosable(pool)
 	r.WithRoutes(func(r1 chi.Router) {
+		// public keys to verify tokens by other services
+		r1.Get("/auth/v1/.well-known/jwks.json", r.JWKSHandler)
+
 		// ==== BEGIN CLIENT routes
 		// pass token signer instance in context
 		r.WithClientTokenSigner(r1, func(r2 chi.Router) {
 			r2.Post("/auth/v1/games/{game_id}/apps/{app_id}/anonymous/sync", h.SyncAnomHandler)
+			r2.Post("/auth/v1/games/{game_id}/apps/{app_id}/anonymous/recover", h.RecoverGuestHandler)
 			r2.Post("/auth/v1/games/{game_id}/apps/{app_id}/users/sync", h.SyncRegHandler)
+			r2.Post("/auth/v1/token/refresh", h.RefreshTokenHandler)
+
+			r2.Post("/auth/v1/games/{game_id}/apps/{app_id}/email/login", h.EmailLoginHandler)
+			r2.Post("/auth/v1/games/{game_id}/apps/{app_id}/email/verify", h.EmailVerifyHandler)
 
 			r2.Group(func(i chi.Router) {
 				i.Use(h.FacebookMiddleware)
@@ -93,6 +275,33 @@ func withSpec(r *runtime.Runtime, s spec) error {
 		r.WithClientAuth(r1, func(r2 chi.Router) {
 			r2.Post("/auth/v1/properties/list", h.GetPropertiesHandler)
 			r2.Put("/auth/v1/properties", h.SetPropertiesHandler)
+
+			r2.Get("/auth/v1/identities", h.ListIdentitiesHandler)
+			r2.Post("/auth/v1/identities", h.LinkIdentityHandler)
+			r2.Delete("/auth/v1/identities/{network}/{network_id}", h.UnlinkIdentityHandler)
+
+			// signer decodes token of the surviving user
+			r.WithClientTokenSigner(r2, func(i chi.Router) {
+				i.Post("/auth/v1/users/merge", h.MergeHandler)
+			})
+
+			r2.Get("/auth/v1/devices", h.ListDevicesHandler)
+			r2.Put("/auth/v1/devices/current", h.UpdateDeviceHandler)
+			r2.Delete("/auth/v1/devices/{device_id}", h.RevokeDeviceHandler)
+
+			r2.Get("/auth/v1/saves", h.ListSavesHandler)
+			r2.Get("/auth/v1/saves/{slot}", h.GetSaveHandler)
+			r2.Put("/auth/v1/saves/{slot}", h.PutSaveHandler)
+			r2.Delete("/auth/v1/saves/{slot}", h.DeleteSaveHandler)
+
+			r2.Delete("/auth/v1/sessions", h.SignoutHandler)
+
+			r2.Put("/auth/v1/users/me/name", h.RenameHandler)
+			r2.Post("/auth/v1/users/me/recovery-code", h.CreateRecoveryCodeHandler)
+
+			r2.Get("/auth/v1/users/me/export", h.ExportPersonalDataHandler)
+			r2.Post("/auth/v1/users/me/deletion", h.RequestDeletionHandler)
+			r2.Delete("/auth/v1/users/me/deletion", h.CancelDeletionHandler)
 		})
 		// ==== END CLIENT routes
 
@@ -109,10 +318,61 @@ func withSpec(r *runtime.Runtime, s spec) error {
 			r2.Post("/auth/v1/signin", h.ServerSignin)
 			r2.Post("/auth/v1/signup", h.ServerSignup)
 		})
-		r1.Delete("/auth/v1/signout", h.ServerSignout)
 
 		r.WithServerAuth(r1, func(r2 chi.Router) {
-			// TODO: add ability to view properties stored per player
+			// second factor is managed by any signed in operator
+			r2.Post("/auth/v1/operators/me/totp", h.SetupTOTPHandler)
+			r2.Post("/auth/v1/operators/me/totp/confirm", h.ConfirmTOTPHandler)
+			r2.Delete("/auth/v1/operators/me/totp", h.DisableTOTPHandler)
+
+			r2.Group(func(i chi.Router) {
+				i.Use(auth.RequireRoles(auth.RoleReadOnly))
+				i.Delete("/auth/v1/signout", h.ServerSignout)
+
+				i.Get("/auth/v1/games/{game_id}/props/sections", h.ListSectionsHandler)
+				i.Get("/auth/v1/games/{game_id}/names/templates", h.ListNameTemplatesHandler)
+				i.Get("/auth/v1/games/{game_id}/guests/policy", h.GetGuestPolicyHandler)
+				i.Get("/auth/v1/games/{game_id}/experiments", h.ListExperimentsHandler)
+
+				i.Get("/auth/v1/games/{game_id}/apps/{app_id}/users/{user_id}/saves", h.ServerListSaves)
+				i.Get("/auth/v1/games/{game_id}/apps/{app_id}/users/{user_id}/devices", h.ServerListDevices)
+				i.Get("/auth/v1/games/{game_id}/apps/{app_id}/users/{user_id}/experiments", h.ServerGetPlayerExperiments)
+
+				i.Get("/auth/v1/organisations/{organisation_id}/members", h.ListMembersHandler)
+			})
+
+			r2.Group(func(i chi.Router) {
+				i.Use(auth.RequireRoles(auth.RoleAdmin))
+				i.Post("/auth/v1/invitations", h.InviteOperatorHandler)
+				i.Put("/auth/v1/organisations/{organisation_id}/members/{operator_id}", h.UpdateMemberHandler)
+				i.Delete("/auth/v1/organisations/{organisation_id}/members/{operator_id}", h.RemoveMemberHandler)
+
+				i.Get("/auth/v1/games/{game_id}/apps/{app_id}/users/{user_id}/export", h.ServerExportPersonalData)
+				i.Post("/auth/v1/games/{game_id}/apps/{app_id}/users/{user_id}/deletion", h.ServerRequestDeletion)
+				i.Delete("/auth/v1/games/{game_id}/apps/{app_id}/users/{user_id}/deletion", h.ServerCancelDeletion)
+			})
+
+			r2.Group(func(i chi.Router) {
+				i.Use(auth.RequireRoles(auth.RoleLiveOps))
+				i.Put("/auth/v1/games/{game_id}/props/sections/{section}", h.UpdateSectionHandler)
+				i.Put("/auth/v1/games/{game_id}/names/templates/{locale}", h.UpdateNameTemplateHandler)
+				i.Delete("/auth/v1/games/{game_id}/names/templates/{locale}", h.DeleteNameTemplateHandler)
+				i.Put("/auth/v1/games/{game_id}/guests/policy", h.UpdateGuestPolicyHandler)
+				i.Put("/auth/v1/games/{game_id}/experiments/{key}", h.UpdateExperimentHandler)
+				i.Delete("/auth/v1/games/{game_id}/experiments/{key}", h.DeleteExperimentHandler)
+			})
+
+			// game servers read and write properties of players with API keys
+			r2.Group(func(i chi.Router) {
+				i.Use(auth.RequireRoleOrScope(auth.RoleReadOnly, auth.ScopePropertiesRead))
+				i.Get("/auth/v1/games/{game_id}/apps/{app_id}/users/{user_id}/props", h.ServerGetPlayerProperties)
+				i.Get("/auth/v1/games/{game_id}/apps/{app_id}/users/{user_id}/props/changes", h.ServerListPropertiesChanges)
+			})
+
+			r2.Group(func(i chi.Router) {
+				i.Use(auth.RequireRoleOrScope(auth.RoleLiveOps, auth.ScopePropertiesWrite))
+				i.Put("/auth/v1/games/{game_id}/apps/{app_id}/users/{user_id}/props", h.ServerSetPlayerProperties)
+			})
 		})
 		// ==== END SERVER routes
 	})
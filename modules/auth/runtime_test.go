@@ -35,11 +35,12 @@ func (s *serviceSuite) TestAuth() {
 		Env: "test",
 	}
 	r := runtime.New("web", sp)
-	aesKey := "<aes>"
+	aesKey := "0123456789abcdef0123456789abcdef"
 	err := withSpec(r, spec{
-		Env:       "test",
-		Postgres:  s.PostgresSuite.Config,
-		AES256Key: aesKey,
+		Env:           "test",
+		Postgres:      s.PostgresSuite.Config,
+		AES256Key:     aesKey,
+		MergeStrategy: "newest",
 	})
 	s.Require().NoError(err)
 
@@ -0,0 +1,23 @@
+package leaderboard
+
+import (
+	"context"
+	"fmt"
+
+	"gitlab.com/balconygames/analytics/modules/leaderboard/internal/service"
+	"gitlab.com/balconygames/analytics/pkg/runtime"
+)
+
+// indexCommand fills index of leaderboards per user by stored scores,
+// scores set before the index aren't moved on merge and renamed
+// otherwise:
+//
+//	analytics leaderboard.index
+func indexCommand(svc *service.Service) runtime.CommandFunc {
+	return func(ctx context.Context, args []string) error {
+		indexed, err := svc.IndexUserLeaderboards(ctx)
+		fmt.Printf("indexed scores: %d\n", indexed)
+
+		return err
+	}
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+
+	"gitlab.com/balconygames/analytics/modules/leaderboard/internal/models"
+)
+
+// UpsertLeaderboard creates or renames leaderboard of the game app,
+// scores are stored by leaderboard id only, so the id can't be
+// used by another game, models.ErrLeaderboardTaken is returned.
+// Upserts of the same id are serialized, otherwise concurrent upserts
+// of different games could both pass the check.
+func (r PostgresRepository) UpsertLeaderboard(ctx context.Context, leaderboard *models.Leaderboard) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('leaderboards:' || $1))`, leaderboard.ID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO
+			leaderboards (
+				id
+				, name
+				, game_id
+				, app_id
+			)
+			SELECT $1, $2, $3, $4
+			WHERE NOT EXISTS (
+				SELECT 1 FROM leaderboards WHERE id=$1 AND game_id<>$3
+			)
+		ON CONFLICT (id, game_id, app_id) DO UPDATE
+		SET
+			name = EXCLUDED.name
+			, updated_at = NOW()
+	`
+
+	tag, err := tx.Exec(ctx, query, leaderboard.ID, leaderboard.Name,
+		leaderboard.GameID, leaderboard.AppID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return models.ErrLeaderboardTaken
+	}
+
+	return tx.Commit(ctx)
+}
+
+// LeaderboardGames respond with games of leaderboards by id.
+func (r PostgresRepository) LeaderboardGames(ctx context.Context, ids []string) (map[string][]string, error) {
+	query := `
+		SELECT DISTINCT id, game_id
+		FROM leaderboards
+		WHERE id = ANY($1)
+	`
+
+	rows, err := r.pool.Query(ctx, query, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	games := make(map[string][]string, len(ids))
+	for rows.Next() {
+		var id, gameID string
+		err = rows.Scan(&id, &gameID)
+		if err != nil {
+			return nil, err
+		}
+
+		games[id] = append(games[id], gameID)
+	}
+
+	return games, rows.Err()
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"sync"
+
+	"gitlab.com/balconygames/analytics/modules/leaderboard/internal/models"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+func (s *serviceSuite) TestUpsertLeaderboardConcurrentGames() {
+	repo := NewPostgresRepository(s.PostgresPool)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, game := range []string{gameID, "other-game"} {
+		wg.Add(1)
+		go func(i int, game string) {
+			defer wg.Done()
+			errs[i] = repo.UpsertLeaderboard(context.Background(), &models.Leaderboard{
+				Scope: sharedmodels.Scope{GameID: game, AppID: appID},
+				ID:    leaderboardID,
+				Name:  "Coins",
+			})
+		}(i, game)
+	}
+	wg.Wait()
+
+	// only one game owns the leaderboard id
+	taken := 0
+	for _, err := range errs {
+		if err == models.ErrLeaderboardTaken {
+			taken++
+			continue
+		}
+		s.Require().NoError(err)
+	}
+	s.Require().Equal(1, taken)
+
+	games, err := repo.LeaderboardGames(context.Background(), []string{leaderboardID})
+	s.Require().NoError(err)
+	s.Require().Len(games[leaderboardID], 1)
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const scanCount = 100
+
+// userLeaderboards respond with leaderboard ids where the user has score.
+func (r *RedisRepository) userLeaderboards(ctx context.Context, userID string) ([]string, error) {
+	ids, err := r.conn.SMembers(ctx, userLeaderboardsKey(userID)).Result()
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't get leaderboards of the user")
+	}
+
+	return ids, nil
+}
+
+// IndexUserLeaderboards fills index of leaderboards per user by score
+// keys, scores set before the index are not indexed otherwise.
+func (r *RedisRepository) IndexUserLeaderboards(ctx context.Context) (int, error) {
+	var indexed int
+	var cursor uint64
+
+	for {
+		keys, next, err := r.conn.Scan(ctx, cursor, "users:*", scanCount).Result()
+		if err != nil {
+			return indexed, errors.WithMessage(err, "can't scan user keys")
+		}
+
+		pipe := r.conn.Pipeline()
+		for _, key := range keys {
+			// users:<leaderboard_id>:<user_id>
+			parts := strings.Split(key, ":")
+			if len(parts) != 3 {
+				continue
+			}
+
+			pipe.SAdd(ctx, userLeaderboardsKey(parts[2]), parts[1])
+			indexed++
+		}
+
+		_, err = pipe.Exec(ctx)
+		if err != nil {
+			return indexed, errors.WithMessage(err, "can't exec pipeline to index user leaderboards")
+		}
+
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+
+	return indexed, nil
+}
+
+// ExportUserScores respond with score attributes of the user per
+// leaderboard including ip and country.
+func (r *RedisRepository) ExportUserScores(ctx context.Context, userID string) (map[string]map[string]string, error) {
+	ids, err := r.userLeaderboards(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]map[string]string, len(ids))
+	for _, id := range ids {
+		attrs, err := r.conn.HGetAll(ctx, userKey(id, userID)).Result()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "can't get score of leaderboard %s", id)
+		}
+
+		out[id] = attrs
+	}
+
+	return out, nil
+}
+
+// DeleteUserScores removes the user from all leaderboards.
+func (r *RedisRepository) DeleteUserScores(ctx context.Context, userID string) error {
+	ids, err := r.userLeaderboards(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		pipe := r.conn.TxPipeline()
+		pipe.ZRem(ctx, scoreKey(id), userID)
+		pipe.Del(ctx, userKey(id, userID))
+
+		_, err = pipe.Exec(ctx)
+		if err != nil {
+			return errors.WithMessagef(err, "can't delete score of leaderboard %s", id)
+		}
+	}
+
+	err = r.conn.Del(ctx, userLeaderboardsKey(userID)).Err()
+	if err != nil {
+		return errors.WithMessage(err, "can't delete leaderboards of the user")
+	}
+
+	return nil
+}
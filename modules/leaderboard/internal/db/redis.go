@@ -32,6 +32,12 @@ func userKey(leaderboardID, userID string) string {
 	return fmt.Sprintf("users:%s:%s", leaderboardID, userID)
 }
 
+// userLeaderboardsKey is index of leaderboards where the user has score,
+// it's read by auth module to move and rename scores of the user.
+func userLeaderboardsKey(userID string) string {
+	return fmt.Sprintf("user_leaderboards:%s", userID)
+}
+
 // internal function to clean up the database before to run
 // test cases.
 func (r *RedisRepository) flush() error {
@@ -68,6 +74,11 @@ func (r *RedisRepository) SetScore(ctx context.Context, score *models.Score) err
 		return err
 	}
 
+	_, err = pipe.SAdd(ctx, userLeaderboardsKey(score.UserID), score.LeaderboardID).Result()
+	if err != nil {
+		return err
+	}
+
 	_, err = pipe.Exec(ctx)
 	if err != nil {
 		return err
@@ -451,3 +451,50 @@ func (s serviceRedisSuite) TestMeHugeAmountOfScores() {
 // 	s.Require().Equal(len(scores), 20)
 // 	s.Require().Nil(err)
 // }
+
+func (s serviceRedisSuite) TestDeleteUserScores() {
+	repo := NewRedisRepository(s.Conn, s.logger)
+
+	score := &models.Score{
+		Scope: sharedmodels.Scope{
+			GameID: gameID,
+			AppID:  appID,
+			UserID: myUserID,
+		},
+		LeaderboardID: leaderboardID,
+		Value:         100,
+		IP:            "127.0.0.1",
+	}
+	err := repo.SetScore(context.Background(), score)
+	s.Require().Nil(err)
+
+	scores, err := repo.ExportUserScores(context.Background(), myUserID)
+	s.Require().Nil(err)
+	s.Require().Equal("127.0.0.1", scores[leaderboardID]["ip"])
+
+	err = repo.DeleteUserScores(context.Background(), myUserID)
+	s.Require().Nil(err)
+
+	scores, err = repo.ExportUserScores(context.Background(), myUserID)
+	s.Require().Nil(err)
+	s.Require().Empty(scores)
+
+	_, err = s.Conn.ZScore(context.Background(), scoreKey(leaderboardID), myUserID).Result()
+	s.Require().NotNil(err)
+}
+
+func (s serviceRedisSuite) TestIndexUserLeaderboards() {
+	repo := NewRedisRepository(s.Conn, s.logger)
+
+	// score set before the index
+	err := s.Conn.HSet(context.Background(), userKey(leaderboardID, myUserID), "value", "100").Err()
+	s.Require().Nil(err)
+
+	indexed, err := repo.IndexUserLeaderboards(context.Background())
+	s.Require().Nil(err)
+	s.Require().Equal(1, indexed)
+
+	ids, err := repo.userLeaderboards(context.Background(), myUserID)
+	s.Require().Nil(err)
+	s.Require().Equal([]string{leaderboardID}, ids)
+}
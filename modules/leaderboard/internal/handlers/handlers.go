@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/go-chi/chi"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
@@ -11,6 +12,7 @@ import (
 	"gitlab.com/balconygames/analytics/modules/leaderboard/internal/service"
 	"gitlab.com/balconygames/analytics/pkg/auth"
 	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
 )
 
 type Handler struct {
@@ -37,9 +39,29 @@ func (h *Handler) CreateLeaderboards(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.service.CreateLeaderboard(r.Context(), data)
+	// game is passed in body, server user should have grant for it
+	// and belong to organisation of the game
+	err = auth.AuthorizeGame(r, data.GameID)
+	if err == auth.ErrForbidden {
+		httpreq.Forbidden(w, err)
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, err)
+		return
+	}
 
-	httpreq.NotImplemented(w)
+	err = h.service.CreateLeaderboard(r.Context(), data)
+	if err == models.ErrLeaderboardTaken {
+		httpreq.JSONWithStatus(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't create leaderboard"))
+		return
+	}
+
+	httpreq.JSON(w, data)
 }
 
 func (h *Handler) ListLeaderboards(w http.ResponseWriter, r *http.Request) {
@@ -53,8 +75,6 @@ type createScoresRequest struct {
 
 // CreateScores is using batch of scores with leaderboard_id per score group
 func (h *Handler) CreateScores(w http.ResponseWriter, r *http.Request) {
-	var err error
-
 	scope := auth.GetScope(r)
 
 	// block spammer
@@ -63,11 +83,30 @@ func (h *Handler) CreateScores(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.createScores(w, r, scope, false)
+}
+
+// ServerCreateScores sets scores of the player by game servers, country
+// is not resolved by ip address of the game server. The player and
+// leaderboards of scores should belong to the game of url.
+func (h *Handler) ServerCreateScores(w http.ResponseWriter, r *http.Request) {
+	scope := &sharedmodels.Scope{
+		GameID: chi.URLParam(r, "game_id"),
+		AppID:  chi.URLParam(r, "app_id"),
+		UserID: chi.URLParam(r, "user_id"),
+	}
+
+	h.createScores(w, r, scope, true)
+}
+
+// createScores sets scores of the scope, server requests don't resolve
+// geo and write only scores of players and leaderboards of the game.
+func (h *Handler) createScores(w http.ResponseWriter, r *http.Request, scope *sharedmodels.Scope, server bool) {
 	log := h.logger.With(scope.Fields()...)
 	log.Debugf("begin create scores user: %v", scope)
 
 	data := createScoresRequest{}
-	err = httpreq.Read(r, &data)
+	err := httpreq.Read(r, &data)
 	if err != nil {
 		httpreq.Error(w, errors.Wrap(err, "can't read leaderboard request"))
 		return
@@ -76,11 +115,15 @@ func (h *Handler) CreateScores(w http.ResponseWriter, r *http.Request) {
 	// set ip and country per score
 	for _, score := range data.Scores {
 		// ensure to have game and app
-		// based on JWT token.
+		// based on JWT token or url of server request.
 		score.UserID = scope.UserID
 		score.GameID = scope.GameID
 		score.AppID = scope.AppID
 
+		if server {
+			continue
+		}
+
 		score.IP = r.RemoteAddr
 		if score.Country == "" {
 			country, err := h.service.Geo.Resolve(score.IP)
@@ -97,6 +140,28 @@ func (h *Handler) CreateScores(w http.ResponseWriter, r *http.Request) {
 			Debugf("refreshed score with geo ip, country information %v", score)
 	}
 
+	if server {
+		err = h.service.CheckPlayer(r.Context(), scope.GameID, scope.UserID)
+		if errors.Is(err, models.ErrPlayerNotInGame) {
+			httpreq.Forbidden(w, err)
+			return
+		}
+		if err != nil {
+			httpreq.Error(w, errors.Wrap(err, "can't check player"))
+			return
+		}
+
+		err = h.service.CheckLeaderboards(r.Context(), scope.GameID, data.Scores)
+		if errors.Is(err, models.ErrLeaderboardNotInGame) {
+			httpreq.Forbidden(w, err)
+			return
+		}
+		if err != nil {
+			httpreq.Error(w, errors.Wrap(err, "can't check leaderboards"))
+			return
+		}
+	}
+
 	err = h.service.SetScores(r.Context(), data.Scores)
 	if err != nil {
 		httpreq.Error(w, errors.Wrap(err, "can't upsert leaderboard data"))
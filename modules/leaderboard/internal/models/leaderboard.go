@@ -1,9 +1,22 @@
 package models
 
 import (
+	"github.com/pkg/errors"
+
 	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
 )
 
+var (
+	// ErrLeaderboardTaken returned once leaderboard id is used by another game.
+	ErrLeaderboardTaken = errors.New("leaderboard id is used by another game")
+	// ErrLeaderboardNotInGame returned on server scores of leaderboard
+	// which isn't created in the game.
+	ErrLeaderboardNotInGame = errors.New("leaderboard doesn't belong to game")
+	// ErrPlayerNotInGame returned on server scores of user which isn't
+	// registered in the game.
+	ErrPlayerNotInGame = errors.New("player doesn't belong to game")
+)
+
 // Leaderboard is container for scores list
 // Example: Coins, Levels highscores
 type Leaderboard struct {
@@ -3,10 +3,62 @@ package service
 import (
 	"context"
 
+	"github.com/pkg/errors"
+
 	"gitlab.com/balconygames/analytics/modules/leaderboard/internal/models"
 )
 
+// CreateLeaderboard creates or renames leaderboard of the game app.
 func (s *Service) CreateLeaderboard(ctx context.Context, leaderboard *models.Leaderboard) error {
-	// s.pgRepo.LeaderboardUpsert(ctx, leaderboard)
+	if leaderboard.ID == "" || leaderboard.Name == "" || leaderboard.GameID == "" || leaderboard.AppID == "" {
+		return errors.New("id, name, game_id and app_id are required")
+	}
+
+	err := s.pgRepo.UpsertLeaderboard(ctx, leaderboard)
+	if err == models.ErrLeaderboardTaken {
+		return err
+	}
+	if err != nil {
+		return errors.WithMessage(err, "can't upsert leaderboard")
+	}
+
+	return nil
+}
+
+// CheckPlayer ensures that the user of server scores is registered
+// in the game, otherwise keys of the game could write scores of users
+// of other games.
+func (s *Service) CheckPlayer(ctx context.Context, gameID, userID string) error {
+	exists, err := s.Players.PlayerExists(ctx, gameID, userID)
+	if err != nil {
+		return errors.WithMessage(err, "can't check player")
+	}
+	if !exists {
+		return errors.Wrap(models.ErrPlayerNotInGame, userID)
+	}
+
+	return nil
+}
+
+// CheckLeaderboards ensures that leaderboards of scores are created
+// in the game, scores are stored by leaderboard id only.
+func (s *Service) CheckLeaderboards(ctx context.Context, gameID string, scores []*models.Score) error {
+	ids := make([]string, 0, len(scores))
+	for _, score := range scores {
+		ids = append(ids, score.LeaderboardID)
+	}
+
+	games, err := s.pgRepo.LeaderboardGames(ctx, ids)
+	if err != nil {
+		return errors.WithMessage(err, "can't find games of leaderboards")
+	}
+
+	for _, id := range ids {
+		owners := games[id]
+		if len(owners) != 1 || owners[0] != gameID {
+			return errors.Wrap(models.ErrLeaderboardNotInGame, id)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gitlab.com/balconygames/analytics/modules/leaderboard/internal/models"
+)
+
+type fakeLeaderboards struct {
+	PostgresRepository
+	games map[string][]string
+}
+
+func (f *fakeLeaderboards) LeaderboardGames(_ context.Context, ids []string) (map[string][]string, error) {
+	games := map[string][]string{}
+	for _, id := range ids {
+		if owners, ok := f.games[id]; ok {
+			games[id] = owners
+		}
+	}
+	return games, nil
+}
+
+func TestCheckLeaderboards(t *testing.T) {
+	svc := NewService(&fakeLeaderboards{games: map[string][]string{
+		"coins":  {"game-a"},
+		"levels": {"game-b"},
+		"shared": {"game-a", "game-b"},
+	}}, nil, nil, zap.NewNop().Sugar())
+
+	scores := func(ids ...string) []*models.Score {
+		var collection []*models.Score
+		for _, id := range ids {
+			collection = append(collection, &models.Score{LeaderboardID: id})
+		}
+		return collection
+	}
+
+	ctx := context.Background()
+	require.NoError(t, svc.CheckLeaderboards(ctx, "game-a", scores("coins")))
+
+	for _, ids := range [][]string{{"levels"}, {"coins", "levels"}, {"unknown"}, {"shared"}} {
+		err := svc.CheckLeaderboards(ctx, "game-a", scores(ids...))
+		require.True(t, errors.Is(err, models.ErrLeaderboardNotInGame), "%v", ids)
+	}
+}
+
+type fakePlayers map[string]string
+
+func (f fakePlayers) PlayerExists(_ context.Context, gameID, userID string) (bool, error) {
+	return f[userID] == gameID, nil
+}
+
+func TestCheckPlayer(t *testing.T) {
+	svc := NewService(nil, nil, nil, zap.NewNop().Sugar())
+	svc.Players = fakePlayers{"player-a": "game-a", "player-b": "game-b"}
+
+	ctx := context.Background()
+	require.NoError(t, svc.CheckPlayer(ctx, "game-a", "player-a"))
+
+	for _, userID := range []string{"player-b", "unknown"} {
+		err := svc.CheckPlayer(ctx, "game-a", userID)
+		require.True(t, errors.Is(err, models.ErrPlayerNotInGame), userID)
+	}
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/pkg/privacy"
+)
+
+// PersonalDataProvider exports and deletes scores of the user,
+// scores contain ip address and country of the user.
+func (s *Service) PersonalDataProvider() privacy.Provider {
+	return leaderboardPersonalData{s}
+}
+
+type leaderboardPersonalData struct {
+	s *Service
+}
+
+type leaderboardArchive struct {
+	// Scores are attributes of score per leaderboard id
+	Scores map[string]map[string]string `json:"scores"`
+}
+
+func (p leaderboardPersonalData) Export(ctx context.Context, subject privacy.Subject) (interface{}, error) {
+	scores, err := p.s.redisRepo.ExportUserScores(ctx, subject.UserID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't export scores")
+	}
+
+	return leaderboardArchive{Scores: scores}, nil
+}
+
+func (p leaderboardPersonalData) Delete(ctx context.Context, subject privacy.Subject) error {
+	err := p.s.redisRepo.DeleteUserScores(ctx, subject.UserID)
+	if err != nil {
+		return errors.WithMessage(err, "can't delete scores")
+	}
+
+	return nil
+}
@@ -22,6 +22,12 @@ func (s Service) SetScore(ctx context.Context, scope sharedmodels.Scope, score *
 	return s.redisRepo.SetScore(ctx, score)
 }
 
+// IndexUserLeaderboards fills index of leaderboards per user for scores
+// set before the index, the index is used to move and rename scores.
+func (s Service) IndexUserLeaderboards(ctx context.Context) (int, error) {
+	return s.redisRepo.IndexUserLeaderboards(ctx)
+}
+
 func (s Service) ListScores(ctx context.Context, scope sharedmodels.Scope, leaderboardID []string) ([]*models.Leaderboard, error) {
 	var ls []*models.Leaderboard
 
@@ -6,16 +6,23 @@ import (
 	"go.uber.org/zap"
 
 	"gitlab.com/balconygames/analytics/modules/leaderboard/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/auth"
 	"gitlab.com/balconygames/analytics/pkg/geo"
 	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
 )
 
 type PostgresRepository interface {
+	UpsertLeaderboard(context.Context, *models.Leaderboard) error
+	LeaderboardGames(ctx context.Context, ids []string) (map[string][]string, error)
 }
 
 type RedisRepository interface {
 	SetScore(context.Context, *models.Score) error
 	ListScores(context.Context, sharedmodels.Scope, string) ([]*models.Score, error)
+
+	ExportUserScores(ctx context.Context, userID string) (map[string]map[string]string, error)
+	DeleteUserScores(ctx context.Context, userID string) error
+	IndexUserLeaderboards(ctx context.Context) (int, error)
 }
 
 // Service contains all dependencies to perform common service tasks.
@@ -24,6 +31,8 @@ type Service struct {
 	redisRepo RedisRepository
 
 	Geo *geo.DB
+	// Players checks users of server scores.
+	Players auth.PlayerChecker
 
 	logger *zap.SugaredLogger
 }
@@ -13,6 +13,7 @@ import (
 	"gitlab.com/balconygames/analytics/modules/leaderboard/internal/db"
 	"gitlab.com/balconygames/analytics/modules/leaderboard/internal/handlers"
 	"gitlab.com/balconygames/analytics/modules/leaderboard/internal/service"
+	"gitlab.com/balconygames/analytics/pkg/auth"
 	"gitlab.com/balconygames/analytics/pkg/geo"
 	"gitlab.com/balconygames/analytics/pkg/logging"
 	"gitlab.com/balconygames/analytics/pkg/postgres"
@@ -75,8 +76,15 @@ func withSpec(r *runtime.Runtime, s spec) error {
 	redisRepo := db.NewRedisRepository(redisConn, logger)
 
 	svc := service.NewService(repo, redisRepo, geoResolver, logger)
+	svc.Players = r.Players()
 	h := handlers.New(svc, logger)
 
+	// scores contain ip address of players
+	r.WithPersonalData("leaderboard", svc.PersonalDataProvider())
+
+	// indexes leaderboards of users set before the index, should be run once
+	r.WithCommand("leaderboard.index", indexCommand(svc))
+
 	r.WithRoutes(func(r1 chi.Router) {
 		r.WithClientAuth(r1, func(r2 chi.Router) {
 			r2.Post("/leaderboard/v1/scores", h.CreateScores)
@@ -85,8 +93,21 @@ func withSpec(r *runtime.Runtime, s spec) error {
 
 		// Server API would be used by dashboard layer
 		r.WithServerAuth(r1, func(r2 chi.Router) {
-			r1.Post("/leaderboard/v1/leaderboards", h.CreateLeaderboards)
-			r1.Get("/leaderboard/v1/leaderboards/list", h.ListLeaderboards)
+			r2.Group(func(i chi.Router) {
+				i.Use(auth.RequireRoles(auth.RoleReadOnly))
+				i.Get("/leaderboard/v1/leaderboards/list", h.ListLeaderboards)
+			})
+
+			r2.Group(func(i chi.Router) {
+				i.Use(auth.RequireRoles(auth.RoleLiveOps))
+				i.Post("/leaderboard/v1/leaderboards", h.CreateLeaderboards)
+			})
+
+			// game servers set scores of players with API keys
+			r2.Group(func(i chi.Router) {
+				i.Use(auth.RequireRoleOrScope(auth.RoleLiveOps, auth.ScopeLeaderboardWrite))
+				i.Post("/leaderboard/v1/games/{game_id}/apps/{app_id}/users/{user_id}/scores", h.ServerCreateScores)
+			})
 		})
 	})
 
@@ -39,6 +39,12 @@ func (h *Handler) PixelHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// variants are stamped by the server, clients can't change them
+	pr.Experiments, err = h.service.Experiments(r.Context(), scope)
+	if err != nil {
+		h.service.Logger.With(scope.Fields()...).Errorf("can't assign experiments: %s", err)
+	}
+
 	err = h.service.Push(pr)
 	if err != nil {
 		httpreq.Error(w, errors.Wrap(err, "can't push message"))
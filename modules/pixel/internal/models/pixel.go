@@ -9,4 +9,8 @@ type Pixel struct {
 	sharedmodels.Scope
 
 	Data map[string]string `json:"data"`
+
+	// Experiments are variants of experiments assigned to the user
+	// by experiment key, used to compare conversion per variant.
+	Experiments map[string]string `json:"experiments,omitempty"`
 }
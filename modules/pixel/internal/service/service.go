@@ -1,24 +1,36 @@
 package service
 
 import (
+	"context"
+
 	"go.uber.org/zap"
 
 	"gitlab.com/balconygames/analytics/modules/pixel/internal/mq"
+	"gitlab.com/balconygames/analytics/pkg/experiments"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
 )
 
 // Service contains all dependencies to perform common service tasks.
 type Service struct {
 	Logger *zap.SugaredLogger
 	m      mq.MQable
+
+	experiments experiments.Assigner
 }
 
-func NewService(l *zap.SugaredLogger, m mq.MQable) *Service {
+func NewService(l *zap.SugaredLogger, m mq.MQable, e experiments.Assigner) *Service {
 	return &Service{
-		Logger: l,
-		m:      m,
+		Logger:      l,
+		m:           m,
+		experiments: e,
 	}
 }
 
+// Experiments respond with variants of experiments assigned to the user.
+func (s Service) Experiments(ctx context.Context, scope *sharedmodels.Scope) (map[string]string, error) {
+	return s.experiments.Assign(ctx, scope)
+}
+
 // Push should send message to message queue
 // it should be in async way handled
 func (s Service) Push(msg interface{}) error {
@@ -46,7 +46,8 @@ func withSpec(r *runtime.Runtime, s spec) error {
 	}
 	r.WithClosable(m)
 
-	svc := service.NewService(r.Logger, m)
+	// experiments are assigned by auth module
+	svc := service.NewService(r.Logger, m, r.Experiments())
 	h := handlers.New(svc)
 
 	r.WithRoutes(func(r1 chi.Router) {
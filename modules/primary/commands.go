@@ -0,0 +1,97 @@
+package primary
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"gitlab.com/balconygames/analytics/modules/primary/internal/service"
+	"gitlab.com/balconygames/analytics/pkg/runtime"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// rotateCredentialsCommand re-encrypts secrets of network credentials
+// with the current key, previous keys could be removed after it:
+//
+//	analytics primary.credentials.rotate
+func rotateCredentialsCommand(svc *service.Service) runtime.CommandFunc {
+	return func(ctx context.Context, args []string) error {
+		rotated, err := svc.RotateCredentials(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("rotated secrets: %d\n", rotated)
+
+		return nil
+	}
+}
+
+// importCredentialsCommand encrypts plain text secrets of deprecated
+// networks table:
+//
+//	analytics primary.credentials.import
+func importCredentialsCommand(svc *service.Service) runtime.CommandFunc {
+	return func(ctx context.Context, args []string) error {
+		imported, err := svc.ImportLegacyNetworks(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("imported credentials: %d\n", imported)
+
+		return nil
+	}
+}
+
+// createOrganisationCommand creates organisation without signed in
+// platform operator, operators are invited into it by auth.invite:
+//
+//	analytics primary.organisations.create -name=Studio
+func createOrganisationCommand(svc *service.Service) runtime.CommandFunc {
+	return func(ctx context.Context, args []string) error {
+		flags := flag.NewFlagSet("primary.organisations.create", flag.ContinueOnError)
+		name := flags.String("name", "", "name of organisation")
+
+		err := flags.Parse(args)
+		if err != nil {
+			return err
+		}
+
+		o := &sharedmodels.Organisation{Name: *name}
+		err = svc.CreateOrganisation(ctx, o)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("organisation: %s\n", o.OrganisationID)
+
+		return nil
+	}
+}
+
+// assignGameCommand moves the game to organisation, used to assign
+// owners of games created before organisations:
+//
+//	analytics primary.games.assign -game=<game id> -organisation=<organisation id>
+func assignGameCommand(svc *service.Service) runtime.CommandFunc {
+	return func(ctx context.Context, args []string) error {
+		flags := flag.NewFlagSet("primary.games.assign", flag.ContinueOnError)
+		gameID := flags.String("game", "", "game id")
+		organisationID := flags.String("organisation", "", "organisation id")
+
+		err := flags.Parse(args)
+		if err != nil {
+			return err
+		}
+
+		err = svc.AssignGame(ctx, *gameID, *organisationID)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("game %s is assigned to organisation %s\n", *gameID, *organisationID)
+
+		return nil
+	}
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/jackc/pgx/v4"
+
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+const selectAPIKeysQuery = `
+	SELECT
+		id
+		, game_id
+		, app_id
+		, name
+		, prefix
+		, key_hash
+		, scopes
+		, created_by
+		, created_at
+		, expires_at
+		, last_used_at
+		, revoked_at
+	FROM api_keys
+`
+
+func scanAPIKey(row pgx.Row) (*sharedmodels.APIKey, error) {
+	k := &sharedmodels.APIKey{}
+
+	var scopes []byte
+	err := row.Scan(&k.ID, &k.GameID, &k.AppID, &k.Name, &k.Prefix, &k.KeyHash, &scopes,
+		&k.CreatedBy, &k.CreatedAt, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return k, json.Unmarshal(scopes, &k.Scopes)
+}
+
+// ListAPIKeys respond with API keys of the game including revoked
+// keys, the latest keys go first.
+func (r PostgresRepository) ListAPIKeys(ctx context.Context, gameID string) ([]*sharedmodels.APIKey, error) {
+	rows, err := r.pool.Query(ctx, selectAPIKeysQuery+`
+		WHERE game_id=$1
+		ORDER BY created_at DESC
+		LIMIT 100
+	`, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*sharedmodels.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, k)
+	}
+
+	return list, rows.Err()
+}
+
+// GetAPIKeyByHash respond with API key by hash of the key,
+// pgx.ErrNoRows is returned for unknown keys.
+func (r PostgresRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*sharedmodels.APIKey, error) {
+	return scanAPIKey(r.pool.QueryRow(ctx, selectAPIKeysQuery+`
+		WHERE key_hash=$1
+	`, keyHash))
+}
+
+// CreateAPIKey stores the new API key of the game, only hash
+// of the key is stored.
+func (r PostgresRepository) CreateAPIKey(ctx context.Context, k *sharedmodels.APIKey) error {
+	scopes, err := json.Marshal(k.Scopes)
+	if err != nil {
+		return err
+	}
+
+	k.ID, err = uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO
+			api_keys (
+				id
+				, game_id
+				, app_id
+				, name
+				, prefix
+				, key_hash
+				, scopes
+				, created_by
+				, expires_at
+			)
+			VALUES (
+				$1
+				, $2
+				, $3
+				, $4
+				, $5
+				, $6
+				, $7
+				, $8
+				, $9
+			)
+		RETURNING created_at
+	`
+
+	return r.pool.QueryRow(ctx, query, k.ID, k.GameID, k.AppID, k.Name, k.Prefix, k.KeyHash,
+		scopes, k.CreatedBy, k.ExpiresAt).Scan(&k.CreatedAt)
+}
+
+// RevokeAPIKey marks API key of the game as revoked, pgx.ErrNoRows
+// is returned for unknown or already revoked keys.
+func (r PostgresRepository) RevokeAPIKey(ctx context.Context, gameID, id string, at time.Time) (*sharedmodels.APIKey, error) {
+	return scanAPIKey(r.pool.QueryRow(ctx, `
+		UPDATE api_keys
+		SET revoked_at = $3
+		WHERE
+			game_id=$1
+			AND id=$2
+			AND revoked_at IS NULL
+		RETURNING
+			id
+			, game_id
+			, app_id
+			, name
+			, prefix
+			, key_hash
+			, scopes
+			, created_by
+			, created_at
+			, expires_at
+			, last_used_at
+			, revoked_at
+	`, gameID, id, at))
+}
+
+// TouchAPIKey records the time the key was used.
+func (r PostgresRepository) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	_, err := r.pool.Exec(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id=$1`, id, at)
+	return err
+}
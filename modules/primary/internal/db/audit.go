@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gitlab.com/balconygames/analytics/pkg/audit"
+)
+
+// InsertAuditEntry stores audit entry of server mutation.
+func (r PostgresRepository) InsertAuditEntry(ctx context.Context, e *audit.Entry) error {
+	changes, err := json.Marshal(e.Changes)
+	if err != nil {
+		return err
+	}
+	if e.Changes == nil {
+		changes = []byte("{}")
+	}
+
+	query := `
+		INSERT INTO
+			audit_log (
+				actor_id
+				, actor_type
+				, action
+				, game_id
+				, target_type
+				, target_id
+				, changes
+				, ip
+				, request_id
+				, created_at
+			)
+			VALUES (
+				$1
+				, $2
+				, $3
+				, $4
+				, $5
+				, $6
+				, $7
+				, $8
+				, $9
+				, $10
+			)
+		RETURNING id
+	`
+
+	return r.pool.QueryRow(ctx, query, e.ActorID, e.ActorType, e.Action, e.GameID, e.TargetType,
+		e.TargetID, changes, e.IP, e.RequestID, e.CreatedAt).Scan(&e.ID)
+}
+
+// ListAuditEntries respond with audit entries matching the filter,
+// the latest entries go first.
+func (r PostgresRepository) ListAuditEntries(ctx context.Context, f audit.Filter) ([]*audit.Entry, error) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if f.GameID != "" {
+		where("game_id=$%d", f.GameID)
+	}
+	if f.ActorID != "" {
+		where("actor_id=$%d", f.ActorID)
+	}
+	if f.Action != "" {
+		where("action=$%d", f.Action)
+	}
+	if f.TargetType != "" {
+		where("target_type=$%d", f.TargetType)
+	}
+	if f.TargetID != "" {
+		where("target_id=$%d", f.TargetID)
+	}
+	if f.Since != nil {
+		where("created_at >= $%d", f.Since.UTC())
+	}
+	if f.Until != nil {
+		where("created_at < $%d", f.Until.UTC())
+	}
+	if f.BeforeID > 0 {
+		where("id < $%d", f.BeforeID)
+	}
+
+	query := `
+		SELECT
+			id
+			, actor_id
+			, actor_type
+			, action
+			, game_id
+			, target_type
+			, target_id
+			, changes
+			, ip
+			, request_id
+			, created_at
+		FROM audit_log
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, f.Limit)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*audit.Entry{}
+	for rows.Next() {
+		e := &audit.Entry{}
+
+		var changes []byte
+		err = rows.Scan(&e.ID, &e.ActorID, &e.ActorType, &e.Action, &e.GameID, &e.TargetType,
+			&e.TargetID, &changes, &e.IP, &e.RequestID, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(changes, &e.Changes)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, e)
+	}
+
+	return list, rows.Err()
+}
@@ -0,0 +1,208 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+const selectCredentialsQuery = `
+	SELECT
+		game_id
+		, network
+		, client_id
+		, config
+		, secret
+		, created_at
+		, updated_at
+	FROM network_credentials
+`
+
+// scanCredentials reads credentials with encrypted secret.
+func scanCredentials(row pgx.Row) (*sharedmodels.NetworkCredentials, error) {
+	c := &sharedmodels.NetworkCredentials{}
+
+	var config []byte
+	err := row.Scan(&c.GameID, &c.Network, &c.ClientID, &config, &c.Secret, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	c.HasSecret = c.Secret != ""
+
+	return c, json.Unmarshal(config, &c.Config)
+}
+
+func (r PostgresRepository) queryCredentials(ctx context.Context, query string, args ...interface{}) ([]*sharedmodels.NetworkCredentials, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*sharedmodels.NetworkCredentials{}
+	for rows.Next() {
+		c, err := scanCredentials(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, c)
+	}
+
+	return list, rows.Err()
+}
+
+// ListCredentials respond with credentials of the game with
+// encrypted secrets.
+func (r PostgresRepository) ListCredentials(ctx context.Context, gameID string) ([]*sharedmodels.NetworkCredentials, error) {
+	return r.queryCredentials(ctx, selectCredentialsQuery+`
+		WHERE game_id=$1
+		ORDER BY network, client_id
+	`, gameID)
+}
+
+// ListAllCredentials respond with credentials of all games with
+// encrypted secrets, used to rotate encryption key.
+func (r PostgresRepository) ListAllCredentials(ctx context.Context) ([]*sharedmodels.NetworkCredentials, error) {
+	return r.queryCredentials(ctx, selectCredentialsQuery+`
+		ORDER BY game_id, network, client_id
+	`)
+}
+
+// GetCredentials respond with credentials of the game in the network
+// with encrypted secret, the first client is used for empty client id.
+// pgx.ErrNoRows is returned for unknown credentials.
+func (r PostgresRepository) GetCredentials(ctx context.Context, gameID, network, clientID string) (*sharedmodels.NetworkCredentials, error) {
+	row := r.pool.QueryRow(ctx, selectCredentialsQuery+`
+		WHERE
+			game_id=$1
+			AND network=$2
+			AND ($3 = '' OR client_id=$3)
+		ORDER BY client_id
+		LIMIT 1
+	`, gameID, network, clientID)
+
+	return scanCredentials(row)
+}
+
+// UpsertCredentials creates or updates credentials of the game,
+// secret should be encrypted.
+func (r PostgresRepository) UpsertCredentials(ctx context.Context, c *sharedmodels.NetworkCredentials) error {
+	config, err := json.Marshal(c.Config)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO
+			network_credentials (
+				game_id
+				, network
+				, client_id
+				, config
+				, secret
+			)
+			VALUES (
+				$1
+				, $2
+				, $3
+				, $4
+				, $5
+			)
+		ON CONFLICT (
+			game_id
+			, network
+			, client_id
+		)
+		DO UPDATE SET
+			config = EXCLUDED.config
+			, secret = EXCLUDED.secret
+			, updated_at = NOW()
+		RETURNING created_at, updated_at
+	`
+
+	return r.pool.QueryRow(ctx, query, c.GameID, c.Network, c.ClientID, config, c.Secret).
+		Scan(&c.CreatedAt, &c.UpdatedAt)
+}
+
+// ReplaceCredentialsSecret sets re-encrypted secret once the secret
+// is not changed since it was read.
+func (r PostgresRepository) ReplaceCredentialsSecret(ctx context.Context, c *sharedmodels.NetworkCredentials, previous string) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE network_credentials
+		SET secret = $5
+		WHERE
+			game_id=$1
+			AND network=$2
+			AND client_id=$3
+			AND secret=$4
+	`, c.GameID, c.Network, c.ClientID, previous, c.Secret)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// DeleteCredentials removes credentials of the game,
+// pgx.ErrNoRows is returned for unknown credentials.
+func (r PostgresRepository) DeleteCredentials(ctx context.Context, gameID, network, clientID string) error {
+	tag, err := r.pool.Exec(ctx, `
+		DELETE FROM network_credentials
+		WHERE
+			game_id=$1
+			AND network=$2
+			AND client_id=$3
+	`, gameID, network, clientID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// ListLegacyNetworks respond with plain text credentials stored
+// in deprecated networks table.
+func (r PostgresRepository) ListLegacyNetworks(ctx context.Context) ([]*sharedmodels.NetworkCredentials, error) {
+	rows, err := r.pool.Query(ctx, `SELECT game_id, type_name, id, secret FROM networks ORDER BY game_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*sharedmodels.NetworkCredentials{}
+	for rows.Next() {
+		c := &sharedmodels.NetworkCredentials{Config: map[string]string{}}
+		err = rows.Scan(&c.GameID, &c.Network, &c.ClientID, &c.Secret)
+		if err != nil {
+			return nil, err
+		}
+
+		c.Network = strings.ToLower(c.Network)
+		list = append(list, c)
+	}
+
+	return list, rows.Err()
+}
+
+// DeleteLegacyNetwork removes plain text credentials once they are
+// imported, type name is matched ignoring case.
+func (r PostgresRepository) DeleteLegacyNetwork(ctx context.Context, c *sharedmodels.NetworkCredentials) error {
+	_, err := r.pool.Exec(ctx, `
+		DELETE FROM networks
+		WHERE
+			game_id=$1
+			AND LOWER(type_name)=$2
+			AND id=$3
+	`, c.GameID, c.Network, c.ClientID)
+	return err
+}
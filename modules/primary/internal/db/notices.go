@@ -0,0 +1,289 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/jackc/pgx/v4"
+
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// ListMaintenance respond with maintenance windows of the game
+// ended after the time, the latest windows go first.
+func (r PostgresRepository) ListMaintenance(ctx context.Context, gameID string, endsAfter time.Time) ([]*sharedmodels.Maintenance, error) {
+	query := `
+		SELECT
+			id
+			, game_id
+			, app_id
+			, starts_at
+			, ends_at
+			, messages
+			, created_at
+			, updated_at
+		FROM maintenance_windows
+		WHERE
+			game_id=$1
+			AND ends_at > $2
+		ORDER BY starts_at DESC
+		LIMIT 100
+	`
+
+	rows, err := r.pool.Query(ctx, query, gameID, endsAfter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*sharedmodels.Maintenance{}
+	for rows.Next() {
+		m := &sharedmodels.Maintenance{}
+
+		var messages []byte
+		err = rows.Scan(&m.ID, &m.GameID, &m.AppID, &m.StartsAt, &m.EndsAt,
+			&messages, &m.CreatedAt, &m.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(messages, &m.Messages)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, m)
+	}
+
+	return list, rows.Err()
+}
+
+// CreateMaintenance stores the new maintenance window of the game.
+func (r PostgresRepository) CreateMaintenance(ctx context.Context, m *sharedmodels.Maintenance) error {
+	messages, err := json.Marshal(m.Messages)
+	if err != nil {
+		return err
+	}
+
+	m.ID, err = uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO
+			maintenance_windows (
+				id
+				, game_id
+				, app_id
+				, starts_at
+				, ends_at
+				, messages
+			)
+			VALUES (
+				$1
+				, $2
+				, $3
+				, $4
+				, $5
+				, $6
+			)
+		RETURNING created_at, updated_at
+	`
+
+	return r.pool.QueryRow(ctx, query, m.ID, m.GameID, m.AppID, m.StartsAt, m.EndsAt, messages).
+		Scan(&m.CreatedAt, &m.UpdatedAt)
+}
+
+// UpdateMaintenance replaces the maintenance window of the game,
+// pgx.ErrNoRows is returned for unknown window.
+func (r PostgresRepository) UpdateMaintenance(ctx context.Context, m *sharedmodels.Maintenance) error {
+	messages, err := json.Marshal(m.Messages)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE maintenance_windows
+		SET
+			app_id = $3
+			, starts_at = $4
+			, ends_at = $5
+			, messages = $6
+			, updated_at = NOW()
+		WHERE
+			id=$1
+			AND game_id=$2
+		RETURNING created_at, updated_at
+	`
+
+	return r.pool.QueryRow(ctx, query, m.ID, m.GameID, m.AppID, m.StartsAt, m.EndsAt, messages).
+		Scan(&m.CreatedAt, &m.UpdatedAt)
+}
+
+// DeleteMaintenance removes the maintenance window of the game.
+func (r PostgresRepository) DeleteMaintenance(ctx context.Context, gameID, id string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM maintenance_windows WHERE id=$1 AND game_id=$2`, id, gameID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// ListAnnouncements respond with announcements of the game not expired
+// at the time, announcements with higher priority go first.
+func (r PostgresRepository) ListAnnouncements(ctx context.Context, gameID string, expiresAfter time.Time) ([]*sharedmodels.Announcement, error) {
+	query := `
+		SELECT
+			id
+			, game_id
+			, app_id
+			, priority
+			, titles
+			, messages
+			, starts_at
+			, expires_at
+			, created_at
+			, updated_at
+		FROM announcements
+		WHERE
+			game_id=$1
+			AND (expires_at IS NULL OR expires_at > $2)
+		ORDER BY priority DESC, starts_at DESC
+		LIMIT 100
+	`
+
+	rows, err := r.pool.Query(ctx, query, gameID, expiresAfter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*sharedmodels.Announcement{}
+	for rows.Next() {
+		a := &sharedmodels.Announcement{}
+
+		var titles, messages []byte
+		err = rows.Scan(&a.ID, &a.GameID, &a.AppID, &a.Priority, &titles, &messages,
+			&a.StartsAt, &a.ExpiresAt, &a.CreatedAt, &a.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(titles, &a.Titles)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(messages, &a.Messages)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, a)
+	}
+
+	return list, rows.Err()
+}
+
+// CreateAnnouncement stores the new announcement of the game.
+func (r PostgresRepository) CreateAnnouncement(ctx context.Context, a *sharedmodels.Announcement) error {
+	titles, err := json.Marshal(a.Titles)
+	if err != nil {
+		return err
+	}
+
+	messages, err := json.Marshal(a.Messages)
+	if err != nil {
+		return err
+	}
+
+	a.ID, err = uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO
+			announcements (
+				id
+				, game_id
+				, app_id
+				, priority
+				, titles
+				, messages
+				, starts_at
+				, expires_at
+			)
+			VALUES (
+				$1
+				, $2
+				, $3
+				, $4
+				, $5
+				, $6
+				, $7
+				, $8
+			)
+		RETURNING created_at, updated_at
+	`
+
+	return r.pool.QueryRow(ctx, query, a.ID, a.GameID, a.AppID, a.Priority,
+		titles, messages, a.StartsAt, a.ExpiresAt).
+		Scan(&a.CreatedAt, &a.UpdatedAt)
+}
+
+// UpdateAnnouncement replaces the announcement of the game,
+// pgx.ErrNoRows is returned for unknown announcement.
+func (r PostgresRepository) UpdateAnnouncement(ctx context.Context, a *sharedmodels.Announcement) error {
+	titles, err := json.Marshal(a.Titles)
+	if err != nil {
+		return err
+	}
+
+	messages, err := json.Marshal(a.Messages)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE announcements
+		SET
+			app_id = $3
+			, priority = $4
+			, titles = $5
+			, messages = $6
+			, starts_at = $7
+			, expires_at = $8
+			, updated_at = NOW()
+		WHERE
+			id=$1
+			AND game_id=$2
+		RETURNING created_at, updated_at
+	`
+
+	return r.pool.QueryRow(ctx, query, a.ID, a.GameID, a.AppID, a.Priority,
+		titles, messages, a.StartsAt, a.ExpiresAt).
+		Scan(&a.CreatedAt, &a.UpdatedAt)
+}
+
+// DeleteAnnouncement removes the announcement of the game.
+func (r PostgresRepository) DeleteAnnouncement(ctx context.Context, gameID, id string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM announcements WHERE id=$1 AND game_id=$2`, id, gameID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/jackc/pgx/v4"
+
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// CreateOrganisation stores the new organisation.
+func (r PostgresRepository) CreateOrganisation(ctx context.Context, o *sharedmodels.Organisation) error {
+	var err error
+
+	o.OrganisationID, err = uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO
+			organisations (
+				organisation_id
+				, name
+			)
+			VALUES (
+				$1
+				, $2
+			)
+		RETURNING created_at, updated_at
+	`
+
+	return r.pool.QueryRow(ctx, query, o.OrganisationID, o.Name).Scan(&o.CreatedAt, &o.UpdatedAt)
+}
+
+// EnsureOrganisation stores organisation with the passed id
+// unless it exists.
+func (r PostgresRepository) EnsureOrganisation(ctx context.Context, o *sharedmodels.Organisation) error {
+	query := `
+		INSERT INTO
+			organisations (
+				organisation_id
+				, name
+			)
+			VALUES (
+				$1
+				, $2
+			)
+		ON CONFLICT (organisation_id) DO NOTHING
+	`
+
+	_, err := r.pool.Exec(ctx, query, o.OrganisationID, o.Name)
+	return err
+}
+
+// ListOrganisations respond with organisations by ids,
+// all organisations are returned for empty ids.
+func (r PostgresRepository) ListOrganisations(ctx context.Context, ids []string) ([]*sharedmodels.Organisation, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT
+			organisation_id
+			, name
+			, created_at
+			, updated_at
+		FROM organisations
+		WHERE
+			COALESCE(cardinality($1::text[]), 0) = 0
+			OR organisation_id = ANY($1)
+		ORDER BY name
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*sharedmodels.Organisation{}
+	for rows.Next() {
+		o := &sharedmodels.Organisation{}
+		err = rows.Scan(&o.OrganisationID, &o.Name, &o.CreatedAt, &o.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, o)
+	}
+
+	return list, rows.Err()
+}
+
+// ListGames respond with games of the organisation, games of all
+// organisations are returned for empty organisation.
+func (r PostgresRepository) ListGames(ctx context.Context, organisationID string) ([]*sharedmodels.Game, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT
+			game_id
+			, COALESCE(organisation_id, '')
+			, name
+			, created_at
+			, updated_at
+		FROM games
+		WHERE
+			$1 = ''
+			OR organisation_id = $1
+		ORDER BY name
+	`, organisationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*sharedmodels.Game{}
+	for rows.Next() {
+		g := &sharedmodels.Game{}
+		err = rows.Scan(&g.GameID, &g.OrganisationID, &g.Name, &g.CreatedAt, &g.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, g)
+	}
+
+	return list, rows.Err()
+}
+
+// GetGameOrganisation respond with owner of the game, empty for games
+// without owner. pgx.ErrNoRows is returned for unknown games.
+func (r PostgresRepository) GetGameOrganisation(ctx context.Context, gameID string) (string, error) {
+	var organisationID string
+
+	err := r.pool.QueryRow(ctx, `
+		SELECT COALESCE(organisation_id, '') FROM games WHERE game_id=$1
+	`, gameID).Scan(&organisationID)
+
+	return organisationID, err
+}
+
+// AssignGame sets owner of the game, pgx.ErrNoRows is returned
+// for unknown games.
+func (r PostgresRepository) AssignGame(ctx context.Context, gameID, organisationID string) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE games
+		SET
+			organisation_id = $2
+			, updated_at = NOW()
+		WHERE game_id=$1
+	`, gameID, organisationID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/hashicorp/go-uuid"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 
 	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
@@ -25,10 +26,12 @@ func (r PostgresRepository) CreateGame(ctx context.Context, game *sharedmodels.G
 			games (
 				game_id
 				, name
+				, organisation_id
 			)
 			VALUES (
 				$1
 				, $2
+				, NULLIF($3, '')
 			)
 		ON CONFLICT (
 			game_id
@@ -50,7 +53,7 @@ func (r PostgresRepository) CreateGame(ctx context.Context, game *sharedmodels.G
 		game.GameID = uuid
 	}
 
-	row := r.pool.QueryRow(ctx, query, game.GameID, game.Name)
+	row := r.pool.QueryRow(ctx, query, game.GameID, game.Name, game.OrganisationID)
 	err = row.Scan(&game.GameID)
 	if err != nil {
 		return err
@@ -131,6 +134,9 @@ func (r PostgresRepository) GetAppInfo(ctx context.Context, app *sharedmodels.Ap
 			, created_at
 			, updated_at
 			, force_update_enabled
+			, min_version
+			, recommended_version
+			, store_url
 		FROM apps
 		WHERE
 			game_id=$1
@@ -139,10 +145,38 @@ func (r PostgresRepository) GetAppInfo(ctx context.Context, app *sharedmodels.Ap
 
 	row := r.pool.QueryRow(ctx, query, app.GameID, app.AppID)
 	err = row.Scan(&app.GameID, &app.AppID, &app.Platform, &app.Market, &app.DeviceType,
-		&app.Version, &app.CreatedAt, &app.UpdatedAt, &app.ForceUpdateEnabled)
+		&app.Version, &app.CreatedAt, &app.UpdatedAt, &app.ForceUpdateEnabled,
+		&app.MinVersion, &app.RecommendedVersion, &app.StoreURL)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// UpdateAppVersions sets version rules of the app.
+func (r PostgresRepository) UpdateAppVersions(ctx context.Context, app *sharedmodels.App) error {
+	query := `
+		UPDATE apps
+		SET
+			min_version = $3
+			, recommended_version = $4
+			, store_url = $5
+			, updated_at = NOW()
+		WHERE
+			game_id=$1
+			AND app_id=$2
+	`
+
+	tag, err := r.pool.Exec(ctx, query, app.GameID, app.AppID,
+		app.MinVersion, app.RecommendedVersion, app.StoreURL)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/primary/internal/service"
+	"gitlab.com/balconygames/analytics/pkg/audit"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+type apiKeyRequest struct {
+	AppID     string     `json:"app_id"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// ListAPIKeys respond with API keys of the game, keys themselves
+// are never returned.
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	list, err := h.service.ListAPIKeys(r.Context(), chi.URLParam(r, "game_id"))
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't list api keys"))
+		return
+	}
+
+	httpreq.JSON(w, map[string]interface{}{"api_keys": list})
+}
+
+// CreateAPIKey generates API key of the game, the key is responded
+// only once.
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	data := apiKeyRequest{}
+	err := httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read api key body"))
+		return
+	}
+
+	k := &sharedmodels.APIKey{
+		GameID:    chi.URLParam(r, "game_id"),
+		AppID:     data.AppID,
+		Name:      data.Name,
+		Scopes:    data.Scopes,
+		ExpiresAt: data.ExpiresAt,
+		CreatedBy: auth.GetUser(r).UserID,
+	}
+
+	err = h.service.CreateAPIKey(r.Context(), k)
+	if err == service.ErrAppNotFound {
+		notFound(w, err)
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't create api key"))
+		return
+	}
+
+	h.logger.
+		With("game_id", k.GameID, "app_id", k.AppID, "api_key_id", k.ID, "created_by", k.CreatedBy).
+		Infof("created api key with scopes %v", k.Scopes)
+
+	// the key itself is never audited
+	audit.SetTarget(r, "api_key", k.ID)
+	audit.SetChanges(r, nil, data)
+
+	httpreq.JSON(w, k)
+}
+
+// RevokeAPIKey rejects further requests of API key.
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "game_id")
+	id := chi.URLParam(r, "api_key_id")
+
+	err := h.service.RevokeAPIKey(r.Context(), gameID, id)
+	if err == service.ErrAPIKeyNotFound {
+		notFound(w, err)
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't revoke api key"))
+		return
+	}
+
+	h.logger.With("game_id", gameID, "api_key_id", id, "revoked_by", auth.GetUser(r).UserID).Info("revoked api key")
+
+	httpreq.OK(w)
+}
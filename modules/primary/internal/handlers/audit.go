@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/pkg/audit"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+)
+
+type auditLogResponse struct {
+	Entries []*audit.Entry `json:"entries"`
+	// NextCursor is passed as cursor to get the next page,
+	// it's empty on the last page.
+	NextCursor string `json:"next_cursor"`
+}
+
+func parseTime(query url.Values, name string) (*time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s", name)
+	}
+
+	return &t, nil
+}
+
+func readAuditFilter(r *http.Request) (audit.Filter, error) {
+	query := r.URL.Query()
+
+	f := audit.Filter{
+		GameID:     query.Get("game_id"),
+		ActorID:    query.Get("actor_id"),
+		Action:     query.Get("action"),
+		TargetType: query.Get("target_type"),
+		TargetID:   query.Get("target_id"),
+	}
+
+	var err error
+	f.Since, err = parseTime(query, "since")
+	if err != nil {
+		return f, err
+	}
+	f.Until, err = parseTime(query, "until")
+	if err != nil {
+		return f, err
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		f.BeforeID, err = strconv.ParseInt(cursor, 10, 64)
+		if err != nil {
+			return f, errors.Wrap(err, "invalid cursor")
+		}
+	}
+
+	f.Limit, _ = strconv.Atoi(query.Get("limit"))
+
+	return f, nil
+}
+
+// ListAuditLog respond with audit entries filtered by game_id, actor_id,
+// action, target_type, target_id, since and until query params, pages
+// are requested by cursor of the previous page. Game is required for
+// operators of organisations.
+func (h *Handler) ListAuditLog(w http.ResponseWriter, r *http.Request) {
+	f, err := readAuditFilter(r)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read audit filter"))
+		return
+	}
+
+	// operators of organisations read audit of their games only
+	if !auth.IsPlatformOperator(r) {
+		if f.GameID == "" {
+			httpreq.Error(w, errors.New("game_id is required"))
+			return
+		}
+
+		err = auth.AuthorizeGame(r, f.GameID)
+		if err == auth.ErrForbidden {
+			httpreq.Forbidden(w, err)
+			return
+		}
+		if err != nil {
+			httpreq.Error(w, err)
+			return
+		}
+	}
+
+	entries, next, err := h.service.ListAuditLog(r.Context(), f)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't list audit log"))
+		return
+	}
+
+	response := auditLogResponse{Entries: entries}
+	if next > 0 {
+		response.NextCursor = strconv.FormatInt(next, 10)
+	}
+
+	httpreq.JSON(w, response)
+}
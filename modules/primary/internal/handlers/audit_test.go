@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadAuditFilter(t *testing.T) {
+	req := httptest.NewRequest("GET", "/primary/v1/audit?game_id=game-1&target_type=app&since=2020-08-01T00:00:00Z&cursor=42&limit=10", nil)
+
+	f, err := readAuditFilter(req)
+	require.Nil(t, err)
+	require.Equal(t, "game-1", f.GameID)
+	require.Equal(t, "app", f.TargetType)
+	require.Equal(t, time.Date(2020, 8, 1, 0, 0, 0, 0, time.UTC), *f.Since)
+	require.Nil(t, f.Until)
+	require.Equal(t, int64(42), f.BeforeID)
+	require.Equal(t, 10, f.Limit)
+
+	for _, query := range []string{"since=yesterday", "until=2020-08-01", "cursor=abc"} {
+		_, err = readAuditFilter(httptest.NewRequest("GET", "/primary/v1/audit?"+query, nil))
+		require.NotNil(t, err, query)
+	}
+}
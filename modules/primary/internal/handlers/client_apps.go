@@ -8,6 +8,7 @@ import (
 	"go.uber.org/zap"
 
 	"gitlab.com/balconygames/analytics/modules/primary/internal/service"
+	"gitlab.com/balconygames/analytics/pkg/auth"
 	httpreq "gitlab.com/balconygames/analytics/pkg/http"
 	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
 )
@@ -32,7 +33,17 @@ func (h *Handler) GetAppInfo(w http.ResponseWriter, r *http.Request) {
 	app.AppID = chi.URLParam(r, "app_id")
 	app.GameID = chi.URLParam(r, "game_id")
 
-	err = h.service.GetAppInfo(r.Context(), app)
+	// client version could be passed by query param or by header
+	version := r.URL.Query().Get("version")
+	if version == "" {
+		version = r.Header.Get(auth.ClientVersionHeader)
+	}
+
+	err = h.service.GetAppInfo(r.Context(), app, version, httpreq.Locale(r))
+	if errors.Is(err, service.ErrAppNotFound) {
+		notFound(w, err)
+		return
+	}
 	if err != nil {
 		httpreq.Error(w, errors.Wrap(err, "can't process get app info request using service"))
 		return
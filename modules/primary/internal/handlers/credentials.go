@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/pkg/audit"
+	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+	"gitlab.com/balconygames/analytics/pkg/secrets"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+type credentialsRequest struct {
+	Config map[string]string `json:"config"`
+	// Secret is kept as is once it's not passed, empty secret removes it.
+	Secret *string `json:"secret"`
+}
+
+// ListCredentials respond with network credentials of the game, secrets
+// are never returned.
+func (h *Handler) ListCredentials(w http.ResponseWriter, r *http.Request) {
+	list, err := h.service.ListCredentials(r.Context(), chi.URLParam(r, "game_id"))
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't list credentials"))
+		return
+	}
+
+	httpreq.JSON(w, map[string]interface{}{"networks": list})
+}
+
+// UpdateCredentials creates or updates credentials of the game in the
+// network, secret is encrypted before it's stored.
+func (h *Handler) UpdateCredentials(w http.ResponseWriter, r *http.Request) {
+	data := credentialsRequest{}
+	err := httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read credentials body"))
+		return
+	}
+
+	c := &sharedmodels.NetworkCredentials{
+		GameID:   chi.URLParam(r, "game_id"),
+		Network:  chi.URLParam(r, "network"),
+		ClientID: chi.URLParam(r, "client_id"),
+		Config:   data.Config,
+	}
+
+	err = h.service.UpdateCredentials(r.Context(), c, data.Secret)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't update credentials"))
+		return
+	}
+
+	h.logger.
+		With("game_id", c.GameID, "network", c.Network, "client_id", c.ClientID).
+		Infof("updated credentials, secret changed: %t", data.Secret != nil)
+
+	// secrets are never audited, only the fact of change
+	audit.SetTarget(r, "network_credentials", c.Network+":"+c.ClientID)
+	audit.SetChanges(r, nil, map[string]interface{}{"config": c.Config, "secret_changed": data.Secret != nil})
+
+	httpreq.JSON(w, c)
+}
+
+// DeleteCredentials removes credentials of the game in the network.
+func (h *Handler) DeleteCredentials(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "game_id")
+	network := chi.URLParam(r, "network")
+	clientID := chi.URLParam(r, "client_id")
+
+	err := h.service.DeleteCredentials(r.Context(), gameID, network, clientID)
+	if err == secrets.ErrCredentialsNotFound {
+		notFound(w, err)
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't delete credentials"))
+		return
+	}
+
+	h.logger.With("game_id", gameID, "network", network, "client_id", clientID).Info("deleted credentials")
+
+	httpreq.OK(w)
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/primary/internal/service"
+	"gitlab.com/balconygames/analytics/pkg/audit"
+	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+type maintenanceRequest struct {
+	AppID    string                `json:"app_id"`
+	StartsAt time.Time             `json:"starts_at"`
+	EndsAt   time.Time             `json:"ends_at"`
+	Messages sharedmodels.Messages `json:"messages"`
+}
+
+type announcementRequest struct {
+	AppID     string                `json:"app_id"`
+	Priority  int                   `json:"priority"`
+	Titles    sharedmodels.Messages `json:"titles"`
+	Messages  sharedmodels.Messages `json:"messages"`
+	StartsAt  time.Time             `json:"starts_at"`
+	ExpiresAt *time.Time            `json:"expires_at"`
+}
+
+func notFound(w http.ResponseWriter, err error) {
+	httpreq.JSONWithStatus(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+}
+
+// ListMaintenance respond with maintenance windows of the game.
+func (h *Handler) ListMaintenance(w http.ResponseWriter, r *http.Request) {
+	list, err := h.service.ListMaintenance(r.Context(), chi.URLParam(r, "game_id"))
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't list maintenance"))
+		return
+	}
+
+	httpreq.JSON(w, map[string]interface{}{"maintenance": list})
+}
+
+func readMaintenance(r *http.Request) (*sharedmodels.Maintenance, error) {
+	data := maintenanceRequest{}
+	err := httpreq.Read(r, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sharedmodels.Maintenance{
+		ID:       chi.URLParam(r, "maintenance_id"),
+		GameID:   chi.URLParam(r, "game_id"),
+		AppID:    data.AppID,
+		StartsAt: data.StartsAt,
+		EndsAt:   data.EndsAt,
+		Messages: data.Messages,
+	}, nil
+}
+
+// CreateMaintenance schedules maintenance window of the game, client
+// routes respond with 503 while the window is active.
+func (h *Handler) CreateMaintenance(w http.ResponseWriter, r *http.Request) {
+	m, err := readMaintenance(r)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read maintenance body"))
+		return
+	}
+
+	err = h.service.CreateMaintenance(r.Context(), m)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't create maintenance"))
+		return
+	}
+
+	h.logger.
+		With("game_id", m.GameID, "app_id", m.AppID, "maintenance_id", m.ID).
+		Infof("scheduled maintenance from %s to %s", m.StartsAt, m.EndsAt)
+
+	audit.SetTarget(r, "maintenance", m.ID)
+	audit.SetChanges(r, nil, m)
+
+	httpreq.JSON(w, m)
+}
+
+// UpdateMaintenance changes maintenance window of the game.
+func (h *Handler) UpdateMaintenance(w http.ResponseWriter, r *http.Request) {
+	m, err := readMaintenance(r)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read maintenance body"))
+		return
+	}
+
+	err = h.service.UpdateMaintenance(r.Context(), m)
+	if err == service.ErrMaintenanceNotFound {
+		notFound(w, err)
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't update maintenance"))
+		return
+	}
+
+	h.logger.
+		With("game_id", m.GameID, "app_id", m.AppID, "maintenance_id", m.ID).
+		Infof("updated maintenance from %s to %s", m.StartsAt, m.EndsAt)
+
+	httpreq.JSON(w, m)
+}
+
+// DeleteMaintenance removes maintenance window of the game.
+func (h *Handler) DeleteMaintenance(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "game_id")
+	id := chi.URLParam(r, "maintenance_id")
+
+	err := h.service.DeleteMaintenance(r.Context(), gameID, id)
+	if err == service.ErrMaintenanceNotFound {
+		notFound(w, err)
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't delete maintenance"))
+		return
+	}
+
+	h.logger.With("game_id", gameID, "maintenance_id", id).Info("deleted maintenance")
+
+	httpreq.OK(w)
+}
+
+// ListAnnouncements respond with not expired announcements of the game.
+func (h *Handler) ListAnnouncements(w http.ResponseWriter, r *http.Request) {
+	list, err := h.service.ListAnnouncements(r.Context(), chi.URLParam(r, "game_id"))
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't list announcements"))
+		return
+	}
+
+	httpreq.JSON(w, map[string]interface{}{"announcements": list})
+}
+
+func readAnnouncement(r *http.Request) (*sharedmodels.Announcement, error) {
+	data := announcementRequest{}
+	err := httpreq.Read(r, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sharedmodels.Announcement{
+		ID:        chi.URLParam(r, "announcement_id"),
+		GameID:    chi.URLParam(r, "game_id"),
+		AppID:     data.AppID,
+		Priority:  data.Priority,
+		Titles:    data.Titles,
+		Messages:  data.Messages,
+		StartsAt:  data.StartsAt,
+		ExpiresAt: data.ExpiresAt,
+	}, nil
+}
+
+// CreateAnnouncement publishes announcement of the game, clients get
+// active announcements with app info.
+func (h *Handler) CreateAnnouncement(w http.ResponseWriter, r *http.Request) {
+	a, err := readAnnouncement(r)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read announcement body"))
+		return
+	}
+
+	err = h.service.CreateAnnouncement(r.Context(), a)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't create announcement"))
+		return
+	}
+
+	h.logger.
+		With("game_id", a.GameID, "app_id", a.AppID, "announcement_id", a.ID).
+		Info("created announcement")
+
+	audit.SetTarget(r, "announcement", a.ID)
+	audit.SetChanges(r, nil, a)
+
+	httpreq.JSON(w, a)
+}
+
+// UpdateAnnouncement changes announcement of the game.
+func (h *Handler) UpdateAnnouncement(w http.ResponseWriter, r *http.Request) {
+	a, err := readAnnouncement(r)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read announcement body"))
+		return
+	}
+
+	err = h.service.UpdateAnnouncement(r.Context(), a)
+	if err == service.ErrAnnouncementNotFound {
+		notFound(w, err)
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't update announcement"))
+		return
+	}
+
+	h.logger.
+		With("game_id", a.GameID, "app_id", a.AppID, "announcement_id", a.ID).
+		Info("updated announcement")
+
+	httpreq.JSON(w, a)
+}
+
+// DeleteAnnouncement removes announcement of the game.
+func (h *Handler) DeleteAnnouncement(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "game_id")
+	id := chi.URLParam(r, "announcement_id")
+
+	err := h.service.DeleteAnnouncement(r.Context(), gameID, id)
+	if err == service.ErrAnnouncementNotFound {
+		notFound(w, err)
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't delete announcement"))
+		return
+	}
+
+	h.logger.With("game_id", gameID, "announcement_id", id).Info("deleted announcement")
+
+	httpreq.OK(w)
+}
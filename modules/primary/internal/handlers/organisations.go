@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/primary/internal/service"
+	"gitlab.com/balconygames/analytics/pkg/audit"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+type organisationRequest struct {
+	Name string `json:"name"`
+}
+
+type assignGameRequest struct {
+	OrganisationID string `json:"organisation_id"`
+}
+
+// requirePlatform responds with forbidden for operators
+// of organisations other than platform.
+func requirePlatform(w http.ResponseWriter, r *http.Request) bool {
+	if auth.IsPlatformOperator(r) {
+		return true
+	}
+
+	httpreq.Forbidden(w, errors.Wrap(auth.ErrForbidden, "platform operators only"))
+	return false
+}
+
+// ListOrganisations respond with organisation of the operator,
+// platform operators get all organisations.
+func (h *Handler) ListOrganisations(w http.ResponseWriter, r *http.Request) {
+	var ids []string
+	if !auth.IsPlatformOperator(r) {
+		organisationID := auth.GetUser(r).OrganisationID
+		if organisationID == "" {
+			httpreq.JSON(w, map[string]interface{}{"organisations": []*sharedmodels.Organisation{}})
+			return
+		}
+		ids = []string{organisationID}
+	}
+
+	list, err := h.service.ListOrganisations(r.Context(), ids...)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't list organisations"))
+		return
+	}
+
+	httpreq.JSON(w, map[string]interface{}{"organisations": list})
+}
+
+// CreateOrganisation creates organisation of partner studio by
+// platform operator.
+func (h *Handler) CreateOrganisation(w http.ResponseWriter, r *http.Request) {
+	if !requirePlatform(w, r) {
+		return
+	}
+
+	data := organisationRequest{}
+	err := httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read organisation body"))
+		return
+	}
+
+	o := &sharedmodels.Organisation{Name: data.Name}
+	err = h.service.CreateOrganisation(r.Context(), o)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't create organisation"))
+		return
+	}
+
+	h.logger.With("organisation_id", o.OrganisationID).Infof("created organisation %s", o.Name)
+
+	audit.SetTarget(r, "organisation", o.OrganisationID)
+	audit.SetChanges(r, nil, o)
+
+	httpreq.JSON(w, o)
+}
+
+// AssignGame moves the game to organisation by platform operator.
+func (h *Handler) AssignGame(w http.ResponseWriter, r *http.Request) {
+	if !requirePlatform(w, r) {
+		return
+	}
+
+	data := assignGameRequest{}
+	err := httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read organisation body"))
+		return
+	}
+
+	gameID := chi.URLParam(r, "game_id")
+
+	previous, err := h.service.GameOrganisation(r.Context(), gameID)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't get organisation of game"))
+		return
+	}
+
+	err = h.service.AssignGame(r.Context(), gameID, data.OrganisationID)
+	if err == service.ErrGameNotFound || err == service.ErrOrganisationNotFound {
+		notFound(w, err)
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't assign game"))
+		return
+	}
+
+	h.logger.With("game_id", gameID, "organisation_id", data.OrganisationID).Info("assigned game")
+
+	audit.SetTarget(r, "game", gameID)
+	audit.SetChanges(r, assignGameRequest{OrganisationID: previous}, data)
+
+	httpreq.OK(w)
+}
@@ -3,7 +3,13 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/modules/primary/internal/service"
+	"gitlab.com/balconygames/analytics/pkg/audit"
 	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
 )
 
 func (h *Handler) ListApps(w http.ResponseWriter, r *http.Request) {
@@ -21,3 +27,57 @@ func (h *Handler) UpdateApp(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) DeleteApp(w http.ResponseWriter, r *http.Request) {
 	httpreq.NotImplemented(w)
 }
+
+type appVersionsRequest struct {
+	MinVersion         string `json:"min_version"`
+	RecommendedVersion string `json:"recommended_version"`
+	StoreURL           string `json:"store_url"`
+}
+
+// UpdateAppVersions sets minimum and recommended client versions of the app,
+// clients below minimum version receive upgrade required response.
+func (h *Handler) UpdateAppVersions(w http.ResponseWriter, r *http.Request) {
+	var req appVersionsRequest
+	err := httpreq.Read(r, &req)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't parse app versions request"))
+		return
+	}
+
+	app := &sharedmodels.App{
+		GameID:             chi.URLParam(r, "game_id"),
+		AppID:              chi.URLParam(r, "app_id"),
+		MinVersion:         req.MinVersion,
+		RecommendedVersion: req.RecommendedVersion,
+		StoreURL:           req.StoreURL,
+	}
+
+	previous := &sharedmodels.App{GameID: app.GameID, AppID: app.AppID}
+	err = h.service.GetAppInfo(r.Context(), previous, "", "")
+	if errors.Is(err, service.ErrAppNotFound) {
+		notFound(w, err)
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't get app"))
+		return
+	}
+
+	err = h.service.UpdateAppVersions(r.Context(), app)
+	if errors.Is(err, service.ErrAppNotFound) {
+		notFound(w, err)
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't update app versions"))
+		return
+	}
+
+	audit.SetChanges(r, appVersionsRequest{
+		MinVersion:         previous.MinVersion,
+		RecommendedVersion: previous.RecommendedVersion,
+		StoreURL:           previous.StoreURL,
+	}, req)
+
+	httpreq.JSON(w, app)
+}
@@ -3,15 +3,83 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/pkg/audit"
+	"gitlab.com/balconygames/analytics/pkg/auth"
 	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
 )
 
+type gameRequest struct {
+	Name string `json:"name"`
+	// OrganisationID is passed by platform operators only, games of
+	// other operators are owned by their organisation.
+	OrganisationID string `json:"organisation_id"`
+}
+
+// ListGames respond with games granted to the operator in the
+// organisation, platform operators filter by organisation_id
+// query param.
 func (h *Handler) ListGames(w http.ResponseWriter, r *http.Request) {
-	httpreq.NotImplemented(w)
+	user := auth.GetUser(r)
+
+	organisationID := user.OrganisationID
+	if auth.IsPlatformOperator(r) {
+		organisationID = r.URL.Query().Get("organisation_id")
+	} else if organisationID == "" {
+		httpreq.JSON(w, map[string]interface{}{"games": []*sharedmodels.Game{}})
+		return
+	}
+
+	list, err := h.service.ListGames(r.Context(), organisationID)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't list games"))
+		return
+	}
+
+	games := make([]*sharedmodels.Game, 0, len(list))
+	for _, g := range list {
+		if user.CanAccessGame(g.GameID) {
+			games = append(games, g)
+		}
+	}
+
+	httpreq.JSON(w, map[string]interface{}{"games": games})
 }
 
+// CreateGame creates the game owned by organisation of the operator.
 func (h *Handler) CreateGame(w http.ResponseWriter, r *http.Request) {
-	httpreq.NotImplemented(w)
+	data := gameRequest{}
+	err := httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read game body"))
+		return
+	}
+
+	game := &sharedmodels.Game{
+		Name:           data.Name,
+		OrganisationID: auth.GetUser(r).OrganisationID,
+	}
+	if auth.IsPlatformOperator(r) {
+		game.OrganisationID = data.OrganisationID
+	} else if game.OrganisationID == "" {
+		httpreq.Forbidden(w, errors.Wrap(auth.ErrForbidden, "operator without organisation"))
+		return
+	}
+
+	err = h.service.CreateGame(r.Context(), game)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't create game"))
+		return
+	}
+
+	h.logger.With("game_id", game.GameID, "organisation_id", game.OrganisationID).Info("created game")
+
+	audit.SetTarget(r, "game", game.GameID)
+	audit.SetChanges(r, nil, game)
+
+	httpreq.JSON(w, game)
 }
 
 func (h *Handler) UpdateGame(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+const (
+	// apiKeyPrefix starts every API key to recognize leaked keys.
+	apiKeyPrefix = "bgk_"
+	// apiKeyLength is length of the key with 32 random bytes.
+	apiKeyLength = len(apiKeyPrefix) + 43
+	// apiKeyShownLength is length of the key prefix stored as is.
+	apiKeyShownLength = 12
+
+	maxAPIKeyNameLength = 256
+)
+
+// apiKeysCacheTTL is time to reload API keys, revoked keys are
+// rejected by other instances after it.
+const apiKeysCacheTTL = 30 * time.Second
+
+// apiKeyTouchInterval is minimum time between updates of last used
+// time of the key.
+const apiKeyTouchInterval = time.Minute
+
+// ErrAPIKeyNotFound returned for unknown or revoked API key.
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+type cachedAPIKey struct {
+	// key is nil for unknown keys, they aren't stored in cache
+	key *sharedmodels.APIKey
+
+	mu        sync.Mutex
+	touchedAt time.Time
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateAPIKey() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func validateAPIKey(k *sharedmodels.APIKey, now time.Time) error {
+	k.Name = strings.TrimSpace(k.Name)
+	if k.Name == "" || len(k.Name) > maxAPIKeyNameLength {
+		return errors.Errorf("name should have 1 to %d characters", maxAPIKeyNameLength)
+	}
+
+	if len(k.Scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range k.Scopes {
+		if !auth.ValidScope(scope) {
+			return errors.Errorf("unknown scope %q", scope)
+		}
+	}
+
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(now) {
+		return errors.New("expires_at should be in the future")
+	}
+
+	return nil
+}
+
+// ListAPIKeys respond with API keys of the game without hashes.
+func (s *Service) ListAPIKeys(ctx context.Context, gameID string) ([]*sharedmodels.APIKey, error) {
+	list, err := s.pgRepo.ListAPIKeys(ctx, gameID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't list api keys")
+	}
+
+	return list, nil
+}
+
+// CreateAPIKey generates the new API key of the game, the key is
+// responded only once and only hash of it is stored.
+func (s *Service) CreateAPIKey(ctx context.Context, k *sharedmodels.APIKey) error {
+	now := time.Now().UTC()
+
+	err := validateAPIKey(k, now)
+	if err != nil {
+		return err
+	}
+
+	if k.AppID != "" {
+		app, err := s.cachedAppInfo(ctx, k.GameID, k.AppID)
+		if err != nil {
+			return err
+		}
+		if app == nil {
+			return ErrAppNotFound
+		}
+	}
+
+	if k.ExpiresAt != nil {
+		expiresAt := k.ExpiresAt.UTC()
+		k.ExpiresAt = &expiresAt
+	}
+
+	key, err := generateAPIKey()
+	if err != nil {
+		return errors.WithMessage(err, "can't generate api key")
+	}
+
+	k.KeyHash = hashAPIKey(key)
+	k.Prefix = key[:apiKeyShownLength]
+
+	err = s.pgRepo.CreateAPIKey(ctx, k)
+	if err != nil {
+		return errors.WithMessage(err, "can't create api key")
+	}
+
+	k.Key = key
+
+	return nil
+}
+
+// RevokeAPIKey rejects the key immediately on this instance and after
+// cache expiration on other instances.
+func (s *Service) RevokeAPIKey(ctx context.Context, gameID, id string) error {
+	k, err := s.pgRepo.RevokeAPIKey(ctx, gameID, id, time.Now().UTC())
+	if err == pgx.ErrNoRows {
+		return ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return errors.WithMessage(err, "can't revoke api key")
+	}
+
+	s.apiKeys.Delete(k.KeyHash)
+
+	return nil
+}
+
+// cachedAPIKey respond with the key by hash or nil for unknown keys.
+func (s *Service) cachedAPIKey(ctx context.Context, keyHash string) (*cachedAPIKey, error) {
+	if cached, ok := s.apiKeys.Get(keyHash); ok {
+		return cached.(*cachedAPIKey), nil
+	}
+
+	k, err := s.pgRepo.GetAPIKeyByHash(ctx, keyHash)
+	if err == pgx.ErrNoRows {
+		// unknown keys aren't cached, otherwise random keys
+		// of unauthenticated requests grow the cache.
+		s.apiKeys.Delete(keyHash)
+
+		return &cachedAPIKey{}, nil
+	}
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't get api key")
+	}
+
+	// usage is recorded once per interval across reloads of the key
+	loaded := &cachedAPIKey{key: k}
+	if k.LastUsedAt != nil {
+		loaded.touchedAt = *k.LastUsedAt
+	}
+
+	s.apiKeys.Set(keyHash, loaded)
+
+	return loaded, nil
+}
+
+// VerifyAPIKey respond with server user of the API key and records
+// the time the key was used.
+func (s *Service) VerifyAPIKey(ctx context.Context, key string) (*auth.UserInfo, error) {
+	if len(key) != apiKeyLength || !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, auth.ErrInvalidAPIKey
+	}
+
+	cached, err := s.cachedAPIKey(ctx, hashAPIKey(key))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	k := cached.key
+	if k == nil || !k.ActiveAt(now) {
+		return nil, auth.ErrInvalidAPIKey
+	}
+
+	cached.mu.Lock()
+	touch := now.Sub(cached.touchedAt) >= apiKeyTouchInterval
+	if touch {
+		cached.touchedAt = now
+	}
+	cached.mu.Unlock()
+
+	if touch {
+		err = s.pgRepo.TouchAPIKey(ctx, k.ID, now)
+		if err != nil {
+			s.logger.With("game_id", k.GameID, "api_key_id", k.ID).Errorf("can't record api key usage: %v", err)
+		}
+	}
+
+	return &auth.UserInfo{
+		UserID: k.ID,
+		GameID: k.GameID,
+		AppID:  k.AppID,
+		Type:   auth.APIKeyType,
+		Scopes: k.Scopes,
+	}, nil
+}
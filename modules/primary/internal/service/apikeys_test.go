@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// fakeAPIKeysRepo stores API keys in memory, other methods
+// of repository are not used.
+type fakeAPIKeysRepo struct {
+	PostgresRepository
+
+	byHash  map[string]*sharedmodels.APIKey
+	touches int
+}
+
+func (f *fakeAPIKeysRepo) CreateAPIKey(_ context.Context, k *sharedmodels.APIKey) error {
+	k.ID = "key-1"
+	stored := *k
+	f.byHash[k.KeyHash] = &stored
+	return nil
+}
+
+func (f *fakeAPIKeysRepo) GetAPIKeyByHash(_ context.Context, keyHash string) (*sharedmodels.APIKey, error) {
+	k, ok := f.byHash[keyHash]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return k, nil
+}
+
+func (f *fakeAPIKeysRepo) RevokeAPIKey(_ context.Context, gameID, id string, at time.Time) (*sharedmodels.APIKey, error) {
+	for _, k := range f.byHash {
+		if k.GameID == gameID && k.ID == id && k.RevokedAt == nil {
+			k.RevokedAt = &at
+			return k, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (f *fakeAPIKeysRepo) TouchAPIKey(_ context.Context, id string, at time.Time) error {
+	f.touches++
+	return nil
+}
+
+func TestAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeAPIKeysRepo{byHash: map[string]*sharedmodels.APIKey{}}
+	svc := NewService(repo, zaptest.NewLogger(t).Sugar())
+
+	k := &sharedmodels.APIKey{
+		GameID: "game-1",
+		Name:   "build pipeline",
+		Scopes: []string{auth.ScopeLeaderboardWrite},
+	}
+	err := svc.CreateAPIKey(ctx, k)
+	require.Nil(t, err)
+	require.Len(t, k.Key, apiKeyLength)
+	require.Equal(t, k.Key[:apiKeyShownLength], k.Prefix)
+	require.NotContains(t, k.KeyHash, k.Key)
+
+	user, err := svc.VerifyAPIKey(ctx, k.Key)
+	require.Nil(t, err)
+	require.Equal(t, auth.APIKeyType, user.Type)
+	require.Equal(t, "game-1", user.GameID)
+	require.True(t, user.HasScope(auth.ScopeLeaderboardWrite))
+
+	// last used time is recorded once per interval
+	_, err = svc.VerifyAPIKey(ctx, k.Key)
+	require.Nil(t, err)
+	require.Equal(t, 1, repo.touches)
+
+	_, err = svc.VerifyAPIKey(ctx, k.Key[:len(k.Key)-1]+"x")
+	require.Equal(t, auth.ErrInvalidAPIKey, err)
+	_, err = svc.VerifyAPIKey(ctx, "unknown")
+	require.Equal(t, auth.ErrInvalidAPIKey, err)
+
+	// unknown keys aren't cached
+	for i := 0; i < 10; i++ {
+		unknown, err := generateAPIKey()
+		require.Nil(t, err)
+		_, err = svc.VerifyAPIKey(ctx, unknown)
+		require.Equal(t, auth.ErrInvalidAPIKey, err)
+	}
+	require.Equal(t, 1, svc.apiKeys.Len())
+
+	err = svc.RevokeAPIKey(ctx, "game-1", k.ID)
+	require.Nil(t, err)
+	_, err = svc.VerifyAPIKey(ctx, k.Key)
+	require.Equal(t, auth.ErrInvalidAPIKey, err)
+
+	err = svc.RevokeAPIKey(ctx, "game-1", k.ID)
+	require.Equal(t, ErrAPIKeyNotFound, err)
+}
+
+func TestValidateAPIKey(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+
+	cases := []struct {
+		name  string
+		k     *sharedmodels.APIKey
+		valid bool
+	}{
+		{"valid", &sharedmodels.APIKey{Name: "server", Scopes: []string{auth.ScopePropertiesRead}}, true},
+		{"empty name", &sharedmodels.APIKey{Name: " ", Scopes: []string{auth.ScopePropertiesRead}}, false},
+		{"no scopes", &sharedmodels.APIKey{Name: "server"}, false},
+		{"unknown scope", &sharedmodels.APIKey{Name: "server", Scopes: []string{"games:delete"}}, false},
+		{"expired", &sharedmodels.APIKey{Name: "server", Scopes: []string{auth.ScopePropertiesRead}, ExpiresAt: &past}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := validateAPIKey(c.k, now)
+			if c.valid {
+				require.Nil(t, err)
+			} else {
+				require.NotNil(t, err)
+			}
+		})
+	}
+}
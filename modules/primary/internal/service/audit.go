@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/pkg/audit"
+)
+
+const (
+	// DefaultAuditLimit is page size of audit entries.
+	DefaultAuditLimit = 50
+	maxAuditLimit     = 200
+)
+
+// Record stores audit entry of server mutation, failures are logged
+// to don't fail mutations which are already done.
+func (s *Service) Record(ctx context.Context, e *audit.Entry) {
+	err := s.pgRepo.InsertAuditEntry(ctx, e)
+	if err != nil {
+		s.logger.
+			With("actor_id", e.ActorID, "action", e.Action, "request_id", e.RequestID).
+			Errorf("can't record audit entry: %v", err)
+	}
+}
+
+// ListAuditLog respond with page of audit entries and cursor of the
+// next page, cursor is zero on the last page.
+func (s *Service) ListAuditLog(ctx context.Context, f audit.Filter) ([]*audit.Entry, int64, error) {
+	if f.Limit <= 0 || f.Limit > maxAuditLimit {
+		f.Limit = DefaultAuditLimit
+	}
+
+	if f.Since != nil && f.Until != nil && !f.Since.Before(*f.Until) {
+		return nil, 0, errors.New("since should be before until")
+	}
+
+	list, err := s.pgRepo.ListAuditEntries(ctx, f)
+	if err != nil {
+		return nil, 0, errors.WithMessage(err, "can't list audit entries")
+	}
+
+	var next int64
+	if len(list) == f.Limit {
+		next = list[len(list)-1].ID
+	}
+
+	return list, next, nil
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/pkg/secrets"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+const (
+	maxClientIDLength    = 256
+	maxCredentialsConfig = 16
+	maxConfigValueLength = 1024
+	maxSecretLength      = 8192
+)
+
+// ErrKeysNotConfigured returned once encryption key is not set.
+var ErrKeysNotConfigured = errors.New("encryption key is not configured")
+
+// credentialsOwner is associated data of encrypted secret, secrets
+// can't be copied between credentials.
+func credentialsOwner(c *sharedmodels.NetworkCredentials) []byte {
+	return []byte(c.GameID + ":" + c.Network + ":" + c.ClientID)
+}
+
+func (s *Service) encryptSecret(c *sharedmodels.NetworkCredentials, secret string) (string, error) {
+	if secret == "" {
+		return "", nil
+	}
+	if s.Keys == nil {
+		return "", ErrKeysNotConfigured
+	}
+
+	return s.Keys.Encrypt([]byte(secret), credentialsOwner(c))
+}
+
+func (s *Service) decryptSecret(c *sharedmodels.NetworkCredentials) (string, error) {
+	if c.Secret == "" {
+		return "", nil
+	}
+	if s.Keys == nil {
+		return "", ErrKeysNotConfigured
+	}
+
+	plaintext, err := s.Keys.Decrypt(c.Secret, credentialsOwner(c))
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+func validateCredentials(c *sharedmodels.NetworkCredentials, secret *string) error {
+	if !sharedmodels.ValidNetwork(c.Network) {
+		return errors.Errorf("unknown network %q", c.Network)
+	}
+
+	if c.ClientID == "" || len(c.ClientID) > maxClientIDLength {
+		return errors.Errorf("client id should have 1 to %d characters", maxClientIDLength)
+	}
+
+	if len(c.Config) > maxCredentialsConfig {
+		return errors.Errorf("config should have up to %d settings", maxCredentialsConfig)
+	}
+	for name, value := range c.Config {
+		if name == "" || len(value) > maxConfigValueLength {
+			return errors.Errorf("config setting %q should have up to %d characters", name, maxConfigValueLength)
+		}
+	}
+	if c.Config == nil {
+		c.Config = map[string]string{}
+	}
+
+	if secret != nil && len(*secret) > maxSecretLength {
+		return errors.Errorf("secret should have up to %d characters", maxSecretLength)
+	}
+
+	return nil
+}
+
+// ListCredentials respond with credentials of the game without secrets.
+func (s *Service) ListCredentials(ctx context.Context, gameID string) ([]*sharedmodels.NetworkCredentials, error) {
+	list, err := s.pgRepo.ListCredentials(ctx, gameID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't list credentials")
+	}
+
+	for _, c := range list {
+		c.Secret = ""
+	}
+
+	return list, nil
+}
+
+// UpdateCredentials creates or updates credentials of the game, secret
+// is encrypted by the current key. The stored secret is kept once
+// secret is not passed.
+func (s *Service) UpdateCredentials(ctx context.Context, c *sharedmodels.NetworkCredentials, secret *string) error {
+	err := validateCredentials(c, secret)
+	if err != nil {
+		return err
+	}
+
+	if secret != nil {
+		c.Secret, err = s.encryptSecret(c, *secret)
+		if err != nil {
+			return errors.WithMessage(err, "can't encrypt secret")
+		}
+	} else {
+		stored, err := s.pgRepo.GetCredentials(ctx, c.GameID, c.Network, c.ClientID)
+		if err != nil && err != pgx.ErrNoRows {
+			return errors.WithMessage(err, "can't get credentials")
+		}
+		if err == nil {
+			c.Secret = stored.Secret
+		}
+	}
+
+	err = s.pgRepo.UpsertCredentials(ctx, c)
+	if err != nil {
+		return errors.WithMessage(err, "can't update credentials")
+	}
+
+	c.HasSecret = c.Secret != ""
+	c.Secret = ""
+
+	return nil
+}
+
+// DeleteCredentials removes credentials of the game.
+func (s *Service) DeleteCredentials(ctx context.Context, gameID, network, clientID string) error {
+	err := s.pgRepo.DeleteCredentials(ctx, gameID, network, clientID)
+	if err == pgx.ErrNoRows {
+		return secrets.ErrCredentialsNotFound
+	}
+	if err != nil {
+		return errors.WithMessage(err, "can't delete credentials")
+	}
+
+	return nil
+}
+
+// LookupCredentials respond with credentials of the game in the network
+// with decrypted secret, used by other modules to sign in users.
+func (s *Service) LookupCredentials(ctx context.Context, gameID, network, clientID string) (*sharedmodels.NetworkCredentials, error) {
+	c, err := s.pgRepo.GetCredentials(ctx, gameID, network, clientID)
+	if err == pgx.ErrNoRows {
+		return nil, secrets.ErrCredentialsNotFound
+	}
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't get credentials")
+	}
+
+	c.Secret, err = s.decryptSecret(c)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't decrypt secret")
+	}
+
+	return c, nil
+}
+
+// RotateCredentials re-encrypts secrets encrypted by previous keys with
+// the current key, previous keys could be removed after rotation.
+func (s *Service) RotateCredentials(ctx context.Context) (int, error) {
+	if s.Keys == nil {
+		return 0, ErrKeysNotConfigured
+	}
+
+	list, err := s.pgRepo.ListAllCredentials(ctx)
+	if err != nil {
+		return 0, errors.WithMessage(err, "can't list credentials")
+	}
+
+	rotated := 0
+	for _, c := range list {
+		if !s.Keys.NeedsRotation(c.Secret) {
+			continue
+		}
+
+		previous := c.Secret
+
+		secret, err := s.decryptSecret(c)
+		if err != nil {
+			return rotated, errors.WithMessagef(err, "can't decrypt secret of %s %s of game %s",
+				c.Network, c.ClientID, c.GameID)
+		}
+
+		c.Secret, err = s.encryptSecret(c, secret)
+		if err != nil {
+			return rotated, err
+		}
+
+		// secrets updated during rotation are already encrypted by the current key
+		ok, err := s.pgRepo.ReplaceCredentialsSecret(ctx, c, previous)
+		if err != nil {
+			return rotated, errors.WithMessage(err, "can't replace secret")
+		}
+		if ok {
+			rotated++
+		}
+	}
+
+	return rotated, nil
+}
+
+// ImportLegacyNetworks encrypts plain text credentials of deprecated
+// networks table and removes them, credentials configured by the new
+// API are kept.
+func (s *Service) ImportLegacyNetworks(ctx context.Context) (int, error) {
+	list, err := s.pgRepo.ListLegacyNetworks(ctx)
+	if err != nil {
+		return 0, errors.WithMessage(err, "can't list legacy networks")
+	}
+
+	imported := 0
+	for _, c := range list {
+		if !sharedmodels.ValidNetwork(c.Network) {
+			s.logger.With("game_id", c.GameID, "network", c.Network).Warn("skip credentials of unknown network")
+			continue
+		}
+
+		_, err := s.pgRepo.GetCredentials(ctx, c.GameID, c.Network, c.ClientID)
+		if err != nil && err != pgx.ErrNoRows {
+			return imported, errors.WithMessage(err, "can't get credentials")
+		}
+
+		if err == pgx.ErrNoRows {
+			secret := c.Secret
+			err = s.UpdateCredentials(ctx, c, &secret)
+			if err != nil {
+				return imported, err
+			}
+			imported++
+		}
+
+		err = s.pgRepo.DeleteLegacyNetwork(ctx, c)
+		if err != nil {
+			return imported, errors.WithMessage(err, "can't delete legacy network")
+		}
+	}
+
+	return imported, nil
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"gitlab.com/balconygames/analytics/pkg/secrets"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+func TestValidateCredentials(t *testing.T) {
+	secret := "secret"
+	long := strings.Repeat("a", maxSecretLength+1)
+
+	cases := []struct {
+		name  string
+		c     *sharedmodels.NetworkCredentials
+		s     *string
+		valid bool
+	}{
+		{"valid", &sharedmodels.NetworkCredentials{Network: sharedmodels.FacebookNetwork, ClientID: "123"}, &secret, true},
+		{"kept secret", &sharedmodels.NetworkCredentials{Network: sharedmodels.GoogleNetwork, ClientID: "web"}, nil, true},
+		{"unknown network", &sharedmodels.NetworkCredentials{Network: "myspace", ClientID: "123"}, &secret, false},
+		{"empty client id", &sharedmodels.NetworkCredentials{Network: sharedmodels.AppleNetwork}, &secret, false},
+		{"long secret", &sharedmodels.NetworkCredentials{Network: sharedmodels.TwitterNetwork, ClientID: "123"}, &long, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := validateCredentials(c.c, c.s)
+			if c.valid {
+				require.Nil(t, err)
+				require.NotNil(t, c.c.Config)
+			} else {
+				require.NotNil(t, err)
+			}
+		})
+	}
+}
+
+func TestEncryptSecret(t *testing.T) {
+	svc := NewService(nil, zaptest.NewLogger(t).Sugar())
+
+	c := &sharedmodels.NetworkCredentials{GameID: "game-1", Network: sharedmodels.FacebookNetwork, ClientID: "123"}
+	_, err := svc.encryptSecret(c, "secret")
+	require.Equal(t, ErrKeysNotConfigured, err)
+
+	previous, err := secrets.NewKeys(strings.Repeat("1", 64))
+	require.Nil(t, err)
+	svc.Keys = previous
+
+	c.Secret, err = svc.encryptSecret(c, "secret")
+	require.Nil(t, err)
+	require.NotContains(t, c.Secret, "secret")
+
+	// secrets of the previous key are decrypted until rotation
+	svc.Keys, err = secrets.NewKeys(strings.Repeat("2", 64), strings.Repeat("1", 64))
+	require.Nil(t, err)
+	require.True(t, svc.Keys.NeedsRotation(c.Secret))
+
+	secret, err := svc.decryptSecret(c)
+	require.Nil(t, err)
+	require.Equal(t, "secret", secret)
+
+	// secret can't be moved to credentials of the other game
+	other := *c
+	other.GameID = "game-2"
+	_, err = svc.decryptSecret(&other)
+	require.NotNil(t, err)
+
+	// empty secret is not encrypted
+	empty, err := svc.encryptSecret(c, "")
+	require.Nil(t, err)
+	require.Equal(t, "", empty)
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// ErrMaintenanceNotFound returned for unknown maintenance window.
+var ErrMaintenanceNotFound = errors.New("maintenance not found")
+
+// ErrAnnouncementNotFound returned for unknown announcement.
+var ErrAnnouncementNotFound = errors.New("announcement not found")
+
+const (
+	// maxMaintenanceDuration limits windows to don't lock clients
+	// out by mistake.
+	maxMaintenanceDuration = 7 * 24 * time.Hour
+	maxNoticeLength        = 2048
+)
+
+// noticesCacheTTL is time to reload notices, maintenance is checked
+// on every client request.
+const noticesCacheTTL = 30 * time.Second
+
+// gameNotices contains not ended maintenance windows and not expired
+// announcements of the game.
+type gameNotices struct {
+	maintenance   []*sharedmodels.Maintenance
+	announcements []*sharedmodels.Announcement
+}
+
+// gameNotices respond with notices of the game.
+func (s *Service) gameNotices(ctx context.Context, gameID string) (*gameNotices, error) {
+	if cached, ok := s.notices.Get(gameID); ok {
+		return cached.(*gameNotices), nil
+	}
+
+	now := time.Now().UTC()
+
+	maintenance, err := s.pgRepo.ListMaintenance(ctx, gameID, now)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't list maintenance")
+	}
+
+	announcements, err := s.pgRepo.ListAnnouncements(ctx, gameID, now)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't list announcements")
+	}
+
+	notices := &gameNotices{
+		maintenance:   maintenance,
+		announcements: announcements,
+	}
+
+	s.notices.Set(gameID, notices)
+
+	return notices, nil
+}
+
+func (s *Service) resetNotices(gameID string) {
+	s.notices.Delete(gameID)
+}
+
+// activeNotices respond with active maintenance window and active
+// announcements of the app localized for the locale.
+func (s *Service) activeNotices(ctx context.Context, gameID, appID, locale string) (*sharedmodels.Maintenance, []*sharedmodels.Announcement, error) {
+	notices, err := s.gameNotices(ctx, gameID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now().UTC()
+
+	var maintenance *sharedmodels.Maintenance
+	for _, m := range notices.maintenance {
+		if (m.AppID != "" && m.AppID != appID) || !m.ActiveAt(now) {
+			continue
+		}
+
+		// overlapped windows respond with the latest end
+		if maintenance == nil || m.EndsAt.After(maintenance.EndsAt) {
+			localized := *m
+			localized.Message = m.Messages.Localize(locale)
+			localized.Messages = nil
+			maintenance = &localized
+		}
+	}
+
+	// announcements are sorted by priority
+	announcements := []*sharedmodels.Announcement{}
+	for _, a := range notices.announcements {
+		if (a.AppID != "" && a.AppID != appID) || !a.ActiveAt(now) {
+			continue
+		}
+
+		localized := *a
+		localized.Title = a.Titles.Localize(locale)
+		localized.Message = a.Messages.Localize(locale)
+		localized.Titles = nil
+		localized.Messages = nil
+		announcements = append(announcements, &localized)
+	}
+
+	return maintenance, announcements, nil
+}
+
+// CheckMaintenance returns auth.MaintenanceError while maintenance
+// window of the app is active.
+func (s *Service) CheckMaintenance(ctx context.Context, gameID, appID, locale string) error {
+	maintenance, _, err := s.activeNotices(ctx, gameID, appID, locale)
+	if err != nil {
+		return err
+	}
+	if maintenance == nil {
+		return nil
+	}
+
+	return &auth.MaintenanceError{Message: maintenance.Message, EndsAt: maintenance.EndsAt}
+}
+
+func validateMessages(messages sharedmodels.Messages, required bool) error {
+	if required && len(messages) == 0 {
+		return errors.New("messages should have at least one locale")
+	}
+
+	for locale, message := range messages {
+		if locale == "" || len(message) > maxNoticeLength {
+			return errors.Errorf("message of locale %q should have up to %d characters", locale, maxNoticeLength)
+		}
+	}
+
+	return nil
+}
+
+func validateMaintenance(m *sharedmodels.Maintenance) error {
+	m.StartsAt = m.StartsAt.UTC()
+	m.EndsAt = m.EndsAt.UTC()
+
+	if m.StartsAt.IsZero() || !m.StartsAt.Before(m.EndsAt) {
+		return errors.New("maintenance should have starts_at before ends_at")
+	}
+	if m.EndsAt.Sub(m.StartsAt) > maxMaintenanceDuration {
+		return errors.Errorf("maintenance should be shorter than %s", maxMaintenanceDuration)
+	}
+
+	return validateMessages(m.Messages, true)
+}
+
+// ListMaintenance respond with maintenance windows of the game.
+func (s *Service) ListMaintenance(ctx context.Context, gameID string) ([]*sharedmodels.Maintenance, error) {
+	list, err := s.pgRepo.ListMaintenance(ctx, gameID, time.Time{})
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't list maintenance")
+	}
+
+	return list, nil
+}
+
+// CreateMaintenance schedules maintenance window of the game or the app.
+func (s *Service) CreateMaintenance(ctx context.Context, m *sharedmodels.Maintenance) error {
+	err := validateMaintenance(m)
+	if err != nil {
+		return err
+	}
+
+	err = s.pgRepo.CreateMaintenance(ctx, m)
+	if err != nil {
+		return errors.WithMessage(err, "can't create maintenance")
+	}
+
+	s.resetNotices(m.GameID)
+
+	return nil
+}
+
+// UpdateMaintenance changes maintenance window, windows could be ended
+// earlier by moving ends_at.
+func (s *Service) UpdateMaintenance(ctx context.Context, m *sharedmodels.Maintenance) error {
+	err := validateMaintenance(m)
+	if err != nil {
+		return err
+	}
+
+	err = s.pgRepo.UpdateMaintenance(ctx, m)
+	if err == pgx.ErrNoRows {
+		return ErrMaintenanceNotFound
+	}
+	if err != nil {
+		return errors.WithMessage(err, "can't update maintenance")
+	}
+
+	s.resetNotices(m.GameID)
+
+	return nil
+}
+
+// DeleteMaintenance removes maintenance window of the game.
+func (s *Service) DeleteMaintenance(ctx context.Context, gameID, id string) error {
+	err := s.pgRepo.DeleteMaintenance(ctx, gameID, id)
+	if err == pgx.ErrNoRows {
+		return ErrMaintenanceNotFound
+	}
+	if err != nil {
+		return errors.WithMessage(err, "can't delete maintenance")
+	}
+
+	s.resetNotices(gameID)
+
+	return nil
+}
+
+func validateAnnouncement(a *sharedmodels.Announcement) error {
+	if a.StartsAt.IsZero() {
+		a.StartsAt = time.Now()
+	}
+	a.StartsAt = a.StartsAt.UTC()
+
+	if a.ExpiresAt != nil {
+		expiresAt := a.ExpiresAt.UTC()
+		if !a.StartsAt.Before(expiresAt) {
+			return errors.New("announcement should have starts_at before expires_at")
+		}
+		a.ExpiresAt = &expiresAt
+	}
+
+	err := validateMessages(a.Titles, false)
+	if err != nil {
+		return err
+	}
+
+	return validateMessages(a.Messages, true)
+}
+
+// ListAnnouncements respond with not expired announcements of the game.
+func (s *Service) ListAnnouncements(ctx context.Context, gameID string) ([]*sharedmodels.Announcement, error) {
+	list, err := s.pgRepo.ListAnnouncements(ctx, gameID, time.Now().UTC())
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't list announcements")
+	}
+
+	return list, nil
+}
+
+// CreateAnnouncement publishes announcement of the game or the app.
+func (s *Service) CreateAnnouncement(ctx context.Context, a *sharedmodels.Announcement) error {
+	err := validateAnnouncement(a)
+	if err != nil {
+		return err
+	}
+
+	err = s.pgRepo.CreateAnnouncement(ctx, a)
+	if err != nil {
+		return errors.WithMessage(err, "can't create announcement")
+	}
+
+	s.resetNotices(a.GameID)
+
+	return nil
+}
+
+// UpdateAnnouncement changes announcement of the game.
+func (s *Service) UpdateAnnouncement(ctx context.Context, a *sharedmodels.Announcement) error {
+	err := validateAnnouncement(a)
+	if err != nil {
+		return err
+	}
+
+	err = s.pgRepo.UpdateAnnouncement(ctx, a)
+	if err == pgx.ErrNoRows {
+		return ErrAnnouncementNotFound
+	}
+	if err != nil {
+		return errors.WithMessage(err, "can't update announcement")
+	}
+
+	s.resetNotices(a.GameID)
+
+	return nil
+}
+
+// DeleteAnnouncement removes announcement of the game.
+func (s *Service) DeleteAnnouncement(ctx context.Context, gameID, id string) error {
+	err := s.pgRepo.DeleteAnnouncement(ctx, gameID, id)
+	if err == pgx.ErrNoRows {
+		return ErrAnnouncementNotFound
+	}
+	if err != nil {
+		return errors.WithMessage(err, "can't delete announcement")
+	}
+
+	s.resetNotices(gameID)
+
+	return nil
+}
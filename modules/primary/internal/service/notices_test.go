@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+func TestActiveNotices(t *testing.T) {
+	svc := NewService(nil, zaptest.NewLogger(t).Sugar())
+
+	now := time.Now().UTC()
+	expired := now.Add(-time.Minute)
+
+	svc.notices.Set("game-1", &gameNotices{
+		maintenance: []*sharedmodels.Maintenance{
+			{
+				AppID:    "app-1",
+				StartsAt: now.Add(-time.Hour),
+				EndsAt:   now.Add(time.Hour),
+				Messages: sharedmodels.Messages{"default": "Maintenance", "pt": "Manutenção"},
+			},
+			{
+				StartsAt: now.Add(time.Hour),
+				EndsAt:   now.Add(2 * time.Hour),
+				Messages: sharedmodels.Messages{"default": "Scheduled"},
+			},
+		},
+		announcements: []*sharedmodels.Announcement{
+			{Priority: 10, StartsAt: now.Add(-time.Hour), Messages: sharedmodels.Messages{"default": "Sale"}},
+			{Priority: 5, StartsAt: now.Add(-time.Hour), ExpiresAt: &expired, Messages: sharedmodels.Messages{"default": "Old"}},
+			{Priority: 1, AppID: "app-2", StartsAt: now.Add(-time.Hour), Messages: sharedmodels.Messages{"default": "Other app"}},
+		},
+	})
+
+	maintenance, announcements, err := svc.activeNotices(context.Background(), "game-1", "app-1", "pt-BR")
+	require.Nil(t, err)
+	require.NotNil(t, maintenance)
+	require.Equal(t, "Manutenção", maintenance.Message)
+	require.Nil(t, maintenance.Messages)
+	require.Len(t, announcements, 1)
+	require.Equal(t, "Sale", announcements[0].Message)
+
+	err = svc.CheckMaintenance(context.Background(), "game-1", "app-1", "en")
+	require.IsType(t, &auth.MaintenanceError{}, err)
+	require.Equal(t, "Maintenance", err.(*auth.MaintenanceError).Message)
+
+	// maintenance of the other app
+	require.Nil(t, svc.CheckMaintenance(context.Background(), "game-1", "app-2", "en"))
+}
+
+func TestValidateMaintenance(t *testing.T) {
+	now := time.Now()
+
+	require.NotNil(t, validateMaintenance(&sharedmodels.Maintenance{
+		StartsAt: now, EndsAt: now.Add(-time.Hour), Messages: sharedmodels.Messages{"default": "a"}}))
+	require.NotNil(t, validateMaintenance(&sharedmodels.Maintenance{
+		StartsAt: now, EndsAt: now.Add(30 * 24 * time.Hour), Messages: sharedmodels.Messages{"default": "a"}}))
+	require.NotNil(t, validateMaintenance(&sharedmodels.Maintenance{
+		StartsAt: now, EndsAt: now.Add(time.Hour)}))
+	require.Nil(t, validateMaintenance(&sharedmodels.Maintenance{
+		StartsAt: now, EndsAt: now.Add(time.Hour), Messages: sharedmodels.Messages{"default": "a"}}))
+}
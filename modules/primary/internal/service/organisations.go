@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+const maxNameLength = 256
+
+// platformOrganisationName is name of organisation created by
+// PLATFORM_ORGANISATION_ID on the first start.
+const platformOrganisationName = "Platform"
+
+// ownersCacheTTL is time to reload owners of games, owners are read
+// on every server request with game.
+const ownersCacheTTL = 30 * time.Second
+
+var (
+	// ErrGameNotFound returned for unknown game.
+	ErrGameNotFound = errors.New("game not found")
+	// ErrOrganisationNotFound returned for unknown organisation.
+	ErrOrganisationNotFound = errors.New("organisation not found")
+)
+
+func validateName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxNameLength {
+		return "", errors.Errorf("name should have 1 to %d characters", maxNameLength)
+	}
+
+	return name, nil
+}
+
+// GameOrganisation respond with organisation owning the game, empty
+// organisation is returned for unknown games and games without owner.
+func (s *Service) GameOrganisation(ctx context.Context, gameID string) (string, error) {
+	if cached, ok := s.owners.Get(gameID); ok {
+		return cached.(string), nil
+	}
+
+	organisationID, err := s.pgRepo.GetGameOrganisation(ctx, gameID)
+	if err != nil && err != pgx.ErrNoRows {
+		return "", errors.WithMessage(err, "can't get organisation of game")
+	}
+
+	s.owners.Set(gameID, organisationID)
+
+	return organisationID, nil
+}
+
+// CreateOrganisation creates organisation, operators are invited
+// into it by auth module.
+func (s *Service) CreateOrganisation(ctx context.Context, o *sharedmodels.Organisation) error {
+	var err error
+
+	o.Name, err = validateName(o.Name)
+	if err != nil {
+		return err
+	}
+
+	err = s.pgRepo.CreateOrganisation(ctx, o)
+	if err != nil {
+		return errors.WithMessage(err, "can't create organisation")
+	}
+
+	return nil
+}
+
+// EnsurePlatformOrganisation creates organisation of platform operators
+// on the first start, operators invited into it manage all games.
+func (s *Service) EnsurePlatformOrganisation(ctx context.Context, organisationID string) error {
+	if organisationID == "" || len(organisationID) > 36 {
+		return errors.New("platform organisation id should have 1 to 36 characters")
+	}
+
+	err := s.pgRepo.EnsureOrganisation(ctx, &sharedmodels.Organisation{
+		OrganisationID: organisationID,
+		Name:           platformOrganisationName,
+	})
+	if err != nil {
+		return errors.WithMessage(err, "can't ensure platform organisation")
+	}
+
+	return nil
+}
+
+// OrganisationExists checks if organisation is stored.
+func (s *Service) OrganisationExists(ctx context.Context, organisationID string) (bool, error) {
+	err := s.checkOrganisation(ctx, organisationID)
+	if err == ErrOrganisationNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ListOrganisations respond with organisations by ids, all
+// organisations are returned for empty ids.
+func (s *Service) ListOrganisations(ctx context.Context, ids ...string) ([]*sharedmodels.Organisation, error) {
+	list, err := s.pgRepo.ListOrganisations(ctx, ids)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't list organisations")
+	}
+
+	return list, nil
+}
+
+// ListGames respond with games of the organisation, games of all
+// organisations are returned for empty organisation.
+func (s *Service) ListGames(ctx context.Context, organisationID string) ([]*sharedmodels.Game, error) {
+	list, err := s.pgRepo.ListGames(ctx, organisationID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't list games")
+	}
+
+	return list, nil
+}
+
+func (s *Service) checkOrganisation(ctx context.Context, organisationID string) error {
+	list, err := s.ListOrganisations(ctx, organisationID)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return ErrOrganisationNotFound
+	}
+
+	return nil
+}
+
+// CreateGame creates the new game owned by the organisation.
+func (s *Service) CreateGame(ctx context.Context, game *sharedmodels.Game) error {
+	var err error
+
+	game.Name, err = validateName(game.Name)
+	if err != nil {
+		return err
+	}
+
+	if game.OrganisationID != "" {
+		err = s.checkOrganisation(ctx, game.OrganisationID)
+		if err != nil {
+			return err
+		}
+	}
+
+	// id is always generated, existing games are not overwritten
+	game.GameID = ""
+
+	err = s.pgRepo.CreateGame(ctx, game)
+	if err != nil {
+		return errors.WithMessage(err, "can't create game")
+	}
+
+	return nil
+}
+
+// AssignGame moves the game to the organisation, operators of
+// the previous owner lose access to it.
+func (s *Service) AssignGame(ctx context.Context, gameID, organisationID string) error {
+	err := s.checkOrganisation(ctx, organisationID)
+	if err != nil {
+		return err
+	}
+
+	err = s.pgRepo.AssignGame(ctx, gameID, organisationID)
+	if err == pgx.ErrNoRows {
+		return ErrGameNotFound
+	}
+	if err != nil {
+		return errors.WithMessage(err, "can't assign game")
+	}
+
+	s.owners.Delete(gameID)
+
+	return nil
+}
@@ -2,20 +2,78 @@ package service
 
 import (
 	"context"
+	"net/url"
+	"time"
 
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
+	"gitlab.com/balconygames/analytics/pkg/audit"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	"gitlab.com/balconygames/analytics/pkg/cache"
+	"gitlab.com/balconygames/analytics/pkg/secrets"
+	"gitlab.com/balconygames/analytics/pkg/semver"
 	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
 )
 
 type PostgresRepository interface {
+	CreateGame(ctx context.Context, game *sharedmodels.Game) error
 	GetAppInfo(ctx context.Context, app *sharedmodels.App) error
+	UpdateAppVersions(ctx context.Context, app *sharedmodels.App) error
+
+	ListMaintenance(ctx context.Context, gameID string, endsAfter time.Time) ([]*sharedmodels.Maintenance, error)
+	CreateMaintenance(ctx context.Context, m *sharedmodels.Maintenance) error
+	UpdateMaintenance(ctx context.Context, m *sharedmodels.Maintenance) error
+	DeleteMaintenance(ctx context.Context, gameID, id string) error
+
+	ListAnnouncements(ctx context.Context, gameID string, expiresAfter time.Time) ([]*sharedmodels.Announcement, error)
+	CreateAnnouncement(ctx context.Context, a *sharedmodels.Announcement) error
+	UpdateAnnouncement(ctx context.Context, a *sharedmodels.Announcement) error
+	DeleteAnnouncement(ctx context.Context, gameID, id string) error
+
+	ListCredentials(ctx context.Context, gameID string) ([]*sharedmodels.NetworkCredentials, error)
+	ListAllCredentials(ctx context.Context) ([]*sharedmodels.NetworkCredentials, error)
+	GetCredentials(ctx context.Context, gameID, network, clientID string) (*sharedmodels.NetworkCredentials, error)
+	UpsertCredentials(ctx context.Context, c *sharedmodels.NetworkCredentials) error
+	ReplaceCredentialsSecret(ctx context.Context, c *sharedmodels.NetworkCredentials, previous string) (bool, error)
+	DeleteCredentials(ctx context.Context, gameID, network, clientID string) error
+	ListLegacyNetworks(ctx context.Context) ([]*sharedmodels.NetworkCredentials, error)
+	DeleteLegacyNetwork(ctx context.Context, c *sharedmodels.NetworkCredentials) error
+
+	ListAPIKeys(ctx context.Context, gameID string) ([]*sharedmodels.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*sharedmodels.APIKey, error)
+	CreateAPIKey(ctx context.Context, k *sharedmodels.APIKey) error
+	RevokeAPIKey(ctx context.Context, gameID, id string, at time.Time) (*sharedmodels.APIKey, error)
+	TouchAPIKey(ctx context.Context, id string, at time.Time) error
+
+	InsertAuditEntry(ctx context.Context, e *audit.Entry) error
+	ListAuditEntries(ctx context.Context, f audit.Filter) ([]*audit.Entry, error)
+
+	CreateOrganisation(ctx context.Context, o *sharedmodels.Organisation) error
+	EnsureOrganisation(ctx context.Context, o *sharedmodels.Organisation) error
+	ListOrganisations(ctx context.Context, ids []string) ([]*sharedmodels.Organisation, error)
+	ListGames(ctx context.Context, organisationID string) ([]*sharedmodels.Game, error)
+	GetGameOrganisation(ctx context.Context, gameID string) (string, error)
+	AssignGame(ctx context.Context, gameID, organisationID string) error
 }
 
 // Service contains all dependencies to perform common service tasks.
 type Service struct {
 	pgRepo PostgresRepository
 
+	// apps caches version rules of apps checked on client requests.
+	apps *cache.TTL
+	// notices caches maintenance windows and announcements per game.
+	notices *cache.TTL
+	// apiKeys caches API keys verified on server requests.
+	apiKeys *cache.TTL
+	// owners caches organisations owning games.
+	owners *cache.TTL
+
+	// Keys encrypt secrets of network credentials.
+	Keys *secrets.Keys
+
 	logger *zap.SugaredLogger
 }
 
@@ -23,11 +81,152 @@ type Service struct {
 // and repositories.
 func NewService(r PostgresRepository, l *zap.SugaredLogger) *Service {
 	return &Service{
-		pgRepo: r,
-		logger: l,
+		pgRepo:  r,
+		apps:    cache.NewTTL(appsCacheTTL),
+		notices: cache.NewTTL(noticesCacheTTL),
+		apiKeys: cache.NewTTL(apiKeysCacheTTL),
+		owners:  cache.NewTTL(ownersCacheTTL),
+		logger:  l,
+	}
+}
+
+// ErrAppNotFound returned for unknown game and app.
+var ErrAppNotFound = errors.New("app not found")
+
+// GetAppInfo fills the app by game and app ids with active maintenance
+// and announcements localized for the locale, update status is set in
+// case if client version is passed.
+func (s *Service) GetAppInfo(ctx context.Context, clientApp *sharedmodels.App, clientVersion, locale string) error {
+	err := s.pgRepo.GetAppInfo(ctx, clientApp)
+	if err == pgx.ErrNoRows {
+		return ErrAppNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	clientApp.Maintenance, clientApp.Announcements, err = s.activeNotices(ctx, clientApp.GameID, clientApp.AppID, locale)
+	if err != nil {
+		return err
+	}
+
+	if clientVersion == "" {
+		return nil
 	}
+
+	clientApp.UpdateStatus, err = clientApp.UpdateStatusOf(clientVersion)
+	if err != nil {
+		return errors.WithMessage(err, "invalid client version")
+	}
+
+	return nil
+}
+
+// appsCacheTTL is time to reload version rules of apps,
+// rules are read on every client request.
+const appsCacheTTL = 30 * time.Second
+
+func appKey(gameID, appID string) string {
+	return gameID + ":" + appID
 }
 
-func (s *Service) GetAppInfo(ctx context.Context, clientApp *sharedmodels.App) error {
-	return s.pgRepo.GetAppInfo(ctx, clientApp)
+// cachedAppInfo respond with the app or nil for unknown apps.
+func (s *Service) cachedAppInfo(ctx context.Context, gameID, appID string) (*sharedmodels.App, error) {
+	key := appKey(gameID, appID)
+
+	if cached, ok := s.apps.Get(key); ok {
+		return cached.(*sharedmodels.App), nil
+	}
+
+	app := &sharedmodels.App{GameID: gameID, AppID: appID}
+	err := s.pgRepo.GetAppInfo(ctx, app)
+	if err == pgx.ErrNoRows {
+		// unknown apps aren't cached, otherwise made up apps of
+		// client requests grow the cache and created apps are
+		// unknown until the entry expires.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithMessage(err, "can't get app info")
+	}
+
+	s.apps.Set(key, app)
+
+	return app, nil
+}
+
+// CheckVersion returns auth.UpgradeRequiredError for client versions
+// below the required version of the app. Unknown apps and versions
+// which can't be parsed are allowed.
+func (s *Service) CheckVersion(ctx context.Context, gameID, appID, version string) error {
+	app, err := s.cachedAppInfo(ctx, gameID, appID)
+	if err != nil {
+		return err
+	}
+	if app == nil {
+		return nil
+	}
+
+	status, err := app.UpdateStatusOf(version)
+	if err != nil {
+		s.logger.With("game_id", gameID, "app_id", appID).Debugf("can't check client version: %s", err)
+		return nil
+	}
+	if status != sharedmodels.UpdateRequired {
+		return nil
+	}
+
+	return &auth.UpgradeRequiredError{
+		Version:            version,
+		MinVersion:         app.RequiredVersion(),
+		RecommendedVersion: app.RecommendedVersion,
+		StoreURL:           app.StoreURL,
+	}
+}
+
+// UpdateAppVersions sets minimum and recommended versions of the app
+// and store url to update the app.
+func (s *Service) UpdateAppVersions(ctx context.Context, app *sharedmodels.App) error {
+	err := validateVersions(app)
+	if err != nil {
+		return err
+	}
+
+	err = s.pgRepo.UpdateAppVersions(ctx, app)
+	if err == pgx.ErrNoRows {
+		return ErrAppNotFound
+	}
+	if err != nil {
+		return errors.WithMessage(err, "can't update app versions")
+	}
+
+	s.apps.Delete(appKey(app.GameID, app.AppID))
+
+	return nil
+}
+
+func validateVersions(app *sharedmodels.App) error {
+	for name, version := range map[string]string{
+		"min_version":         app.MinVersion,
+		"recommended_version": app.RecommendedVersion,
+	} {
+		if version != "" && !semver.Valid(version) {
+			return errors.Errorf("%s %q is not semantic version", name, version)
+		}
+	}
+
+	if app.MinVersion != "" && app.RecommendedVersion != "" &&
+		semver.MustParse(app.RecommendedVersion).Less(semver.MustParse(app.MinVersion)) {
+		return errors.New("recommended_version should be greater or equal to min_version")
+	}
+
+	if app.StoreURL != "" {
+		u, err := url.Parse(app.StoreURL)
+		// stores use own schemes like market:// or itms-apps://
+		if err != nil || u.Scheme == "" || (u.Host == "" && u.Opaque == "") {
+			return errors.Errorf("store_url %q is not valid url", app.StoreURL)
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// fakeAppsRepo stores apps in memory, other methods of repository
+// are not used.
+type fakeAppsRepo struct {
+	PostgresRepository
+
+	byKey map[string]*sharedmodels.App
+}
+
+func (f *fakeAppsRepo) GetAppInfo(_ context.Context, app *sharedmodels.App) error {
+	stored, ok := f.byKey[appKey(app.GameID, app.AppID)]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	*app = *stored
+	return nil
+}
+
+func TestCheckVersionUnknownApps(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeAppsRepo{byKey: map[string]*sharedmodels.App{}}
+	svc := NewService(repo, zaptest.NewLogger(t).Sugar())
+
+	// unknown apps are allowed and aren't cached
+	for _, appID := range []string{"app-1", "app-2", "app-3"} {
+		err := svc.CheckVersion(ctx, "game-1", appID, "1.0.0")
+		require.Nil(t, err)
+	}
+	require.Equal(t, 0, svc.apps.Len())
+
+	// app created after the first request is checked right away
+	repo.byKey[appKey("game-1", "app-1")] = &sharedmodels.App{
+		GameID:     "game-1",
+		AppID:      "app-1",
+		MinVersion: "2.0.0",
+	}
+	err := svc.CheckVersion(ctx, "game-1", "app-1", "1.0.0")
+	upgrade, ok := err.(*auth.UpgradeRequiredError)
+	require.True(t, ok)
+	require.Equal(t, "2.0.0", upgrade.MinVersion)
+	require.Equal(t, 1, svc.apps.Len())
+}
@@ -0,0 +1,4 @@
+ALTER TABLE apps
+    DROP COLUMN min_version,
+    DROP COLUMN recommended_version,
+    DROP COLUMN store_url;
@@ -0,0 +1,6 @@
+ALTER TABLE apps
+    ADD COLUMN min_version varchar(64) not null default '',
+    ADD COLUMN recommended_version varchar(64) not null default '',
+    ADD COLUMN store_url varchar(1024) not null default '';
+
+COMMENT ON COLUMN apps.min_version IS 'Clients below the version are rejected with upgrade required error';
@@ -0,0 +1,47 @@
+CREATE TABLE maintenance_windows (
+    -- GUID
+    id varchar(36) not null,
+    game_id varchar(36) not null,
+    -- empty for maintenance of all apps of the game
+    app_id varchar(36) not null default '',
+
+    -- client requests are rejected between starts_at and ends_at
+    starts_at timestamp not null,
+    ends_at timestamp not null,
+
+    -- messages by locale, default locale is used as fallback
+    messages jsonb not null default '{}',
+
+    created_at timestamp not null default now(),
+    updated_at timestamp not null default now(),
+
+    PRIMARY KEY(id)
+);
+CREATE INDEX idx_maintenance_windows_game_id_ends_at ON maintenance_windows(game_id, ends_at);
+COMMENT ON TABLE maintenance_windows IS 'Windows when clients of the game or the app get maintenance error';
+
+CREATE TABLE announcements (
+    -- GUID
+    id varchar(36) not null,
+    game_id varchar(36) not null,
+    -- empty for announcement of all apps of the game
+    app_id varchar(36) not null default '',
+
+    -- announcements with higher priority go first
+    priority int not null default 0,
+
+    -- titles and messages by locale
+    titles jsonb not null default '{}',
+    messages jsonb not null default '{}',
+
+    starts_at timestamp not null,
+    -- empty for announcements shown until deleted
+    expires_at timestamp,
+
+    created_at timestamp not null default now(),
+    updated_at timestamp not null default now(),
+
+    PRIMARY KEY(id)
+);
+CREATE INDEX idx_announcements_game_id ON announcements(game_id);
+COMMENT ON TABLE announcements IS 'Messages shown to clients of the game or the app until expiration';
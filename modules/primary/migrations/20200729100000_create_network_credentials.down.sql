@@ -0,0 +1 @@
+DROP TABLE network_credentials;
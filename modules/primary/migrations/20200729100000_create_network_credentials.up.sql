@@ -0,0 +1,22 @@
+CREATE TABLE network_credentials (
+    -- GUID
+    game_id varchar(36) not null REFERENCES games(game_id),
+
+    -- facebook, google, apple, twitter
+    network varchar(64) not null,
+    -- app id, client id or service id in the network
+    client_id varchar(256) not null,
+
+    -- settings which are not secret, e.g. team id of apple
+    config jsonb not null default '{}',
+    -- secret encrypted by AES-256-GCM as v1:<key id>:<base64>,
+    -- empty for credentials without secret
+    secret text not null default '',
+
+    created_at timestamp not null default now(),
+    updated_at timestamp not null default now(),
+
+    PRIMARY KEY(game_id, network, client_id)
+);
+COMMENT ON TABLE network_credentials IS 'Credentials of games in networks, secrets are encrypted at rest';
+COMMENT ON TABLE networks IS 'Deprecated: plain text credentials, imported into network_credentials';
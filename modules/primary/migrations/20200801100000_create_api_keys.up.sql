@@ -0,0 +1,28 @@
+CREATE TABLE api_keys (
+    -- GUID
+    id varchar(36) not null,
+    game_id varchar(36) not null REFERENCES games(game_id),
+    -- empty for keys of all apps of the game
+    app_id varchar(36) not null default '',
+
+    name varchar(256) not null,
+    -- beginning of the key shown in dashboard
+    prefix varchar(16) not null,
+    -- hex encoded sha256 of the key, the key itself is not stored
+    key_hash varchar(64) not null,
+    -- e.g. ["leaderboard:write", "properties:read"]
+    scopes jsonb not null default '[]',
+
+    -- user id of operator created the key
+    created_by varchar(36) not null default '',
+    created_at timestamp not null default now(),
+    -- empty for keys without expiration
+    expires_at timestamp,
+    last_used_at timestamp,
+    revoked_at timestamp,
+
+    PRIMARY KEY(id)
+);
+CREATE UNIQUE INDEX idx_api_keys_key_hash ON api_keys(key_hash);
+CREATE INDEX idx_api_keys_game_id ON api_keys(game_id);
+COMMENT ON TABLE api_keys IS 'Hashed API keys of server integrations with scopes';
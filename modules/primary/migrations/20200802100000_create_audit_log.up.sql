@@ -0,0 +1,27 @@
+CREATE TABLE audit_log (
+    id bigserial PRIMARY KEY,
+
+    -- user id of operator or id of api key
+    actor_id varchar(36) not null default '',
+    -- server or api-key
+    actor_type varchar(32) not null default '',
+
+    -- route of the request by default, e.g. PUT /primary/v1/games/{game_id}
+    action varchar(256) not null,
+
+    game_id varchar(36) not null default '',
+    target_type varchar(64) not null default '',
+    target_id varchar(256) not null default '',
+
+    -- changed fields of the target: {"field": {"before": ..., "after": ...}}
+    changes jsonb not null default '{}',
+
+    ip varchar(64) not null default '',
+    request_id varchar(128) not null default '',
+
+    created_at timestamp not null default now()
+);
+CREATE INDEX idx_audit_log_game_id ON audit_log(game_id, id);
+CREATE INDEX idx_audit_log_actor_id ON audit_log(actor_id, id);
+CREATE INDEX idx_audit_log_target ON audit_log(target_type, target_id, id);
+COMMENT ON TABLE audit_log IS 'Mutations made by operators and api keys on server routes';
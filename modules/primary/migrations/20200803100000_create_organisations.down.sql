@@ -0,0 +1,3 @@
+ALTER TABLE games DROP COLUMN organisation_id;
+
+DROP TABLE organisations;
@@ -0,0 +1,16 @@
+CREATE TABLE organisations (
+    -- GUID
+    organisation_id varchar(36) not null,
+
+    name varchar(256) not null,
+
+    created_at timestamp not null default now(),
+    updated_at timestamp not null default now(),
+
+    PRIMARY KEY(organisation_id)
+);
+COMMENT ON TABLE organisations IS 'Studios owning games, operators manage games of their organisation';
+
+-- empty for games managed by platform operators only
+ALTER TABLE games ADD COLUMN organisation_id varchar(36) REFERENCES organisations(organisation_id);
+CREATE INDEX idx_games_organisation_id ON games(organisation_id);
@@ -12,14 +12,21 @@ import (
 	"gitlab.com/balconygames/analytics/modules/primary/internal/db"
 	"gitlab.com/balconygames/analytics/modules/primary/internal/handlers"
 	"gitlab.com/balconygames/analytics/modules/primary/internal/service"
+	"gitlab.com/balconygames/analytics/pkg/auth"
 	"gitlab.com/balconygames/analytics/pkg/logging"
 	"gitlab.com/balconygames/analytics/pkg/postgres"
 	"gitlab.com/balconygames/analytics/pkg/runtime"
+	"gitlab.com/balconygames/analytics/pkg/secrets"
 )
 
 type spec struct {
 	Env      string          `envconfig:"ENV" required:"True"`
 	Postgres postgres.Config `envconfig:"POSTGRES" required:"True"`
+
+	// AES256Key encrypts secrets of network credentials
+	AES256Key string `envconfig:"AES256_KEY" required:"True"`
+	// AES256PreviousKeys decrypt secrets until they are rotated
+	AES256PreviousKeys []string `envconfig:"AES256_PREVIOUS_KEYS"`
 }
 
 func New(r *runtime.Runtime) error {
@@ -59,8 +66,40 @@ func withSpec(r *runtime.Runtime, s spec) error {
 
 	repo := db.NewPostgresRepository(pool)
 	svc := service.NewService(repo, logger)
+	svc.Keys, err = secrets.NewKeys(s.AES256Key, s.AES256PreviousKeys...)
+	if err != nil {
+		return errors.Wrap(err, "invalid aes256 key")
+	}
 	h := handlers.New(svc, logger)
 
+	// client routes of all modules reject versions below minimum version
+	// of the app.
+	r.WithVersionChecker(svc)
+	// client routes of all modules respond with 503 during maintenance
+	r.WithMaintenanceChecker(svc)
+	// auth module signs in users with network credentials of the game
+	r.WithCredentials(svc)
+	// server routes of all modules accept API keys with scopes
+	r.WithAPIKeys(svc)
+	// server routes of all modules record mutations into audit log
+	r.WithAuditRecorder(svc)
+	// server routes of all modules restrict operators to games
+	// of their organisation
+	r.WithOrganisations(svc)
+
+	// tests and migrations run without platform organisation
+	if platformID := r.PlatformOrganisationID(); platformID != "" {
+		err = svc.EnsurePlatformOrganisation(context.Background(), platformID)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.WithCommand("primary.credentials.rotate", rotateCredentialsCommand(svc))
+	r.WithCommand("primary.credentials.import", importCredentialsCommand(svc))
+	r.WithCommand("primary.organisations.create", createOrganisationCommand(svc))
+	r.WithCommand("primary.games.assign", assignGameCommand(svc))
+
 	r.WithRoutes(func(r1 chi.Router) {
 		// on the client we should give ability to get application info like
 		// version name.
@@ -70,15 +109,50 @@ func withSpec(r *runtime.Runtime, s spec) error {
 		})
 
 		r.WithServerAuth(r1, func(r2 chi.Router) {
-			r2.Get("/primary/v1/games", h.ListGames)
-			r2.Post("/primary/v1/games", h.CreateGame)
-			r2.Put("/primary/v1/games/{game_id}", h.UpdateGame)
-			r2.Delete("/primary/v1/games/{game_id}", h.DeleteGame)
-
-			r2.Get("/primary/v1/games/{game_id}/apps", h.ListApps)
-			r2.Post("/primary/v1/games/{game_id}/apps", h.CreateApp)
-			r2.Put("/primary/v1/games/{game_id}/apps/{app_id}", h.UpdateApp)
-			r2.Delete("/primary/v1/games/{game_id}/apps/{app_id}", h.DeleteApp)
+			r2.Group(func(i chi.Router) {
+				i.Use(auth.RequireRoles(auth.RoleReadOnly))
+				i.Get("/primary/v1/organisations", h.ListOrganisations)
+				i.Get("/primary/v1/games", h.ListGames)
+				i.Get("/primary/v1/games/{game_id}/apps", h.ListApps)
+				i.Get("/primary/v1/games/{game_id}/maintenance", h.ListMaintenance)
+				i.Get("/primary/v1/games/{game_id}/announcements", h.ListAnnouncements)
+				i.Get("/primary/v1/games/{game_id}/networks", h.ListCredentials)
+				i.Get("/primary/v1/games/{game_id}/api-keys", h.ListAPIKeys)
+			})
+
+			r2.Group(func(i chi.Router) {
+				i.Use(auth.RequireRoles(auth.RoleAdmin))
+				i.Post("/primary/v1/games", h.CreateGame)
+				i.Put("/primary/v1/games/{game_id}", h.UpdateGame)
+				i.Delete("/primary/v1/games/{game_id}", h.DeleteGame)
+				i.Get("/primary/v1/audit", h.ListAuditLog)
+
+				// platform operators only
+				i.Post("/primary/v1/organisations", h.CreateOrganisation)
+				i.Put("/primary/v1/games/{game_id}/organisation", h.AssignGame)
+
+				i.Put("/primary/v1/games/{game_id}/networks/{network}/credentials/{client_id}", h.UpdateCredentials)
+				i.Delete("/primary/v1/games/{game_id}/networks/{network}/credentials/{client_id}", h.DeleteCredentials)
+
+				i.Post("/primary/v1/games/{game_id}/api-keys", h.CreateAPIKey)
+				i.Delete("/primary/v1/games/{game_id}/api-keys/{api_key_id}", h.RevokeAPIKey)
+			})
+
+			r2.Group(func(i chi.Router) {
+				i.Use(auth.RequireRoles(auth.RoleLiveOps))
+				i.Post("/primary/v1/games/{game_id}/apps", h.CreateApp)
+				i.Put("/primary/v1/games/{game_id}/apps/{app_id}", h.UpdateApp)
+				i.Delete("/primary/v1/games/{game_id}/apps/{app_id}", h.DeleteApp)
+				i.Put("/primary/v1/games/{game_id}/apps/{app_id}/versions", h.UpdateAppVersions)
+
+				i.Post("/primary/v1/games/{game_id}/maintenance", h.CreateMaintenance)
+				i.Put("/primary/v1/games/{game_id}/maintenance/{maintenance_id}", h.UpdateMaintenance)
+				i.Delete("/primary/v1/games/{game_id}/maintenance/{maintenance_id}", h.DeleteMaintenance)
+
+				i.Post("/primary/v1/games/{game_id}/announcements", h.CreateAnnouncement)
+				i.Put("/primary/v1/games/{game_id}/announcements/{announcement_id}", h.UpdateAnnouncement)
+				i.Delete("/primary/v1/games/{game_id}/announcements/{announcement_id}", h.DeleteAnnouncement)
+			})
 		})
 	})
 
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"gitlab.com/balconygames/analytics/modules/remoteconfig/internal/models"
+)
+
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
+	return &PostgresRepository{pool: pool}
+}
+
+const selectConfigQuery = `
+	SELECT
+		game_id
+		, version
+		, config_values
+		, overrides
+		, comment
+		, created_by
+		, rollback_of
+		, created_at
+	FROM config_versions
+`
+
+func scanConfig(row pgx.Row) (*models.Config, error) {
+	config := &models.Config{}
+
+	var values, overrides []byte
+	err := row.Scan(&config.GameID, &config.Version, &values, &overrides,
+		&config.Comment, &config.CreatedBy, &config.RollbackOf, &config.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(values, &config.Values)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(overrides, &config.Overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// PublishConfig stores the config as the next version of the game,
+// the latest version is served to clients.
+func (r PostgresRepository) PublishConfig(ctx context.Context, config *models.Config) error {
+	values, err := json.Marshal(config.Values)
+	if err != nil {
+		return err
+	}
+
+	overrides, err := json.Marshal(config.Overrides)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// publishes of the same game are serialized until commit
+	_, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('config:' || $1))`, config.GameID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO
+			config_versions (
+				game_id
+				, version
+				, config_values
+				, overrides
+				, comment
+				, created_by
+				, rollback_of
+			)
+		SELECT
+			$1
+			, COALESCE(MAX(version), 0) + 1
+			, $2::jsonb
+			, $3::jsonb
+			, $4
+			, $5
+			, $6::bigint
+		FROM config_versions
+		WHERE game_id=$1
+		RETURNING version, created_at
+	`
+
+	err = tx.QueryRow(ctx, query, config.GameID, values, overrides,
+		config.Comment, config.CreatedBy, config.RollbackOf).
+		Scan(&config.Version, &config.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetActiveConfig respond with the latest version of config of the game.
+func (r PostgresRepository) GetActiveConfig(ctx context.Context, gameID string) (*models.Config, error) {
+	row := r.pool.QueryRow(ctx, selectConfigQuery+`
+		WHERE game_id=$1
+		ORDER BY version DESC
+		LIMIT 1
+	`, gameID)
+
+	config, err := scanConfig(row)
+	if err == pgx.ErrNoRows {
+		return nil, models.ErrConfigNotFound
+	}
+
+	return config, err
+}
+
+// GetConfigVersion respond with the version of config of the game.
+func (r PostgresRepository) GetConfigVersion(ctx context.Context, gameID string, version int64) (*models.Config, error) {
+	row := r.pool.QueryRow(ctx, selectConfigQuery+`
+		WHERE
+			game_id=$1
+			AND version=$2
+	`, gameID, version)
+
+	config, err := scanConfig(row)
+	if err == pgx.ErrNoRows {
+		return nil, models.ErrConfigNotFound
+	}
+
+	return config, err
+}
+
+// ListConfigVersions respond with versions of config of the game,
+// the latest versions go first.
+func (r PostgresRepository) ListConfigVersions(ctx context.Context, gameID string, limit, offset int) ([]*models.Config, error) {
+	rows, err := r.pool.Query(ctx, selectConfigQuery+`
+		WHERE game_id=$1
+		ORDER BY version DESC
+		LIMIT $2
+		OFFSET $3
+	`, gameID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	configs := []*models.Config{}
+	for rows.Next() {
+		config, err := scanConfig(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		configs = append(configs, config)
+	}
+
+	return configs, rows.Err()
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"gitlab.com/balconygames/analytics/modules/remoteconfig/internal/models"
+	test_helpers "gitlab.com/balconygames/analytics/pkg/test_helpers"
+)
+
+type serviceSuite struct {
+	test_helpers.PostgresSuite
+}
+
+const gameID = "2c8e1e2a-1b8d-4a1e-9c2f-1d6a5f3b7e01"
+
+func TestEntrypointSuite(t *testing.T) {
+	handler := &serviceSuite{
+		PostgresSuite: test_helpers.NewDefaultPostgresSuite(t, "test_remote_config"),
+	}
+
+	suite.Run(t, handler)
+}
+
+func (s *serviceSuite) TestPublishConfig() {
+	ctx := context.Background()
+	repo := NewPostgresRepository(s.PostgresPool)
+
+	_, err := repo.GetActiveConfig(ctx, gameID)
+	s.Require().Equal(models.ErrConfigNotFound, err)
+
+	for _, coins := range []float64{10, 20} {
+		err = repo.PublishConfig(ctx, &models.Config{
+			GameID: gameID,
+			Values: map[string]interface{}{"coins": coins},
+			Overrides: []*models.Override{{
+				Name:       "android",
+				Conditions: models.Conditions{Platforms: []string{"ANDROID"}},
+				Values:     map[string]interface{}{"coins": coins * 2},
+			}},
+		})
+		s.Require().NoError(err)
+	}
+
+	active, err := repo.GetActiveConfig(ctx, gameID)
+	s.Require().NoError(err)
+	s.Require().Equal(int64(2), active.Version)
+	s.Require().Equal(20.0, active.Values["coins"])
+	s.Require().Len(active.Overrides, 1)
+	s.Require().Equal([]string{"ANDROID"}, active.Overrides[0].Conditions.Platforms)
+
+	first, err := repo.GetConfigVersion(ctx, gameID, 1)
+	s.Require().NoError(err)
+	s.Require().Equal(10.0, first.Values["coins"])
+
+	_, err = repo.GetConfigVersion(ctx, gameID, 3)
+	s.Require().Equal(models.ErrConfigNotFound, err)
+
+	versions, err := repo.ListConfigVersions(ctx, gameID, 1, 1)
+	s.Require().NoError(err)
+	s.Require().Len(versions, 1)
+	s.Require().Equal(int64(1), versions[0].Version)
+}
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"gitlab.com/balconygames/analytics/modules/remoteconfig/internal/models"
+	"gitlab.com/balconygames/analytics/modules/remoteconfig/internal/service"
+	"gitlab.com/balconygames/analytics/pkg/audit"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	httpreq "gitlab.com/balconygames/analytics/pkg/http"
+)
+
+type Handler struct {
+	service *service.Service
+
+	logger *zap.SugaredLogger
+}
+
+func New(s *service.Service, l *zap.SugaredLogger) *Handler {
+	return &Handler{
+		service: s,
+		logger:  l.With("scope", "remoteconfig.handler"),
+	}
+}
+
+// GetConfig respond with config resolved for the app of the client,
+// platform and version are passed by query params, country is resolved
+// by ip address. Clients pass ETag of the cached config by
+// If-None-Match and get 304 once config is not changed.
+func (h *Handler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	scope := auth.GetScope(r)
+	query := r.URL.Query()
+
+	audience := models.Audience{
+		AppID:    scope.AppID,
+		Platform: query.Get("platform"),
+		Version:  query.Get("version"),
+	}
+	if audience.Version == "" {
+		audience.Version = r.Header.Get(auth.ClientVersionHeader)
+	}
+
+	country, err := h.service.Geo.Resolve(clientIP(r))
+	if err != nil {
+		// overrides by country are skipped for unknown countries
+		h.logger.With(scope.Fields()...).Debugf("can't resolve country of client: %s", err)
+	}
+	audience.Country = country
+
+	resolved, err := h.service.ResolveConfig(r.Context(), scope.GameID, audience)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't resolve config"))
+		return
+	}
+
+	etag, err := service.ETag(resolved)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't build config etag"))
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+
+	if etagMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	httpreq.JSON(w, resolved)
+}
+
+// etagMatch checks entity tags listed in If-None-Match header.
+func etagMatch(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// GetActiveConfig respond with the latest version of config of the game.
+func (h *Handler) GetActiveConfig(w http.ResponseWriter, r *http.Request) {
+	config, err := h.service.GetActiveConfig(r.Context(), chi.URLParam(r, "game_id"))
+	if err == models.ErrConfigNotFound {
+		httpreq.JSONWithStatus(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't get active config"))
+		return
+	}
+
+	httpreq.JSON(w, config)
+}
+
+type listVersionsResponse struct {
+	Versions []*models.Config `json:"versions"`
+}
+
+// ListConfigVersions respond with published versions of config of the
+// game, paginated by limit and offset query params.
+func (h *Handler) ListConfigVersions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	versions, err := h.service.ListConfigVersions(r.Context(), chi.URLParam(r, "game_id"), limit, offset)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't list config versions"))
+		return
+	}
+
+	httpreq.JSON(w, listVersionsResponse{versions})
+}
+
+// GetConfigVersion respond with the version of config of the game.
+func (h *Handler) GetConfigVersion(w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.ParseInt(chi.URLParam(r, "version"), 10, 64)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "invalid version"))
+		return
+	}
+
+	config, err := h.service.GetConfigVersion(r.Context(), chi.URLParam(r, "game_id"), version)
+	if err == models.ErrConfigNotFound {
+		httpreq.JSONWithStatus(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't get config version"))
+		return
+	}
+
+	httpreq.JSON(w, config)
+}
+
+type configRequest struct {
+	Values    map[string]interface{} `json:"values"`
+	Overrides []*models.Override     `json:"overrides"`
+	Comment   string                 `json:"comment"`
+}
+
+// PublishConfig stores config as the next version of the game, clients
+// get it once cache of instances is expired.
+func (h *Handler) PublishConfig(w http.ResponseWriter, r *http.Request) {
+	data := configRequest{}
+	err := httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read config body"))
+		return
+	}
+
+	config := &models.Config{
+		GameID:    chi.URLParam(r, "game_id"),
+		Values:    data.Values,
+		Overrides: data.Overrides,
+		Comment:   data.Comment,
+		CreatedBy: auth.GetUser(r).UserID,
+	}
+
+	previous, err := h.service.GetActiveConfig(r.Context(), config.GameID)
+	if err != nil && err != models.ErrConfigNotFound {
+		httpreq.Error(w, errors.Wrap(err, "can't get active config"))
+		return
+	}
+
+	err = h.service.PublishConfig(r.Context(), config)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't publish config"))
+		return
+	}
+	auditConfig(r, previous, config)
+
+	h.logger.
+		With("game_id", config.GameID, "version", config.Version, "user_id", config.CreatedBy).
+		Info("published config")
+
+	httpreq.JSON(w, config)
+}
+
+// auditConfig records published version and changed values of config,
+// previous is nil for the first version.
+func auditConfig(r *http.Request, previous, config *models.Config) {
+	audit.SetTarget(r, "config_version", strconv.FormatInt(config.Version, 10))
+
+	changed := func(c *models.Config) map[string]interface{} {
+		if c == nil {
+			return nil
+		}
+		return map[string]interface{}{"values": c.Values, "overrides": c.Overrides}
+	}
+	audit.SetChanges(r, changed(previous), changed(config))
+}
+
+type previewRequest struct {
+	// Config is previewed instead of active config once passed.
+	Config   *configRequest  `json:"config"`
+	Audience models.Audience `json:"audience"`
+}
+
+// PreviewConfig respond with config resolved for the audience and names
+// of matched overrides, nothing is published.
+func (h *Handler) PreviewConfig(w http.ResponseWriter, r *http.Request) {
+	data := previewRequest{}
+	err := httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read preview body"))
+		return
+	}
+
+	gameID := chi.URLParam(r, "game_id")
+
+	var config *models.Config
+	if data.Config != nil {
+		config = &models.Config{
+			GameID:    gameID,
+			Values:    data.Config.Values,
+			Overrides: data.Config.Overrides,
+		}
+	}
+
+	resolved, err := h.service.PreviewConfig(r.Context(), gameID, config, data.Audience)
+	if err == models.ErrConfigNotFound {
+		httpreq.JSONWithStatus(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't preview config"))
+		return
+	}
+
+	httpreq.JSON(w, resolved)
+}
+
+type rollbackRequest struct {
+	Version int64  `json:"version"`
+	Comment string `json:"comment"`
+}
+
+// RollbackConfig publishes copy of the previous version of config.
+func (h *Handler) RollbackConfig(w http.ResponseWriter, r *http.Request) {
+	data := rollbackRequest{}
+	err := httpreq.Read(r, &data)
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't read rollback body"))
+		return
+	}
+
+	gameID := chi.URLParam(r, "game_id")
+	userID := auth.GetUser(r).UserID
+
+	previous, err := h.service.GetActiveConfig(r.Context(), gameID)
+	if err != nil && err != models.ErrConfigNotFound {
+		httpreq.Error(w, errors.Wrap(err, "can't get active config"))
+		return
+	}
+
+	config, err := h.service.RollbackConfig(r.Context(), gameID, data.Version, userID, data.Comment)
+	if err == models.ErrConfigNotFound {
+		httpreq.JSONWithStatus(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		httpreq.Error(w, errors.Wrap(err, "can't rollback config"))
+		return
+	}
+
+	auditConfig(r, previous, config)
+
+	h.logger.
+		With("game_id", gameID, "version", config.Version, "rollback_of", data.Version, "user_id", userID).
+		Info("rolled back config")
+
+	httpreq.JSON(w, config)
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestETagMatch(t *testing.T) {
+	etag := `"3-abc"`
+
+	require.True(t, etagMatch(`"3-abc"`, etag))
+	require.True(t, etagMatch(`"2-def", W/"3-abc"`, etag))
+	require.True(t, etagMatch(`*`, etag))
+	require.False(t, etagMatch(``, etag))
+	require.False(t, etagMatch(`"2-def"`, etag))
+}
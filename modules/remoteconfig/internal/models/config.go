@@ -0,0 +1,178 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/balconygames/analytics/pkg/semver"
+)
+
+// ErrConfigNotFound returned once the game has no published config
+// or the version doesn't exist.
+var ErrConfigNotFound = errors.New("config not found")
+
+// Config is published version of remote config of the game.
+type Config struct {
+	GameID  string `json:"game_id"`
+	Version int64  `json:"version"`
+
+	// Values are default values for all clients of the game.
+	Values map[string]interface{} `json:"values"`
+	// Overrides are applied in order on top of values once
+	// conditions match the audience of the client.
+	Overrides []*Override `json:"overrides"`
+
+	Comment    string `json:"comment"`
+	CreatedBy  string `json:"created_by"`
+	RollbackOf *int64 `json:"rollback_of,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Override replaces values for clients matched by conditions,
+// nested objects are merged.
+type Override struct {
+	Name       string                 `json:"name"`
+	Conditions Conditions             `json:"conditions"`
+	Values     map[string]interface{} `json:"values"`
+}
+
+// Conditions of override, empty conditions match any client.
+// MinVersion is inclusive and MaxVersion is exclusive.
+type Conditions struct {
+	AppIDs     []string `json:"app_ids,omitempty"`
+	Platforms  []string `json:"platforms,omitempty"`
+	Countries  []string `json:"countries,omitempty"`
+	MinVersion string   `json:"min_version,omitempty"`
+	MaxVersion string   `json:"max_version,omitempty"`
+}
+
+// Audience describes the client requested config.
+type Audience struct {
+	AppID    string `json:"app_id"`
+	Platform string `json:"platform"`
+	Country  string `json:"country"`
+	Version  string `json:"version"`
+}
+
+// Resolved is config for the audience.
+type Resolved struct {
+	Version int64                  `json:"version"`
+	Values  map[string]interface{} `json:"values"`
+	// Overrides are names of matched overrides, used by preview.
+	Overrides []string `json:"overrides,omitempty"`
+}
+
+// Validate checks versions of conditions and names of overrides.
+func (c *Config) Validate() error {
+	names := make(map[string]bool, len(c.Overrides))
+
+	for i, o := range c.Overrides {
+		if o == nil {
+			return errors.Errorf("override %d is empty", i)
+		}
+		if o.Name == "" {
+			return errors.Errorf("override %d should have name", i)
+		}
+		if names[o.Name] {
+			return errors.Errorf("override %s is duplicated", o.Name)
+		}
+		names[o.Name] = true
+
+		for _, version := range []string{o.Conditions.MinVersion, o.Conditions.MaxVersion} {
+			if version != "" && !semver.Valid(version) {
+				return errors.Errorf("override %s has invalid version %q", o.Name, version)
+			}
+		}
+
+		min, max := o.Conditions.MinVersion, o.Conditions.MaxVersion
+		if min != "" && max != "" && !semver.MustParse(min).Less(semver.MustParse(max)) {
+			return errors.Errorf("override %s should have min_version less than max_version", o.Name)
+		}
+	}
+
+	return nil
+}
+
+// Match checks if the audience matches all conditions.
+func (c Conditions) Match(a Audience) bool {
+	if len(c.AppIDs) != 0 && !contains(c.AppIDs, a.AppID) {
+		return false
+	}
+	if len(c.Platforms) != 0 && !contains(c.Platforms, a.Platform) {
+		return false
+	}
+	if len(c.Countries) != 0 && !contains(c.Countries, a.Country) {
+		return false
+	}
+
+	if c.MinVersion == "" && c.MaxVersion == "" {
+		return true
+	}
+
+	// version conditions don't match clients with unknown version
+	version, err := semver.Parse(a.Version)
+	if err != nil {
+		return false
+	}
+	if c.MinVersion != "" && version.Less(semver.MustParse(c.MinVersion)) {
+		return false
+	}
+	if c.MaxVersion != "" && !version.Less(semver.MustParse(c.MaxVersion)) {
+		return false
+	}
+
+	return true
+}
+
+// Resolve applies matched overrides on top of values.
+func (c *Config) Resolve(a Audience) *Resolved {
+	resolved := &Resolved{
+		Version: c.Version,
+		Values:  merge(make(map[string]interface{}), c.Values),
+	}
+
+	for _, o := range c.Overrides {
+		if !o.Conditions.Match(a) {
+			continue
+		}
+
+		resolved.Values = merge(resolved.Values, o.Values)
+		resolved.Overrides = append(resolved.Overrides, o.Name)
+	}
+
+	return resolved
+}
+
+// merge copies src into dst, objects are merged recursively and other
+// values are replaced.
+func merge(dst, src map[string]interface{}) map[string]interface{} {
+	for key, value := range src {
+		srcObject, ok := value.(map[string]interface{})
+		if !ok {
+			dst[key] = value
+			continue
+		}
+
+		dstObject, ok := dst[key].(map[string]interface{})
+		if !ok {
+			dstObject = make(map[string]interface{}, len(srcObject))
+		}
+		dst[key] = merge(dstObject, srcObject)
+	}
+
+	return dst
+}
+
+// contains compares values case insensitive, platforms and countries
+// are passed in different cases by clients.
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
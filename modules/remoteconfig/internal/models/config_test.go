@@ -0,0 +1,81 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig() *Config {
+	return &Config{
+		GameID:  "game-1",
+		Version: 3,
+		Values: map[string]interface{}{
+			"coins": 10.0,
+			"shop": map[string]interface{}{
+				"discount": 0.0,
+				"enabled":  true,
+			},
+		},
+		Overrides: []*Override{
+			{
+				Name:       "android",
+				Conditions: Conditions{Platforms: []string{"ANDROID"}},
+				Values:     map[string]interface{}{"coins": 20.0},
+			},
+			{
+				Name:       "us-sale",
+				Conditions: Conditions{Countries: []string{"us"}, MinVersion: "1.2.0", MaxVersion: "2.0.0"},
+				Values: map[string]interface{}{
+					"shop": map[string]interface{}{"discount": 0.5},
+				},
+			},
+		},
+	}
+}
+
+func TestResolve(t *testing.T) {
+	config := testConfig()
+
+	resolved := config.Resolve(Audience{Platform: "ios", Country: "de", Version: "1.5.0"})
+	require.Equal(t, int64(3), resolved.Version)
+	require.Equal(t, 10.0, resolved.Values["coins"])
+	require.Empty(t, resolved.Overrides)
+
+	resolved = config.Resolve(Audience{Platform: "android", Country: "US", Version: "1.5.0"})
+	require.Equal(t, 20.0, resolved.Values["coins"])
+	require.Equal(t, map[string]interface{}{"discount": 0.5, "enabled": true}, resolved.Values["shop"])
+	require.Equal(t, []string{"android", "us-sale"}, resolved.Overrides)
+
+	// defaults are not changed by overrides
+	require.Equal(t, 0.0, config.Values["shop"].(map[string]interface{})["discount"])
+}
+
+func TestConditionsMatchVersion(t *testing.T) {
+	conditions := Conditions{MinVersion: "1.2.0", MaxVersion: "2.0.0"}
+
+	require.True(t, conditions.Match(Audience{Version: "1.2.0"}))
+	require.True(t, conditions.Match(Audience{Version: "1.9.9"}))
+	require.False(t, conditions.Match(Audience{Version: "2.0.0"}))
+	require.False(t, conditions.Match(Audience{Version: "1.1.0"}))
+	require.False(t, conditions.Match(Audience{}))
+
+	require.True(t, Conditions{}.Match(Audience{}))
+	require.False(t, Conditions{AppIDs: []string{"app-1"}}.Match(Audience{AppID: "app-2"}))
+}
+
+func TestValidate(t *testing.T) {
+	require.Nil(t, testConfig().Validate())
+
+	config := testConfig()
+	config.Overrides[1].Name = "android"
+	require.NotNil(t, config.Validate())
+
+	config = testConfig()
+	config.Overrides[1].Conditions.MinVersion = "2.0.0"
+	require.NotNil(t, config.Validate())
+
+	config = testConfig()
+	config.Overrides[0].Conditions.MaxVersion = "latest"
+	require.NotNil(t, config.Validate())
+}
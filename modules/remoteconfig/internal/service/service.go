@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"gitlab.com/balconygames/analytics/modules/remoteconfig/internal/models"
+	"gitlab.com/balconygames/analytics/pkg/cache"
+)
+
+type PostgresRepository interface {
+	PublishConfig(ctx context.Context, config *models.Config) error
+	GetActiveConfig(ctx context.Context, gameID string) (*models.Config, error)
+	GetConfigVersion(ctx context.Context, gameID string, version int64) (*models.Config, error)
+	ListConfigVersions(ctx context.Context, gameID string, limit, offset int) ([]*models.Config, error)
+}
+
+// GeoResolver respond with country code by ip address.
+type GeoResolver interface {
+	Resolve(ip string) (string, error)
+}
+
+// Service contains all dependencies to perform common service tasks.
+type Service struct {
+	pgRepo PostgresRepository
+
+	Geo GeoResolver
+
+	// configs caches active configs read on every client request.
+	configs *cache.TTL
+
+	logger *zap.SugaredLogger
+}
+
+// NewService should build the service to having the layer between handlers
+// and repositories.
+func NewService(r PostgresRepository, g GeoResolver, l *zap.SugaredLogger) *Service {
+	return &Service{
+		pgRepo:  r,
+		Geo:     g,
+		configs: cache.NewTTL(configsCacheTTL),
+		logger:  l,
+	}
+}
+
+// MaxConfigSize limits size of values and overrides of config in bytes,
+// resolved config is downloaded by clients on start.
+const MaxConfigSize = 512 * 1024
+
+// configsCacheTTL is time to reload active config of the game,
+// published config is served by other instances after the ttl.
+const configsCacheTTL = 30 * time.Second
+
+func (s *Service) activeConfig(ctx context.Context, gameID string) (*models.Config, error) {
+	// games without published config are cached as nil
+	if cached, ok := s.configs.Get(gameID); ok {
+		return cached.(*models.Config), nil
+	}
+
+	config, err := s.pgRepo.GetActiveConfig(ctx, gameID)
+	if err == models.ErrConfigNotFound {
+		config = nil
+	} else if err != nil {
+		return nil, errors.WithMessage(err, "can't get active config")
+	}
+
+	s.configs.Set(gameID, config)
+
+	return config, nil
+}
+
+// ResolveConfig respond with active config of the game for the audience,
+// games without published config respond with empty values.
+func (s *Service) ResolveConfig(ctx context.Context, gameID string, audience models.Audience) (*models.Resolved, error) {
+	config, err := s.activeConfig(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return &models.Resolved{Values: map[string]interface{}{}}, nil
+	}
+
+	resolved := config.Resolve(audience)
+	// names of overrides are internal details of the config
+	resolved.Overrides = nil
+
+	return resolved, nil
+}
+
+// ETag respond with entity tag of the resolved config, clients pass it
+// by If-None-Match to skip download of unchanged config.
+func ETag(resolved *models.Resolved) (string, error) {
+	b, err := json.Marshal(resolved)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+	return fmt.Sprintf(`"%d-%s"`, resolved.Version, hex.EncodeToString(sum[:12])), nil
+}
+
+// PreviewConfig resolves the config for the audience without publishing,
+// active config is used once config is not passed.
+func (s *Service) PreviewConfig(ctx context.Context, gameID string, config *models.Config, audience models.Audience) (*models.Resolved, error) {
+	if config == nil {
+		var err error
+		config, err = s.pgRepo.GetActiveConfig(ctx, gameID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		err := validateConfig(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return config.Resolve(audience), nil
+}
+
+func validateConfig(config *models.Config) error {
+	if config.Values == nil {
+		config.Values = map[string]interface{}{}
+	}
+	if config.Overrides == nil {
+		config.Overrides = []*models.Override{}
+	}
+
+	err := config.Validate()
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(struct {
+		Values    map[string]interface{} `json:"values"`
+		Overrides []*models.Override     `json:"overrides"`
+	}{config.Values, config.Overrides})
+	if err != nil {
+		return err
+	}
+	if len(b) > MaxConfigSize {
+		return errors.Errorf("config is %d bytes, should be less than %d bytes", len(b), MaxConfigSize)
+	}
+
+	return nil
+}
+
+// PublishConfig validates and stores the config as the next version
+// served to clients.
+func (s *Service) PublishConfig(ctx context.Context, config *models.Config) error {
+	err := validateConfig(config)
+	if err != nil {
+		return err
+	}
+
+	err = s.pgRepo.PublishConfig(ctx, config)
+	if err != nil {
+		return errors.WithMessage(err, "can't publish config")
+	}
+
+	s.configs.Delete(config.GameID)
+
+	return nil
+}
+
+// RollbackConfig publishes copy of the previous version as the next
+// version, history of versions is kept as is.
+func (s *Service) RollbackConfig(ctx context.Context, gameID string, version int64, createdBy, comment string) (*models.Config, error) {
+	previous, err := s.pgRepo.GetConfigVersion(ctx, gameID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if comment == "" {
+		comment = fmt.Sprintf("rollback to version %d", version)
+	}
+
+	config := &models.Config{
+		GameID:     gameID,
+		Values:     previous.Values,
+		Overrides:  previous.Overrides,
+		Comment:    comment,
+		CreatedBy:  createdBy,
+		RollbackOf: &version,
+	}
+
+	err = s.PublishConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// GetActiveConfig respond with the latest version of config of the game.
+func (s *Service) GetActiveConfig(ctx context.Context, gameID string) (*models.Config, error) {
+	return s.pgRepo.GetActiveConfig(ctx, gameID)
+}
+
+// GetConfigVersion respond with the version of config of the game.
+func (s *Service) GetConfigVersion(ctx context.Context, gameID string, version int64) (*models.Config, error) {
+	return s.pgRepo.GetConfigVersion(ctx, gameID, version)
+}
+
+const (
+	defaultVersionsLimit = 20
+	maxVersionsLimit     = 100
+)
+
+// ListConfigVersions respond with page of versions of config of the game,
+// the latest versions go first.
+func (s *Service) ListConfigVersions(ctx context.Context, gameID string, limit, offset int) ([]*models.Config, error) {
+	if limit <= 0 {
+		limit = defaultVersionsLimit
+	}
+	if limit > maxVersionsLimit {
+		limit = maxVersionsLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	return s.pgRepo.ListConfigVersions(ctx, gameID, limit, offset)
+}
@@ -0,0 +1,24 @@
+CREATE TABLE config_versions (
+    -- GUID
+    game_id varchar(36) not null,
+
+    -- versions are increased per game on every publish,
+    -- the latest version is served to clients
+    version bigint not null,
+
+    -- default values and overrides matched by audience
+    -- of the client, overrides are applied in order
+    config_values jsonb not null default '{}',
+    overrides jsonb not null default '[]',
+
+    comment text not null default '',
+    -- GUID of server user published the version
+    created_by varchar(36) not null default '',
+    -- version copied on rollback
+    rollback_of bigint,
+
+    created_at timestamp not null default now(),
+
+    PRIMARY KEY(game_id, version)
+);
+COMMENT ON TABLE config_versions IS 'Published versions of remote config per game';
@@ -0,0 +1,93 @@
+package remoteconfig
+
+import (
+	"context"
+
+	"github.com/go-chi/chi"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"gitlab.com/balconygames/analytics/modules/remoteconfig/internal/db"
+	"gitlab.com/balconygames/analytics/modules/remoteconfig/internal/handlers"
+	"gitlab.com/balconygames/analytics/modules/remoteconfig/internal/service"
+	"gitlab.com/balconygames/analytics/pkg/auth"
+	"gitlab.com/balconygames/analytics/pkg/geo"
+	"gitlab.com/balconygames/analytics/pkg/logging"
+	"gitlab.com/balconygames/analytics/pkg/postgres"
+	"gitlab.com/balconygames/analytics/pkg/runtime"
+)
+
+type spec struct {
+	Env      string          `envconfig:"ENV" required:"True"`
+	Postgres postgres.Config `envconfig:"POSTGRES" required:"True"`
+}
+
+func New(r *runtime.Runtime) error {
+	var s spec
+	if err := envconfig.Process("MODULE_REMOTE_CONFIG", &s); err != nil {
+		return err
+	}
+	return withSpec(r, s)
+}
+
+func withSpec(r *runtime.Runtime, s spec) error {
+	err := r.WithMigrations("modules/remoteconfig/migrations", s.Postgres)
+	if err != nil {
+		return err
+	}
+
+	l, err := logging.ConfigForEnv(s.Env).Build(
+		zap.Fields(zap.String("project", "modules/remoteconfig")),
+	)
+	if err != nil {
+		return err
+	}
+
+	defer l.Sync()
+	logger := l.Sugar()
+
+	poolConfig, err := pgxpool.ParseConfig(s.Postgres.URL())
+	if err != nil {
+		return errors.New("unable to parse database url")
+	}
+
+	pool, err := pgxpool.ConnectConfig(context.Background(), poolConfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to establish db connection")
+	}
+	r.WithClosable(pool)
+
+	geoResolver := geo.New()
+	r.WithClosable(geoResolver)
+
+	repo := db.NewPostgresRepository(pool)
+	svc := service.NewService(repo, geoResolver, logger)
+	h := handlers.New(svc, logger)
+
+	r.WithRoutes(func(r1 chi.Router) {
+		r.WithClientAuth(r1, func(r2 chi.Router) {
+			r2.Get("/remote-config/v1/config", h.GetConfig)
+		})
+
+		// Server API would be used by dashboard layer
+		r.WithServerAuth(r1, func(r2 chi.Router) {
+			r2.Group(func(i chi.Router) {
+				i.Use(auth.RequireRoles(auth.RoleReadOnly))
+				i.Get("/remote-config/v1/games/{game_id}/config", h.GetActiveConfig)
+				i.Get("/remote-config/v1/games/{game_id}/config/versions", h.ListConfigVersions)
+				i.Get("/remote-config/v1/games/{game_id}/config/versions/{version}", h.GetConfigVersion)
+				i.Post("/remote-config/v1/games/{game_id}/config/preview", h.PreviewConfig)
+			})
+
+			r2.Group(func(i chi.Router) {
+				i.Use(auth.RequireRoles(auth.RoleLiveOps))
+				i.Post("/remote-config/v1/games/{game_id}/config", h.PublishConfig)
+				i.Post("/remote-config/v1/games/{game_id}/config/rollback", h.RollbackConfig)
+			})
+		})
+	})
+
+	return nil
+}
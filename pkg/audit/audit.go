@@ -0,0 +1,225 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+
+	"gitlab.com/balconygames/analytics/pkg/auth"
+)
+
+// Change of the field, before is nil for added fields
+// and after is nil for removed fields.
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Entry is audit record of mutation made by server user.
+type Entry struct {
+	ID int64 `json:"id"`
+
+	// ActorID is user id of operator or id of API key.
+	ActorID   string `json:"actor_id"`
+	ActorType string `json:"actor_type"`
+
+	// Action is route of the request by default,
+	// e.g. PUT /primary/v1/games/{game_id}/apps/{app_id}/versions
+	Action string `json:"action"`
+
+	GameID     string `json:"game_id"`
+	TargetType string `json:"target_type"`
+	TargetID   string `json:"target_id"`
+
+	// Changes are changed fields of the target by name.
+	Changes map[string]Change `json:"changes,omitempty"`
+
+	IP        string    `json:"ip"`
+	RequestID string    `json:"request_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Recorder stores audit entries, failures are logged by recorder
+// and don't fail audited requests.
+type Recorder interface {
+	Record(ctx context.Context, e *Entry)
+}
+
+// Filter of audit entries, empty fields are not filtered.
+type Filter struct {
+	GameID     string
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	Since      *time.Time
+	Until      *time.Time
+
+	// BeforeID is cursor of the next page, entries go from
+	// the latest to the oldest.
+	BeforeID int64
+	Limit    int
+}
+
+// Diff respond with changed fields of JSON encoded values, values
+// which are not JSON objects are compared as a whole.
+func Diff(before, after interface{}) map[string]Change {
+	b, bok := fields(before)
+	a, aok := fields(after)
+	if !bok || !aok {
+		if reflect.DeepEqual(before, after) {
+			return nil
+		}
+		return map[string]Change{"value": {Before: before, After: after}}
+	}
+
+	changes := map[string]Change{}
+	for name, value := range b {
+		if !reflect.DeepEqual(value, a[name]) {
+			changes[name] = Change{Before: value, After: a[name]}
+		}
+	}
+	for name, value := range a {
+		if _, ok := b[name]; !ok {
+			changes[name] = Change{After: value}
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	return changes
+}
+
+// fields respond with fields of JSON object, nil is an empty object.
+func fields(v interface{}) (map[string]interface{}, bool) {
+	out := map[string]interface{}{}
+
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+		return out, true
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+
+	err = json.Unmarshal(data, &out)
+	if err != nil {
+		return nil, false
+	}
+
+	return out, true
+}
+
+type contextKey string
+
+const contextEntryKey contextKey = "audit"
+
+func entryOf(r *http.Request) *Entry {
+	e, _ := r.Context().Value(contextEntryKey).(*Entry)
+	return e
+}
+
+// SetTarget overrides target of the audited request, handlers call
+// it once the target is not the last url param, e.g. created targets.
+func SetTarget(r *http.Request, targetType, targetID string) {
+	e := entryOf(r)
+	if e == nil {
+		return
+	}
+
+	e.TargetType = targetType
+	e.TargetID = targetID
+}
+
+// SetChanges records difference of the target before and after
+// the audited request.
+func SetChanges(r *http.Request, before, after interface{}) {
+	e := entryOf(r)
+	if e == nil {
+		return
+	}
+
+	e.Changes = Diff(before, after)
+}
+
+// NewEntry builds entry of the request with actor, route and the last
+// url param as target, e.g. app of /games/{game_id}/apps/{app_id}.
+func NewEntry(r *http.Request) *Entry {
+	e := &Entry{
+		IP:        clientIP(r),
+		RequestID: middleware.GetReqID(r.Context()),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if user := auth.GetUser(r); user != nil {
+		e.ActorID = user.UserID
+		e.ActorType = user.Type
+	}
+
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		e.Action = r.Method + " " + r.URL.Path
+		return e
+	}
+
+	e.Action = r.Method + " " + rctx.RoutePattern()
+	e.GameID = rctx.URLParam("game_id")
+
+	keys := rctx.URLParams.Keys
+	if len(keys) > 0 {
+		last := len(keys) - 1
+		e.TargetType = strings.TrimSuffix(keys[last], "_id")
+		e.TargetID = rctx.URLParams.Values[last]
+	}
+
+	return e
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// NewMiddleware records successful mutations of server users, reads
+// are not audited.
+func NewMiddleware(recorder Recorder) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			e := NewEntry(r)
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			ctx := context.WithValue(r.Context(), contextEntryKey, e)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			// status is not set once handler didn't write anything
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= http.StatusBadRequest {
+				return
+			}
+
+			recorder.Record(r.Context(), e)
+		})
+	}
+}
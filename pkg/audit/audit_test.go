@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/balconygames/analytics/pkg/auth"
+)
+
+type fakeRecorder struct {
+	entries []*Entry
+}
+
+func (f *fakeRecorder) Record(_ context.Context, e *Entry) {
+	f.entries = append(f.entries, e)
+}
+
+func TestDiff(t *testing.T) {
+	type app struct {
+		MinVersion string `json:"min_version"`
+		StoreURL   string `json:"store_url"`
+	}
+
+	changes := Diff(&app{MinVersion: "1.0.0", StoreURL: "x"}, &app{MinVersion: "1.1.0", StoreURL: "x"})
+	require.Equal(t, map[string]Change{"min_version": {Before: "1.0.0", After: "1.1.0"}}, changes)
+
+	// created target
+	changes = Diff(nil, map[string]int{"priority": 1})
+	require.Equal(t, map[string]Change{"priority": {After: float64(1)}}, changes)
+
+	// values which are not objects
+	require.Equal(t, map[string]Change{"value": {Before: 1, After: 2}}, Diff(1, 2))
+	require.Nil(t, Diff(&app{}, &app{}))
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := &fakeRecorder{}
+
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	router.Group(func(r chi.Router) {
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				user := &auth.UserInfo{UserID: "operator-1", Type: auth.ServerType}
+				next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), auth.ContextUserKey, user)))
+			})
+		})
+		r.Use(NewMiddleware(recorder))
+
+		r.Put("/games/{game_id}/apps/{app_id}", func(w http.ResponseWriter, req *http.Request) {
+			SetChanges(req, map[string]string{"name": "a"}, map[string]string{"name": "b"})
+		})
+		r.Delete("/games/{game_id}/apps/{app_id}", func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+		r.Get("/games/{game_id}/apps/{app_id}", func(w http.ResponseWriter, req *http.Request) {})
+	})
+
+	for _, method := range []string{"PUT", "DELETE", "GET"} {
+		req := httptest.NewRequest(method, "/games/game-1/apps/app-1", nil)
+		req.RemoteAddr = "10.0.0.1:5000"
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// failed mutations and reads are not audited
+	require.Len(t, recorder.entries, 1)
+
+	e := recorder.entries[0]
+	require.Equal(t, "operator-1", e.ActorID)
+	require.Equal(t, auth.ServerType, e.ActorType)
+	require.Equal(t, "PUT /games/{game_id}/apps/{app_id}", e.Action)
+	require.Equal(t, "game-1", e.GameID)
+	require.Equal(t, "app", e.TargetType)
+	require.Equal(t, "app-1", e.TargetID)
+	require.Equal(t, "10.0.0.1", e.IP)
+	require.NotEmpty(t, e.RequestID)
+	require.Equal(t, Change{Before: "a", After: "b"}, e.Changes["name"])
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+)
+
+// AliasResolver maps user id of the merged account to the surviving
+// user id, it's used to keep old JWT tokens working after merge.
+type AliasResolver interface {
+	ResolveAlias(ctx context.Context, userID string) (string, error)
+}
+
+// AliasKey is redis key to store surviving user id by merged user id.
+func AliasKey(userID string) string {
+	return fmt.Sprintf("aliases:%s", userID)
+}
+
+// RedisAliasResolver reads aliases stored by auth module.
+type RedisAliasResolver struct {
+	conn *redis.Client
+}
+
+func NewRedisAliasResolver(c *redis.Client) *RedisAliasResolver {
+	return &RedisAliasResolver{conn: c}
+}
+
+// ResolveAlias respond with surviving user id or the same user id
+// in case if user wasn't merged.
+func (a *RedisAliasResolver) ResolveAlias(ctx context.Context, userID string) (string, error) {
+	survivorID, err := a.conn.Get(ctx, AliasKey(userID)).Result()
+	if err == redis.Nil {
+		return userID, nil
+	}
+	if err != nil {
+		return "", errors.WithMessage(err, "can't get user alias")
+	}
+
+	return survivorID, nil
+}
+
+// ResolveUserAlias replaces user id in request context by
+// surviving user id.
+func ResolveUserAlias(req *http.Request, resolver AliasResolver) (*http.Request, error) {
+	user := GetUser(req)
+	if user == nil {
+		return req, nil
+	}
+
+	survivorID, err := resolver.ResolveAlias(req.Context(), user.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if survivorID == user.UserID {
+		return req, nil
+	}
+
+	resolved := *user
+	resolved.UserID = survivorID
+
+	ctx := context.WithValue(req.Context(), ContextUserKey, &resolved)
+	return req.WithContext(ctx), nil
+}
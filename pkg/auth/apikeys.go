@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+
+	pkghttp "gitlab.com/balconygames/analytics/pkg/http"
+)
+
+// APIKeyHeader is API key sent by game servers and build pipelines
+// instead of JWT token.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyType is used by integrations calling server API with API key,
+// user id is id of the key.
+const APIKeyType = "api-key"
+
+// ErrInvalidAPIKey returned for unknown, revoked or expired API keys.
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// Scopes of API keys, API keys have no roles and are allowed only
+// on routes requiring one of their scopes.
+const (
+	ScopeLeaderboardWrite = "leaderboard:write"
+	ScopePropertiesRead   = "properties:read"
+	ScopePropertiesWrite  = "properties:write"
+)
+
+var scopes = map[string]bool{
+	ScopeLeaderboardWrite: true,
+	ScopePropertiesRead:   true,
+	ScopePropertiesWrite:  true,
+}
+
+// ValidScope checks if the scope is known.
+func ValidScope(scope string) bool {
+	return scopes[scope]
+}
+
+// APIKeyVerifier respond with user of the API key, it returns
+// ErrInvalidAPIKey for keys which can't be used.
+type APIKeyVerifier interface {
+	VerifyAPIKey(ctx context.Context, key string) (*UserInfo, error)
+}
+
+// HasScope checks if API key user has the scope.
+func (u *UserInfo) HasScope(scope string) bool {
+	for _, s := range u.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewAPIKeyMiddleware extracts user of API key from X-API-Key header
+// and pass it via context.
+func NewAPIKeyMiddleware(verifier APIKeyVerifier) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := verifier.VerifyAPIKey(r.Context(), r.Header.Get(APIKeyHeader))
+			if err == ErrInvalidAPIKey {
+				pkghttp.Unauthorized(w, err)
+				return
+			}
+			if err != nil {
+				pkghttp.Error(w, errors.Wrap(err, "can't verify api key"))
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), ContextUserKey, user)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireRoleOrScope allows requests of API keys having the scope,
+// other server users are checked by RequireRoles with the role. API
+// keys of the app are allowed only on routes of the app.
+func RequireRoleOrScope(role, scope string) func(next http.Handler) http.Handler {
+	requireRole := RequireRoles(role)
+
+	return func(next http.Handler) http.Handler {
+		withRole := requireRole(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := GetUser(r)
+			if user == nil || user.Type != APIKeyType {
+				withRole.ServeHTTP(w, r)
+				return
+			}
+
+			if !user.HasScope(scope) {
+				pkghttp.Forbidden(w, errors.Wrap(ErrForbidden, "missing scope"))
+				return
+			}
+
+			gameID := chi.URLParam(r, "game_id")
+			if gameID == "" || gameID != user.GameID {
+				pkghttp.Forbidden(w, errors.Wrap(ErrForbidden, "missing game grant"))
+				return
+			}
+
+			appID := chi.URLParam(r, "app_id")
+			if user.AppID != "" && appID != user.AppID {
+				pkghttp.Forbidden(w, errors.Wrap(ErrForbidden, "missing app grant"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
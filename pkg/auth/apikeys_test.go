@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAPIKeys map[string]*UserInfo
+
+func (f fakeAPIKeys) VerifyAPIKey(_ context.Context, key string) (*UserInfo, error) {
+	user, ok := f[key]
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+	return user, nil
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	keys := fakeAPIKeys{
+		"game-key": {Type: APIKeyType, GameID: "game-1", Scopes: []string{ScopeLeaderboardWrite}},
+		"app-key":  {Type: APIKeyType, GameID: "game-1", AppID: "app-2", Scopes: []string{ScopeLeaderboardWrite}},
+		"read-key": {Type: APIKeyType, GameID: "game-1", Scopes: []string{ScopePropertiesRead}},
+		"game-2":   {Type: APIKeyType, GameID: "game-2", Scopes: []string{ScopeLeaderboardWrite}},
+	}
+
+	ok := func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	router := chi.NewRouter()
+	router.Use(NewAPIKeyMiddleware(keys))
+	router.Group(func(r chi.Router) {
+		r.Use(RequireRoleOrScope(RoleLiveOps, ScopeLeaderboardWrite))
+		r.Post("/games/{game_id}/apps/{app_id}/scores", ok)
+	})
+	router.Group(func(r chi.Router) {
+		r.Use(RequireRoles(RoleReadOnly))
+		r.Get("/games/{game_id}", ok)
+	})
+
+	cases := []struct {
+		method string
+		path   string
+		key    string
+		code   int
+	}{
+		{"POST", "/games/game-1/apps/app-1/scores", "unknown", http.StatusUnauthorized},
+		{"POST", "/games/game-1/apps/app-1/scores", "game-key", http.StatusOK},
+		{"POST", "/games/game-1/apps/app-1/scores", "app-key", http.StatusForbidden},
+		{"POST", "/games/game-1/apps/app-2/scores", "app-key", http.StatusOK},
+		{"POST", "/games/game-1/apps/app-1/scores", "read-key", http.StatusForbidden},
+		{"POST", "/games/game-1/apps/app-1/scores", "game-2", http.StatusForbidden},
+		// routes without scopes reject API keys
+		{"GET", "/games/game-1", "game-key", http.StatusForbidden},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		req.Header.Set(APIKeyHeader, c.key)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, c.code, w.Code, "%s %s with %s", c.method, c.path, c.key)
+	}
+}
+
+func TestRequireRoleOrScopeServerUser(t *testing.T) {
+	router := chi.NewRouter()
+	router.Group(func(r chi.Router) {
+		r.Use(RequireRoleOrScope(RoleLiveOps, ScopeLeaderboardWrite))
+		r.Post("/games/{game_id}", func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	})
+
+	cases := []struct {
+		user *UserInfo
+		code int
+	}{
+		{&UserInfo{Type: ServerType, Roles: []string{RoleReadOnly}, Games: []string{AllGames}}, http.StatusForbidden},
+		{&UserInfo{Type: ServerType, Roles: []string{RoleLiveOps}, Games: []string{"game-1"}}, http.StatusOK},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest("POST", "/games/game-1", nil)
+		req = req.WithContext(context.WithValue(req.Context(), ContextUserKey, c.user))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, c.code, w.Code)
+	}
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"sync"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// SigningMethodEdDSA implements Ed25519 signatures, expects
+// ed25519.PrivateKey for signing and ed25519.PublicKey for validation.
+type SigningMethodEdDSA struct{}
+
+// EdDSA is the instance of Ed25519 signing method.
+var EdDSA = &SigningMethodEdDSA{}
+
+var registerEdDSAOnce sync.Once
+
+// registerEdDSA makes EdDSA known by jwt parser, jwt-go doesn't
+// support it out of the box.
+func registerEdDSA() {
+	registerEdDSAOnce.Do(func() {
+		jwt.RegisterSigningMethod(EdDSA.Alg(), func() jwt.SigningMethod {
+			return EdDSA
+		})
+	})
+}
+
+func (m *SigningMethodEdDSA) Alg() string {
+	return "EdDSA"
+}
+
+// Verify checks encoded signature using ed25519.PublicKey.
+func (m *SigningMethodEdDSA) Verify(signingString, signature string, key interface{}) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		return jwt.ErrSignatureInvalid
+	}
+
+	return nil
+}
+
+// Sign respond with encoded signature using ed25519.PrivateKey.
+func (m *SigningMethodEdDSA) Sign(signingString string, key interface{}) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
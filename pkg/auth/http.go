@@ -25,20 +25,35 @@ func NewJWTHttpVerifierMiddleware(secret string) func(handler http.Handler) http
 	return jwtauth.Verifier(auth)
 }
 
-// NewJWTUserMiddleware should extract user from JWT token and pass it via context
-func NewJWTUserMiddleware(secret string) func(next http.Handler) http.Handler {
-	tokenSigner := NewSigner(secret)
+// NewJWTUserMiddleware should extract user from JWT token and pass it via context,
+// tokens of revoked sessions are rejected in case if revocations are passed.
+func NewJWTUserMiddleware(keys *KeySet, revocations RevocationChecker) func(next http.Handler) http.Handler {
+	tokenSigner := NewKeySetSigner(keys)
 
 	fn := func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			user, err := DecodeJWT(r, tokenSigner)
+			claims, err := DecodeClaims(r, tokenSigner)
 			if err != nil {
 				pkghttp.Error(w, errors.Wrap(err, "invalid jwt token"))
 				return
 			}
 
+			if revocations != nil {
+				revoked, err := revocations.IsRevoked(r.Context(), claims)
+				if err != nil {
+					pkghttp.Error(w, errors.Wrap(err, "can't check jwt token revocation"))
+					return
+				}
+				if revoked {
+					pkghttp.Unauthorized(w, ErrRevokedToken)
+					return
+				}
+			}
+
+			user := claims.UserInfo
+
 			ctx := r.Context()
-			ctx = context.WithValue(ctx, ContextUserKey, user)
+			ctx = context.WithValue(ctx, ContextUserKey, &user)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -46,9 +61,24 @@ func NewJWTUserMiddleware(secret string) func(next http.Handler) http.Handler {
 	return fn
 }
 
-func NewTokenSignerMiddleware(secret string) func(next http.Handler) http.Handler {
-	tokenSigner := NewSigner(secret)
+// JWKSHandler publishes public keys of key sets to verify
+// tokens outside of the service.
+func JWKSHandler(sets ...*KeySet) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		out := JWKS{Keys: []JWK{}}
+		for _, ks := range sets {
+			if ks == nil {
+				continue
+			}
+			out.Keys = append(out.Keys, ks.JWKS().Keys...)
+		}
 
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		pkghttp.JSON(w, out)
+	}
+}
+
+func NewTokenSignerMiddleware(tokenSigner *Signer) func(next http.Handler) http.Handler {
 	fn := func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := r.Context()
@@ -63,6 +93,18 @@ func NewTokenSignerMiddleware(secret string) func(next http.Handler) http.Handle
 // DecodeJWT returns parsed user information using JWT token from
 // Authorization header.
 func DecodeJWT(req *http.Request, tokenSigner *Signer) (*UserInfo, error) {
+	claims, err := DecodeClaims(req, tokenSigner)
+	if err != nil {
+		return nil, err
+	}
+	user := claims.UserInfo
+
+	return &user, nil
+}
+
+// DecodeClaims returns parsed claims using JWT token from
+// Authorization header.
+func DecodeClaims(req *http.Request, tokenSigner *Signer) (*Claims, error) {
 	token := req.Header.Get("Authorization")
 
 	if token == "" {
@@ -77,9 +119,8 @@ func DecodeJWT(req *http.Request, tokenSigner *Signer) (*UserInfo, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "invalid jwt token")
 	}
-	user := claims.UserInfo
 
-	return &user, nil
+	return claims, nil
 }
 
 // GetUser retrieve user information from context of request.
@@ -12,6 +12,10 @@ var ErrInvalidToken = errors.New("invalid token")
 const GuestType = "guest"
 const RealType = "real"
 
+// ServerType is used by dashboard operators and
+// integrations calling server API.
+const ServerType = "server"
+
 type UserInfo struct {
 	UserID   string `json:"uid"`
 	DeviceID string `json:"did"`
@@ -21,39 +25,89 @@ type UserInfo struct {
 	// guest or real users
 	// real means the user signed up using social network
 	Type string `json:"utp"`
+
+	// SessionID is shared by access tokens issued by the same
+	// refresh token chain, used to revoke the session.
+	SessionID string `json:"sid,omitempty"`
+
+	// Roles, Games are set for server users only,
+	// games are ids of granted games.
+	Roles []string `json:"roles,omitempty"`
+	Games []string `json:"games,omitempty"`
+	// OrganisationID is organisation of dashboard operator.
+	OrganisationID string `json:"org,omitempty"`
+
+	// Scopes are set for API keys only, see APIKeyType.
+	Scopes []string `json:"scopes,omitempty"`
 }
 
 // Claims are typical jwt claims shared by all platforms.
 type Claims struct {
 	UserInfo
 	jwt.StandardClaims
+
+	// IssuedAtNano is issue time in nanoseconds, iat has seconds
+	// only and can't be compared with revocation in the same second.
+	IssuedAtNano int64 `json:"iat_ns,omitempty"`
+}
+
+// issuedAtNano respond with issue time in nanoseconds, tokens issued
+// before IssuedAtNano claim have seconds only.
+func (c *Claims) issuedAtNano() int64 {
+	if c.IssuedAtNano > 0 {
+		return c.IssuedAtNano
+	}
+
+	return c.IssuedAt * int64(time.Second)
 }
 
 type Signer struct {
-	hmacSecret []byte
+	keys *KeySet
+
+	// ttl is zero for tokens which never expire
+	ttl time.Duration
 }
 
+// NewSigner creates signer using the single HS256 secret.
 func NewSigner(hmacSecret string) *Signer {
-	return &Signer{hmacSecret: []byte(hmacSecret)}
+	return NewKeySetSigner(NewSecretKeySet(hmacSecret))
+}
+
+// NewKeySetSigner creates signer using the signing key of key set,
+// all active keys of key set are accepted on decode.
+func NewKeySetSigner(keys *KeySet) *Signer {
+	return &Signer{keys: keys}
+}
+
+// WithTTL sets expiration time of encoded tokens.
+func (s *Signer) WithTTL(ttl time.Duration) *Signer {
+	s.ttl = ttl
+	return s
+}
+
+// TTL respond with expiration time of encoded tokens.
+func (s *Signer) TTL() time.Duration {
+	return s.ttl
 }
 
 func (s *Signer) Encode(claims Claims) (string, error) {
+	now := time.Now().UTC()
+
 	claims.StandardClaims = jwt.StandardClaims{
-		IssuedAt: time.Now().UTC().Unix(),
+		IssuedAt: now.Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.hmacSecret)
+	claims.IssuedAtNano = now.UnixNano()
+	if s.ttl > 0 {
+		claims.StandardClaims.ExpiresAt = now.Add(s.ttl).Unix()
+	}
+
+	return s.keys.sign(claims)
 }
 
 func (s *Signer) Decode(ts string) (*Claims, error) {
 	claimsOut := &Claims{}
 
-	token, err := jwt.ParseWithClaims(ts, claimsOut, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
-		}
-		return s.hmacSecret, nil
-	})
+	token, err := jwt.ParseWithClaims(ts, claimsOut, s.keys.keyFunc)
 	if err != nil {
 		return claimsOut, errors.Wrapf(err, ErrInvalidToken.Error())
 	}
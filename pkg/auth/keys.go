@@ -0,0 +1,304 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+)
+
+// Supported algorithms of keys.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// ErrUnknownKey returned for tokens signed by unknown or retired key.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Key is configuration of signing or verification key.
+type Key struct {
+	ID        string `json:"kid"`
+	Algorithm string `json:"alg"`
+
+	// Secret is used by HS256 keys.
+	Secret string `json:"secret,omitempty"`
+	// PrivateKey, PublicKey are PEM encoded keys used by RS256 and EdDSA,
+	// private key is required only for the signing key.
+	PrivateKey string `json:"private_key,omitempty"`
+	PublicKey  string `json:"public_key,omitempty"`
+
+	// RetireAt is the time when the key stops to be accepted,
+	// keep it longer than token lifetime after rotation.
+	RetireAt *time.Time `json:"retire_at,omitempty"`
+}
+
+// KeySetConfig is the format of keys file.
+type KeySetConfig struct {
+	SigningKeyID string `json:"signing_kid"`
+	Keys         []Key  `json:"keys"`
+}
+
+type parsedKey struct {
+	id       string
+	method   jwt.SigningMethod
+	signKey  interface{}
+	verifKey interface{}
+	retireAt *time.Time
+}
+
+// KeySet contains the signing key and keys accepted on verification.
+type KeySet struct {
+	signing *parsedKey
+	keys    map[string]*parsedKey
+}
+
+// NewSecretKeySet builds key set with the single HS256 secret
+// without key id.
+func NewSecretKeySet(secret string) *KeySet {
+	key := &parsedKey{
+		method:   jwt.SigningMethodHS256,
+		signKey:  []byte(secret),
+		verifKey: []byte(secret),
+	}
+
+	return &KeySet{
+		signing: key,
+		keys:    map[string]*parsedKey{"": key},
+	}
+}
+
+// LoadKeySet reads key set from JSON file.
+func LoadKeySet(path string) (*KeySet, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't read keys file %s", path)
+	}
+
+	config := KeySetConfig{}
+	err = json.Unmarshal(b, &config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't parse keys file %s", path)
+	}
+
+	return NewKeySet(config)
+}
+
+// NewKeySet parses keys, the signing key should be one of keys.
+func NewKeySet(config KeySetConfig) (*KeySet, error) {
+	registerEdDSA()
+
+	ks := &KeySet{keys: make(map[string]*parsedKey, len(config.Keys))}
+
+	for _, k := range config.Keys {
+		parsed, err := parseKey(k)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "can't parse key %s", k.ID)
+		}
+
+		if _, ok := ks.keys[k.ID]; ok {
+			return nil, errors.Errorf("duplicated key %s", k.ID)
+		}
+		ks.keys[k.ID] = parsed
+	}
+
+	signing, ok := ks.keys[config.SigningKeyID]
+	if !ok {
+		return nil, errors.Errorf("signing key %s not found", config.SigningKeyID)
+	}
+	if signing.signKey == nil {
+		return nil, errors.Errorf("signing key %s requires private key", config.SigningKeyID)
+	}
+	ks.signing = signing
+
+	return ks, nil
+}
+
+func parseKey(k Key) (*parsedKey, error) {
+	key := &parsedKey{id: k.ID, retireAt: k.RetireAt}
+
+	switch k.Algorithm {
+	case AlgHS256:
+		if k.Secret == "" {
+			return nil, errors.New("secret is required")
+		}
+		key.method = jwt.SigningMethodHS256
+		key.signKey = []byte(k.Secret)
+		key.verifKey = []byte(k.Secret)
+	case AlgRS256:
+		key.method = jwt.SigningMethodRS256
+		if k.PrivateKey != "" {
+			privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(k.PrivateKey))
+			if err != nil {
+				return nil, err
+			}
+			key.signKey = privateKey
+			key.verifKey = &privateKey.PublicKey
+		}
+		if k.PublicKey != "" {
+			publicKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(k.PublicKey))
+			if err != nil {
+				return nil, err
+			}
+			key.verifKey = publicKey
+		}
+	case AlgEdDSA:
+		key.method = EdDSA
+		if k.PrivateKey != "" {
+			privateKey, err := parseEd25519PrivateKey([]byte(k.PrivateKey))
+			if err != nil {
+				return nil, err
+			}
+			key.signKey = privateKey
+			key.verifKey = privateKey.Public()
+		}
+		if k.PublicKey != "" {
+			publicKey, err := parseEd25519PublicKey([]byte(k.PublicKey))
+			if err != nil {
+				return nil, err
+			}
+			key.verifKey = publicKey
+		}
+	default:
+		return nil, errors.Errorf("unsupported algorithm %s", k.Algorithm)
+	}
+
+	if key.verifKey == nil {
+		return nil, errors.New("public or private key is required")
+	}
+
+	return key, nil
+}
+
+func parseEd25519PrivateKey(b []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("invalid pem")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("not ed25519 private key")
+	}
+
+	return privateKey, nil
+}
+
+func parseEd25519PublicKey(b []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("invalid pem")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("not ed25519 public key")
+	}
+
+	return publicKey, nil
+}
+
+// sign signs token by the signing key and sets kid header.
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.method, claims)
+	if ks.signing.id != "" {
+		token.Header["kid"] = ks.signing.id
+	}
+
+	return token.SignedString(ks.signing.signKey)
+}
+
+// keyFunc finds verification key by kid header, algorithm of the token
+// should match the algorithm of the key.
+func (ks *KeySet) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	if key.retireAt != nil && time.Now().After(*key.retireAt) {
+		return nil, ErrUnknownKey
+	}
+
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, ErrInvalidToken
+	}
+
+	return key.verifKey, nil
+}
+
+// JWK is public key in JSON Web Key format.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKS is the set of public keys.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS respond with public keys of asymmetric algorithms,
+// HS256 secrets are never published.
+func (ks *KeySet) JWKS() JWKS {
+	out := JWKS{Keys: []JWK{}}
+
+	for id, key := range ks.keys {
+		if key.retireAt != nil && time.Now().After(*key.retireAt) {
+			continue
+		}
+
+		switch publicKey := key.verifKey.(type) {
+		case *rsa.PublicKey:
+			out.Keys = append(out.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     id,
+				Algorithm: AlgRS256,
+				Use:       "sig",
+				N:         jwt.EncodeSegment(publicKey.N.Bytes()),
+				E:         jwt.EncodeSegment(big.NewInt(int64(publicKey.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			out.Keys = append(out.Keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     id,
+				Algorithm: AlgEdDSA,
+				Use:       "sig",
+				Curve:     "Ed25519",
+				X:         jwt.EncodeSegment(publicKey),
+			})
+		}
+	}
+
+	return out
+}
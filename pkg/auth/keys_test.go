@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func rsaKey(t *testing.T, id string) Key {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+
+	b := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	})
+
+	return Key{ID: id, Algorithm: AlgRS256, PrivateKey: string(b)}
+}
+
+func ed25519Key(t *testing.T, id string) Key {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.Nil(t, err)
+
+	b := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	return Key{ID: id, Algorithm: AlgEdDSA, PrivateKey: string(b)}
+}
+
+func TestKeySetSignAndVerify(t *testing.T) {
+	for _, key := range []Key{
+		{ID: "hs", Algorithm: AlgHS256, Secret: "secret"},
+		rsaKey(t, "rs"),
+		ed25519Key(t, "ed"),
+	} {
+		ks, err := NewKeySet(KeySetConfig{SigningKeyID: key.ID, Keys: []Key{key}})
+		require.Nil(t, err)
+
+		signer := NewKeySetSigner(ks)
+		token, err := signer.Encode(Claims{UserInfo: UserInfo{UserID: "user-1"}})
+		require.Nil(t, err, key.Algorithm)
+
+		claims, err := signer.Decode(token)
+		require.Nil(t, err, key.Algorithm)
+		require.Equal(t, "user-1", claims.UserID)
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	oldKey := rsaKey(t, "old")
+	newKey := ed25519Key(t, "new")
+
+	oldSigner := NewKeySetSigner(mustKeySet(t, "old", oldKey))
+	token, err := oldSigner.Encode(Claims{UserInfo: UserInfo{UserID: "user-1"}})
+	require.Nil(t, err)
+
+	// old key is still accepted after rotation
+	rotated := NewKeySetSigner(mustKeySet(t, "new", oldKey, newKey))
+	_, err = rotated.Decode(token)
+	require.Nil(t, err)
+
+	// retired key is rejected
+	retireAt := time.Now().Add(-time.Minute)
+	oldKey.RetireAt = &retireAt
+	retired := NewKeySetSigner(mustKeySet(t, "new", oldKey, newKey))
+	_, err = retired.Decode(token)
+	require.NotNil(t, err)
+
+	// unknown key is rejected
+	_, err = NewKeySetSigner(mustKeySet(t, "new", newKey)).Decode(token)
+	require.NotNil(t, err)
+}
+
+func TestKeySetRejectsAlgorithmMismatch(t *testing.T) {
+	// token signed by HS256 with the same kid should not be
+	// verified by RSA key.
+	hsSigner := NewKeySetSigner(mustKeySet(t, "key", Key{ID: "key", Algorithm: AlgHS256, Secret: "secret"}))
+	token, err := hsSigner.Encode(Claims{})
+	require.Nil(t, err)
+
+	_, err = NewKeySetSigner(mustKeySet(t, "key", rsaKey(t, "key"))).Decode(token)
+	require.NotNil(t, err)
+}
+
+func TestKeySetJWKS(t *testing.T) {
+	ks := mustKeySet(t, "rs",
+		Key{ID: "hs", Algorithm: AlgHS256, Secret: "secret"},
+		rsaKey(t, "rs"),
+		ed25519Key(t, "ed"),
+	)
+
+	jwks := ks.JWKS()
+	require.Len(t, jwks.Keys, 2)
+
+	byID := map[string]JWK{}
+	for _, k := range jwks.Keys {
+		byID[k.KeyID] = k
+	}
+	require.Equal(t, "RSA", byID["rs"].KeyType)
+	require.Equal(t, "AQAB", byID["rs"].E)
+	require.Equal(t, "OKP", byID["ed"].KeyType)
+	require.Equal(t, "Ed25519", byID["ed"].Curve)
+}
+
+func mustKeySet(t *testing.T, signingKID string, keys ...Key) *KeySet {
+	ks, err := NewKeySet(KeySetConfig{SigningKeyID: signingKID, Keys: keys})
+	require.Nil(t, err)
+	return ks
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+
+	pkghttp "gitlab.com/balconygames/analytics/pkg/http"
+)
+
+// MaintenanceChecker checks if the app is under maintenance, it returns
+// MaintenanceError with message localized for the client.
+type MaintenanceChecker interface {
+	CheckMaintenance(ctx context.Context, gameID, appID, locale string) error
+}
+
+// MaintenanceError returned while maintenance window of the app is active.
+type MaintenanceError struct {
+	Message string
+	EndsAt  time.Time
+}
+
+func (e *MaintenanceError) Error() string {
+	return fmt.Sprintf("maintenance until %s", e.EndsAt.Format(time.RFC3339))
+}
+
+// MaintenanceCode is error code of maintenance response.
+const MaintenanceCode = "maintenance"
+
+type maintenanceResponse struct {
+	Error   string    `json:"error"`
+	Message string    `json:"message"`
+	EndsAt  time.Time `json:"ends_at"`
+}
+
+// RespondMaintenance responds with 503 and message of maintenance,
+// the client should retry after the end of the window.
+func RespondMaintenance(w http.ResponseWriter, err *MaintenanceError) {
+	retryAfter := int64(time.Until(err.EndsAt).Seconds())
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+
+	w.Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+	pkghttp.JSONWithStatus(w, http.StatusServiceUnavailable, maintenanceResponse{
+		Error:   MaintenanceCode,
+		Message: err.Message,
+		EndsAt:  err.EndsAt,
+	})
+}
+
+// NewMaintenanceMiddleware rejects client requests while the app is under
+// maintenance. The app is read from JWT user or from game_id and app_id
+// url params of routes issuing tokens.
+func NewMaintenanceMiddleware(checker MaintenanceChecker) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gameID, appID := chi.URLParam(r, "game_id"), chi.URLParam(r, "app_id")
+			if user := GetUser(r); user != nil {
+				gameID, appID = user.GameID, user.AppID
+			}
+			if gameID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			err := checker.CheckMaintenance(r.Context(), gameID, appID, pkghttp.Locale(r))
+
+			var maintenance *MaintenanceError
+			if errors.As(err, &maintenance) {
+				RespondMaintenance(w, maintenance)
+				return
+			}
+			if err != nil {
+				pkghttp.Error(w, errors.Wrap(err, "can't check maintenance"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+type gameMaintenance struct {
+	gameID string
+	locale string
+}
+
+func (m *gameMaintenance) CheckMaintenance(ctx context.Context, gameID, appID, locale string) error {
+	m.locale = locale
+	if gameID != m.gameID {
+		return nil
+	}
+
+	return &MaintenanceError{Message: "back soon", EndsAt: time.Now().Add(time.Hour)}
+}
+
+func TestMaintenanceMiddleware(t *testing.T) {
+	checker := &gameMaintenance{gameID: "game-1"}
+
+	router := chi.NewRouter()
+	router.Group(func(r chi.Router) {
+		r.Use(NewMaintenanceMiddleware(checker))
+		r.Post("/games/{game_id}/apps/{app_id}/sync", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	})
+
+	request := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Accept-Language", "pt-BR,pt;q=0.9")
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	require.Equal(t, http.StatusOK, request("/games/game-2/apps/app-1/sync").Code)
+
+	rr := request("/games/game-1/apps/app-1/sync")
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	require.Equal(t, "pt-BR", checker.locale)
+	require.NotEmpty(t, rr.Header().Get("Retry-After"))
+
+	var resp maintenanceResponse
+	require.Nil(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Equal(t, MaintenanceCode, resp.Error)
+	require.Equal(t, "back soon", resp.Message)
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+
+	pkghttp "gitlab.com/balconygames/analytics/pkg/http"
+)
+
+// ErrOtherOrganisation returned once server user accesses game
+// or organisation owned by other organisation.
+var ErrOtherOrganisation = errors.New("game of other organisation")
+
+// OrganisationResolver respond with organisation owning the game,
+// empty organisation is returned for unknown and not owned games.
+type OrganisationResolver interface {
+	GameOrganisation(ctx context.Context, gameID string) (string, error)
+	OrganisationExists(ctx context.Context, organisationID string) (bool, error)
+}
+
+// organisations checks ownership of games for server users of the request.
+type organisations struct {
+	platformID string
+	resolver   OrganisationResolver
+}
+
+const contextOrganisationsKey ContextKey = "organisations"
+
+// isPlatform checks if the user is operator of platform organisation,
+// platform operators manage games of all organisations.
+func (o *organisations) isPlatform(user *UserInfo) bool {
+	return o.platformID != "" && user.OrganisationID == o.platformID
+}
+
+func (o *organisations) checkGame(ctx context.Context, user *UserInfo, gameID string) error {
+	if o.isPlatform(user) {
+		return nil
+	}
+
+	owner, err := o.resolver.GameOrganisation(ctx, gameID)
+	if err != nil {
+		return errors.Wrap(err, "can't resolve organisation of game")
+	}
+
+	// games without owner are managed by platform operators only
+	if owner == "" || owner != user.OrganisationID {
+		return ErrOtherOrganisation
+	}
+
+	return nil
+}
+
+// NewOrganisationMiddleware rejects requests of operators to games and
+// organisations of other organisations by game_id and organisation_id
+// url params. API keys are limited to their game by RequireRoleOrScope.
+func NewOrganisationMiddleware(platformID string, resolver OrganisationResolver) func(next http.Handler) http.Handler {
+	o := &organisations{platformID: platformID, resolver: resolver}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := GetUser(r)
+			if user == nil || user.Type != ServerType {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			organisationID := chi.URLParam(r, "organisation_id")
+			if organisationID != "" && organisationID != user.OrganisationID && !o.isPlatform(user) {
+				pkghttp.Forbidden(w, errors.Wrap(ErrForbidden, "organisation of other operators"))
+				return
+			}
+
+			if gameID := chi.URLParam(r, "game_id"); gameID != "" {
+				err := o.checkGame(r.Context(), user, gameID)
+				if err == ErrOtherOrganisation {
+					pkghttp.Forbidden(w, errors.Wrap(ErrForbidden, err.Error()))
+					return
+				}
+				if err != nil {
+					pkghttp.Error(w, err)
+					return
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), contextOrganisationsKey, o)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// IsPlatformOperator checks if server user of the request is operator
+// of platform organisation.
+func IsPlatformOperator(r *http.Request) bool {
+	o, _ := r.Context().Value(contextOrganisationsKey).(*organisations)
+	user := GetUser(r)
+
+	return o != nil && user != nil && user.Type == ServerType && o.isPlatform(user)
+}
+
+// AuthorizeGame checks grants and organisation of server user for the
+// game passed outside of url, e.g. in request body.
+func AuthorizeGame(r *http.Request, gameID string) error {
+	user := GetUser(r)
+	if user == nil || !user.CanAccessGame(gameID) {
+		return ErrForbidden
+	}
+
+	o, _ := r.Context().Value(contextOrganisationsKey).(*organisations)
+	if o == nil {
+		return ErrForbidden
+	}
+
+	err := o.checkGame(r.Context(), user, gameID)
+	if err == ErrOtherOrganisation {
+		return ErrForbidden
+	}
+
+	return err
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOwners map[string]string
+
+func (f fakeOwners) GameOrganisation(_ context.Context, gameID string) (string, error) {
+	return f[gameID], nil
+}
+
+func (f fakeOwners) OrganisationExists(_ context.Context, organisationID string) (bool, error) {
+	for _, owner := range f {
+		if owner == organisationID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestOrganisationMiddleware(t *testing.T) {
+	owners := fakeOwners{"game-1": "studio-1", "game-2": "studio-2"}
+
+	ok := func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	router := chi.NewRouter()
+	router.Group(func(r chi.Router) {
+		r.Use(NewOrganisationMiddleware("platform", owners))
+		r.Get("/games/{game_id}", ok)
+		r.Get("/organisations/{organisation_id}/members", ok)
+	})
+
+	studio := &UserInfo{Type: ServerType, OrganisationID: "studio-1", Games: []string{AllGames}}
+	platform := &UserInfo{Type: ServerType, OrganisationID: "platform", Games: []string{AllGames}}
+
+	cases := []struct {
+		path string
+		user *UserInfo
+		code int
+	}{
+		{"/games/game-1", studio, http.StatusOK},
+		{"/games/game-2", studio, http.StatusForbidden},
+		// games without owner are managed by platform only
+		{"/games/game-3", studio, http.StatusForbidden},
+		{"/games/game-2", platform, http.StatusOK},
+		{"/games/game-3", platform, http.StatusOK},
+		{"/organisations/studio-1/members", studio, http.StatusOK},
+		{"/organisations/studio-2/members", studio, http.StatusForbidden},
+		{"/organisations/studio-2/members", platform, http.StatusOK},
+		// players are limited by their own scope
+		{"/games/game-2", &UserInfo{Type: RealType, GameID: "game-2"}, http.StatusOK},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", c.path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), ContextUserKey, c.user))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, c.code, w.Code, "%s by %s", c.path, c.user.OrganisationID)
+	}
+}
+
+func TestAuthorizeGame(t *testing.T) {
+	owners := fakeOwners{"game-1": "studio-1", "game-2": "studio-2"}
+
+	var err error
+	router := chi.NewRouter()
+	router.Group(func(r chi.Router) {
+		r.Use(NewOrganisationMiddleware("platform", owners))
+		r.Post("/leaderboards", func(w http.ResponseWriter, r *http.Request) {
+			err = AuthorizeGame(r, r.URL.Query().Get("game_id"))
+		})
+	})
+
+	authorize := func(user *UserInfo, gameID string) error {
+		req := httptest.NewRequest("POST", "/leaderboards?game_id="+gameID, nil)
+		req = req.WithContext(context.WithValue(req.Context(), ContextUserKey, user))
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		return err
+	}
+
+	studio := &UserInfo{Type: ServerType, OrganisationID: "studio-1", Games: []string{"game-1", "game-2"}}
+	require.NoError(t, authorize(studio, "game-1"))
+	require.Equal(t, ErrForbidden, authorize(studio, "game-2"))
+
+	limited := &UserInfo{Type: ServerType, OrganisationID: "platform", Games: []string{"game-1"}}
+	require.NoError(t, authorize(limited, "game-1"))
+	require.Equal(t, ErrForbidden, authorize(limited, "game-2"))
+}
@@ -0,0 +1,11 @@
+package auth
+
+import (
+	"context"
+)
+
+// PlayerChecker checks if the player is registered in the game, it's
+// used by server routes taking user id from url.
+type PlayerChecker interface {
+	PlayerExists(ctx context.Context, gameID, userID string) (bool, error)
+}
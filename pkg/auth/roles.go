@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+
+	pkghttp "gitlab.com/balconygames/analytics/pkg/http"
+)
+
+// ErrForbidden returned in case if server user doesn't have
+// required role or access to the game.
+var ErrForbidden = errors.New("forbidden")
+
+// Server roles, each role includes permissions of the
+// following roles.
+const (
+	RoleAdmin    = "admin"
+	RoleLiveOps  = "live-ops"
+	RoleReadOnly = "read-only"
+)
+
+// AllGames grants access to every game.
+const AllGames = "*"
+
+var roleLevels = map[string]int{
+	RoleAdmin:    3,
+	RoleLiveOps:  2,
+	RoleReadOnly: 1,
+}
+
+// ValidRole checks if the role is known.
+func ValidRole(role string) bool {
+	return roleLevels[role] > 0
+}
+
+// HasRole checks if the user has the role or the role including it.
+func (u *UserInfo) HasRole(role string) bool {
+	required := roleLevels[role]
+	if required == 0 {
+		return false
+	}
+
+	for _, r := range u.Roles {
+		if roleLevels[r] >= required {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CanAccessGame checks if the user has grant to the game,
+// admin has access to all games.
+func (u *UserInfo) CanAccessGame(gameID string) bool {
+	if u.HasRole(RoleAdmin) {
+		return true
+	}
+
+	for _, g := range u.Games {
+		if g == AllGames || g == gameID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RequireRoles allows requests of server users having one of the roles,
+// game_id url param is checked against game grants of the user.
+func RequireRoles(roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := GetUser(r)
+			if user == nil || user.Type != ServerType {
+				pkghttp.Forbidden(w, ErrForbidden)
+				return
+			}
+
+			allowed := false
+			for _, role := range roles {
+				if user.HasRole(role) {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				pkghttp.Forbidden(w, errors.Wrap(ErrForbidden, "missing role"))
+				return
+			}
+
+			gameID := chi.URLParam(r, "game_id")
+			if gameID != "" && !user.CanAccessGame(gameID) {
+				pkghttp.Forbidden(w, errors.Wrap(ErrForbidden, "missing game grant"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
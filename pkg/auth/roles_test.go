@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasRole(t *testing.T) {
+	user := &UserInfo{Type: ServerType, Roles: []string{RoleLiveOps}}
+
+	require.True(t, user.HasRole(RoleReadOnly))
+	require.True(t, user.HasRole(RoleLiveOps))
+	require.False(t, user.HasRole(RoleAdmin))
+	require.False(t, user.HasRole("unknown"))
+}
+
+func TestCanAccessGame(t *testing.T) {
+	user := &UserInfo{Type: ServerType, Roles: []string{RoleReadOnly}, Games: []string{"game-1"}}
+	require.True(t, user.CanAccessGame("game-1"))
+	require.False(t, user.CanAccessGame("game-2"))
+
+	user.Games = []string{AllGames}
+	require.True(t, user.CanAccessGame("game-2"))
+
+	admin := &UserInfo{Type: ServerType, Roles: []string{RoleAdmin}}
+	require.True(t, admin.CanAccessGame("game-2"))
+}
+
+func TestRequireRoles(t *testing.T) {
+	router := chi.NewRouter()
+	router.Group(func(r chi.Router) {
+		r.Use(RequireRoles(RoleLiveOps))
+		r.Post("/games/{game_id}", func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	})
+
+	cases := []struct {
+		user *UserInfo
+		code int
+	}{
+		{&UserInfo{Type: GuestType, Roles: []string{RoleAdmin}}, http.StatusForbidden},
+		{&UserInfo{Type: ServerType, Roles: []string{RoleReadOnly}, Games: []string{AllGames}}, http.StatusForbidden},
+		{&UserInfo{Type: ServerType, Roles: []string{RoleLiveOps}, Games: []string{"game-2"}}, http.StatusForbidden},
+		{&UserInfo{Type: ServerType, Roles: []string{RoleLiveOps}, Games: []string{"game-1"}}, http.StatusOK},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest("POST", "/games/game-1", nil)
+		req = req.WithContext(context.WithValue(req.Context(), ContextUserKey, c.user))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, c.code, w.Code)
+	}
+}
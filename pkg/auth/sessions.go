@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+)
+
+// ErrRevokedToken returned for tokens of revoked sessions.
+var ErrRevokedToken = errors.New("revoked token")
+
+// RevocationChecker checks if token belongs to revoked session.
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, claims *Claims) (bool, error)
+}
+
+// RevokedSessionKey is redis key to mark session as revoked.
+func RevokedSessionKey(sessionID string) string {
+	return fmt.Sprintf("revoked:sessions:%s", sessionID)
+}
+
+// RevokedUserKey is redis key to store revocation time, all user tokens
+// issued before it are revoked.
+func RevokedUserKey(userID string) string {
+	return fmt.Sprintf("revoked:users:%s", userID)
+}
+
+// RevokedAt formats revocation time stored by RevokedUserKey.
+func RevokedAt(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// revokedBefore checks if token of claims is issued before
+// revocation time formatted by RevokedAt.
+func revokedBefore(claims *Claims, revokedAt string) (bool, error) {
+	timestamp, err := strconv.ParseInt(revokedAt, 10, 64)
+	if err != nil {
+		return false, errors.WithMessage(err, "can't parse revoked timestamp")
+	}
+
+	return claims.issuedAtNano() < timestamp, nil
+}
+
+// RedisRevocationChecker reads revoked sessions stored by auth module.
+type RedisRevocationChecker struct {
+	conn *redis.Client
+}
+
+func NewRedisRevocationChecker(c *redis.Client) *RedisRevocationChecker {
+	return &RedisRevocationChecker{conn: c}
+}
+
+// IsRevoked checks if the session or all user sessions were revoked.
+func (c *RedisRevocationChecker) IsRevoked(ctx context.Context, claims *Claims) (bool, error) {
+	pipe := c.conn.Pipeline()
+
+	var sessionCmd *redis.IntCmd
+	if claims.SessionID != "" {
+		sessionCmd = pipe.Exists(ctx, RevokedSessionKey(claims.SessionID))
+	}
+	userCmd := pipe.Get(ctx, RevokedUserKey(claims.UserID))
+
+	_, err := pipe.Exec(ctx)
+	if err != nil && err != redis.Nil {
+		return false, errors.WithMessage(err, "can't exec pipeline to check revoked tokens")
+	}
+
+	if sessionCmd != nil && sessionCmd.Val() > 0 {
+		return true, nil
+	}
+
+	revokedAt, err := userCmd.Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return revokedBefore(claims, revokedAt)
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevokedBefore(t *testing.T) {
+	revokedAt := time.Date(2020, 8, 1, 10, 0, 0, 500000000, time.UTC)
+
+	issued := func(at time.Time) *Claims {
+		return &Claims{
+			StandardClaims: jwt.StandardClaims{IssuedAt: at.Unix()},
+			IssuedAtNano:   at.UnixNano(),
+		}
+	}
+
+	// token issued in the same second after revocation keeps working
+	revoked, err := revokedBefore(issued(revokedAt.Add(time.Millisecond)), RevokedAt(revokedAt))
+	require.Nil(t, err)
+	require.False(t, revoked)
+
+	revoked, err = revokedBefore(issued(revokedAt.Add(-time.Millisecond)), RevokedAt(revokedAt))
+	require.Nil(t, err)
+	require.True(t, revoked)
+
+	// tokens without nanoseconds are revoked in the same second
+	legacy := &Claims{StandardClaims: jwt.StandardClaims{IssuedAt: revokedAt.Unix()}}
+	revoked, err = revokedBefore(legacy, RevokedAt(revokedAt))
+	require.Nil(t, err)
+	require.True(t, revoked)
+
+	_, err = revokedBefore(legacy, "unknown")
+	require.NotNil(t, err)
+}
+
+func TestSignerIssuedAtNano(t *testing.T) {
+	signer := NewSigner("secret")
+
+	token, err := signer.Encode(Claims{UserInfo: UserInfo{UserID: "user"}})
+	require.Nil(t, err)
+
+	claims, err := signer.Decode(token)
+	require.Nil(t, err)
+	require.Equal(t, claims.IssuedAt, claims.IssuedAtNano/int64(time.Second))
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	pkghttp "gitlab.com/balconygames/analytics/pkg/http"
+)
+
+// ClientVersionHeader is version of the client app sent by clients,
+// requests without the header are not checked.
+const ClientVersionHeader = "X-Client-Version"
+
+// VersionChecker checks if the client version is supported by the app,
+// it returns UpgradeRequiredError for versions below the minimum.
+type VersionChecker interface {
+	CheckVersion(ctx context.Context, gameID, appID, version string) error
+}
+
+// UpgradeRequiredError returned for client versions below
+// the minimum version of the app.
+type UpgradeRequiredError struct {
+	Version            string
+	MinVersion         string
+	RecommendedVersion string
+	StoreURL           string
+}
+
+func (e *UpgradeRequiredError) Error() string {
+	return fmt.Sprintf("version %s is not supported, minimum version is %s", e.Version, e.MinVersion)
+}
+
+// UpgradeRequiredCode is error code of upgrade required response.
+const UpgradeRequiredCode = "upgrade_required"
+
+type upgradeRequiredResponse struct {
+	Error string `json:"error"`
+
+	Version            string `json:"version"`
+	MinVersion         string `json:"min_version"`
+	RecommendedVersion string `json:"recommended_version,omitempty"`
+	StoreURL           string `json:"store_url,omitempty"`
+}
+
+// RespondUpgradeRequired responds with 426 and versions of the app,
+// the client should open the store to update.
+func RespondUpgradeRequired(w http.ResponseWriter, err *UpgradeRequiredError) {
+	pkghttp.JSONWithStatus(w, http.StatusUpgradeRequired, upgradeRequiredResponse{
+		Error:              UpgradeRequiredCode,
+		Version:            err.Version,
+		MinVersion:         err.MinVersion,
+		RecommendedVersion: err.RecommendedVersion,
+		StoreURL:           err.StoreURL,
+	})
+}
+
+// NewClientVersionMiddleware rejects requests of client versions below
+// the minimum version of the app of JWT user.
+func NewClientVersionMiddleware(checker VersionChecker) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			version := r.Header.Get(ClientVersionHeader)
+			user := GetUser(r)
+			if version == "" || user == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			err := checker.CheckVersion(r.Context(), user.GameID, user.AppID, version)
+
+			var upgrade *UpgradeRequiredError
+			if errors.As(err, &upgrade) {
+				RespondUpgradeRequired(w, upgrade)
+				return
+			}
+			if err != nil {
+				pkghttp.Error(w, errors.Wrap(err, "can't check client version"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type minVersionChecker struct {
+	calls int
+}
+
+func (c *minVersionChecker) CheckVersion(ctx context.Context, gameID, appID, version string) error {
+	c.calls++
+	if version == "1.0.0" {
+		return &UpgradeRequiredError{
+			Version:    version,
+			MinVersion: "1.2.0",
+			StoreURL:   "market://details?id=" + appID,
+		}
+	}
+	return nil
+}
+
+func TestClientVersionMiddleware(t *testing.T) {
+	checker := &minVersionChecker{}
+	handler := NewClientVersionMiddleware(checker)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(version string, user *UserInfo) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if version != "" {
+			req.Header.Set(ClientVersionHeader, version)
+		}
+		if user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), ContextUserKey, user))
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	user := &UserInfo{GameID: "game-1", AppID: "app-1"}
+
+	// requests without version or user are not checked
+	require.Equal(t, http.StatusOK, request("", user).Code)
+	require.Equal(t, http.StatusOK, request("1.0.0", nil).Code)
+	require.Equal(t, 0, checker.calls)
+
+	require.Equal(t, http.StatusOK, request("1.2.0", user).Code)
+
+	rr := request("1.0.0", user)
+	require.Equal(t, http.StatusUpgradeRequired, rr.Code)
+
+	var resp upgradeRequiredResponse
+	require.Nil(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Equal(t, UpgradeRequiredCode, resp.Error)
+	require.Equal(t, "1.2.0", resp.MinVersion)
+	require.Equal(t, "market://details?id=app-1", resp.StoreURL)
+}
@@ -0,0 +1,52 @@
+// Package blobs stores binary objects, e.g. cloud saves, in postgres
+// large objects or in files for local development.
+package blobs
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+)
+
+// ErrNotFound returned if blob doesn't exist.
+var ErrNotFound = errors.New("blob not found")
+
+// Store stores blobs by references generated on put.
+type Store interface {
+	// Put stores data and respond with reference to the blob.
+	Put(ctx context.Context, data []byte) (string, error)
+	// Get respond with data of the blob.
+	Get(ctx context.Context, ref string) ([]byte, error)
+	// Delete removes the blob, missing blobs are ignored.
+	Delete(ctx context.Context, ref string) error
+}
+
+type Config struct {
+	// Driver is postgres, file or memory
+	Driver string `envconfig:"DRIVER" default:"postgres"`
+
+	// Dir is used by file driver to write blobs
+	Dir string `envconfig:"DIR" default:"tmp/blobs"`
+}
+
+// Drivers of store.
+const (
+	DriverPostgres = "postgres"
+	DriverFile     = "file"
+	DriverMemory   = "memory"
+)
+
+// New creates store by driver of config, pool is used by postgres driver.
+func New(c Config, pool *pgxpool.Pool) (Store, error) {
+	switch c.Driver {
+	case DriverPostgres, "":
+		return NewPostgresStore(pool), nil
+	case DriverFile:
+		return NewFileStore(c.Dir), nil
+	case DriverMemory:
+		return NewMemoryStore(), nil
+	default:
+		return nil, errors.Errorf("unknown blobs driver %s", c.Driver)
+	}
+}
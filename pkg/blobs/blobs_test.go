@@ -0,0 +1,40 @@
+package blobs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobs")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	s := NewFileStore(dir)
+
+	ref, err := s.Put(ctx, []byte("save"))
+	require.Nil(t, err)
+
+	data, err := s.Get(ctx, ref)
+	require.Nil(t, err)
+	require.Equal(t, []byte("save"), data)
+
+	require.Nil(t, s.Delete(ctx, ref))
+	require.Nil(t, s.Delete(ctx, ref))
+
+	_, err = s.Get(ctx, ref)
+	require.Equal(t, ErrNotFound, err)
+
+	_, err = s.Get(ctx, "../../etc/passwd")
+	require.Equal(t, ErrNotFound, err)
+}
+
+func TestNewUnknownDriver(t *testing.T) {
+	_, err := New(Config{Driver: "s3"}, nil)
+	require.NotNil(t, err)
+}
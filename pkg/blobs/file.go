@@ -0,0 +1,87 @@
+package blobs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/pkg/errors"
+)
+
+// FileStore stores blobs as files in directory,
+// used in development.
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+func (s *FileStore) Put(ctx context.Context, data []byte) (string, error) {
+	ref, err := uuid.GenerateUUID()
+	if err != nil {
+		return "", err
+	}
+
+	name := s.path(ref)
+	err = os.MkdirAll(filepath.Dir(name), 0755)
+	if err != nil {
+		return "", errors.Wrapf(err, "can't create blobs dir %s", s.dir)
+	}
+
+	// written into temporary file to not expose partial blobs
+	err = ioutil.WriteFile(name+".tmp", data, 0644)
+	if err != nil {
+		return "", errors.Wrapf(err, "can't write blob %s", ref)
+	}
+
+	err = os.Rename(name+".tmp", name)
+	if err != nil {
+		return "", errors.Wrapf(err, "can't write blob %s", ref)
+	}
+
+	return ref, nil
+}
+
+func (s *FileStore) Get(ctx context.Context, ref string) ([]byte, error) {
+	if !validRef(ref) {
+		return nil, ErrNotFound
+	}
+
+	data, err := ioutil.ReadFile(s.path(ref))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't read blob %s", ref)
+	}
+
+	return data, nil
+}
+
+func (s *FileStore) Delete(ctx context.Context, ref string) error {
+	if !validRef(ref) {
+		return nil
+	}
+
+	err := os.Remove(s.path(ref))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "can't delete blob %s", ref)
+	}
+
+	return nil
+}
+
+// path shards blobs by first chars of reference.
+func (s *FileStore) path(ref string) string {
+	return filepath.Join(s.dir, ref[:2], ref)
+}
+
+// validRef protects from reading files outside of the directory.
+func validRef(ref string) bool {
+	return len(ref) > 2 && !strings.ContainsAny(ref, `/\.`)
+}
@@ -0,0 +1,57 @@
+package blobs
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// MemoryStore keeps blobs in memory, used in tests.
+type MemoryStore struct {
+	mu    sync.Mutex
+	seq   int
+	blobs map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{blobs: make(map[string][]byte)}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, data []byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	ref := strconv.Itoa(s.seq)
+	s.blobs[ref] = append([]byte(nil), data...)
+
+	return ref, nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, ref string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.blobs[ref]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return append([]byte(nil), data...), nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, ref string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.blobs, ref)
+	return nil
+}
+
+// Len respond with number of stored blobs.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.blobs)
+}
@@ -0,0 +1,114 @@
+package blobs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+)
+
+// PostgresStore stores blobs as postgres large objects,
+// reference is oid of the object.
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresStore(pool *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{pool: pool}
+}
+
+func (s *PostgresStore) Put(ctx context.Context, data []byte) (string, error) {
+	var oid uint32
+
+	err := s.withTx(ctx, func(lo pgx.LargeObjects) error {
+		var err error
+		oid, err = lo.Create(ctx, 0)
+		if err != nil {
+			return errors.Wrap(err, "can't create large object")
+		}
+
+		obj, err := lo.Open(ctx, oid, pgx.LargeObjectModeWrite)
+		if err != nil {
+			return errors.Wrap(err, "can't open large object")
+		}
+		defer obj.Close()
+
+		_, err = io.Copy(obj, bytes.NewReader(data))
+		return errors.Wrap(err, "can't write large object")
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.FormatUint(uint64(oid), 10), nil
+}
+
+func (s *PostgresStore) Get(ctx context.Context, ref string) ([]byte, error) {
+	oid, err := parseOID(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = s.withTx(ctx, func(lo pgx.LargeObjects) error {
+		obj, err := lo.Open(ctx, oid, pgx.LargeObjectModeRead)
+		if err != nil {
+			return ErrNotFound
+		}
+		defer obj.Close()
+
+		_, err = io.Copy(&buf, obj)
+		return errors.Wrap(err, "can't read large object")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, ref string) error {
+	oid, err := parseOID(ref)
+	if err != nil {
+		return err
+	}
+
+	// lo_unlink fails on missing objects, so existence is checked first
+	_, err = s.pool.Exec(ctx, `
+		SELECT lo_unlink(oid)
+		FROM pg_largeobject_metadata
+		WHERE oid=$1
+	`, oid)
+
+	return errors.Wrap(err, "can't delete large object")
+}
+
+// withTx runs fn in transaction, large objects can be used
+// only inside transactions.
+func (s *PostgresStore) withTx(ctx context.Context, fn func(lo pgx.LargeObjects) error) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = fn(tx.LargeObjects())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func parseOID(ref string) (uint32, error) {
+	oid, err := strconv.ParseUint(ref, 10, 32)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid large object reference %s", ref)
+	}
+
+	return uint32(oid), nil
+}
@@ -0,0 +1,81 @@
+// Package cache keeps values loaded from storage in memory for a short
+// time, e.g. settings of games read on every request.
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type entry struct {
+	value    interface{}
+	loadedAt time.Time
+}
+
+// TTL is concurrency safe cache of values by key, values are expired
+// after ttl and evicted by Set at most once per ttl.
+type TTL struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	items   map[string]entry
+	sweptAt time.Time
+}
+
+// NewTTL creates cache which keeps values for ttl.
+func NewTTL(ttl time.Duration) *TTL {
+	return &TTL{
+		ttl:   ttl,
+		now:   time.Now,
+		items: make(map[string]entry),
+	}
+}
+
+// Get respond with the value of the key, expired values aren't returned.
+func (c *TTL) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok || c.now().Sub(e.loadedAt) >= c.ttl {
+		return nil, false
+	}
+
+	return e.value, true
+}
+
+// Set stores the value of the key. Expired values are evicted once
+// per ttl, so keys which aren't requested anymore don't grow the cache
+// and writes don't walk all values every time.
+func (c *TTL) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if now.Sub(c.sweptAt) >= c.ttl {
+		for k, e := range c.items {
+			if now.Sub(e.loadedAt) >= c.ttl {
+				delete(c.items, k)
+			}
+		}
+		c.sweptAt = now
+	}
+
+	c.items[key] = entry{value: value, loadedAt: now}
+}
+
+// Delete removes the value of the key, next Get reloads it.
+func (c *TTL) Delete(key string) {
+	c.mu.Lock()
+	delete(c.items, key)
+	c.mu.Unlock()
+}
+
+// Len respond with number of stored values including expired ones.
+func (c *TTL) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.items)
+}
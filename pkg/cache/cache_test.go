@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTL(t *testing.T) {
+	now := time.Now()
+
+	c := NewTTL(30 * time.Second)
+	c.now = func() time.Time { return now }
+
+	_, ok := c.Get("game-1")
+	require.False(t, ok)
+
+	c.Set("game-1", "config")
+	value, ok := c.Get("game-1")
+	require.True(t, ok)
+	require.Equal(t, "config", value)
+
+	// nil values are cached as well, e.g. unknown apps
+	c.Set("game-2", nil)
+	value, ok = c.Get("game-2")
+	require.True(t, ok)
+	require.Nil(t, value)
+
+	c.Delete("game-2")
+	_, ok = c.Get("game-2")
+	require.False(t, ok)
+
+	now = now.Add(30 * time.Second)
+	_, ok = c.Get("game-1")
+	require.False(t, ok)
+	require.Equal(t, 1, c.Len())
+
+	// expired values are evicted by the first set after ttl
+	c.Set("game-3", "config")
+	require.Equal(t, 1, c.Len())
+
+	now = now.Add(time.Second)
+	c.Set("game-4", "config")
+	require.Equal(t, 2, c.Len())
+
+	now = now.Add(30 * time.Second)
+	c.Set("game-5", "config")
+	require.Equal(t, 1, c.Len())
+}
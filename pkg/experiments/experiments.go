@@ -0,0 +1,53 @@
+package experiments
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// Variant of experiment, users are split between variants
+// proportionally to weights.
+type Variant struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+}
+
+// Assigner respond with variants of running experiments assigned
+// to the user by experiment key.
+type Assigner interface {
+	Assign(ctx context.Context, scope *sharedmodels.Scope) (map[string]string, error)
+}
+
+// Assign picks variant of the user by hash of salt and user id, the same
+// user gets the same variant until weights are changed. Salt should be
+// unique per experiment to don't assign the same users to the first
+// variants of all experiments.
+func Assign(salt, userID string, variants []Variant) string {
+	var total uint64
+	for _, v := range variants {
+		if v.Weight > 0 {
+			total += uint64(v.Weight)
+		}
+	}
+	if total == 0 {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(salt + ":" + userID))
+	point := binary.BigEndian.Uint64(sum[:8]) % total
+
+	for _, v := range variants {
+		if v.Weight <= 0 {
+			continue
+		}
+		if point < uint64(v.Weight) {
+			return v.Name
+		}
+		point -= uint64(v.Weight)
+	}
+
+	return ""
+}
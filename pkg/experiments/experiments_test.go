@@ -0,0 +1,32 @@
+package experiments
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssignDeterministic(t *testing.T) {
+	variants := []Variant{{Name: "control", Weight: 50}, {Name: "hard", Weight: 50}}
+
+	for i := 0; i < 100; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		require.Equal(t, Assign("game:difficulty", userID, variants), Assign("game:difficulty", userID, variants))
+	}
+}
+
+func TestAssignWeights(t *testing.T) {
+	variants := []Variant{{Name: "control", Weight: 80}, {Name: "sale", Weight: 20}, {Name: "off", Weight: 0}}
+
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		counts[Assign("game:prices", fmt.Sprintf("user-%d", i), variants)]++
+	}
+
+	require.InDelta(t, 8000, counts["control"], 300)
+	require.InDelta(t, 2000, counts["sale"], 300)
+	require.Zero(t, counts["off"])
+
+	require.Equal(t, "", Assign("game:prices", "user-1", []Variant{{Name: "off"}}))
+}
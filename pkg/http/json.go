@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"strings"
 )
 
 // JSONB should return json key value as string
@@ -28,6 +29,13 @@ func JSON(w http.ResponseWriter, o interface{}) {
 	_, _ = w.Write(EncodeJSON(o))
 }
 
+// JSONWithStatus writes object with the status code, used to
+// respond with structured errors.
+func JSONWithStatus(w http.ResponseWriter, status int, o interface{}) {
+	w.WriteHeader(status)
+	JSON(w, o)
+}
+
 func OK(w http.ResponseWriter) {
 	_, _ = w.Write(JSONB("response", "OK"))
 }
@@ -55,3 +63,36 @@ func Read(r *http.Request, out interface{}) error {
 func Error(w http.ResponseWriter, err error) {
 	http.Error(w, EncodeJSONKV("error", err.Error()), http.StatusBadRequest)
 }
+
+// Unauthorized with unauthorized code and message, client should
+// sign in again.
+func Unauthorized(w http.ResponseWriter, err error) {
+	http.Error(w, EncodeJSONKV("error", err.Error()), http.StatusUnauthorized)
+}
+
+// Forbidden with forbidden code and message, the user doesn't
+// have permissions to perform request.
+func Forbidden(w http.ResponseWriter, err error) {
+	http.Error(w, EncodeJSONKV("error", err.Error()), http.StatusForbidden)
+}
+
+// TooManyRequests with too many requests code and message, client
+// should retry later.
+func TooManyRequests(w http.ResponseWriter, err error) {
+	http.Error(w, EncodeJSONKV("error", err.Error()), http.StatusTooManyRequests)
+}
+
+// Locale respond with locale of the client passed by locale query
+// param or the first language of Accept-Language header.
+func Locale(r *http.Request) string {
+	if locale := r.URL.Query().Get("locale"); locale != "" {
+		return locale
+	}
+
+	language := r.Header.Get("Accept-Language")
+	if i := strings.IndexAny(language, ",;"); i >= 0 {
+		language = language[:i]
+	}
+
+	return strings.TrimSpace(language)
+}
@@ -0,0 +1,437 @@
+// Package jsonschema validates JSON documents by the subset of JSON Schema
+// (draft-07) used to describe game data: type, enum, const, properties,
+// required, additionalProperties, items, numeric and length limits and
+// pattern. Unsupported keywords are ignored.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Schema is compiled JSON schema.
+type Schema struct {
+	Types []string
+
+	Enum  []interface{}
+	Const interface{}
+
+	HasConst bool
+
+	Properties           map[string]*Schema
+	Required             []string
+	AdditionalProperties *Schema
+	NoAdditional         bool
+	MaxProperties        *int
+
+	Items    *Schema
+	MinItems *int
+	MaxItems *int
+
+	Minimum *float64
+	Maximum *float64
+
+	MinLength *int
+	MaxLength *int
+	Pattern   *regexp.Regexp
+}
+
+// ValidationError describes the first invalid value of the document.
+type ValidationError struct {
+	// Path is JSON pointer to the invalid value
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "/"
+	}
+
+	return fmt.Sprintf("%s: %s", path, e.Message)
+}
+
+type rawSchema struct {
+	Type  json.RawMessage `json:"type"`
+	Enum  []interface{}   `json:"enum"`
+	Const json.RawMessage `json:"const"`
+
+	Properties           map[string]json.RawMessage `json:"properties"`
+	Required             []string                   `json:"required"`
+	AdditionalProperties json.RawMessage            `json:"additionalProperties"`
+	MaxProperties        *int                       `json:"maxProperties"`
+
+	Items    json.RawMessage `json:"items"`
+	MinItems *int            `json:"minItems"`
+	MaxItems *int            `json:"maxItems"`
+
+	Minimum *float64 `json:"minimum"`
+	Maximum *float64 `json:"maximum"`
+
+	MinLength *int   `json:"minLength"`
+	MaxLength *int   `json:"maxLength"`
+	Pattern   string `json:"pattern"`
+}
+
+var knownTypes = map[string]bool{
+	"null":    true,
+	"boolean": true,
+	"object":  true,
+	"array":   true,
+	"number":  true,
+	"integer": true,
+	"string":  true,
+}
+
+// Compile parses JSON schema.
+func Compile(data []byte) (*Schema, error) {
+	return compile(data, "")
+}
+
+func compile(data []byte, path string) (*Schema, error) {
+	data = bytes.TrimSpace(data)
+
+	// boolean schemas accept or reject everything
+	switch string(data) {
+	case "true":
+		return &Schema{}, nil
+	case "false":
+		return &Schema{Types: []string{}}, nil
+	}
+
+	var raw rawSchema
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid schema at %s", pathOrRoot(path))
+	}
+
+	s := &Schema{
+		Enum:          raw.Enum,
+		Required:      raw.Required,
+		MaxProperties: raw.MaxProperties,
+		MinItems:      raw.MinItems,
+		MaxItems:      raw.MaxItems,
+		Minimum:       raw.Minimum,
+		Maximum:       raw.Maximum,
+		MinLength:     raw.MinLength,
+		MaxLength:     raw.MaxLength,
+	}
+
+	if len(raw.Type) > 0 {
+		s.Types, err = parseTypes(raw.Type)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid schema at %s", pathOrRoot(path))
+		}
+	}
+
+	if len(raw.Const) > 0 {
+		s.HasConst = true
+		err = json.Unmarshal(raw.Const, &s.Const)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid const at %s", pathOrRoot(path))
+		}
+	}
+
+	if raw.Pattern != "" {
+		s.Pattern, err = regexp.Compile(raw.Pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern at %s", pathOrRoot(path))
+		}
+	}
+
+	if len(raw.Properties) > 0 {
+		s.Properties = make(map[string]*Schema, len(raw.Properties))
+		for name, property := range raw.Properties {
+			s.Properties[name], err = compile(property, path+"/properties/"+name)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	switch additional := string(bytes.TrimSpace(raw.AdditionalProperties)); additional {
+	case "":
+	case "false":
+		s.NoAdditional = true
+	default:
+		s.AdditionalProperties, err = compile(raw.AdditionalProperties, path+"/additionalProperties")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(raw.Items) > 0 {
+		s.Items, err = compile(raw.Items, path+"/items")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func parseTypes(data json.RawMessage) ([]string, error) {
+	var types []string
+
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		types = []string{single}
+	} else if err := json.Unmarshal(data, &types); err != nil {
+		return nil, errors.New("type should be string or array of strings")
+	}
+
+	for _, t := range types {
+		if !knownTypes[t] {
+			return nil, errors.Errorf("unknown type %s", t)
+		}
+	}
+
+	return types, nil
+}
+
+// Validate validates JSON document.
+func (s *Schema) Validate(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	err := decoder.Decode(&value)
+	if err != nil {
+		return &ValidationError{Message: "invalid JSON: " + err.Error()}
+	}
+
+	return s.ValidateValue(value)
+}
+
+// ValidateValue validates decoded JSON value, numbers could be
+// decoded as float64 or json.Number.
+func (s *Schema) ValidateValue(value interface{}) error {
+	return s.validate(value, "")
+}
+
+func (s *Schema) validate(value interface{}, path string) error {
+	fail := func(format string, args ...interface{}) error {
+		return &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)}
+	}
+
+	if s.Types != nil && !s.matchType(value) {
+		if len(s.Types) == 0 {
+			return fail("value is not allowed")
+		}
+		return fail("expected %s, got %s", strings.Join(s.Types, " or "), typeOf(value))
+	}
+
+	if s.Enum != nil {
+		found := false
+		for _, allowed := range s.Enum {
+			if equal(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fail("value is not one of enum values")
+		}
+	}
+
+	if s.HasConst && !equal(s.Const, value) {
+		return fail("value should be equal to const")
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return s.validateObject(v, path)
+	case []interface{}:
+		return s.validateArray(v, path)
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			return fail("string is shorter than %d", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			return fail("string is longer than %d", *s.MaxLength)
+		}
+		if s.Pattern != nil && !s.Pattern.MatchString(v) {
+			return fail("string doesn't match pattern %s", s.Pattern)
+		}
+	default:
+		if number, ok := toFloat(value); ok {
+			if s.Minimum != nil && number < *s.Minimum {
+				return fail("number is less than %v", *s.Minimum)
+			}
+			if s.Maximum != nil && number > *s.Maximum {
+				return fail("number is greater than %v", *s.Maximum)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) validateObject(object map[string]interface{}, path string) error {
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("property %s is required", name)}
+		}
+	}
+
+	if s.MaxProperties != nil && len(object) > *s.MaxProperties {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("object has more than %d properties", *s.MaxProperties)}
+	}
+
+	// sorted to respond with the same error for the same document
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property, ok := s.Properties[name]
+		if !ok {
+			if s.NoAdditional {
+				return &ValidationError{Path: path, Message: fmt.Sprintf("property %s is not allowed", name)}
+			}
+			property = s.AdditionalProperties
+		}
+		if property == nil {
+			continue
+		}
+
+		err := property.validate(object[name], path+"/"+escape(name))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) validateArray(array []interface{}, path string) error {
+	if s.MinItems != nil && len(array) < *s.MinItems {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("array has less than %d items", *s.MinItems)}
+	}
+	if s.MaxItems != nil && len(array) > *s.MaxItems {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("array has more than %d items", *s.MaxItems)}
+	}
+
+	if s.Items == nil {
+		return nil
+	}
+
+	for i, item := range array {
+		err := s.Items.validate(item, fmt.Sprintf("%s/%d", path, i))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) matchType(value interface{}) bool {
+	actual := typeOf(value)
+	for _, t := range s.Types {
+		if t == actual {
+			return true
+		}
+		if t == "number" && actual == "integer" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		number, ok := toFloat(v)
+		if !ok {
+			return "unknown"
+		}
+		if number == math.Trunc(number) {
+			return "integer"
+		}
+		return "number"
+	}
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		number, err := v.Float64()
+		return number, err == nil
+	}
+
+	return 0, false
+}
+
+// equal compares decoded JSON values, numbers are compared by value.
+func equal(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			if !equal(value, y[key]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return a == b
+}
+
+// escape encodes property name as JSON pointer token.
+func escape(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+
+	return path
+}
@@ -0,0 +1,62 @@
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const progressSchema = `{
+	"type": "object",
+	"required": ["level"],
+	"additionalProperties": false,
+	"properties": {
+		"level": {"type": "integer", "minimum": 1, "maximum": 100},
+		"name": {"type": "string", "maxLength": 8, "pattern": "^[a-z]+$"},
+		"mode": {"enum": ["easy", "hard"]},
+		"items": {"type": "array", "maxItems": 2, "items": {"type": ["string", "null"]}},
+		"stats": {"type": "object", "additionalProperties": {"type": "number"}}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	schema, err := Compile([]byte(progressSchema))
+	require.Nil(t, err)
+
+	valid := []string{
+		`{"level": 1}`,
+		`{"level": 100, "name": "hero", "mode": "hard", "items": ["sword", null], "stats": {"hp": 1.5}}`,
+	}
+	for _, document := range valid {
+		require.Nil(t, schema.Validate([]byte(document)), document)
+	}
+
+	invalid := map[string]string{
+		`{}`:                                 "/: property level is required",
+		`{"level": 1.5}`:                     "/level: expected integer, got number",
+		`{"level": 0}`:                       "/level: number is less than 1",
+		`{"level": 1, "name": "Hero"}`:       "/name: string doesn't match pattern ^[a-z]+$",
+		`{"level": 1, "mode": "mid"}`:        "/mode: value is not one of enum values",
+		`{"level": 1, "items": [1]}`:         "/items/0: expected string or null, got integer",
+		`{"level": 1, "stats": {"hp": "x"}}`: "/stats/hp: expected number, got string",
+		`{"level": 1, "coins": 10}`:          "/: property coins is not allowed",
+		`[]`:                                 "/: expected object, got array",
+	}
+	for document, message := range invalid {
+		err = schema.Validate([]byte(document))
+		require.NotNil(t, err, document)
+		require.Equal(t, message, err.Error(), document)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, schema := range []string{
+		`{"type": "text"}`,
+		`{"pattern": "("}`,
+		`{"properties": {"level": {"type": 1}}}`,
+		`[]`,
+	} {
+		_, err := Compile([]byte(schema))
+		require.NotNil(t, err, schema)
+	}
+}
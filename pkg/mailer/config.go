@@ -0,0 +1,25 @@
+package mailer
+
+import (
+	"fmt"
+)
+
+type Config struct {
+	// Driver is smtp, file or memory, features sending emails
+	// are disabled for empty driver.
+	Driver string `envconfig:"DRIVER"`
+
+	From string `envconfig:"FROM"`
+
+	User string `envconfig:"USER"`
+	Pass string `envconfig:"PASS"`
+	Host string `envconfig:"HOST"`
+	Port int    `envconfig:"PORT" default:"587"`
+
+	// Dir is used by file driver to write messages
+	Dir string `envconfig:"DIR" default:"tmp/mails"`
+}
+
+func (c Config) Addr() string {
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
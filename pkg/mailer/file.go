@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FileMailer writes messages as JSON files into directory,
+// used in development.
+type FileMailer struct {
+	dir string
+}
+
+func NewFileMailer(dir string) *FileMailer {
+	return &FileMailer{dir: dir}
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	err := os.MkdirAll(m.dir, 0755)
+	if err != nil {
+		return errors.Wrapf(err, "can't create mails dir %s", m.dir)
+	}
+
+	b, err := json.MarshalIndent(msg, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Join(m.dir, fmt.Sprintf("%d.json", time.Now().UnixNano()))
+	err = ioutil.WriteFile(name, b, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "can't write mail %s", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Message is plain text email.
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+}
+
+// Mailer sends emails, use memory or file mailer in tests
+// and development.
+type Mailer interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// Drivers of mailer.
+const (
+	DriverSMTP   = "smtp"
+	DriverFile   = "file"
+	DriverMemory = "memory"
+)
+
+// New creates mailer by driver of config.
+func New(c Config) (Mailer, error) {
+	switch c.Driver {
+	case DriverSMTP:
+		return NewSMTPMailer(c), nil
+	case DriverFile:
+		return NewFileMailer(c.Dir), nil
+	case DriverMemory:
+		return NewMemoryMailer(), nil
+	case "":
+		return nil, errors.New("mailer driver is required")
+	default:
+		return nil, errors.Errorf("unknown mailer driver %s", c.Driver)
+	}
+}
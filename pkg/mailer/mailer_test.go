@@ -0,0 +1,63 @@
+package mailer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryMailer(t *testing.T) {
+	m := NewMemoryMailer()
+
+	require.Nil(t, m.Send(context.Background(), Message{To: "a@example.com", Subject: "1"}))
+	require.Nil(t, m.Send(context.Background(), Message{To: "b@example.com", Subject: "2"}))
+	require.Nil(t, m.Send(context.Background(), Message{To: "a@example.com", Subject: "3"}))
+
+	require.Len(t, m.Messages(), 3)
+
+	msg, ok := m.Last("a@example.com")
+	require.True(t, ok)
+	require.Equal(t, "3", msg.Subject)
+
+	_, ok = m.Last("c@example.com")
+	require.False(t, ok)
+}
+
+func TestMemoryMailerLimit(t *testing.T) {
+	m := NewMemoryMailer()
+
+	for i := 0; i < maxMemoryMessages+10; i++ {
+		require.Nil(t, m.Send(context.Background(), Message{To: "a@example.com", Subject: strconv.Itoa(i)}))
+	}
+
+	messages := m.Messages()
+	require.Len(t, messages, maxMemoryMessages)
+	require.Equal(t, "10", messages[0].Subject)
+	require.Equal(t, strconv.Itoa(maxMemoryMessages+9), messages[len(messages)-1].Subject)
+}
+
+func TestNewMailer(t *testing.T) {
+	_, err := New(Config{})
+	require.NotNil(t, err)
+
+	m, err := New(Config{Driver: DriverMemory})
+	require.Nil(t, err)
+	require.IsType(t, &MemoryMailer{}, m)
+}
+
+func TestFileMailer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mails")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	m := NewFileMailer(dir)
+	require.Nil(t, m.Send(context.Background(), Message{To: "a@example.com", Subject: "hello"}))
+
+	files, err := ioutil.ReadDir(dir)
+	require.Nil(t, err)
+	require.Len(t, files, 1)
+}
@@ -0,0 +1,56 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// maxMemoryMessages is number of the last messages kept by memory mailer.
+const maxMemoryMessages = 1000
+
+// MemoryMailer keeps the last sent messages in memory, used by tests.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.messages) >= maxMemoryMessages {
+		m.messages = append(m.messages[:0], m.messages[1:]...)
+	}
+	m.messages = append(m.messages, msg)
+
+	return nil
+}
+
+// Messages respond with sent messages.
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]Message, len(m.messages))
+	copy(out, m.messages)
+
+	return out
+}
+
+// Last respond with the last sent message to the recipient.
+func (m *MemoryMailer) Last(to string) (Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To == to {
+			return m.messages[i], true
+		}
+	}
+
+	return Message{}, false
+}
@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SMTPMailer sends emails via SMTP server, STARTTLS is used
+// in case if server supports it.
+type SMTPMailer struct {
+	config Config
+}
+
+func NewSMTPMailer(c Config) *SMTPMailer {
+	return &SMTPMailer{config: c}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if m.config.User != "" {
+		auth = smtp.PlainAuth("", m.config.User, m.config.Pass, m.config.Host)
+	}
+
+	err := smtp.SendMail(m.config.Addr(), auth, m.config.From, []string{msg.To}, m.encode(msg))
+	if err != nil {
+		return errors.Wrapf(err, "can't send email via %s", m.config.Addr())
+	}
+
+	return nil
+}
+
+func (m *SMTPMailer) encode(msg Message) []byte {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "From: %s\r\n", m.config.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Text)
+
+	return b.Bytes()
+}
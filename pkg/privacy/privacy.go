@@ -0,0 +1,115 @@
+package privacy
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Subject is the player whose personal data is exported or deleted.
+type Subject struct {
+	GameID string `json:"game_id"`
+	AppID  string `json:"app_id"`
+	UserID string `json:"user_id"`
+}
+
+// Provider is implemented by modules storing personal data.
+type Provider interface {
+	// Export respond with data held about the subject,
+	// it's encoded as JSON into the archive.
+	Export(ctx context.Context, subject Subject) (interface{}, error)
+	// Delete removes or anonymises data of the subject.
+	Delete(ctx context.Context, subject Subject) error
+}
+
+// Archive is personal data gathered from all modules.
+type Archive struct {
+	Subject     Subject                `json:"subject"`
+	GeneratedAt time.Time              `json:"generated_at"`
+	Modules     map[string]interface{} `json:"modules"`
+}
+
+// Registry contains providers registered by modules.
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+}
+
+func NewRegistry() *Registry {
+	return &Registry{providers: make(map[string]Provider)}
+}
+
+// Register adds provider of the module.
+func (r *Registry) Register(module string, p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.providers[module] = p
+}
+
+// modules respond with names of modules in stable order.
+func (r *Registry) modules() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (r *Registry) provider(module string) Provider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.providers[module]
+}
+
+// Export gathers personal data of the subject from every module.
+func (r *Registry) Export(ctx context.Context, subject Subject) (*Archive, error) {
+	archive := &Archive{
+		Subject:     subject,
+		GeneratedAt: time.Now().UTC(),
+		Modules:     make(map[string]interface{}),
+	}
+
+	for _, module := range r.modules() {
+		data, err := r.provider(module).Export(ctx, subject)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "can't export personal data of %s", module)
+		}
+
+		archive.Modules[module] = data
+	}
+
+	return archive, nil
+}
+
+// Delete removes personal data of the subject from every module,
+// failed module doesn't stop deletion in other modules. Providers
+// should be idempotent to retry failed deletions.
+func (r *Registry) Delete(ctx context.Context, subject Subject) error {
+	var failed []string
+	var last error
+
+	for _, module := range r.modules() {
+		err := r.provider(module).Delete(ctx, subject)
+		if err != nil {
+			failed = append(failed, module)
+			last = err
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.WithMessagef(last, "can't delete personal data of %s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package privacy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProvider struct {
+	data    interface{}
+	deleted []Subject
+	err     error
+}
+
+func (p *fakeProvider) Export(ctx context.Context, subject Subject) (interface{}, error) {
+	return p.data, p.err
+}
+
+func (p *fakeProvider) Delete(ctx context.Context, subject Subject) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.deleted = append(p.deleted, subject)
+	return nil
+}
+
+func TestRegistryExportAndDelete(t *testing.T) {
+	subject := Subject{GameID: "game-1", AppID: "app-1", UserID: "user-1"}
+
+	auth := &fakeProvider{data: map[string]string{"name": "Guest-1"}}
+	leaderboard := &fakeProvider{data: []string{"score"}}
+
+	r := NewRegistry()
+	r.Register("auth", auth)
+	r.Register("leaderboard", leaderboard)
+
+	archive, err := r.Export(context.Background(), subject)
+	require.Nil(t, err)
+	require.Equal(t, subject, archive.Subject)
+	require.Equal(t, auth.data, archive.Modules["auth"])
+	require.Equal(t, leaderboard.data, archive.Modules["leaderboard"])
+
+	require.Nil(t, r.Delete(context.Background(), subject))
+	require.Equal(t, []Subject{subject}, auth.deleted)
+	require.Equal(t, []Subject{subject}, leaderboard.deleted)
+}
+
+func TestRegistryFailedProvider(t *testing.T) {
+	subject := Subject{GameID: "game-1", AppID: "app-1", UserID: "user-1"}
+	leaderboard := &fakeProvider{}
+
+	r := NewRegistry()
+	r.Register("broken", &fakeProvider{err: errors.New("boom")})
+	r.Register("leaderboard", leaderboard)
+
+	_, err := r.Export(context.Background(), subject)
+	require.NotNil(t, err)
+
+	// other modules delete data of the subject
+	err = r.Delete(context.Background(), subject)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "broken")
+	require.Equal(t, []Subject{subject}, leaderboard.deleted)
+}
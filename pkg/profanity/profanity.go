@@ -0,0 +1,144 @@
+// Package profanity detects offensive words in texts written by players,
+// e.g. display names. Texts are normalized before matching to catch
+// leetspeak and separated letters.
+package profanity
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// shortWord is max length of words matched only as the whole word,
+// longer words are matched as part of text too, e.g. "ass" shouldn't
+// reject "class".
+const shortWord = 3
+
+// DefaultWords are used by default filter.
+var DefaultWords = []string{
+	"anal", "anus", "arse", "ass", "bastard", "bitch", "bollock", "boner",
+	"boob", "cock", "crap", "cunt", "dick", "dildo", "douche", "fag",
+	"fuck", "hitler", "jerkoff", "kike", "nazi", "nigga", "nigger", "penis",
+	"piss", "porn", "pussy", "rape", "retard", "scrotum", "sex", "shit",
+	"slut", "spic", "tits", "twat", "vagina", "wank", "whore",
+}
+
+var leet = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'!': 'i',
+	'|': 'i',
+	'3': 'e',
+	'4': 'a',
+	'@': 'a',
+	'5': 's',
+	'$': 's',
+	'7': 't',
+	'+': 't',
+	'8': 'b',
+	'9': 'g',
+}
+
+// Filter matches texts against the list of words.
+type Filter struct {
+	words map[string]bool
+}
+
+// New creates filter of the words, words are normalized as texts.
+func New(words []string) *Filter {
+	f := &Filter{words: make(map[string]bool, len(words))}
+	f.Add(words...)
+
+	return f
+}
+
+// Default creates filter of DefaultWords.
+func Default() *Filter {
+	return New(DefaultWords)
+}
+
+// Load creates filter of DefaultWords and words of the file,
+// file contains a word per line, lines starting with # are skipped.
+func Load(path string) (*Filter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't open words file")
+	}
+	defer file.Close()
+
+	f := Default()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f.Add(line)
+	}
+
+	return f, errors.Wrap(scanner.Err(), "can't read words file")
+}
+
+// Add adds words to the filter.
+func (f *Filter) Add(words ...string) {
+	for _, word := range words {
+		word = strings.Join(normalize(word), "")
+		if word != "" {
+			f.words[word] = true
+		}
+	}
+}
+
+// Contains checks if text contains any word of the filter.
+func (f *Filter) Contains(text string) bool {
+	tokens := normalize(text)
+	// letters separated by spaces or dots are matched as a word too
+	compact := strings.Join(tokens, "")
+
+	for _, token := range append(tokens, compact) {
+		if f.words[token] {
+			return true
+		}
+	}
+
+	for word := range f.words {
+		if len(word) > shortWord && strings.Contains(compact, word) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalize splits text into lower case words, leetspeak
+// characters are replaced by letters.
+func normalize(text string) []string {
+	var tokens []string
+	var token strings.Builder
+
+	for _, r := range strings.ToLower(text) {
+		if replacement, ok := leet[r]; ok {
+			r = replacement
+		}
+
+		if unicode.IsLetter(r) {
+			token.WriteRune(r)
+			continue
+		}
+
+		if token.Len() > 0 {
+			tokens = append(tokens, token.String())
+			token.Reset()
+		}
+	}
+
+	if token.Len() > 0 {
+		tokens = append(tokens, token.String())
+	}
+
+	return tokens
+}
@@ -0,0 +1,39 @@
+package profanity
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContains(t *testing.T) {
+	f := Default()
+
+	for _, text := range []string{"shit", "Sh1tHead", "f.u.c.k", "a$$", "big ass", "s e x", "xXwh0r3Xx"} {
+		require.True(t, f.Contains(text), text)
+	}
+
+	for _, text := range []string{"Guest-12345", "classic", "grass hopper", "Sussex", "Player 1"} {
+		require.False(t, f.Contains(text), text)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file, err := ioutil.TempFile("", "words")
+	require.Nil(t, err)
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString("# custom words\n\nnoob\n")
+	require.Nil(t, err)
+	require.Nil(t, file.Close())
+
+	f, err := Load(file.Name())
+	require.Nil(t, err)
+	require.True(t, f.Contains("N00B"))
+	require.True(t, f.Contains("shit"))
+
+	_, err = Load(file.Name() + ".missing")
+	require.NotNil(t, err)
+}
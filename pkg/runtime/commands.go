@@ -0,0 +1,29 @@
+package runtime
+
+import (
+	"context"
+	"os"
+)
+
+// CommandFunc is one-off task runnable by action name instead
+// of api, args are command line arguments after the action.
+type CommandFunc func(ctx context.Context, args []string) error
+
+// WithCommand registers command of module, e.g. `analytics auth.invite
+// -email=...` runs the command registered by name auth.invite.
+func (r *Runtime) WithCommand(name string, fn CommandFunc) {
+	if r.commands == nil {
+		r.commands = make(map[string]CommandFunc)
+	}
+	r.commands[name] = fn
+}
+
+// runCommand runs registered command of runtime action.
+func (r *Runtime) runCommand(fn CommandFunc) error {
+	var args []string
+	if len(os.Args) > 2 {
+		args = os.Args[2:]
+	}
+
+	return fn(context.Background(), args)
+}
@@ -25,7 +25,7 @@ func (r *Runtime) WithMigrations(path string, pgConfig postgres.Config) error {
 	// only allowed in dev
 	if r.action == dbResetCommand {
 		if !r.spec.Dev() {
-			return errors.Errorf("db reset can't be runnable in dev environment", r.spec.Env)
+			return errors.Errorf("db reset can't be runnable in %s environment", r.spec.Env)
 		}
 
 		err := r.drop(pgConfig)
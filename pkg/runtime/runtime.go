@@ -17,8 +17,14 @@ import (
 	"github.com/ztrue/tracerr"
 	"go.uber.org/zap"
 
+	"gitlab.com/balconygames/analytics/pkg/audit"
 	"gitlab.com/balconygames/analytics/pkg/auth"
+	"gitlab.com/balconygames/analytics/pkg/experiments"
+	pkghttp "gitlab.com/balconygames/analytics/pkg/http"
 	"gitlab.com/balconygames/analytics/pkg/logging"
+	"gitlab.com/balconygames/analytics/pkg/privacy"
+	"gitlab.com/balconygames/analytics/pkg/secrets"
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
 )
 
 var shutdownTimeout = 5 * time.Second
@@ -32,6 +38,22 @@ type Spec struct {
 
 	JWTClientSecret string `envconfig:"JWT_CLIENT_SHA_256_SECRET"`
 	JWTServerSecret string `envconfig:"JWT_SERVER_SHA_256_SECRET"`
+
+	// JWTClientTTL, JWTServerTTL are lifetime of access tokens,
+	// zero means tokens never expire.
+	JWTClientTTL time.Duration `envconfig:"JWT_CLIENT_TTL" default:"1h"`
+	JWTServerTTL time.Duration `envconfig:"JWT_SERVER_TTL" default:"12h"`
+
+	// JWTClientKeysFile, JWTServerKeysFile are paths to JSON key sets
+	// with key ids to rotate keys and use RS256/EdDSA, secrets are
+	// used in case if they are not set.
+	JWTClientKeysFile string `envconfig:"JWT_CLIENT_KEYS_FILE"`
+	JWTServerKeysFile string `envconfig:"JWT_SERVER_KEYS_FILE"`
+
+	// PlatformOrganisationID is organisation of operators managing
+	// games of all organisations and games without owner, other
+	// operators manage only games of their organisation.
+	PlatformOrganisationID string `envconfig:"PLATFORM_ORGANISATION_ID" required:"True"`
 }
 
 func (s Spec) Dev() bool {
@@ -50,6 +72,49 @@ type Runtime struct {
 	closables []Closable
 	readyCh   chan struct{}
 
+	// aliases registered by auth module to resolve
+	// merged users.
+	aliases auth.AliasResolver
+	// revocations registered by auth module to reject
+	// tokens of revoked sessions.
+	revocations auth.RevocationChecker
+	// versions registered by primary module to reject
+	// unsupported client versions.
+	versions auth.VersionChecker
+	// maintenance registered by primary module to reject
+	// client requests during maintenance windows.
+	maintenance auth.MaintenanceChecker
+	// credentials registered by primary module to look up
+	// decrypted credentials of networks.
+	credentials secrets.CredentialsProvider
+	// apiKeys registered by primary module to authenticate
+	// server requests of integrations.
+	apiKeys auth.APIKeyVerifier
+	// audit registered by primary module to store mutations
+	// made by server users.
+	audit audit.Recorder
+	// organisations registered by primary module to restrict
+	// operators to games of their organisation.
+	organisations auth.OrganisationResolver
+	// experiments registered by auth module to stamp
+	// variants of users on events.
+	experiments experiments.Assigner
+	// players registered by auth module to check users
+	// of server requests.
+	players auth.PlayerChecker
+
+	// clientKeys, serverKeys are used to sign and verify tokens.
+	clientKeys *auth.KeySet
+	serverKeys *auth.KeySet
+
+	// personalData contains providers of modules storing
+	// personal data of players.
+	personalData *privacy.Registry
+
+	// commands registered by modules, runnable
+	// by action name.
+	commands map[string]CommandFunc
+
 	errCh chan error
 	dieCh chan os.Signal
 
@@ -57,6 +122,7 @@ type Runtime struct {
 	// types:
 	// default - running web api
 	// migrate - running migrate/migrate package
+	// <command> - running command registered by module
 	action string
 
 	Logger *zap.SugaredLogger
@@ -74,6 +140,8 @@ func New(action string, s Spec) *Runtime {
 		errCh:   make(chan error),
 		spec:    s,
 		action:  action,
+
+		personalData: privacy.NewRegistry(),
 	}
 
 	if r.action == dbMigrateCommand || r.action == dbResetCommand {
@@ -82,6 +150,10 @@ func New(action string, s Spec) *Runtime {
 		return r
 	}
 
+	if err := r.loadKeys(); err != nil {
+		panic(err)
+	}
+
 	if r.action == "api" {
 		// add handlers routing
 		// A good base middleware stack
@@ -155,25 +227,50 @@ func (r *Runtime) WithRoutes(fn func(r chi.Router)) {
 
 func (r *Runtime) WithClientAuth(base chi.Router, fn func(r chi.Router)) {
 	base.Group(func(router chi.Router) {
-		router.Use(auth.NewJWTHttpVerifierMiddleware(r.spec.JWTClientSecret))
-		router.Use(auth.NewJWTUserMiddleware(r.spec.JWTClientSecret))
+		router.Use(auth.NewJWTUserMiddleware(r.clientKeys, runtimeRevocations{r}))
+		router.Use(r.aliasMiddleware)
+		router.Use(auth.NewClientVersionMiddleware(runtimeVersions{r}))
+		router.Use(auth.NewMaintenanceMiddleware(runtimeMaintenance{r}))
 
 		fn(router)
 	})
 }
 
+// WithServerAuth verifies tokens signed by server secret, use
+// auth.RequireRoles per route group to check roles of the server user.
 func (r *Runtime) WithServerAuth(base chi.Router, fn func(r chi.Router)) {
 	base.Group(func(router chi.Router) {
-		router.Use(auth.NewJWTHttpVerifierMiddleware(r.spec.JWTClientSecret))
-		router.Use(auth.NewJWTUserMiddleware(r.spec.JWTClientSecret))
+		router.Use(r.serverAuth)
+		router.Use(auth.NewOrganisationMiddleware(r.spec.PlatformOrganisationID, runtimeOrganisations{r}))
+		router.Use(audit.NewMiddleware(runtimeAudit{r}))
 
 		fn(router)
 	})
 }
 
+// serverAuth authenticates server requests by X-API-Key header of
+// integrations or by JWT token of operators. API keys are allowed
+// only on routes requiring their scopes, see auth.RequireRoleOrScope.
+func (r *Runtime) serverAuth(next http.Handler) http.Handler {
+	withAPIKey := auth.NewAPIKeyMiddleware(runtimeAPIKeys{r})(next)
+	withJWT := r.serverSecretRequired(
+		auth.NewJWTUserMiddleware(r.serverKeys, runtimeRevocations{r})(next))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get(auth.APIKeyHeader) != "" {
+			withAPIKey.ServeHTTP(w, req)
+			return
+		}
+
+		withJWT.ServeHTTP(w, req)
+	})
+}
+
 func (r *Runtime) WithClientTokenSigner(base chi.Router, fn func(r chi.Router)) {
 	base.Group(func(router chi.Router) {
-		router.Use(auth.NewTokenSignerMiddleware(r.spec.JWTClientSecret))
+		router.Use(auth.NewMaintenanceMiddleware(runtimeMaintenance{r}))
+		router.Use(auth.NewTokenSignerMiddleware(
+			auth.NewKeySetSigner(r.clientKeys).WithTTL(r.spec.JWTClientTTL)))
 
 		fn(router)
 	})
@@ -181,12 +278,307 @@ func (r *Runtime) WithClientTokenSigner(base chi.Router, fn func(r chi.Router))
 
 func (r *Runtime) WithServerTokenSigner(base chi.Router, fn func(r chi.Router)) {
 	base.Group(func(router chi.Router) {
-		router.Use(auth.NewTokenSignerMiddleware(r.spec.JWTClientSecret))
+		router.Use(r.serverSecretRequired)
+		router.Use(auth.NewTokenSignerMiddleware(
+			auth.NewKeySetSigner(r.serverKeys).WithTTL(r.spec.JWTServerTTL)))
 
 		fn(router)
 	})
 }
 
+// loadKeys reads key sets from files or falls back to secrets.
+func (r *Runtime) loadKeys() error {
+	load := func(path, secret string) (*auth.KeySet, error) {
+		if path == "" {
+			return auth.NewSecretKeySet(secret), nil
+		}
+		return auth.LoadKeySet(path)
+	}
+
+	var err error
+	r.clientKeys, err = load(r.spec.JWTClientKeysFile, r.spec.JWTClientSecret)
+	if err != nil {
+		return errors.WithMessage(err, "can't load client keys")
+	}
+
+	r.serverKeys, err = load(r.spec.JWTServerKeysFile, r.spec.JWTServerSecret)
+	if err != nil {
+		return errors.WithMessage(err, "can't load server keys")
+	}
+
+	return nil
+}
+
+// JWKSHandler publishes public keys of client and server key sets.
+func (r *Runtime) JWKSHandler(w http.ResponseWriter, req *http.Request) {
+	auth.JWKSHandler(r.clientKeys, r.serverKeys)(w, req)
+}
+
+// serverSecretRequired rejects server requests in case if server secret
+// is not configured, otherwise tokens signed by empty key would be accepted.
+func (r *Runtime) serverSecretRequired(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if r.spec.JWTServerSecret == "" && r.spec.JWTServerKeysFile == "" {
+			pkghttp.Unauthorized(w, errors.New("server auth is not configured"))
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// WithAliasResolver registers resolver of merged users, client
+// requests made with JWT token of merged user are served for the
+// surviving user.
+func (r *Runtime) WithAliasResolver(a auth.AliasResolver) {
+	r.aliases = a
+}
+
+// WithRevocationChecker registers checker of revoked sessions used
+// by client and server auth.
+func (r *Runtime) WithRevocationChecker(c auth.RevocationChecker) {
+	r.revocations = c
+}
+
+// WithVersionChecker registers checker of client versions used
+// by client auth.
+func (r *Runtime) WithVersionChecker(c auth.VersionChecker) {
+	r.versions = c
+}
+
+// WithMaintenanceChecker registers checker of maintenance windows used
+// by client routes.
+func (r *Runtime) WithMaintenanceChecker(c auth.MaintenanceChecker) {
+	r.maintenance = c
+}
+
+// runtimeMaintenance reads checker on every call like aliasMiddleware,
+// games aren't in maintenance until checker is registered.
+type runtimeMaintenance struct {
+	r *Runtime
+}
+
+func (c runtimeMaintenance) CheckMaintenance(ctx context.Context, gameID, appID, locale string) error {
+	if c.r.maintenance == nil {
+		return nil
+	}
+
+	return c.r.maintenance.CheckMaintenance(ctx, gameID, appID, locale)
+}
+
+// runtimeVersions reads checker on every call like aliasMiddleware,
+// all client versions are allowed until checker is registered.
+type runtimeVersions struct {
+	r *Runtime
+}
+
+func (c runtimeVersions) CheckVersion(ctx context.Context, gameID, appID, version string) error {
+	if c.r.versions == nil {
+		return nil
+	}
+
+	return c.r.versions.CheckVersion(ctx, gameID, appID, version)
+}
+
+// WithExperiments registers assigner of experiment variants.
+func (r *Runtime) WithExperiments(a experiments.Assigner) {
+	r.experiments = a
+}
+
+// Experiments respond with assigner of experiment variants, users
+// have no variants until assigner is registered.
+func (r *Runtime) Experiments() experiments.Assigner {
+	return runtimeExperiments{r}
+}
+
+// runtimeExperiments reads assigner on every call like aliasMiddleware.
+type runtimeExperiments struct {
+	r *Runtime
+}
+
+func (a runtimeExperiments) Assign(ctx context.Context, scope *sharedmodels.Scope) (map[string]string, error) {
+	if a.r.experiments == nil {
+		return nil, nil
+	}
+
+	return a.r.experiments.Assign(ctx, scope)
+}
+
+// WithCredentials registers provider of network credentials.
+func (r *Runtime) WithCredentials(p secrets.CredentialsProvider) {
+	r.credentials = p
+}
+
+// Credentials respond with provider of network credentials, credentials
+// are not found until provider is registered.
+func (r *Runtime) Credentials() secrets.CredentialsProvider {
+	return runtimeCredentials{r}
+}
+
+// runtimeCredentials reads provider on every call like aliasMiddleware.
+type runtimeCredentials struct {
+	r *Runtime
+}
+
+func (p runtimeCredentials) LookupCredentials(ctx context.Context, gameID, network, clientID string) (*sharedmodels.NetworkCredentials, error) {
+	if p.r.credentials == nil {
+		return nil, secrets.ErrCredentialsNotFound
+	}
+
+	return p.r.credentials.LookupCredentials(ctx, gameID, network, clientID)
+}
+
+// WithAPIKeys registers verifier of API keys used by server auth.
+func (r *Runtime) WithAPIKeys(v auth.APIKeyVerifier) {
+	r.apiKeys = v
+}
+
+// WithOrganisations registers resolver of organisations owning games.
+func (r *Runtime) WithOrganisations(o auth.OrganisationResolver) {
+	r.organisations = o
+}
+
+// runtimeOrganisations reads resolver on every call like aliasMiddleware,
+// games have no owner until resolver is registered.
+// resolver is registered.
+type runtimeOrganisations struct {
+	r *Runtime
+}
+
+func (o runtimeOrganisations) GameOrganisation(ctx context.Context, gameID string) (string, error) {
+	if o.r.organisations == nil {
+		return "", nil
+	}
+
+	return o.r.organisations.GameOrganisation(ctx, gameID)
+}
+
+func (o runtimeOrganisations) OrganisationExists(ctx context.Context, organisationID string) (bool, error) {
+	if o.r.organisations == nil {
+		return false, nil
+	}
+
+	return o.r.organisations.OrganisationExists(ctx, organisationID)
+}
+
+// Organisations respond with resolver of organisations registered
+// by primary module.
+func (r *Runtime) Organisations() auth.OrganisationResolver {
+	return runtimeOrganisations{r}
+}
+
+// Dev checks if runtime is started in dev, staging or test environment.
+func (r *Runtime) Dev() bool {
+	return r.spec.Dev()
+}
+
+// PlatformOrganisationID respond with organisation of platform operators.
+func (r *Runtime) PlatformOrganisationID() string {
+	return r.spec.PlatformOrganisationID
+}
+
+// WithPlayers registers checker of players of games.
+func (r *Runtime) WithPlayers(p auth.PlayerChecker) {
+	r.players = p
+}
+
+// Players respond with checker of players registered by auth module.
+func (r *Runtime) Players() auth.PlayerChecker {
+	return runtimePlayers{r}
+}
+
+// runtimePlayers reads checker on every call like aliasMiddleware,
+// players are unknown until checker is registered.
+type runtimePlayers struct {
+	r *Runtime
+}
+
+func (p runtimePlayers) PlayerExists(ctx context.Context, gameID, userID string) (bool, error) {
+	if p.r.players == nil {
+		return false, nil
+	}
+
+	return p.r.players.PlayerExists(ctx, gameID, userID)
+}
+
+// WithAuditRecorder registers recorder of mutations made by
+// server users.
+func (r *Runtime) WithAuditRecorder(a audit.Recorder) {
+	r.audit = a
+}
+
+// runtimeAudit reads recorder on every call like aliasMiddleware,
+// mutations aren't recorded until recorder is registered.
+type runtimeAudit struct {
+	r *Runtime
+}
+
+func (a runtimeAudit) Record(ctx context.Context, e *audit.Entry) {
+	if a.r.audit == nil {
+		return
+	}
+
+	a.r.audit.Record(ctx, e)
+}
+
+// runtimeAPIKeys reads verifier on every call like aliasMiddleware,
+// API keys are rejected until verifier is registered.
+type runtimeAPIKeys struct {
+	r *Runtime
+}
+
+func (v runtimeAPIKeys) VerifyAPIKey(ctx context.Context, key string) (*auth.UserInfo, error) {
+	if v.r.apiKeys == nil {
+		return nil, auth.ErrInvalidAPIKey
+	}
+
+	return v.r.apiKeys.VerifyAPIKey(ctx, key)
+}
+
+// runtimeRevocations reads checker on every call like aliasMiddleware,
+// sessions aren't revoked until checker is registered.
+type runtimeRevocations struct {
+	r *Runtime
+}
+
+func (c runtimeRevocations) IsRevoked(ctx context.Context, claims *auth.Claims) (bool, error) {
+	if c.r.revocations == nil {
+		return false, nil
+	}
+
+	return c.r.revocations.IsRevoked(ctx, claims)
+}
+
+// aliasMiddleware reads resolver on every request instead of the
+// time routes are built, so modules may register it in any order.
+func (r *Runtime) aliasMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if r.aliases == nil {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		resolved, err := auth.ResolveUserAlias(req, r.aliases)
+		if err != nil {
+			pkghttp.Error(w, errors.Wrap(err, "can't resolve user alias"))
+			return
+		}
+
+		next.ServeHTTP(w, resolved)
+	})
+}
+
+// WithPersonalData registers provider of module storing personal data
+// of players, it's used to export and delete data on player request.
+func (r *Runtime) WithPersonalData(module string, p privacy.Provider) {
+	r.personalData.Register(module, p)
+}
+
+// PersonalData respond with providers of all modules.
+func (r *Runtime) PersonalData() *privacy.Registry {
+	return r.personalData
+}
+
 func (r *Runtime) WithLogger(env, namespace string) error {
 	l, err := logging.ConfigForEnv(env).Build(
 		zap.Fields(zap.String("project", namespace)),
@@ -217,6 +609,10 @@ func (r *Runtime) Run() error {
 		return nil
 	}
 
+	if fn, ok := r.commands[r.action]; ok {
+		return r.runCommand(fn)
+	}
+
 	s := r.spec
 
 	r.router.Get("/healthz", health)
@@ -0,0 +1,37 @@
+package secrets
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	sharedmodels "gitlab.com/balconygames/analytics/shared/models"
+)
+
+// ErrCredentialsNotFound returned once credentials of the network
+// are not configured for the game.
+var ErrCredentialsNotFound = errors.New("network credentials not found")
+
+// CredentialsProvider respond with decrypted credentials of the game
+// in the network, the first credentials of the network are used once
+// client id is empty.
+type CredentialsProvider interface {
+	LookupCredentials(ctx context.Context, gameID, network, clientID string) (*sharedmodels.NetworkCredentials, error)
+}
+
+type contextKey string
+
+// ContextCredentialsKey should have credentials of the network found
+// by the game of the request.
+const ContextCredentialsKey contextKey = "credentials"
+
+// WithCredentials stores credentials in the context.
+func WithCredentials(ctx context.Context, c *sharedmodels.NetworkCredentials) context.Context {
+	return context.WithValue(ctx, ContextCredentialsKey, c)
+}
+
+// CredentialsFromContext respond with credentials stored by WithCredentials.
+func CredentialsFromContext(ctx context.Context) (*sharedmodels.NetworkCredentials, bool) {
+	c, ok := ctx.Value(ContextCredentialsKey).(*sharedmodels.NetworkCredentials)
+	return c, ok
+}
@@ -0,0 +1,167 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// KeySize is size of AES-256 keys in bytes.
+const KeySize = 32
+
+// ErrUnknownKey returned on decrypting values encrypted by key
+// which is not configured anymore.
+var ErrUnknownKey = errors.New("value is encrypted by unknown key")
+
+// ErrInvalidValue returned on decrypting malformed or changed values.
+var ErrInvalidValue = errors.New("invalid encrypted value")
+
+// prefix is version of encrypted values format.
+const prefix = "v1"
+
+type key struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// Keys encrypts values with AES-256-GCM by the current key and decrypts
+// values encrypted by the current or previous keys, values of previous
+// keys should be re-encrypted on rotation.
+type Keys struct {
+	current *key
+	byID    map[string]*key
+}
+
+// NewKeys builds keys from the current key and previous keys kept to
+// decrypt values until they are re-encrypted. Keys are passed as 32
+// bytes, 64 hex characters or base64 of 32 bytes.
+func NewKeys(current string, previous ...string) (*Keys, error) {
+	keys := &Keys{byID: make(map[string]*key)}
+
+	for i, s := range append([]string{current}, previous...) {
+		k, err := parseKey(s)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid key %d", i)
+		}
+
+		if i == 0 {
+			keys.current = k
+		}
+		keys.byID[k.id] = k
+	}
+
+	return keys, nil
+}
+
+func parseKey(s string) (*key, error) {
+	var raw []byte
+
+	switch {
+	case len(s) == KeySize:
+		raw = []byte(s)
+	case len(s) == hex.EncodedLen(KeySize):
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		raw = b
+	default:
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil || len(b) != KeySize {
+			return nil, errors.Errorf("key should have %d bytes", KeySize)
+		}
+		raw = b
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// id identifies key of encrypted value without exposing the key
+	sum := sha256.Sum256(raw)
+
+	return &key{id: hex.EncodeToString(sum[:4]), aead: aead}, nil
+}
+
+// CurrentKeyID respond with id of the key used to encrypt values.
+func (k *Keys) CurrentKeyID() string {
+	return k.current.id
+}
+
+// Encrypt respond with value encrypted by the current key, associated
+// data binds the value to its owner and should be passed on decrypt.
+func (k *Keys) Encrypt(plaintext, associated []byte) (string, error) {
+	nonce := make([]byte, k.current.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := k.current.aead.Seal(nonce, nonce, plaintext, associated)
+
+	return prefix + ":" + k.current.id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt respond with plain value encrypted by any of keys.
+func (k *Keys) Decrypt(value string, associated []byte) ([]byte, error) {
+	keyID, sealed, err := split(value)
+	if err != nil {
+		return nil, err
+	}
+
+	decryptor, ok := k.byID[keyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	size := decryptor.aead.NonceSize()
+	if len(sealed) < size {
+		return nil, ErrInvalidValue
+	}
+
+	plaintext, err := decryptor.aead.Open(nil, sealed[:size], sealed[size:], associated)
+	if err != nil {
+		return nil, ErrInvalidValue
+	}
+
+	return plaintext, nil
+}
+
+// NeedsRotation checks if the value is encrypted by previous key.
+func (k *Keys) NeedsRotation(value string) bool {
+	keyID, _, err := split(value)
+	return err == nil && keyID != k.current.id
+}
+
+// Encrypted checks if the value is encrypted by Keys, values stored
+// before encryption was introduced are plain.
+func Encrypted(value string) bool {
+	_, _, err := split(value)
+	return err == nil
+}
+
+func split(value string) (string, []byte, error) {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) != 3 || parts[0] != prefix {
+		return "", nil, ErrInvalidValue
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, ErrInvalidValue
+	}
+
+	return parts[1], sealed, nil
+}
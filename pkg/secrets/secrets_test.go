@@ -0,0 +1,66 @@
+package secrets
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	oldKey = "0123456789abcdef0123456789abcdef"
+	newKey = "fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	keys, err := NewKeys(oldKey)
+	require.Nil(t, err)
+
+	value, err := keys.Encrypt([]byte("app-secret"), []byte("game-1:facebook"))
+	require.Nil(t, err)
+	require.False(t, strings.Contains(value, "app-secret"))
+
+	plaintext, err := keys.Decrypt(value, []byte("game-1:facebook"))
+	require.Nil(t, err)
+	require.Equal(t, "app-secret", string(plaintext))
+
+	// values can't be moved to other owners
+	_, err = keys.Decrypt(value, []byte("game-2:facebook"))
+	require.Equal(t, ErrInvalidValue, err)
+
+	_, err = keys.Decrypt("plain", nil)
+	require.Equal(t, ErrInvalidValue, err)
+
+	require.True(t, Encrypted(value))
+	require.False(t, Encrypted("JBSWY3DPEHPK3PXP"))
+}
+
+func TestRotation(t *testing.T) {
+	old, err := NewKeys(oldKey)
+	require.Nil(t, err)
+
+	value, err := old.Encrypt([]byte("app-secret"), nil)
+	require.Nil(t, err)
+
+	rotated, err := NewKeys(newKey, oldKey)
+	require.Nil(t, err)
+	require.True(t, rotated.NeedsRotation(value))
+
+	plaintext, err := rotated.Decrypt(value, nil)
+	require.Nil(t, err)
+
+	value, err = rotated.Encrypt(plaintext, nil)
+	require.Nil(t, err)
+	require.False(t, rotated.NeedsRotation(value))
+
+	_, err = old.Decrypt(value, nil)
+	require.Equal(t, ErrUnknownKey, err)
+}
+
+func TestNewKeysInvalid(t *testing.T) {
+	_, err := NewKeys("short")
+	require.NotNil(t, err)
+
+	_, err = NewKeys(oldKey, "short")
+	require.NotNil(t, err)
+}
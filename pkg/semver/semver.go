@@ -0,0 +1,144 @@
+// Package semver parses and compares semantic versions of apps,
+// e.g. 1.2.3, 1.2 or 1.2.3-beta.1. Build metadata is ignored.
+package semver
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Version is parsed semantic version.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+
+	// Pre is pre-release identifiers, pre-release versions
+	// have lower precedence than release ones.
+	Pre []string
+}
+
+// Parse parses version, missing minor and patch are zero and
+// leading v is allowed, e.g. v1.2 is 1.2.0.
+func Parse(s string) (Version, error) {
+	var v Version
+
+	raw := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.Index(raw, "+"); i >= 0 {
+		raw = raw[:i]
+	}
+	if i := strings.Index(raw, "-"); i >= 0 {
+		v.Pre = strings.Split(raw[i+1:], ".")
+		raw = raw[:i]
+
+		for _, id := range v.Pre {
+			if id == "" {
+				return Version{}, errors.Errorf("invalid version %q: empty pre-release identifier", s)
+			}
+		}
+	}
+
+	parts := strings.Split(raw, ".")
+	if len(parts) > 3 {
+		return Version{}, errors.Errorf("invalid version %q: too many parts", s)
+	}
+
+	numbers := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, errors.Errorf("invalid version %q: %q is not a number", s, part)
+		}
+		*numbers[i] = n
+	}
+
+	return v, nil
+}
+
+// MustParse parses version or panics, used for constants.
+func MustParse(s string) Version {
+	v, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+// Valid checks if version could be parsed.
+func Valid(s string) bool {
+	_, err := Parse(s)
+	return err == nil
+}
+
+// Compare respond with -1, 0 or 1 if v is lower, equal or
+// greater than o by semver precedence.
+func (v Version) Compare(o Version) int {
+	for _, pair := range [][2]int{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if pair[0] != pair[1] {
+			return sign(pair[0] - pair[1])
+		}
+	}
+
+	switch {
+	case len(v.Pre) == 0 && len(o.Pre) == 0:
+		return 0
+	case len(v.Pre) == 0:
+		return 1
+	case len(o.Pre) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.Pre) && i < len(o.Pre); i++ {
+		if c := comparePre(v.Pre[i], o.Pre[i]); c != 0 {
+			return c
+		}
+	}
+
+	return sign(len(v.Pre) - len(o.Pre))
+}
+
+// Less checks if v is lower than o.
+func (v Version) Less(o Version) bool {
+	return v.Compare(o) < 0
+}
+
+func (v Version) String() string {
+	s := strconv.Itoa(v.Major) + "." + strconv.Itoa(v.Minor) + "." + strconv.Itoa(v.Patch)
+	if len(v.Pre) > 0 {
+		s += "-" + strings.Join(v.Pre, ".")
+	}
+
+	return s
+}
+
+// comparePre compares pre-release identifiers, numeric identifiers
+// have lower precedence than alphanumeric ones.
+func comparePre(a, b string) int {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+
+	switch {
+	case errA == nil && errB == nil:
+		return sign(x - y)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+
+	return strings.Compare(a, b)
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+
+	return 0
+}
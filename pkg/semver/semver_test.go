@@ -0,0 +1,37 @@
+package semver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	v, err := Parse("v1.2")
+	require.Nil(t, err)
+	require.Equal(t, "1.2.0", v.String())
+
+	v, err = Parse("1.2.3-beta.1+build.5")
+	require.Nil(t, err)
+	require.Equal(t, Version{Major: 1, Minor: 2, Patch: 3, Pre: []string{"beta", "1"}}, v)
+
+	for _, s := range []string{"", "1.2.3.4", "1.x", "1.2.3-", "1.2.3-beta..1", "-1.0"} {
+		_, err = Parse(s)
+		require.NotNil(t, err, s)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.2", "1.10.0", "2.0.0",
+	}
+
+	for i := 0; i < len(ordered)-1; i++ {
+		a, b := MustParse(ordered[i]), MustParse(ordered[i+1])
+		require.True(t, a.Less(b), "%s < %s", a, b)
+		require.False(t, b.Less(a), "%s > %s", b, a)
+	}
+
+	require.Equal(t, 0, MustParse("1.2").Compare(MustParse("1.2.0+build")))
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// APIKey authenticates server requests of game servers and build
+// pipelines, only hash of the key is stored.
+type APIKey struct {
+	ID     string `json:"id"`
+	GameID string `json:"game_id"`
+	// AppID is empty for keys of all apps of the game
+	AppID string `json:"app_id"`
+
+	Name string `json:"name"`
+	// Prefix is the beginning of the key to recognize it
+	Prefix string   `json:"prefix"`
+	Scopes []string `json:"scopes"`
+
+	// Key is set only once the key is created
+	Key     string `json:"key,omitempty"`
+	KeyHash string `json:"-"`
+
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// ActiveAt checks if the key is not revoked and not expired.
+func (k *APIKey) ActiveAt(t time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+
+	return k.ExpiresAt == nil || t.Before(*k.ExpiresAt)
+}
@@ -1,9 +1,16 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gitlab.com/balconygames/analytics/pkg/semver"
+)
 
 type Game struct {
 	GameID string `json:"game_id"`
+	// OrganisationID is owner of the game, empty for games
+	// managed by platform operators only.
+	OrganisationID string `json:"organisation_id"`
 
 	Name string `json:"name"`
 
@@ -21,10 +28,65 @@ type App struct {
 	Version            string `json:"version"`
 	ForceUpdateEnabled bool   `json:"force_update_enabled"`
 
+	// MinVersion is the lowest client version allowed to use the API,
+	// RecommendedVersion is the version clients are asked to update to.
+	MinVersion         string `json:"min_version"`
+	RecommendedVersion string `json:"recommended_version"`
+	StoreURL           string `json:"store_url"`
+
+	// UpdateStatus is set for the client version requested by client.
+	UpdateStatus string `json:"update_status,omitempty"`
+
+	// Maintenance is active maintenance window of the app and
+	// Announcements are active announcements, both are set for clients.
+	Maintenance   *Maintenance    `json:"maintenance,omitempty"`
+	Announcements []*Announcement `json:"announcements,omitempty"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Update statuses of client version.
+const (
+	UpdateNotRequired = "up_to_date"
+	UpdateRecommended = "recommended"
+	UpdateRequired    = "required"
+)
+
+// RequiredVersion respond with the lowest supported version, the
+// version of the app is required once force update is enabled.
+func (a *App) RequiredVersion() string {
+	required := a.MinVersion
+	if !a.ForceUpdateEnabled || !semver.Valid(a.Version) {
+		return required
+	}
+
+	if !semver.Valid(required) || semver.MustParse(required).Less(semver.MustParse(a.Version)) {
+		return a.Version
+	}
+
+	return required
+}
+
+// UpdateStatusOf respond with update status of the client version,
+// versions are compared by semver precedence.
+func (a *App) UpdateStatusOf(version string) (string, error) {
+	client, err := semver.Parse(version)
+	if err != nil {
+		return "", err
+	}
+
+	if required := a.RequiredVersion(); semver.Valid(required) && client.Less(semver.MustParse(required)) {
+		return UpdateRequired, nil
+	}
+
+	if semver.Valid(a.RecommendedVersion) && client.Less(semver.MustParse(a.RecommendedVersion)) {
+		return UpdateRecommended, nil
+	}
+
+	return UpdateNotRequired, nil
+}
+
 const (
 	GooglePlayMarket = "GOOGLE_PLAY"
 	TapTapMarket     = "TAP_TAP"
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Networks with credentials configured per game.
+const (
+	FacebookNetwork = "facebook"
+	GoogleNetwork   = "google"
+	AppleNetwork    = "apple"
+	TwitterNetwork  = "twitter"
+)
+
+// ValidNetwork checks if credentials of the network are supported.
+func ValidNetwork(network string) bool {
+	switch network {
+	case FacebookNetwork, GoogleNetwork, AppleNetwork, TwitterNetwork:
+		return true
+	}
+	return false
+}
+
+// NetworkCredentials are credentials of the game in social network, e.g.
+// Facebook app id and secret, Google client id and secret or Apple
+// service id with team id, key id in config and private key as secret.
+type NetworkCredentials struct {
+	GameID   string `json:"game_id"`
+	Network  string `json:"network"`
+	ClientID string `json:"client_id"`
+
+	// Config contains settings which are not secret.
+	Config map[string]string `json:"config"`
+	// Secret is encrypted at rest and set only by lookup of credentials.
+	Secret    string `json:"secret,omitempty"`
+	HasSecret bool   `json:"has_secret"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
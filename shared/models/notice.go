@@ -0,0 +1,81 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// DefaultLocale is used for clients of locales without message.
+const DefaultLocale = "default"
+
+// Messages are texts by locale, e.g. en, pt-BR or default.
+type Messages map[string]string
+
+// Localize respond with message of the locale, messages of the language
+// and default locale are used as fallback.
+func (m Messages) Localize(locale string) string {
+	if message, ok := m[locale]; ok {
+		return message
+	}
+
+	if i := strings.Index(locale, "-"); i > 0 {
+		if message, ok := m[locale[:i]]; ok {
+			return message
+		}
+	}
+
+	return m[DefaultLocale]
+}
+
+// Maintenance is window when clients of the game or the app
+// can't use the API.
+type Maintenance struct {
+	ID     string `json:"id"`
+	GameID string `json:"game_id"`
+	// AppID is empty for maintenance of all apps of the game.
+	AppID string `json:"app_id"`
+
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+
+	Messages Messages `json:"messages,omitempty"`
+	// Message is localized for the client.
+	Message string `json:"message,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ActiveAt checks if the window is active at the time.
+func (m *Maintenance) ActiveAt(t time.Time) bool {
+	return !t.Before(m.StartsAt) && t.Before(m.EndsAt)
+}
+
+// Announcement is message shown to clients of the game or the app
+// until it expires, announcements with higher priority go first.
+type Announcement struct {
+	ID     string `json:"id"`
+	GameID string `json:"game_id"`
+	// AppID is empty for announcement of all apps of the game.
+	AppID string `json:"app_id"`
+
+	Priority int `json:"priority"`
+
+	Titles   Messages `json:"titles,omitempty"`
+	Messages Messages `json:"messages,omitempty"`
+	// Title and Message are localized for the client.
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	StartsAt time.Time `json:"starts_at"`
+	// ExpiresAt is empty for announcements shown until deleted.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ActiveAt checks if the announcement is shown at the time.
+func (a *Announcement) ActiveAt(t time.Time) bool {
+	return !t.Before(a.StartsAt) && (a.ExpiresAt == nil || t.Before(*a.ExpiresAt))
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Organisation is studio owning games, operators of organisation
+// manage only its games.
+type Organisation struct {
+	OrganisationID string `json:"organisation_id"`
+
+	Name string `json:"name"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}